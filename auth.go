@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+)
+
+const (
+	// webAuthModeBasic checks the credentials against --web-auth-user and --web-auth-password
+	webAuthModeBasic = "basic"
+	// webAuthModeQTS checks the credentials against the accounts of the administrators group of QTS
+	webAuthModeQTS = "qts"
+
+	// qtsAuthCacheTTL is how long verified credentials are accepted without asking QTS again, so that every scrape
+	// doesn't open a QTS session
+	qtsAuthCacheTTL = 5 * time.Minute
+	qtsAuthTimeout  = 10 * time.Second
+
+	// qtsAuthMinBackoff is how long the credentials of a user are rejected without asking QTS after a failed
+	// attempt, doubled for every failed attempt in a row up to qtsAuthMaxBackoff, so that the exporter can't be used
+	// to guess the passwords of QTS
+	qtsAuthMinBackoff = time.Second
+	qtsAuthMaxBackoff = 5 * time.Minute
+)
+
+// authenticator verifies the credentials sent through HTTP basic authentication
+type authenticator interface {
+	// authenticate reports whether the credentials grant access to the endpoints. An error means that they
+	// couldn't be verified.
+	authenticate(ctx context.Context, user, password string) (bool, error)
+}
+
+// staticAuthenticator accepts a single user name and password
+type staticAuthenticator struct {
+	userHash, passwordHash string
+}
+
+func newStaticAuthenticator(user, password string) staticAuthenticator {
+	return staticAuthenticator{userHash: hashCredential(user), passwordHash: hashCredential(password)}
+}
+
+func (a staticAuthenticator) authenticate(_ context.Context, user, password string) (bool, error) {
+	// Compare hashes so that the comparison takes constant time regardless of the lengths
+	userMatch := subtle.ConstantTimeCompare([]byte(hashCredential(user)), []byte(a.userHash)) == 1
+	passwordMatch := subtle.ConstantTimeCompare([]byte(hashCredential(password)), []byte(a.passwordHash)) == 1
+
+	return userMatch && passwordMatch, nil
+}
+
+// qtsAuthenticator accepts the accounts of the administrators group of QTS, by logging in to the QTS web
+// administration through authLogin.cgi, as the QTS web interface itself does
+type qtsAuthenticator struct {
+	baseURL string
+	client  *http.Client
+	logger  *logging.Logger
+
+	mu sync.Mutex
+	// verified maps the hash of the verified credentials to when they expire
+	verified map[string]time.Time
+	// failures maps the users whose credentials were rejected to the failed attempts in a row
+	failures map[string]qtsAuthFailures
+	// logins maps the users being logged in to QTS to a channel closed once the login is done, so that only one
+	// attempt per user is sent to QTS at a time
+	logins map[string]chan struct{}
+}
+
+// qtsAuthFailures are the failed attempts in a row of a user
+type qtsAuthFailures struct {
+	count int
+	// until is when the credentials of the user are checked with QTS again
+	until time.Time
+}
+
+// qtsLoginResponse is the part of the authLogin.cgi response used to verify the credentials, e.g.:
+//
+//	<QDocRoot version="1.0">
+//	  <authPassed><![CDATA[1]]></authPassed>
+//	  <authSid><![CDATA[x2wdk1u3]]></authSid>
+//	  <isAdmin><![CDATA[1]]></isAdmin>
+//	</QDocRoot>
+type qtsLoginResponse struct {
+	AuthPassed string `xml:"authPassed"`
+	AuthSid    string `xml:"authSid"`
+	IsAdmin    string `xml:"isAdmin"`
+}
+
+func newQtsAuthenticator(baseURL string, logger *logging.Logger) (*qtsAuthenticator, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid QTS URL %q: must be an http or https URL", baseURL)
+	}
+
+	return &qtsAuthenticator{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		client:   &http.Client{Timeout: qtsAuthTimeout},
+		logger:   logger,
+		verified: make(map[string]time.Time),
+		failures: make(map[string]qtsAuthFailures),
+		logins:   make(map[string]chan struct{}),
+	}, nil
+}
+
+func (a *qtsAuthenticator) authenticate(ctx context.Context, user, password string) (bool, error) {
+	if user == "" || password == "" {
+		return false, nil
+	}
+
+	key := hashCredential(user + "\x00" + password)
+	done, ok, err := a.reserveLogin(ctx, user, key)
+	if done == nil {
+		return ok, err
+	}
+	defer a.releaseLogin(user, done)
+
+	ok, err = a.login(ctx, user, password)
+	if err != nil {
+		a.logger.Warn("Error verifying the credentials with QTS", "user", user, "err", err)
+		return false, err
+	}
+	if !ok {
+		a.logger.Info("Rejected the credentials of a request", "user", user)
+		a.recordFailure(user, time.Now())
+		return false, nil
+	}
+
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for k, expiry := range a.verified {
+		if !now.Before(expiry) {
+			delete(a.verified, k)
+		}
+	}
+	a.verified[key] = now.Add(qtsAuthCacheTTL)
+	delete(a.failures, user)
+
+	return true, nil
+}
+
+// reserveLogin reserves the login of user to QTS, waiting for the login of the same user already in progress if any.
+// It returns the channel to pass to releaseLogin once done, or nil along with the outcome if the credentials were
+// decided without asking QTS, from the cache or the backoff of the failed attempts.
+func (a *qtsAuthenticator) reserveLogin(ctx context.Context, user, key string) (chan struct{}, bool, error) {
+	for {
+		now := time.Now()
+		a.mu.Lock()
+		if expiry, found := a.verified[key]; found && now.Before(expiry) {
+			a.mu.Unlock()
+			return nil, true, nil
+		}
+		if failures, failed := a.failures[user]; failed && now.Before(failures.until) {
+			a.mu.Unlock()
+			a.logger.Debug("Rejected the credentials of a request without asking QTS, after failed attempts", "user", user, "failures", failures.count)
+			return nil, false, nil
+		}
+		pending, busy := a.logins[user]
+		if !busy {
+			done := make(chan struct{})
+			a.logins[user] = done
+			a.mu.Unlock()
+			return done, false, nil
+		}
+		a.mu.Unlock()
+
+		select {
+		case <-pending:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+}
+
+// releaseLogin releases the login of user reserved by reserveLogin, waking up the requests waiting for it
+func (a *qtsAuthenticator) releaseLogin(user string, done chan struct{}) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.logins, user)
+	close(done)
+}
+
+// recordFailure records a failed attempt of user at now, backing off further attempts
+func (a *qtsAuthenticator) recordFailure(user string, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for u, f := range a.failures {
+		// The failures of a user are forgotten once the longest backoff has elapsed since the last one
+		if now.Sub(f.until) > qtsAuthMaxBackoff {
+			delete(a.failures, u)
+		}
+	}
+
+	f := a.failures[user]
+	f.count++
+
+	backoff := qtsAuthMinBackoff
+	for i := 1; i < f.count && backoff < qtsAuthMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > qtsAuthMaxBackoff {
+		backoff = qtsAuthMaxBackoff
+	}
+	f.until = now.Add(backoff)
+	a.failures[user] = f
+}
+
+// login logs in to QTS with the credentials, and reports whether they are valid and belong to an administrator.
+// The session opened by a successful login is closed right away.
+func (a *qtsAuthenticator) login(ctx context.Context, user, password string) (bool, error) {
+	form := url.Values{
+		"user": {user},
+		// QTS expects the password to be base64 encoded
+		"pwd": {base64.StdEncoding.EncodeToString([]byte(password))},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/cgi-bin/authLogin.cgi", strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, fmt.Errorf("QTS login: unexpected status %s", resp.Status)
+	}
+
+	var r qtsLoginResponse
+	if err := xml.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return false, fmt.Errorf("parse QTS login response: %w", err)
+	}
+
+	if sid := strings.TrimSpace(r.AuthSid); sid != "" {
+		a.logout(ctx, sid)
+	}
+
+	return strings.TrimSpace(r.AuthPassed) == "1" && strings.TrimSpace(r.IsAdmin) == "1", nil
+}
+
+// logout closes the QTS session sid. A failure only leaves the session to expire on its own.
+func (a *qtsAuthenticator) logout(ctx context.Context, sid string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.baseURL+"/cgi-bin/authLogout.cgi?"+url.Values{"sid": {sid}}.Encode(), nil)
+	if err != nil {
+		return
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		a.logger.Debug("Error closing the QTS session", "err", err)
+		return
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
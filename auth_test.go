@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestQtsAuthenticator(t *testing.T) (*qtsAuthenticator, *atomic.Int64) {
+	t.Helper()
+
+	var logins atomic.Int64
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cgi-bin/authLogin.cgi", func(w http.ResponseWriter, r *http.Request) {
+		logins.Add(1)
+		passed := 0
+		if r.FormValue("user") == "admin" && r.FormValue("pwd") == "c2VjcmV0" { // secret
+			passed = 1
+		}
+		fmt.Fprintf(w, "<QDocRoot><authPassed>%d</authPassed><isAdmin>1</isAdmin></QDocRoot>", passed)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	a, err := newQtsAuthenticator(srv.URL, logging.Discard())
+	require.NoError(t, err)
+
+	return a, &logins
+}
+
+func TestQtsAuthenticatorBacksOffFailedAttempts(t *testing.T) {
+	a, logins := newTestQtsAuthenticator(t)
+	ctx := context.Background()
+
+	ok, err := a.authenticate(ctx, "admin", "guess")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, int64(1), logins.Load())
+
+	// While backing off, even the right password is rejected without asking QTS
+	ok, err = a.authenticate(ctx, "admin", "secret")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, int64(1), logins.Load())
+
+	// The other users are not affected
+	ok, err = a.authenticate(ctx, "guest", "guess")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, int64(2), logins.Load())
+
+	// Once the backoff has elapsed, QTS is asked again, and a success clears the failures
+	a.failures["admin"] = qtsAuthFailures{count: 1, until: time.Now()}
+	ok, err = a.authenticate(ctx, "admin", "secret")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), logins.Load())
+	assert.NotContains(t, a.failures, "admin")
+
+	// Verified credentials are served from the cache
+	ok, err = a.authenticate(ctx, "admin", "secret")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), logins.Load())
+}
+
+func TestQtsAuthenticatorConcurrentAttempts(t *testing.T) {
+	const attempts = 20
+
+	tests := map[string]struct {
+		password       string
+		expectedValid  bool
+		expectedLogins int64
+	}{
+		"wrong password": {password: "guess", expectedValid: false, expectedLogins: 1},
+		"right password": {password: "secret", expectedValid: true, expectedLogins: 1},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			a, logins := newTestQtsAuthenticator(t)
+
+			var wg sync.WaitGroup
+			results := make([]bool, attempts)
+			errs := make([]error, attempts)
+			for i := 0; i < attempts; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					results[i], errs[i] = a.authenticate(context.Background(), "admin", tc.password)
+				}(i)
+			}
+			wg.Wait()
+
+			for i := 0; i < attempts; i++ {
+				assert.NoError(t, errs[i])
+				assert.Equal(t, tc.expectedValid, results[i])
+			}
+			assert.Equal(t, tc.expectedLogins, logins.Load())
+			assert.Empty(t, a.logins)
+		})
+	}
+}
+
+func TestQtsAuthenticatorRecordFailure(t *testing.T) {
+	now := time.Now()
+
+	tests := map[string]struct {
+		failures        int
+		expectedBackoff time.Duration
+	}{
+		"first failure": {failures: 1, expectedBackoff: qtsAuthMinBackoff},
+		"third failure": {failures: 3, expectedBackoff: 4 * qtsAuthMinBackoff},
+		"many failures": {failures: 100, expectedBackoff: qtsAuthMaxBackoff},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			a := &qtsAuthenticator{failures: make(map[string]qtsAuthFailures)}
+			for i := 0; i < tc.failures; i++ {
+				a.recordFailure("admin", now)
+			}
+
+			assert.Equal(t, tc.failures, a.failures["admin"].count)
+			assert.Equal(t, now.Add(tc.expectedBackoff), a.failures["admin"].until)
+		})
+	}
+}
+
+func TestQtsAuthenticatorForgetsOldFailures(t *testing.T) {
+	now := time.Now()
+	a := &qtsAuthenticator{failures: map[string]qtsAuthFailures{
+		"old": {count: 3, until: now.Add(-qtsAuthMaxBackoff - time.Second)},
+	}}
+
+	a.recordFailure("admin", now)
+
+	assert.NotContains(t, a.failures, "old")
+	assert.Contains(t, a.failures, "admin")
+}
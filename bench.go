@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/config"
+	"github.com/pedropombeiro/qnapexporter/lib/exporter/prometheus"
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+)
+
+// runBench implements the `bench` subcommand, which reports the cost of each collector
+func runBench(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	configFile := flags.String("config", "", "Path to a YAML configuration file, used to select the collectors to benchmark.")
+	iterations := flags.Int("n", 10, "Number of times to run each collector.")
+	verbose := flags.Bool("v", false, "Log the exporter output to STDERR.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *iterations <= 0 {
+		return fmt.Errorf("invalid number of iterations: %d", *iterations)
+	}
+
+	cfg, err := loadConfig(*configFile, config.Config{
+		PingMode:       prometheus.PingModeICMP,
+		HostnameSource: prometheus.HostnameSourceOS,
+	})
+	if err != nil {
+		return err
+	}
+
+	logWriter := io.Discard
+	if *verbose {
+		logWriter = os.Stderr
+	}
+	logger := logging.New(logWriter, logging.LevelInfo, logging.FormatText, nil)
+
+	fmt.Printf("Running each collector %d times...\n\n", *iterations)
+	results := prometheus.Benchmark(context.Background(), newExporterConfig(cfg, logger, func() {}), *iterations)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "collector\twall/run\tcpu/run\tallocs/run\tbytes/run\tforks/run\terrors\t")
+	for _, r := range results {
+		runs := int64(r.Runs)
+		fmt.Fprintf(
+			w,
+			"%s\t%v\t%v\t%d\t%d\t%.1f\t%d\t\n",
+			r.Name,
+			(r.WallTime / time.Duration(runs)).Round(time.Microsecond),
+			(r.CPUTime / time.Duration(runs)).Round(time.Microsecond),
+			r.Allocs/uint64(runs),
+			r.AllocBytes/uint64(runs),
+			float64(r.Forks)/float64(runs),
+			r.Errors,
+		)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pedropombeiro/qnapexporter/lib/config"
+	"github.com/pedropombeiro/qnapexporter/lib/exporter/prometheus"
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+)
+
+// runDiff implements the `diff` subcommand, which compares the series exported under two configurations
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	oldConfigFile := flags.String("old-config", "", "Path to the current YAML configuration file (defaults to empty, i.e. the default settings).")
+	newConfigFile := flags.String("new-config", "", "Path to the YAML configuration file to migrate to (defaults to empty, i.e. the default settings).")
+	verbose := flags.Bool("v", false, "Log the exporter output to STDERR.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *oldConfigFile == *newConfigFile {
+		return fmt.Errorf("--old-config and --new-config must be different")
+	}
+
+	baseConfig := config.Config{
+		PingMode:       prometheus.PingModeICMP,
+		HostnameSource: prometheus.HostnameSourceOS,
+	}
+	oldCfg, err := loadConfig(*oldConfigFile, baseConfig)
+	if err != nil {
+		return err
+	}
+	newCfg, err := loadConfig(*newConfigFile, baseConfig)
+	if err != nil {
+		return err
+	}
+
+	logWriter := io.Discard
+	if *verbose {
+		logWriter = os.Stderr
+	}
+	logger := logging.New(logWriter, logging.LevelInfo, logging.FormatText, nil)
+
+	diff := prometheus.Diff(
+		context.Background(),
+		newExporterConfig(oldCfg, logger, func() {}),
+		newExporterConfig(newCfg, logger, func() {}),
+	)
+
+	for _, s := range diff.Removed {
+		fmt.Printf("- %s\n", s)
+	}
+	for _, s := range diff.Added {
+		fmt.Printf("+ %s\n", s)
+	}
+	for _, r := range diff.Renamed {
+		fmt.Printf("~ %s -> %s\n", r.Old, r.New)
+	}
+	fmt.Printf("\n%d removed, %d added, %d renamed\n", len(diff.Removed), len(diff.Added), len(diff.Renamed))
+
+	return nil
+}
@@ -20,7 +20,7 @@ func handleDockerEvents(ctx context.Context, args httpServerArgs, annotator noti
 	cli, err := client.NewClientWithOpts(client.WithAPIVersionNegotiation())
 	if err != nil {
 		exporterStatus.Docker = err.Error()
-		args.logger.Println(err)
+		args.logger.Error("Error connecting to the Docker daemon", "err", err)
 		return err
 	}
 
@@ -31,7 +31,7 @@ func handleDockerEvents(ctx context.Context, args httpServerArgs, annotator noti
 		case err := <-errs:
 			if err != nil {
 				exporterStatus.Docker = err.Error()
-				args.logger.Println(err)
+				args.logger.Error("Error reading Docker events", "err", err)
 
 				select {
 				case <-time.After(10 * time.Second):
@@ -46,7 +46,7 @@ func handleDockerEvents(ctx context.Context, args httpServerArgs, annotator noti
 			t := time.Unix(0, msg.TimeNano)
 			m := strings.Join([]string{msg.Type, msg.Action, msg.Actor.ID, formatDockerActorAttributes(msg.Actor.Attributes)}, " ")
 			exporterStatus.Docker = m
-			args.logger.Printf("%v: %s\n", t, m)
+			args.logger.Info("Docker event", "time", t, "event", m)
 			_, _ = annotator.Post(m, t)
 		case <-ctx.Done():
 			exporterStatus.Docker = "Done"
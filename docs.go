@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/pedropombeiro/qnapexporter/lib/exporter/prometheus"
+)
+
+// runDocs implements the `docs` subcommand, which writes a reference of every metric family the exporter can produce
+func runDocs(args []string) error {
+	flags := flag.NewFlagSet("docs", flag.ExitOnError)
+	format := flags.String("format", prometheus.DocsFormatMarkdown, "Format of the reference: markdown or html.")
+	output := flags.String("o", "", "Path of the file to write the reference to (defaults to empty, i.e. STDOUT).")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return prometheus.WriteMetricDocs(w, *format)
+}
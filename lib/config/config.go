@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes the settings which can be provided through a configuration file
+type Config struct {
+	Port       string          `yaml:"port"`
+	PingTarget string          `yaml:"ping_target"`
+	PingMode   string          `yaml:"ping_mode"`
+	UpsAddress string          `yaml:"ups_address"`
+	UpsName    string          `yaml:"ups_name"`
+	Collectors map[string]bool `yaml:"collectors"`
+
+	GetsysinfoCommands []string `yaml:"getsysinfo_commands"`
+	// ExecPaths are searched for the tools before the inherited PATH, and ExecEnv is added to the environment of the commands
+	ExecPaths []string          `yaml:"exec_paths"`
+	ExecEnv   map[string]string `yaml:"exec_env"`
+
+	Hostname       string `yaml:"hostname"`
+	HostnameSource string `yaml:"hostname_source"`
+
+	MetricNamespace string `yaml:"metric_namespace"`
+	// DeprecatedNamesUntil is the last day (e.g. 2027-06-30) on which the old names of the renamed metrics are emitted
+	DeprecatedNamesUntil string `yaml:"deprecated_names_until"`
+
+	NodeLabel      string            `yaml:"node_label"`
+	DropNodeLabel  bool              `yaml:"drop_node_label"`
+	MachineIDLabel string            `yaml:"machine_id_label"`
+	Labels         map[string]string `yaml:"labels"`
+	// CollectorLabels maps collector names to labels added to the metrics of that collector only
+	CollectorLabels map[string]map[string]string `yaml:"collector_labels"`
+
+	UpsCacheTTL      time.Duration `yaml:"ups_cache_ttl"`
+	ScrapeCacheTTL   time.Duration `yaml:"scrape_cache_ttl"`
+	CollectorTimeout time.Duration `yaml:"collector_timeout"`
+	WatchdogTimeout  time.Duration `yaml:"watchdog_timeout"`
+	WatchdogExit     bool          `yaml:"watchdog_exit"`
+	StaleValueMaxAge time.Duration `yaml:"stale_value_max_age"`
+
+	SmbProbeShare    string `yaml:"smb_probe_share"`
+	SmbProbeUser     string `yaml:"smb_probe_user"`
+	SmbProbePassword string `yaml:"smb_probe_password"`
+
+	ErrorComments  bool   `yaml:"error_comments"`
+	StateFile      string `yaml:"state_file"`
+	EventLogSyslog string `yaml:"event_log_syslog"`
+
+	UpdateCheckInterval    time.Duration `yaml:"update_check_interval"`
+	FirmwareCheckInterval  time.Duration `yaml:"firmware_check_interval"`
+	FirmwareReleaseURL     string        `yaml:"firmware_release_url"`
+	TemperatureTrendWindow time.Duration `yaml:"temperature_trend_window"`
+	VolumeForecastWindow   time.Duration `yaml:"volume_forecast_window"`
+	TopProcesses           int           `yaml:"top_processes"`
+	PowerDrawWatts         float64       `yaml:"power_draw_watts"`
+	ElectricityPrice       float64       `yaml:"electricity_price"`
+	ElectricityCurrency    string        `yaml:"electricity_currency"`
+	TrafficResetDay        int           `yaml:"traffic_reset_day"`
+	VolumeProbe            bool          `yaml:"volume_probe"`
+	VolumeProbeWrite       bool          `yaml:"volume_probe_write"`
+	SafeMode               bool          `yaml:"safe_mode"`
+	LowMemory              bool          `yaml:"low_memory"`
+	QuirksFile             string        `yaml:"quirks_file"`
+	// HealthWeights maps the components of the health score to their weights
+	HealthWeights map[string]float64 `yaml:"health_weights"`
+	// FederateTargets are the other exporters whose metrics are served on the federation endpoint
+	FederateTargets []FederateTarget `yaml:"federate_targets"`
+}
+
+// FederateTarget is another exporter whose metrics are federated
+type FederateTarget struct {
+	Name     string `yaml:"name"`
+	URL      string `yaml:"url"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Load reads the YAML configuration file at path on top of the values already present in c,
+// so that only the settings present in the file are overridden
+func (c *Config) Load(path string) error {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	// The file adds to the labels, so copy them to avoid modifying the map of the configuration it is loaded on top of
+	labels := make(map[string]string, len(c.Labels))
+	for name, value := range c.Labels {
+		labels[name] = value
+	}
+	c.Labels = labels
+
+	// The same applies to the health score weights
+	weights := make(map[string]float64, len(c.HealthWeights))
+	for component, weight := range c.HealthWeights {
+		weights[component] = weight
+	}
+	c.HealthWeights = weights
+
+	// The same applies to the environment variables of the commands
+	env := make(map[string]string, len(c.ExecEnv))
+	for name, value := range c.ExecEnv {
+		env[name] = value
+	}
+	c.ExecEnv = env
+
+	if err := yaml.Unmarshal(contents, c); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// ListenAddresses returns the addresses to serve at, which are separated by commas in Port
+func (c *Config) ListenAddresses() []string {
+	return splitList(c.Port)
+}
+
+// PingTargets returns the hosts to ping, which are separated by commas in PingTarget
+func (c *Config) PingTargets() []string {
+	return splitList(c.PingTarget)
+}
+
+// UpsNames returns the names of the UPS devices to export, which are separated by commas in UpsName
+func (c *Config) UpsNames() []string {
+	return splitList(c.UpsName)
+}
+
+func splitList(s string) []string {
+	var values []string
+	for _, value := range strings.Split(s, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// Validate checks that the configuration only refers to known collectors
+func (c *Config) Validate(collectorNames []string) error {
+	known := make(map[string]bool, len(collectorNames))
+	for _, name := range collectorNames {
+		known[name] = true
+	}
+
+	for name := range c.Collectors {
+		if !known[name] {
+			return fmt.Errorf("unknown collector %q in config file", name)
+		}
+	}
+	for name := range c.CollectorLabels {
+		if !known[name] {
+			return fmt.Errorf("unknown collector %q in collector_labels", name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qnapexporter.yml")
+	err := os.WriteFile(path, []byte(`
+ping_target: 8.8.8.8
+watchdog_timeout: 2m
+collectors:
+  smart: false
+`), 0o644)
+	require.NoError(t, err)
+
+	c := Config{Port: ":9094", PingTarget: "1.1.1.1"}
+	err = c.Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, ":9094", c.Port)
+	assert.Equal(t, "8.8.8.8", c.PingTarget)
+	assert.Equal(t, map[string]bool{"smart": false}, c.Collectors)
+	assert.Equal(t, 2*time.Minute, c.WatchdogTimeout)
+}
+
+func TestLoadLabels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qnapexporter.yml")
+	err := os.WriteFile(path, []byte(`
+node_label: instance_name
+labels:
+  site: office
+`), 0o644)
+	require.NoError(t, err)
+
+	base := Config{Labels: map[string]string{"rack": "1"}}
+	c := base
+	require.NoError(t, c.Load(path))
+
+	assert.Equal(t, "instance_name", c.NodeLabel)
+	assert.Equal(t, map[string]string{"rack": "1", "site": "office"}, c.Labels)
+	assert.Equal(t, map[string]string{"rack": "1"}, base.Labels)
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	var c Config
+	err := c.Load(filepath.Join(t.TempDir(), "missing.yml"))
+	require.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	c := Config{Collectors: map[string]bool{"smart": false}}
+
+	assert.NoError(t, c.Validate([]string{"smart", "ups"}))
+	assert.Error(t, c.Validate([]string{"ups"}))
+
+	c = Config{CollectorLabels: map[string]map[string]string{"ups": {"circuit": "office"}}}
+	assert.NoError(t, c.Validate([]string{"smart", "ups"}))
+	assert.Error(t, c.Validate([]string{"smart"}))
+}
+
+func TestListenAddresses(t *testing.T) {
+	c := Config{Port: "0.0.0.0:9094, [::]:9094"}
+	assert.Equal(t, []string{"0.0.0.0:9094", "[::]:9094"}, c.ListenAddresses())
+
+	c = Config{Port: ":9094"}
+	assert.Equal(t, []string{":9094"}, c.ListenAddresses())
+}
+
+func TestPingTargets(t *testing.T) {
+	testCases := map[string]struct {
+		pingTarget string
+		expected   []string
+	}{
+		"empty":           {},
+		"single target":   {pingTarget: "1.1.1.1", expected: []string{"1.1.1.1"}},
+		"several targets": {pingTarget: "1.1.1.1, 8.8.8.8,,", expected: []string{"1.1.1.1", "8.8.8.8"}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c := Config{PingTarget: tc.pingTarget}
+			assert.Equal(t, tc.expected, c.PingTargets())
+		})
+	}
+}
+
+func TestUpsNames(t *testing.T) {
+	c := Config{UpsName: "qnapups, backup"}
+	assert.Equal(t, []string{"qnapups", "backup"}, c.UpsNames())
+
+	c = Config{}
+	assert.Empty(t, c.UpsNames())
+}
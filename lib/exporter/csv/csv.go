@@ -0,0 +1,90 @@
+package csv
+
+import (
+	"context"
+	gocsv "encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+	promclient "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var header = []string{"metric", "labels", "value", "type", "timestamp", "help"}
+
+type csvExporter struct {
+	gatherer promclient.Gatherer
+}
+
+// NewExporter returns an exporter which renders the metrics gathered from gatherer as CSV, one row per series,
+// so that a snapshot of the metrics can be pasted into a spreadsheet
+func NewExporter(gatherer promclient.Gatherer) exporter.Exporter {
+	return &csvExporter{gatherer: gatherer}
+}
+
+func (e *csvExporter) WriteMetrics(ctx context.Context, w io.Writer) error {
+	// Keep going on errors, so that the metrics from the remaining collectors are still written
+	families, err := e.gatherer.Gather()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	cw := gocsv.NewWriter(w)
+	_ = cw.Write(header)
+
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			var value float64
+			switch f.GetType() {
+			case dto.MetricType_GAUGE:
+				value = m.GetGauge().GetValue()
+			case dto.MetricType_COUNTER:
+				value = m.GetCounter().GetValue()
+			case dto.MetricType_UNTYPED:
+				value = m.GetUntyped().GetValue()
+			default:
+				continue
+			}
+
+			ts := timestamp
+			if m.TimestampMs != nil {
+				ts = time.UnixMilli(m.GetTimestampMs()).UTC().Format(time.RFC3339)
+			}
+
+			_ = cw.Write([]string{
+				f.GetName(),
+				formatLabels(m.GetLabel()),
+				strconv.FormatFloat(value, 'g', -1, 64),
+				strings.ToLower(f.GetType().String()),
+				ts,
+				f.GetHelp(),
+			})
+		}
+	}
+
+	cw.Flush()
+	if flushErr := cw.Error(); flushErr != nil {
+		return flushErr
+	}
+
+	return err
+}
+
+func (e *csvExporter) Close() {
+}
+
+// formatLabels formats the labels sorted by name as name=value pairs separated by semicolons, e.g. node=nas;volume=DataVol1
+func formatLabels(labels []*dto.LabelPair) string {
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		pairs = append(pairs, l.GetName()+"="+l.GetValue())
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ";")
+}
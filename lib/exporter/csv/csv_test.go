@@ -0,0 +1,38 @@
+package csv
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	promclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	registry := promclient.NewRegistry()
+	gauge := promclient.NewGaugeVec(promclient.GaugeOpts{Name: "node_volume_avail_bytes", Help: "Free space, in bytes"}, []string{"node", "volume"})
+	gauge.WithLabelValues("nas", "Data Vol, 1").Set(1024)
+	gauge.WithLabelValues("nas", "Backup").Set(math.NaN())
+	counter := promclient.NewCounter(promclient.CounterOpts{Name: "node_time_seconds", Help: "Uptime", ConstLabels: promclient.Labels{"node": "nas"}})
+	counter.Add(3600.5)
+	require.NoError(t, registry.Register(gauge))
+	require.NoError(t, registry.Register(counter))
+
+	e := NewExporter(registry)
+	defer e.Close()
+
+	b := new(bytes.Buffer)
+	err := e.WriteMetrics(context.Background(), b)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, "metric,labels,value,type,timestamp,help", lines[0])
+	assert.Regexp(t, `^node_time_seconds,node=nas,3600.5,counter,\d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ,Uptime$`, lines[1])
+	assert.Regexp(t, `^node_volume_avail_bytes,node=nas;volume=Backup,NaN,gauge,[^,]+,"Free space, in bytes"$`, lines[2])
+	assert.Regexp(t, `^node_volume_avail_bytes,"node=nas;volume=Data Vol, 1",1024,gauge,[^,]+,"Free space, in bytes"$`, lines[3])
+}
@@ -1,20 +1,31 @@
 package exporter
 
 import (
+	"context"
 	"io"
 	"time"
 )
 
 // Exporter defines an interface for capturing and writing out a set of metrics
 type Exporter interface {
-	WriteMetrics(w io.Writer) error
+	WriteMetrics(ctx context.Context, w io.Writer) error
 	Close()
 }
 
 type Status struct {
 	Branch, Revision, Built, Version string
 
-	Uptime            time.Time
+	// StartTime is when the exporter process started
+	StartTime time.Time
+	// BootTime is when the NAS was last booted, as read by the uptime collector
+	BootTime time.Time
+
+	// EnvironmentRead is when the environment (e.g. devices, volumes and tools) was last read
+	EnvironmentRead time.Time
+
+	// Model is the NAS model reported by getsysinfo, and Firmware the version of the QTS firmware
+	Model, Firmware string
+
 	LastFetch         time.Time
 	LastFetchDuration time.Duration
 	MetricCount       int
@@ -26,4 +37,31 @@ type Status struct {
 	DmCaches          []string
 	DmCacheDevice     string
 	Docker            string
+	// AbsentSubsystems are the collectors skipped because the hardware they read is not present
+	AbsentSubsystems []string
+	// Collectors holds the outcome of each enabled collector in the last fetch
+	Collectors []CollectorStatus
+	// Discovery holds the outcome of each step of the last environment discovery
+	Discovery []DiscoveryStatus
+}
+
+// CollectorStatus describes the outcome of a collector run
+type CollectorStatus struct {
+	Name     string
+	Duration time.Duration
+	// Error is the error returned by the collector, if any
+	Error string
+	// Absent is set if the collector was skipped because the hardware it reads is not present
+	Absent bool
+	// ErrorClass is the coarse class of Error (e.g. timeout), as reported by qnapexporter_collector_error_info
+	ErrorClass string
+}
+
+// DiscoveryStatus describes the outcome of discovering one kind of item (e.g. devices or a tool)
+type DiscoveryStatus struct {
+	Item string
+	// Count is the number of items found
+	Count int
+	// Error is set if the items could not be discovered, as opposed to not being present
+	Error string
 }
@@ -0,0 +1,97 @@
+package influx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+	promclient "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	measurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `)
+	tagEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `)
+)
+
+type influxExporter struct {
+	gatherer promclient.Gatherer
+}
+
+// NewExporter returns an exporter which renders the metrics gathered from gatherer as InfluxDB line protocol.
+// Each metric becomes a measurement named after it, with its labels as tags and a single field named after
+// its type (gauge, counter or value), matching the layout of the Telegraf prometheus input plugin.
+func NewExporter(gatherer promclient.Gatherer) exporter.Exporter {
+	return &influxExporter{gatherer: gatherer}
+}
+
+func (e *influxExporter) WriteMetrics(ctx context.Context, w io.Writer) error {
+	// Keep going on errors, so that the metrics from the remaining collectors are still written
+	families, err := e.gatherer.Gather()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	timestamp := time.Now().UnixNano()
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			var field string
+			var value float64
+			switch f.GetType() {
+			case dto.MetricType_GAUGE:
+				field, value = "gauge", m.GetGauge().GetValue()
+			case dto.MetricType_COUNTER:
+				field, value = "counter", m.GetCounter().GetValue()
+			case dto.MetricType_UNTYPED:
+				field, value = "value", m.GetUntyped().GetValue()
+			default:
+				continue
+			}
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				// Not representable in line protocol
+				continue
+			}
+
+			ts := timestamp
+			if m.TimestampMs != nil {
+				ts = m.GetTimestampMs() * int64(time.Millisecond)
+			}
+
+			_, _ = fmt.Fprintf(w, "%s %s=%s %d\n", formatSeries(f.GetName(), m.GetLabel()), field, strconv.FormatFloat(value, 'g', -1, 64), ts)
+		}
+	}
+
+	return err
+}
+
+func (e *influxExporter) Close() {
+}
+
+// formatSeries formats the measurement and tag set, with the tags sorted by key as recommended by InfluxDB
+func formatSeries(name string, labels []*dto.LabelPair) string {
+	sorted := make([]*dto.LabelPair, 0, len(labels))
+	for _, l := range labels {
+		if l.GetValue() != "" {
+			// Empty tag values are not allowed
+			sorted = append(sorted, l)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].GetName() < sorted[j].GetName() })
+
+	var sb strings.Builder
+	sb.WriteString(measurementEscaper.Replace(name))
+	for _, l := range sorted {
+		sb.WriteByte(',')
+		sb.WriteString(tagEscaper.Replace(l.GetName()))
+		sb.WriteByte('=')
+		sb.WriteString(tagEscaper.Replace(l.GetValue()))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,36 @@
+package influx
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	promclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	registry := promclient.NewRegistry()
+	gauge := promclient.NewGaugeVec(promclient.GaugeOpts{Name: "node_volume_avail_bytes", Help: "Free space"}, []string{"node", "volume", "status"})
+	gauge.WithLabelValues("nas", "Data Vol, 1", "").Set(1024)
+	gauge.WithLabelValues("nas", "Backup", "ready").Set(math.NaN())
+	counter := promclient.NewCounter(promclient.CounterOpts{Name: "node_time_seconds", Help: "Uptime", ConstLabels: promclient.Labels{"node": "nas"}})
+	counter.Add(3600.5)
+	require.NoError(t, registry.Register(gauge))
+	require.NoError(t, registry.Register(counter))
+
+	e := NewExporter(registry)
+	defer e.Close()
+
+	b := new(bytes.Buffer)
+	err := e.WriteMetrics(context.Background(), b)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Regexp(t, `^node_time_seconds,node=nas counter=3600.5 \d+$`, lines[0])
+	assert.Regexp(t, `^node_volume_avail_bytes,node=nas,volume=Data\\ Vol\\,\\ 1 gauge=1024 \d+$`, lines[1])
+}
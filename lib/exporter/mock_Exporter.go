@@ -3,6 +3,7 @@
 package exporter
 
 import (
+	context "context"
 	io "io"
 
 	mock "github.com/stretchr/testify/mock"
@@ -18,13 +19,13 @@ func (_m *MockExporter) Close() {
 	_m.Called()
 }
 
-// WriteMetrics provides a mock function with given fields: w
-func (_m *MockExporter) WriteMetrics(w io.Writer) error {
-	ret := _m.Called(w)
+// WriteMetrics provides a mock function with given fields: ctx, w
+func (_m *MockExporter) WriteMetrics(ctx context.Context, w io.Writer) error {
+	ret := _m.Called(ctx, w)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(io.Writer) error); ok {
-		r0 = rf(w)
+	if rf, ok := ret.Get(0).(func(context.Context, io.Writer) error); ok {
+		r0 = rf(ctx, w)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -0,0 +1,70 @@
+package prometheus
+
+import (
+	"sort"
+	"sync"
+)
+
+// hardwareCollectors are the collectors reading optional hardware, whose presence is
+// reported by qnapexporter_subsystem_present
+var hardwareCollectors = []string{"ups", "sysfan", "enclosurefan", "hwmon", "flashcache", "dmcache", "ssdcache", "zfs"}
+
+// subsystemAbsentError is returned by a collector when the hardware or service it reads is not present,
+// so that the collector is skipped instead of reporting an error on every scrape
+type subsystemAbsentError struct {
+	reason string
+}
+
+func (e subsystemAbsentError) Error() string {
+	return e.reason
+}
+
+// absentSubsystems keeps track of the collectors whose subsystem was found to be absent
+type absentSubsystems struct {
+	mu         sync.Mutex
+	collectors map[string]bool
+}
+
+// add records that the subsystem of collector is absent, returning false if it was already known
+func (a *absentSubsystems) add(collector string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.collectors[collector] {
+		return false
+	}
+	if a.collectors == nil {
+		a.collectors = make(map[string]bool)
+	}
+	a.collectors[collector] = true
+
+	return true
+}
+
+func (a *absentSubsystems) contains(collector string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.collectors[collector]
+}
+
+func (a *absentSubsystems) names() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	names := make([]string, 0, len(a.collectors))
+	for name := range a.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// reset forgets the absent subsystems, so that they are detected again (e.g. a UPS which was plugged in)
+func (a *absentSubsystems) reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.collectors = nil
+}
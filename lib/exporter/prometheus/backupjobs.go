@@ -0,0 +1,195 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupJobCategory is the event log category of the Hybrid Backup Sync (HBS 3) events
+const backupJobCategory = "Hybrid Backup Sync"
+
+var (
+	// backupJobNameRe matches the quoted job name of an HBS 3 event, e.g. `Backup job "Nightly USB" finished.`
+	backupJobNameRe = regexp.MustCompile(`"([^"]+)"`)
+	// backupJobFailedRe and backupJobSucceededRe tell the events which end a job run from the other events (e.g. started)
+	backupJobFailedRe    = regexp.MustCompile(`(?i)\b(failed|failure|error|aborted)\b`)
+	backupJobSucceededRe = regexp.MustCompile(`(?i)\b(finished|completed|succeeded|successfully)\b`)
+	// backupJobTransferredRe matches the amount of data transferred, when it is part of the event, e.g. "Transferred: 1.5 GB"
+	backupJobTransferredRe = regexp.MustCompile(`(?i)transferred(?: size| data)?:?\s*([\d.,]+)\s*([KMGT]?B)\b`)
+)
+
+type backupJobRun struct {
+	job         string
+	time        time.Time
+	success     bool
+	transferred *float64
+}
+
+type backupJob struct {
+	lastRun     time.Time
+	lastSuccess time.Time
+	succeeded   bool
+	transferred *float64
+	runs        map[bool]float64
+}
+
+// backupJobState holds the state of each backup job, which is kept across scrapes to only read new events
+type backupJobState struct {
+	mu     sync.Mutex
+	lastID int64
+	jobs   map[string]*backupJob
+}
+
+func (e *promExporter) getBackupJobMetrics(ctx context.Context) ([]metric, error) {
+	if e.sqlite3 == "" {
+		return nil, subsystemAbsentError{"sqlite3 not found"}
+	}
+	if _, err := e.sys.Stat(eventLogPath); os.IsNotExist(err) {
+		return nil, subsystemAbsentError{fmt.Sprintf("%s not found", eventLogPath)}
+	}
+
+	s := &e.backupJobs
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := readEventLog(e.sys, ctx, e.sqlite3, eventLogPath, s.lastID)
+	if err != nil {
+		return nil, err
+	}
+	s.add(entries)
+
+	return s.metrics(), nil
+}
+
+// parseBackupJobRun returns the outcome of the backup job run ended by entry, if it is the end of an HBS 3 job run
+func parseBackupJobRun(entry eventLogEntry) (backupJobRun, bool) {
+	if !strings.EqualFold(entry.category, backupJobCategory) {
+		return backupJobRun{}, false
+	}
+
+	matches := backupJobNameRe.FindStringSubmatch(entry.desc)
+	if len(matches) < 2 {
+		return backupJobRun{}, false
+	}
+
+	run := backupJobRun{job: matches[1], time: entry.time}
+	switch {
+	case backupJobFailedRe.MatchString(entry.desc):
+	case backupJobSucceededRe.MatchString(entry.desc):
+		run.success = true
+	default:
+		return backupJobRun{}, false
+	}
+
+	if matches := backupJobTransferredRe.FindStringSubmatch(entry.desc); len(matches) == 3 {
+		if size, err := parseVolSize(matches[1] + " " + strings.ToUpper(matches[2])); err == nil {
+			run.transferred = &size
+		}
+	}
+
+	return run, true
+}
+
+func (s *backupJobState) add(entries []eventLogEntry) {
+	if s.jobs == nil {
+		s.jobs = make(map[string]*backupJob)
+	}
+
+	for _, entry := range entries {
+		if entry.id > s.lastID {
+			s.lastID = entry.id
+		}
+
+		run, ok := parseBackupJobRun(entry)
+		if !ok {
+			continue
+		}
+
+		job, found := s.jobs[run.job]
+		if !found {
+			job = &backupJob{runs: make(map[bool]float64)}
+			s.jobs[run.job] = job
+		}
+		job.runs[run.success]++
+		if run.time.Before(job.lastRun) {
+			continue
+		}
+
+		job.lastRun = run.time
+		job.succeeded = run.success
+		job.transferred = run.transferred
+		if run.success {
+			job.lastSuccess = run.time
+		}
+	}
+}
+
+func (s *backupJobState) metrics() []metric {
+	names := make([]string, 0, len(s.jobs))
+	for name := range s.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metrics := make([]metric, 0, len(names)*6)
+	for _, name := range names {
+		job := s.jobs[name]
+		attr := fmt.Sprintf("job=%q", name)
+
+		var succeeded float64
+		if job.succeeded {
+			succeeded = 1
+		}
+		metrics = append(metrics,
+			metric{
+				name:  "node_backup_job_last_run_timestamp_seconds",
+				attr:  attr,
+				value: float64(job.lastRun.Unix()),
+				help:  "Time at which the last run of the Hybrid Backup Sync job ended",
+			},
+			metric{
+				name:  "node_backup_job_last_run_success",
+				attr:  attr,
+				value: succeeded,
+				help:  "Whether the last run of the Hybrid Backup Sync job succeeded",
+			},
+		)
+		if !job.lastSuccess.IsZero() {
+			metrics = append(metrics, metric{
+				name:  "node_backup_job_last_success_timestamp_seconds",
+				attr:  attr,
+				value: float64(job.lastSuccess.Unix()),
+				help:  "Time at which the last successful run of the Hybrid Backup Sync job ended",
+			})
+		}
+		if job.transferred != nil {
+			metrics = append(metrics, metric{
+				name:  "node_backup_job_last_transferred_bytes",
+				attr:  attr,
+				value: *job.transferred,
+				help:  "Amount of data transferred by the last run of the Hybrid Backup Sync job, when reported in the event log",
+			})
+		}
+		for _, success := range []bool{true, false} {
+			result := "failure"
+			if success {
+				result = "success"
+			}
+			metrics = append(metrics, metric{
+				name:       "node_backup_job_runs_total",
+				attr:       fmt.Sprintf("%s,result=%q", attr, result),
+				value:      job.runs[success],
+				help:       "Number of runs of the Hybrid Backup Sync job found in the event log, by result",
+				metricType: "counter",
+			})
+		}
+	}
+
+	return metrics
+}
@@ -0,0 +1,104 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBackupJobRun(t *testing.T) {
+	at := time.Date(2023, 1, 1, 3, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		entry   eventLogEntry
+		wantRun *backupJobRun
+	}{
+		"finished with transferred data": {
+			entry: eventLogEntry{
+				time:     at,
+				category: "Hybrid Backup Sync",
+				desc:     `[Hybrid Backup Sync] Backup job "Nightly USB" finished. Transferred: 1,5 GB`,
+			},
+			wantRun: &backupJobRun{job: "Nightly USB", time: at, success: true, transferred: floatPtr(1.5 * 1024 * 1024 * 1024)},
+		},
+		"failed": {
+			entry: eventLogEntry{
+				time:     at,
+				category: "Hybrid Backup Sync",
+				desc:     `[Hybrid Backup Sync] Backup job "Nightly USB" failed. Error: the destination is not available.`,
+			},
+			wantRun: &backupJobRun{job: "Nightly USB", time: at},
+		},
+		"started": {
+			entry: eventLogEntry{
+				time:     at,
+				category: "Hybrid Backup Sync",
+				desc:     `[Hybrid Backup Sync] Backup job "Nightly USB" started.`,
+			},
+		},
+		"other category": {
+			entry: eventLogEntry{
+				time:     at,
+				category: "Storage & Snapshots",
+				desc:     `Snapshot "daily" finished.`,
+			},
+		},
+		"no job name": {
+			entry: eventLogEntry{
+				time:     at,
+				category: "Hybrid Backup Sync",
+				desc:     "[Hybrid Backup Sync] Finished updating the job list.",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			run, ok := parseBackupJobRun(tc.entry)
+
+			if tc.wantRun == nil {
+				assert.False(t, ok)
+				return
+			}
+			assert.True(t, ok)
+			assert.Equal(t, *tc.wantRun, run)
+		})
+	}
+}
+
+func TestBackupJobState(t *testing.T) {
+	day := time.Date(2023, 1, 1, 3, 0, 0, 0, time.UTC)
+	event := func(id int64, t time.Time, desc string) eventLogEntry {
+		return eventLogEntry{id: id, severity: "information", time: t, category: "Hybrid Backup Sync", desc: desc}
+	}
+
+	var s backupJobState
+	s.add([]eventLogEntry{
+		event(1, day, `Backup job "Nightly USB" finished. Transferred: 200 MB`),
+		event(2, day.Add(time.Hour), `Backup job "Cloud" completed successfully.`),
+		{id: 3, severity: "error", time: day, category: "Storage & Snapshots", desc: "Disk 2 was removed."},
+	})
+	s.add([]eventLogEntry{
+		event(4, day.Add(24*time.Hour), `Backup job "Nightly USB" failed.`),
+	})
+
+	assert.Equal(t, int64(4), s.lastID)
+
+	var metrics []metric
+	for _, m := range s.metrics() {
+		metrics = append(metrics, metric{name: m.name, attr: m.attr, value: m.value})
+	}
+	assert.Equal(t, []metric{
+		{name: "node_backup_job_last_run_timestamp_seconds", attr: `job="Cloud"`, value: float64(day.Add(time.Hour).Unix())},
+		{name: "node_backup_job_last_run_success", attr: `job="Cloud"`, value: 1},
+		{name: "node_backup_job_last_success_timestamp_seconds", attr: `job="Cloud"`, value: float64(day.Add(time.Hour).Unix())},
+		{name: "node_backup_job_runs_total", attr: `job="Cloud",result="success"`, value: 1},
+		{name: "node_backup_job_runs_total", attr: `job="Cloud",result="failure"`, value: 0},
+		{name: "node_backup_job_last_run_timestamp_seconds", attr: `job="Nightly USB"`, value: float64(day.Add(24 * time.Hour).Unix())},
+		{name: "node_backup_job_last_run_success", attr: `job="Nightly USB"`, value: 0},
+		{name: "node_backup_job_last_success_timestamp_seconds", attr: `job="Nightly USB"`, value: float64(day.Unix())},
+		{name: "node_backup_job_runs_total", attr: `job="Nightly USB",result="success"`, value: 1},
+		{name: "node_backup_job_runs_total", attr: `job="Nightly USB",result="failure"`, value: 1},
+	}, metrics)
+}
@@ -0,0 +1,63 @@
+package prometheus
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+// CollectorBenchmark holds the total cost of running a collector a number of times
+type CollectorBenchmark struct {
+	Name   string
+	Runs   int
+	Errors int
+	// WallTime is the elapsed time
+	WallTime time.Duration
+	// CPUTime is the user and system CPU time consumed by the exporter and the processes it started
+	CPUTime    time.Duration
+	Allocs     uint64
+	AllocBytes uint64
+	// Forks is the number of processes started
+	Forks int64
+}
+
+// Benchmark runs each enabled collector the given number of times, one collector at a time,
+// so that the cost of each collector can be measured on the hardware the exporter runs on
+func Benchmark(ctx context.Context, config ExporterConfig, iterations int) []CollectorBenchmark {
+	e := NewExporter(config, &exporter.Status{}).(*promExporter)
+	defer e.Close()
+
+	e.readEnvironment(ctx)
+
+	results := make([]CollectorBenchmark, 0, len(e.fns))
+	for _, c := range e.fns {
+		result := CollectorBenchmark{Name: c.name, Runs: iterations}
+
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		cpuBefore := processCPUTime()
+		forksBefore := utils.ExecCount()
+		start := time.Now()
+
+		for i := 0; i < iterations; i++ {
+			if _, err := c.fn(ctx); err != nil {
+				result.Errors++
+			}
+		}
+
+		result.WallTime = time.Since(start)
+		result.Forks = utils.ExecCount() - forksBefore
+		result.CPUTime = processCPUTime() - cpuBefore
+		runtime.ReadMemStats(&after)
+		result.Allocs = after.Mallocs - before.Mallocs
+		result.AllocBytes = after.TotalAlloc - before.TotalAlloc
+
+		results = append(results, result)
+	}
+
+	return results
+}
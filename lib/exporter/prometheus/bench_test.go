@@ -0,0 +1,32 @@
+package prometheus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBenchmark(t *testing.T) {
+	config := ExporterConfig{
+		Collectors: map[string]bool{},
+		Logger:     logging.Discard(),
+	}
+	for _, name := range CollectorNames() {
+		config.Collectors[name] = name == "uptime" || name == "loadavg"
+	}
+
+	results := Benchmark(context.Background(), config, 3)
+
+	require.Len(t, results, 2)
+	assert.Equal(t, "uptime", results[0].Name)
+	assert.Equal(t, "loadavg", results[1].Name)
+	for _, r := range results {
+		assert.Equal(t, 3, r.Runs)
+		assert.Zero(t, r.Errors)
+		assert.NotZero(t, r.WallTime)
+		assert.Zero(t, r.Forks)
+	}
+}
@@ -0,0 +1,201 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+// virtualBlockDevicePrefixes are the prefixes of the block devices built on the disks:
+// RAID arrays (md), device mapper targets such as storage pools, volumes and caches (dm-) and replicated devices (drbd)
+var virtualBlockDevicePrefixes = []string{"md", "dm-", "drbd"}
+
+// blockDeviceSampler keeps the statistics of the previous scrape, from which the latency of the I/Os completed since is derived
+type blockDeviceSampler struct {
+	mu    sync.Mutex
+	stats map[string]diskStats
+}
+
+func isVirtualBlockDevice(name string) bool {
+	for _, prefix := range virtualBlockDevicePrefixes {
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// discoverVirtualBlockDevices returns the RAID arrays and device mapper devices found in root (e.g. /sys/class/block)
+func discoverVirtualBlockDevices(sys utils.System, root string) ([]string, error) {
+	info, err := sys.ReadDir(root)
+	devices := make([]string, 0, len(info))
+	for _, d := range info {
+		if isVirtualBlockDevice(d.Name()) {
+			devices = append(devices, d.Name())
+		}
+	}
+
+	return devices, err
+}
+
+// getBlockDeviceMetrics reports the I/O statistics of the RAID arrays and device mapper devices, along with the
+// average latency of the I/Os completed since the previous scrape, so that the slow storage pool or volume can be found
+func (e *promExporter) getBlockDeviceMetrics(ctx context.Context) ([]metric, error) {
+	if len(e.virtualDevices) == 0 {
+		return nil, subsystemAbsentError{"no RAID or device mapper devices found"}
+	}
+
+	volumes := make(map[string]string)
+	for volume, stack := range e.volumeDeviceStacks(ctx) {
+		volumes[stack[0]] = volume
+	}
+
+	s := &e.blockDevices
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make(map[string]diskStats, len(e.virtualDevices))
+	var metrics []metric
+	for _, dev := range e.virtualDevices {
+		stat, err := readBlockDeviceStat(e.sys, e.blockDir, dev)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// The device was removed since the discovery
+				continue
+			}
+
+			return metrics, err
+		}
+		stats[dev] = stat
+
+		attr := fmt.Sprintf("device=%q,name=%q,volume=%q", dev, readDmName(e.sys, e.blockDir, dev), volumes[dev])
+		metrics = append(metrics, blockDeviceMetrics(attr, stat)...)
+
+		if prev, found := s.stats[dev]; found {
+			if await, ok := averageLatency(prev.readTimeMs, stat.readTimeMs, prev.readsCompleted, stat.readsCompleted); ok {
+				metrics = append(metrics, metric{
+					name:  "node_block_device_read_await_seconds",
+					attr:  attr,
+					value: await,
+					help:  "Average time taken by the reads completed since the previous scrape",
+				})
+			}
+			if await, ok := averageLatency(prev.writeTimeMs, stat.writeTimeMs, prev.writesCompleted, stat.writesCompleted); ok {
+				metrics = append(metrics, metric{
+					name:  "node_block_device_write_await_seconds",
+					attr:  attr,
+					value: await,
+					help:  "Average time taken by the writes completed since the previous scrape",
+				})
+			}
+		}
+	}
+	s.stats = stats
+
+	return metrics, nil
+}
+
+func blockDeviceMetrics(attr string, s diskStats) []metric {
+	return []metric{
+		{
+			name:       "node_block_device_read_bytes_total",
+			attr:       attr,
+			value:      s.sectorsRead * diskSectorSize,
+			help:       "Total number of bytes read",
+			metricType: "counter",
+		},
+		{
+			name:       "node_block_device_written_bytes_total",
+			attr:       attr,
+			value:      s.sectorsWritten * diskSectorSize,
+			help:       "Total number of bytes written",
+			metricType: "counter",
+		},
+		{
+			name:       "node_block_device_read_ops_total",
+			attr:       attr,
+			value:      s.readsCompleted,
+			help:       "Total number of read operations",
+			metricType: "counter",
+		},
+		{
+			name:       "node_block_device_write_ops_total",
+			attr:       attr,
+			value:      s.writesCompleted,
+			help:       "Total number of write operations",
+			metricType: "counter",
+		},
+		{
+			name:       "node_block_device_read_time_seconds_total",
+			attr:       attr,
+			value:      s.readTimeMs / 1000,
+			help:       "Total time spent reading (its rate divided by the rate of the reads is the average read latency)",
+			metricType: "counter",
+		},
+		{
+			name:       "node_block_device_write_time_seconds_total",
+			attr:       attr,
+			value:      s.writeTimeMs / 1000,
+			help:       "Total time spent writing (its rate divided by the rate of the writes is the average write latency)",
+			metricType: "counter",
+		},
+		{
+			name:       "node_block_device_io_time_seconds_total",
+			attr:       attr,
+			value:      s.ioTimeMs / 1000,
+			help:       "Total time during which the device had I/Os in progress (its rate is the utilization)",
+			metricType: "counter",
+		},
+		{
+			name:  "node_block_device_iops_in_progress",
+			attr:  attr,
+			value: s.iosInProgress,
+			help:  "Number of I/Os currently in progress",
+		},
+	}
+}
+
+// averageLatency returns the average time in seconds taken by the operations completed between two samples,
+// or false if the counters were reset (e.g. the device was recreated). It is 0 if no operation completed.
+func averageLatency(prevTimeMs, timeMs, prevOps, ops float64) (float64, bool) {
+	if timeMs < prevTimeMs || ops < prevOps {
+		return 0, false
+	}
+	if ops == prevOps {
+		return 0, true
+	}
+
+	return (timeMs - prevTimeMs) / (ops - prevOps) / 1000, true
+}
+
+// readBlockDeviceStat reads the I/O statistics of the device from <root>/<device>/stat, e.g.:
+//
+//	120583 3478 19523370 1083421 246032 187539 24189560 2975823 0 1187560 4059244 0 0 0 0
+func readBlockDeviceStat(sys utils.System, root string, dev string) (diskStats, error) {
+	lines, err := sys.ReadFileLines(filepath.Join(root, dev, "stat"))
+	if err != nil {
+		return diskStats{}, err
+	}
+	if len(lines) == 0 {
+		return diskStats{}, fmt.Errorf("parse %s statistics: empty file", dev)
+	}
+
+	return parseDiskStatsFields(dev, strings.Fields(lines[0]))
+}
+
+// readDmName returns the device mapper name of dev (e.g. cachedev1 or vg1-lv1), or an empty string if dev is not a
+// device mapper device
+func readDmName(sys utils.System, root string, dev string) string {
+	name, err := sys.ReadFile(filepath.Join(root, dev, "dm", "name"))
+	if err != nil {
+		return ""
+	}
+
+	return name
+}
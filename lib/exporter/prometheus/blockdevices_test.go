@@ -0,0 +1,88 @@
+package prometheus
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsVirtualBlockDevice(t *testing.T) {
+	testCases := map[string]bool{
+		"md1":     true,
+		"md9":     true,
+		"dm-0":    true,
+		"drbd1":   true,
+		"md":      false,
+		"dm-":     false,
+		"sda":     false,
+		"nvme0n1": false,
+		"loop0":   false,
+	}
+
+	for name, expected := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, expected, isVirtualBlockDevice(name))
+		})
+	}
+}
+
+func TestReadBlockDeviceStat(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "dm-0", "dm"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "dm-0", "stat"),
+		[]byte("  120583     3478 19523370  1083421   246032   187539 24189560  2975823        0  1187560  4059244        0        0        0        0\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "dm-0", "dm", "name"), []byte("cachedev1\n"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "md1"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "md1", "stat"), []byte("1 2 x\n"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sda"), 0o755))
+
+	devices, err := discoverVirtualBlockDevices(utils.System{}, root)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dm-0", "md1"}, devices)
+
+	stat, err := readBlockDeviceStat(utils.System{}, root, "dm-0")
+	require.NoError(t, err)
+	assert.Equal(t, "dm-0", stat.name)
+	assert.Equal(t, 120583.0, stat.readsCompleted)
+	assert.Equal(t, 2975823.0, stat.writeTimeMs)
+	assert.Equal(t, 4059244.0, stat.weightedIoTime)
+
+	_, err = readBlockDeviceStat(utils.System{}, root, "md1")
+	assert.Error(t, err)
+
+	assert.Equal(t, "cachedev1", readDmName(utils.System{}, root, "dm-0"))
+	assert.Empty(t, readDmName(utils.System{}, root, "md1"))
+}
+
+func TestAverageLatency(t *testing.T) {
+	tests := map[string]struct {
+		prevTimeMs, timeMs, prevOps, ops float64
+		want                             float64
+		wantOk                           bool
+	}{
+		"I/Os completed": {
+			prevTimeMs: 1000, timeMs: 1500, prevOps: 100, ops: 150,
+			want: 0.01, wantOk: true,
+		},
+		"no I/O": {
+			prevTimeMs: 1000, timeMs: 1000, prevOps: 100, ops: 100,
+			want: 0, wantOk: true,
+		},
+		"counters reset": {
+			prevTimeMs: 1000, timeMs: 10, prevOps: 100, ops: 1,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := averageLatency(tc.prevTimeMs, tc.timeMs, tc.prevOps, tc.ops)
+
+			assert.Equal(t, tc.wantOk, ok)
+			assert.InDelta(t, tc.want, got, 1e-9)
+		})
+	}
+}
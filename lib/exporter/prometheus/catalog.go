@@ -0,0 +1,369 @@
+package prometheus
+
+// MetricDescription describes a metric family which the exporter can produce
+type MetricDescription struct {
+	Name      string   `json:"name"`
+	Help      string   `json:"help"`
+	Type      string   `json:"type"`
+	Unit      string   `json:"unit,omitempty"`
+	Labels    []string `json:"labels"`
+	Collector string   `json:"collector"`
+	// Deprecated holds the new name of a metric family which was renamed, and is only emitted under its old name
+	// during the transition period
+	Deprecated string `json:"deprecated,omitempty"`
+}
+
+// exporterCollectorName is the collector name under which metrics about the exporter itself are cataloged
+const exporterCollectorName = "exporter"
+
+// metricCatalog lists the metric families produced by each collector, keyed by collector name.
+// Names ending in `*` denote families whose names are derived from data read at runtime.
+var metricCatalog = map[string][]MetricDescription{
+	exporterCollectorName: {
+		{Name: "qnap_exporter_scrape_duration_seconds", Help: "Time taken to collect all the metrics", Type: "gauge", Unit: "seconds"},
+		{Name: "qnap_exporter_collector_duration_seconds", Help: "Time taken by the collector to retrieve its metrics", Type: "gauge", Unit: "seconds", Labels: []string{"collector"}},
+		{Name: "qnap_exporter_collector_success", Help: "Whether the collector succeeded in retrieving its metrics", Type: "gauge", Labels: []string{"collector"}},
+		{Name: "qnapexporter_collector_error_info", Help: "Class of the error which occurred while retrieving the collector metrics (details are logged)", Type: "gauge", Labels: []string{"collector", "error_class"}},
+		{Name: "qnapexporter_collector_data_age_seconds", Help: "Age of the data served for the collector, which is 0 unless it comes from a cache (e.g. the UPS cache, the last-known-good metrics or the scrape cache)", Type: "gauge", Unit: "seconds", Labels: []string{"collector"}},
+		{Name: "qnapexporter_environment_age_seconds", Help: "Time since the environment (hostname, disks, volumes, network interfaces) was last discovered", Type: "gauge", Unit: "seconds"},
+		{Name: "qnapexporter_collector_stale_seconds", Help: "Age of the metrics served for the collector, which is 0 unless the last-known-good metrics are served after a failure (only when stale values are enabled)", Type: "gauge", Unit: "seconds", Labels: []string{"collector"}},
+		{Name: "qnapexporter_degraded", Help: "Whether any collector is disabled, timing out or failing", Type: "gauge"},
+		{Name: "qnapexporter_degraded_reason", Help: "Whether the exporter is degraded for the given reason (disabled, timeout or error)", Type: "gauge", Labels: []string{"reason"}},
+		{Name: "qnapexporter_parse_failures_total", Help: "Number of values which could not be parsed from command output, and were skipped", Type: "counter", Labels: []string{"collector"}},
+		{Name: "qnapexporter_discovery_success", Help: "Whether the last discovery of the item succeeded (0 means its list may be incomplete, see the log)", Type: "gauge", Labels: []string{"item"}},
+		{Name: "qnapexporter_discovery_items", Help: "Number of items found by the last discovery", Type: "gauge", Labels: []string{"item"}},
+		{Name: "qnap_health_score", Help: "Overall health of the NAS, from 0 to 100, as the weighted average of the component scores", Type: "gauge"},
+		{Name: "qnap_health_component_score", Help: "Health score of the component, from 0 (failed) to 100 (healthy)", Type: "gauge", Labels: []string{"component"}},
+		{Name: "qnap_exporter_watchdog_detections_total", Help: "Number of times the watchdog detected a hung collector (only when the watchdog is enabled)", Type: "counter", Labels: []string{"collector"}},
+	},
+	"version": {
+		{Name: "go_program", Help: "Information about qnapexporter", Type: "gauge", Labels: []string{"branch", "revision", "built", "version"}},
+		{Name: "qnap_exporter_build_info", Help: "Version of qnapexporter and of the Go toolchain used to build it", Type: "gauge", Labels: []string{"version", "commit", "goversion"}},
+		{Name: "qnap_exporter_update_available", Help: "Whether a newer release of qnapexporter is available", Type: "gauge", Labels: []string{"latest_version"}},
+	},
+	"config": {
+		{Name: "qnapexporter_config_info", Help: "Setting of the effective configuration of the exporter", Type: "gauge", Labels: []string{"setting", "value"}},
+	},
+	"firmware": {
+		{Name: "qnap_firmware_info", Help: "Version of the installed QTS firmware", Type: "gauge", Labels: []string{"version", "build"}},
+		{Name: "qnap_firmware_update_available", Help: "Whether a newer QTS firmware is available for the model of the NAS (only when the firmware check is enabled)", Type: "gauge", Labels: []string{"current_version", "current_build", "latest_version", "latest_build"}},
+	},
+	"uptime": {
+		{Name: "node_time_seconds", Help: "System uptime measured in seconds", Type: "counter", Unit: "seconds"},
+		{Name: "qnapexporter_uptime_seconds", Help: "Time since the exporter process started, in seconds", Type: "counter", Unit: "seconds"},
+	},
+	"loadavg": {
+		{Name: "node_load1", Help: "1m load average", Type: "gauge"},
+		{Name: "node_load5", Help: "5m load average", Type: "gauge"},
+		{Name: "node_load15", Help: "15m load average", Type: "gauge"},
+		{Name: "node_load1_per_cpu", Help: "1m load average divided by the number of online CPUs", Type: "gauge"},
+		{Name: "node_load5_per_cpu", Help: "5m load average divided by the number of online CPUs", Type: "gauge"},
+		{Name: "node_load15_per_cpu", Help: "15m load average divided by the number of online CPUs", Type: "gauge"},
+		{Name: "node_load_saturated", Help: "Whether the 5m load average exceeds the number of online CPUs", Type: "gauge"},
+	},
+	"cpu": {
+		{Name: "node_cpu_seconds_total", Help: "Seconds each CPU core spent in each mode", Type: "counter", Unit: "seconds", Labels: []string{"cpu", "mode"}},
+		{Name: "node_cpu_count", Help: "Number of physical CPU cores", Type: "gauge"},
+	},
+	"meminfo": {
+		{Name: "node_memory_MemTotal_bytes", Help: "Total usable memory", Type: "gauge", Unit: "bytes"},
+		{Name: "node_memory_MemFree_bytes", Help: "Unused memory", Type: "gauge", Unit: "bytes"},
+		{Name: "node_memory_Cached_bytes", Help: "Memory used by the page cache", Type: "gauge", Unit: "bytes"},
+		{Name: "node_memory_Active_bytes", Help: "Memory used recently", Type: "gauge", Unit: "bytes"},
+		{Name: "node_memory_Inactive_bytes", Help: "Memory not used recently", Type: "gauge", Unit: "bytes"},
+		{Name: "node_memory_SwapTotal_bytes", Help: "Total swap space", Type: "gauge", Unit: "bytes"},
+		{Name: "node_memory_SwapFree_bytes", Help: "Unused swap space", Type: "gauge", Unit: "bytes"},
+		{Name: "node_memory_MemAvailable_bytes", Help: "Memory available for starting new applications", Type: "gauge", Unit: "bytes"},
+		{Name: "node_memory_MemAvailable_bytes_min", Help: "Lowest memory available for starting new applications since boot", Type: "gauge", Unit: "bytes"},
+		{Name: "node_memory_Buffers_bytes", Help: "Memory used by block device buffers", Type: "gauge", Unit: "bytes"},
+		{Name: "node_memory_Slab_bytes", Help: "Memory used by kernel data structures", Type: "gauge", Unit: "bytes"},
+		{Name: "node_memory_Dirty_bytes", Help: "Memory waiting to be written back to disk", Type: "gauge", Unit: "bytes"},
+		{Name: "node_memory_Writeback_bytes", Help: "Memory being written back to disk", Type: "gauge", Unit: "bytes"},
+		{Name: "node_memory_*", Help: "Other /proc/meminfo field (on Linux), suffixed with _bytes for fields measured in kB", Type: "gauge"},
+	},
+	"ups": {
+		{Name: "ups_*", Help: "Numeric NUT variable (e.g. ups_battery_charge for battery.charge), described by the UPS driver", Type: "gauge", Labels: []string{"ups"}},
+		{Name: "ups_ups_status", Help: "UPS status (0: online, 1: charging, 2: on battery, 3: off, 99: unknown, 999: replace battery)", Type: "gauge", Labels: []string{"status", "firmware", "ups"}},
+		{Name: "qnap_exporter_ups_cache_age_seconds", Help: "Age of the UPS metrics served from the cache (only when the UPS cache is enabled)", Type: "gauge", Unit: "seconds"},
+		{Name: "node_ups_info", Help: "Information about the UPS, as reported by the NUT driver", Type: "gauge", Labels: []string{"model", "driver", "serial", "firmware", "ups"}},
+		{Name: "node_ups_status_flag", Help: "Whether the UPS status flag (e.g. OL: online, OB: on battery, LB: low battery) is set", Type: "gauge", Labels: []string{"flag", "ups"}},
+	},
+	"systemp": {
+		{Name: "node_cputmp_C", Help: "CPU temperature", Type: "gauge", Unit: "celsius"},
+		{Name: "node_systmp_C", Help: "System temperature", Type: "gauge", Unit: "celsius"},
+		{Name: "node_cputmp_C_max", Help: "Highest CPU temperature since boot", Type: "gauge", Unit: "celsius"},
+	},
+	"sysfan": {
+		{Name: "node_sysfan_RPM", Help: "System fan speed", Type: "gauge", Unit: "rpm", Labels: []string{"fan", "type"}},
+	},
+	"enclosurefan": {
+		{Name: "node_sysfan_RPM", Help: "Expansion enclosure fan speed", Type: "gauge", Unit: "rpm", Labels: []string{"fan", "type"}},
+	},
+	"fanpolicy": {
+		{Name: "qnap_fan_policy_info", Help: "Fan control configured in QTS: smart (driven by the temperatures) or manual, and its profile or speed level", Type: "gauge", Labels: []string{"mode", "profile"}},
+		{Name: "qnap_fan_policy_threshold_celsius", Help: "Temperature threshold of the fan control configured in QTS", Type: "gauge", Unit: "celsius", Labels: []string{"setting"}},
+	},
+	"hwmon": {
+		{Name: "node_hwmon_temp_celsius", Help: "Temperature reported by a hardware monitoring sensor", Type: "gauge", Unit: "celsius", Labels: []string{"chip", "device", "sensor"}},
+		{Name: "node_hwmon_fan_rpm", Help: "Fan speed reported by a hardware monitoring sensor", Type: "gauge", Unit: "rpm", Labels: []string{"chip", "device", "sensor"}},
+		{Name: "node_hwmon_in_volts", Help: "Voltage reported by a hardware monitoring sensor", Type: "gauge", Unit: "volts", Labels: []string{"chip", "device", "sensor"}},
+		{Name: "node_cputmp_C", Help: "CPU temperature read from hwmon (only when getsysinfo is not available)", Type: "gauge", Unit: "celsius"},
+		{Name: "node_cputmp_C_max", Help: "Highest CPU temperature read from hwmon since boot (only when getsysinfo is not available)", Type: "gauge", Unit: "celsius"},
+	},
+	"hdtemp": {
+		{Name: "node_hdtmp_C", Help: "Hard disk temperature", Type: "gauge", Unit: "celsius", Labels: []string{"hd", "smart"}},
+	},
+	"volume": {
+		{Name: "node_volume_avail_bytes", Help: "Free space in the volume", Type: "gauge", Unit: "bytes", Labels: []string{"volume", "filesystem", "status"}},
+		{Name: "node_volume_size_bytes", Help: "Total size of the volume", Type: "gauge", Unit: "bytes", Labels: []string{"volume", "filesystem", "status"}},
+		{Name: "node_volume_usage_ratio_max", Help: "Highest ratio of used space in the volume since boot", Type: "gauge", Labels: []string{"volume", "filesystem", "status"}},
+		{Name: "node_volume_usage_growth_bytes_per_day", Help: "Trend of the used space in the volume over the volume forecast window, as the least squares slope in bytes per day", Type: "gauge", Unit: "bytes", Labels: []string{"volume", "filesystem", "status"}},
+		{Name: "node_volume_days_until_full", Help: "Estimated number of days until the volume is full, extrapolating the usage growth over the volume forecast window", Type: "gauge", Unit: "days", Labels: []string{"volume", "filesystem", "status"}},
+	},
+	"volumedevices": {
+		{Name: "node_volume_device_info", Help: "Block devices backing each mounted volume, from the mapped device down to the physical disks", Type: "gauge", Labels: []string{"volume", "mountpoint", "device"}},
+	},
+	"getsysinfo": {
+		{Name: "node_getsysinfo_value", Help: "Numeric output of a getsysinfo subcommand configured by the user", Type: "gauge", Labels: []string{"command"}},
+	},
+	"snapshot": {
+		{Name: "node_volume_snapshot_count", Help: "Number of snapshots of the volume", Type: "gauge", Labels: []string{"volume_id"}},
+		{Name: "node_volume_snapshot_reserved_bytes", Help: "Space reserved for the snapshots of the volume", Type: "gauge", Unit: "bytes", Labels: []string{"volume_id"}},
+		{Name: "node_volume_snapshot_used_bytes", Help: "Space used by the snapshots of the volume", Type: "gauge", Unit: "bytes", Labels: []string{"volume_id"}},
+	},
+	"energy": {
+		{Name: "node_power_watts", Help: "Power drawn by the NAS, as measured by the UPS or estimated", Type: "gauge", Labels: []string{"source"}},
+		{Name: "node_energy_kwh_total", Help: "Energy consumed by the NAS, integrated from its power draw", Type: "counter"},
+		{Name: "node_energy_cost_total", Help: "Estimated cost of the energy consumed by the NAS, at the configured electricity price", Type: "counter", Labels: []string{"currency"}},
+	},
+	"quota": {
+		{Name: "node_user_quota_used_bytes", Help: "Space used by the user, as counted by its quota", Type: "gauge", Unit: "bytes", Labels: []string{"volume", "user"}},
+		{Name: "node_user_quota_soft_limit_bytes", Help: "Soft limit of the quota of the user, which may be exceeded during the grace period", Type: "gauge", Unit: "bytes", Labels: []string{"volume", "user"}},
+		{Name: "node_user_quota_hard_limit_bytes", Help: "Hard limit of the quota of the user, beyond which writes fail", Type: "gauge", Unit: "bytes", Labels: []string{"volume", "user"}},
+		{Name: "node_share_quota_used_bytes", Help: "Space used by the shared folder, as counted by its quota", Type: "gauge", Unit: "bytes", Labels: []string{"volume", "share"}},
+		{Name: "node_share_quota_soft_limit_bytes", Help: "Soft limit of the quota of the shared folder, which may be exceeded during the grace period", Type: "gauge", Unit: "bytes", Labels: []string{"volume", "share"}},
+		{Name: "node_share_quota_hard_limit_bytes", Help: "Hard limit of the quota of the shared folder, beyond which writes fail", Type: "gauge", Unit: "bytes", Labels: []string{"volume", "share"}},
+	},
+	"filesystem": {
+		{Name: "node_filesystem_size_bytes", Help: "Filesystem size in bytes", Type: "gauge", Unit: "bytes", Labels: []string{"device", "mountpoint", "fstype"}},
+		{Name: "node_filesystem_avail_bytes", Help: "Filesystem space available to non-root users in bytes", Type: "gauge", Unit: "bytes", Labels: []string{"device", "mountpoint", "fstype"}},
+		{Name: "node_filesystem_files", Help: "Filesystem total file nodes", Type: "gauge", Labels: []string{"device", "mountpoint", "fstype"}},
+		{Name: "node_filesystem_files_free", Help: "Filesystem total free file nodes", Type: "gauge", Labels: []string{"device", "mountpoint", "fstype"}},
+	},
+	"diskstats": {
+		{Name: "node_disk_read_bytes_total", Help: "Total number of bytes read", Type: "counter", Unit: "bytes", Labels: []string{"device"}},
+		{Name: "node_disk_written_bytes_total", Help: "Total number of bytes written", Type: "counter", Unit: "bytes", Labels: []string{"device"}},
+		{Name: "node_disk_read_ops_total", Help: "Total number of read operations", Type: "counter", Labels: []string{"device"}},
+		{Name: "node_disk_write_ops_total", Help: "Total number of write operations", Type: "counter", Labels: []string{"device"}},
+		{Name: "node_disk_reads_merged_total", Help: "Total number of adjacent reads merged into a single operation", Type: "counter", Labels: []string{"device"}},
+		{Name: "node_disk_writes_merged_total", Help: "Total number of adjacent writes merged into a single operation", Type: "counter", Labels: []string{"device"}},
+		{Name: "node_disk_read_time_msec", Help: "# of milliseconds spent reading", Type: "counter", Unit: "milliseconds", Labels: []string{"device"}},
+		{Name: "node_disk_write_time_msec", Help: "# of milliseconds spent writing", Type: "counter", Unit: "milliseconds", Labels: []string{"device"}},
+		{Name: "node_disk_iops_in_progress", Help: "# of I/Os currently in progress", Type: "gauge", Labels: []string{"device"}},
+		{Name: "node_disk_iotime_msec", Help: "# of milliseconds spent doing I/Os", Type: "counter", Unit: "milliseconds", Labels: []string{"device"}},
+		{Name: "node_disk_io_time_weighted_seconds_total", Help: "Time spent doing I/Os weighted by the number of I/Os in progress (its rate is the average queue depth)", Type: "counter", Unit: "seconds", Labels: []string{"device"}},
+	},
+	"blockdevices": {
+		{Name: "node_block_device_read_bytes_total", Help: "Total number of bytes read", Type: "counter", Unit: "bytes", Labels: []string{"device", "name", "volume"}},
+		{Name: "node_block_device_written_bytes_total", Help: "Total number of bytes written", Type: "counter", Unit: "bytes", Labels: []string{"device", "name", "volume"}},
+		{Name: "node_block_device_read_ops_total", Help: "Total number of read operations", Type: "counter", Labels: []string{"device", "name", "volume"}},
+		{Name: "node_block_device_write_ops_total", Help: "Total number of write operations", Type: "counter", Labels: []string{"device", "name", "volume"}},
+		{Name: "node_block_device_read_time_seconds_total", Help: "Total time spent reading (its rate divided by the rate of the reads is the average read latency)", Type: "counter", Unit: "seconds", Labels: []string{"device", "name", "volume"}},
+		{Name: "node_block_device_write_time_seconds_total", Help: "Total time spent writing (its rate divided by the rate of the writes is the average write latency)", Type: "counter", Unit: "seconds", Labels: []string{"device", "name", "volume"}},
+		{Name: "node_block_device_io_time_seconds_total", Help: "Total time during which the device had I/Os in progress (its rate is the utilization)", Type: "counter", Unit: "seconds", Labels: []string{"device", "name", "volume"}},
+		{Name: "node_block_device_iops_in_progress", Help: "Number of I/Os currently in progress", Type: "gauge", Labels: []string{"device", "name", "volume"}},
+		{Name: "node_block_device_read_await_seconds", Help: "Average time taken by the reads completed since the previous scrape", Type: "gauge", Unit: "seconds", Labels: []string{"device", "name", "volume"}},
+		{Name: "node_block_device_write_await_seconds", Help: "Average time taken by the writes completed since the previous scrape", Type: "gauge", Unit: "seconds", Labels: []string{"device", "name", "volume"}},
+	},
+	"flashcache": {
+		{Name: "node_flashcache_*", Help: "Flashcache statistic read from /proc/flashcache (QTS 4 only)", Type: "gauge"},
+	},
+	"dmcache": {
+		{Name: "node_flashcache_cached_blocks", Help: "Number of blocks resident in the cache", Type: "counter"},
+		{Name: "node_flashcache_total_blocks", Help: "Total number of cache blocks", Type: "counter"},
+		{Name: "node_dmcache_used_bytes_total", Help: "Number of blocks resident in the cache", Type: "counter", Unit: "bytes", Labels: []string{"device"}},
+		{Name: "node_dmcache_bytes_total", Help: "Total number of cache blocks", Type: "counter", Unit: "bytes", Labels: []string{"device"}},
+		{Name: "node_flashcache_read_hits", Help: "Number of times a READ bio has been mapped to the cache", Type: "counter", Labels: []string{"device"}},
+		{Name: "node_dmcache_read_hit_total", Help: "Number of times a READ bio has been mapped to the cache", Type: "counter", Labels: []string{"device"}},
+		{Name: "node_flashcache_reads", Help: "Number of times a READ bio has ocurred", Type: "counter", Labels: []string{"device"}},
+		{Name: "node_dmcache_read_total", Help: "Number of times a READ bio has ocurred", Type: "counter", Labels: []string{"device"}},
+		{Name: "node_flashcache_read_hit_percent", Help: "Percentage of READ bios mapped to the cache", Type: "counter", Unit: "percent", Labels: []string{"device"}},
+		{Name: "node_dmcache_read_hit_percent", Help: "Percentage of READ bios mapped to the cache", Type: "counter", Unit: "percent", Labels: []string{"device"}},
+		{Name: "node_flashcache_write_hits", Help: "Number of times a WRITE bio has been mapped to the cache", Type: "counter", Labels: []string{"device"}},
+		{Name: "node_dmcache_write_hit_total", Help: "Number of times a WRITE bio has been mapped to the cache", Type: "counter", Labels: []string{"device"}},
+		{Name: "node_flashcache_writes", Help: "Number of times a WRITE bio has ocurred", Type: "counter", Labels: []string{"device"}},
+		{Name: "node_dmcache_write_total", Help: "Number of times a WRITE bio has ocurred", Type: "counter", Labels: []string{"device"}},
+		{Name: "node_flashcache_write_hit_percent", Help: "Percentage of WRITE bios mapped to the cache", Type: "counter", Unit: "percent", Labels: []string{"device"}},
+		{Name: "node_dmcache_write_hit_percent", Help: "Percentage of WRITE bios mapped to the cache", Type: "counter", Unit: "percent", Labels: []string{"device"}},
+	},
+	"ssdcache": {
+		{Name: "node_ssd_cache_reads_total", Help: "Number of reads from the cached volume", Type: "counter", Labels: []string{"cache_group", "volume"}},
+		{Name: "node_ssd_cache_read_hits_total", Help: "Number of reads served by the SSD cache", Type: "counter", Labels: []string{"cache_group", "volume"}},
+		{Name: "node_ssd_cache_writes_total", Help: "Number of writes to the cached volume", Type: "counter", Labels: []string{"cache_group", "volume"}},
+		{Name: "node_ssd_cache_write_hits_total", Help: "Number of writes to blocks resident in the SSD cache", Type: "counter", Labels: []string{"cache_group", "volume"}},
+		{Name: "node_ssd_cache_read_hit_ratio", Help: "Ratio of reads served by the SSD cache", Type: "gauge", Unit: "ratio", Labels: []string{"cache_group", "volume"}},
+		{Name: "node_ssd_cache_write_hit_ratio", Help: "Ratio of writes to blocks resident in the SSD cache", Type: "gauge", Unit: "ratio", Labels: []string{"cache_group", "volume"}},
+		{Name: "node_ssd_cache_dirty_blocks", Help: "Number of cache blocks not yet written back to the volume", Type: "gauge", Labels: []string{"cache_group", "volume"}},
+		{Name: "node_ssd_cache_promotions_total", Help: "Number of blocks promoted to the SSD cache (dm-cache only)", Type: "counter", Labels: []string{"cache_group", "volume"}},
+		{Name: "node_ssd_cache_demotions_total", Help: "Number of blocks demoted from the SSD cache (dm-cache only)", Type: "counter", Labels: []string{"cache_group", "volume"}},
+		{Name: "node_ssd_cache_used_blocks", Help: "Number of blocks resident in the SSD cache (dm-cache only)", Type: "gauge", Labels: []string{"cache_group", "volume"}},
+		{Name: "node_ssd_cache_blocks", Help: "Total number of blocks in the SSD cache (dm-cache only)", Type: "gauge", Labels: []string{"cache_group", "volume"}},
+	},
+	"zfs": {
+		{Name: "node_zfs_pool_size_bytes", Help: "Total size of the ZFS pool", Type: "gauge", Unit: "bytes", Labels: []string{"pool"}},
+		{Name: "node_zfs_pool_allocated_bytes", Help: "Space allocated in the ZFS pool", Type: "gauge", Unit: "bytes", Labels: []string{"pool"}},
+		{Name: "node_zfs_pool_free_bytes", Help: "Free space in the ZFS pool", Type: "gauge", Unit: "bytes", Labels: []string{"pool"}},
+		{Name: "node_zfs_pool_capacity_ratio", Help: "Ratio of the ZFS pool space which is allocated", Type: "gauge", Unit: "ratio", Labels: []string{"pool"}},
+		{Name: "node_zfs_pool_dedup_ratio", Help: "Deduplication ratio of the ZFS pool", Type: "gauge", Unit: "ratio", Labels: []string{"pool"}},
+		{Name: "node_zfs_pool_fragmentation_ratio", Help: "Fragmentation of the free space of the ZFS pool", Type: "gauge", Unit: "ratio", Labels: []string{"pool"}},
+		{Name: "node_zfs_pool_healthy", Help: "Whether the ZFS pool is ONLINE", Type: "gauge", Labels: []string{"pool"}},
+		{Name: "node_zfs_pool_state", Help: "Whether the ZFS pool is in the given state", Type: "gauge", Labels: []string{"pool", "state"}},
+		{Name: "node_zfs_pool_scrub_in_progress", Help: "Whether a scrub of the ZFS pool is in progress", Type: "gauge", Labels: []string{"pool"}},
+		{Name: "node_zfs_pool_resilver_in_progress", Help: "Whether a resilver of the ZFS pool is in progress", Type: "gauge", Labels: []string{"pool"}},
+		{Name: "node_zfs_pool_scrub_progress_ratio", Help: "Progress of the scrub of the ZFS pool in progress", Type: "gauge", Unit: "ratio", Labels: []string{"pool"}},
+		{Name: "node_zfs_pool_last_scrub_timestamp_seconds", Help: "Time at which the last scrub of the ZFS pool completed", Type: "gauge", Unit: "seconds", Labels: []string{"pool"}},
+		{Name: "node_zfs_pool_last_scrub_errors", Help: "Number of errors found by the last scrub of the ZFS pool", Type: "gauge", Labels: []string{"pool"}},
+		{Name: "node_zfs_pool_data_errors", Help: "Number of data errors reported by the ZFS pool", Type: "gauge", Labels: []string{"pool"}},
+		{Name: "node_zfs_dataset_used_bytes", Help: "Space used by the ZFS dataset and its descendants", Type: "gauge", Unit: "bytes", Labels: []string{"pool", "dataset"}},
+		{Name: "node_zfs_dataset_available_bytes", Help: "Space available to the ZFS dataset", Type: "gauge", Unit: "bytes", Labels: []string{"pool", "dataset"}},
+		{Name: "node_zfs_dataset_compression_ratio", Help: "Compression ratio achieved for the ZFS dataset", Type: "gauge", Unit: "ratio", Labels: []string{"pool", "dataset"}},
+		{Name: "node_zfs_arc_size_bytes", Help: "Current size of the ZFS ARC", Type: "gauge", Unit: "bytes"},
+		{Name: "node_zfs_arc_target_size_bytes", Help: "Target size of the ZFS ARC", Type: "gauge", Unit: "bytes"},
+		{Name: "node_zfs_arc_max_size_bytes", Help: "Maximum size of the ZFS ARC", Type: "gauge", Unit: "bytes"},
+		{Name: "node_zfs_arc_hits_total", Help: "Number of ZFS ARC hits", Type: "counter"},
+		{Name: "node_zfs_arc_misses_total", Help: "Number of ZFS ARC misses", Type: "counter"},
+		{Name: "node_zfs_l2arc_size_bytes", Help: "Current size of the ZFS L2ARC (SSD cache)", Type: "gauge", Unit: "bytes"},
+		{Name: "node_zfs_l2arc_hits_total", Help: "Number of ZFS L2ARC (SSD cache) hits", Type: "counter"},
+		{Name: "node_zfs_l2arc_misses_total", Help: "Number of ZFS L2ARC (SSD cache) misses", Type: "counter"},
+	},
+	"network": {
+		{Name: "node_network_receive_bytes_total", Help: "Total number of bytes received", Type: "counter", Unit: "bytes", Labels: []string{"device"}},
+		{Name: "node_network_transmit_bytes_total", Help: "Total number of bytes transmitted", Type: "counter", Unit: "bytes", Labels: []string{"device"}},
+		{Name: "node_network_monthly_receive_bytes", Help: "Number of bytes received since the start of the monthly accounting period", Type: "gauge", Unit: "bytes", Labels: []string{"device"}},
+		{Name: "node_network_monthly_transmit_bytes", Help: "Number of bytes transmitted since the start of the monthly accounting period", Type: "gauge", Unit: "bytes", Labels: []string{"device"}},
+		{Name: "node_network_monthly_period_start_timestamp_seconds", Help: "Start of the monthly traffic accounting period, in seconds since the epoch", Type: "gauge", Unit: "seconds"},
+		{Name: "node_network_container_receive_bytes_total", Help: "Total number of bytes received by the container bridge", Type: "counter", Unit: "bytes", Labels: []string{"device", "type"}},
+		{Name: "node_network_container_transmit_bytes_total", Help: "Total number of bytes transmitted by the container bridge", Type: "counter", Unit: "bytes", Labels: []string{"device", "type"}},
+		{Name: "node_network_receive_errs_total", Help: "Total number of receive errors", Type: "counter", Labels: []string{"device"}},
+		{Name: "node_network_transmit_errs_total", Help: "Total number of transmit errors", Type: "counter", Labels: []string{"device"}},
+		{Name: "node_network_receive_drop_total", Help: "Total number of received packets dropped", Type: "counter", Labels: []string{"device"}},
+		{Name: "node_network_transmit_drop_total", Help: "Total number of packets dropped before transmission", Type: "counter", Labels: []string{"device"}},
+		{Name: "node_network_carrier", Help: "Whether the interface has a link (carrier)", Type: "gauge", Labels: []string{"device"}},
+		{Name: "node_network_speed_bytes", Help: "Negotiated link speed, in bytes per second", Type: "gauge", Unit: "bytes", Labels: []string{"device"}},
+		{Name: "node_bonding_slaves", Help: "Number of interfaces enslaved to the bond", Type: "gauge", Labels: []string{"master"}},
+		{Name: "node_bonding_active", Help: "Number of interfaces enslaved to the bond which have a link", Type: "gauge", Labels: []string{"master"}},
+	},
+	"ping": {
+		{Name: "node_network_external_roundtrip_time_ms", Help: "Round trip time to the ping target (NaN if unreachable)", Type: "gauge", Unit: "milliseconds", Labels: []string{"target"}},
+		{Name: "node_network_external_packet_loss_ratio", Help: "Ratio of probes to the ping target which got no reply", Type: "gauge", Unit: "ratio", Labels: []string{"target"}},
+	},
+	"diskpower": {
+		{Name: "node_disk_standby", Help: "Whether the disk was in standby (spun down) at the last check", Type: "gauge", Labels: []string{"device"}},
+		{Name: "node_disk_standby_seconds_total", Help: "Time spent by the disk in standby since the exporter started, as found by the periodic checks", Type: "counter", Unit: "seconds", Labels: []string{"device"}},
+		{Name: "node_disk_active_seconds_total", Help: "Time spent by the disk spun up since the exporter started, as found by the periodic checks", Type: "counter", Unit: "seconds", Labels: []string{"device"}},
+		{Name: "node_disk_spinups_total", Help: "Number of times the disk was found spun up after being in standby", Type: "counter", Labels: []string{"device"}},
+		{Name: "node_disk_standby_ratio", Help: "Ratio of the time spent by the disk in standby since the exporter started", Type: "gauge", Labels: []string{"device"}},
+	},
+	"smart": {
+		{Name: "node_disk_smart_healthy", Help: "Whether the device passed the S.M.A.R.T. overall-health self-assessment test", Type: "gauge", Labels: []string{"device", "serial", "model"}},
+		{Name: "node_disk_smart_reallocated_sectors", Help: "Number of reallocated sectors", Type: "gauge", Labels: []string{"device", "serial"}},
+		{Name: "node_disk_smart_pending_sectors", Help: "Number of sectors pending reallocation", Type: "gauge", Labels: []string{"device", "serial"}},
+		{Name: "node_disk_smart_power_on_hours", Help: "Number of hours the device has been powered on", Type: "gauge", Unit: "hours", Labels: []string{"device", "serial"}},
+		{Name: "node_disk_smart_temperature_celsius", Help: "Device temperature as reported by S.M.A.R.T.", Type: "gauge", Unit: "celsius", Labels: []string{"device", "serial"}},
+		{Name: "node_disk_smart_attribute_delta", Help: "Change of the S.M.A.R.T. attribute since it was first seen by the exporter", Type: "gauge", Labels: []string{"device", "serial", "attribute"}},
+		{Name: "node_disk_smart_attribute_delta_per_day", Help: "Average daily change of the S.M.A.R.T. attribute since it was first seen by the exporter", Type: "gauge", Labels: []string{"device", "serial", "attribute"}},
+		{Name: "node_disk_temperature_slope_celsius_per_hour", Help: "Trend of the device temperature over the temperature trend window, as the least squares slope in degrees Celsius per hour", Type: "gauge", Labels: []string{"device", "serial"}},
+	},
+	"externaldisk": {
+		{Name: "node_external_disks", Help: "Number of external USB and eSATA disks connected", Type: "gauge"},
+		{Name: "node_external_disk_info", Help: "External disk connected through USB or eSATA", Type: "gauge", Labels: []string{"device", "bus", "model"}},
+		{Name: "node_external_disk_size_bytes", Help: "Capacity of the external disk", Type: "gauge", Unit: "bytes", Labels: []string{"device"}},
+		{Name: "node_external_disk_temperature_celsius", Help: "Temperature of the external disk as reported by S.M.A.R.T.", Type: "gauge", Unit: "celsius", Labels: []string{"device"}},
+	},
+	"processes": {
+		{Name: "node_processes_pids", Help: "Number of processes", Type: "gauge"},
+		{Name: "node_processes_threads", Help: "Number of threads in all the processes", Type: "gauge"},
+		{Name: "node_process_top_cpu_ratio", Help: "CPU used since the previous scrape by the processes with the command name, among the top processes by CPU usage (1 is one full CPU)", Type: "gauge", Unit: "ratio", Labels: []string{"comm"}},
+		{Name: "node_process_top_resident_memory_bytes", Help: "Resident memory used by the processes with the command name, among the top processes by memory usage", Type: "gauge", Unit: "bytes", Labels: []string{"comm"}},
+		{Name: "node_process_top_count", Help: "Number of processes with the command name, among the top processes by memory usage", Type: "gauge", Labels: []string{"comm"}},
+	},
+	"mdstat": {
+		{Name: "node_md_active", Help: "Whether the md array is active", Type: "gauge", Labels: []string{"md", "level"}},
+		{Name: "node_md_degraded", Help: "Whether the md array has fewer in-sync devices than required", Type: "gauge", Labels: []string{"md"}},
+		{Name: "node_md_disks", Help: "Number of member devices in the md array, by state", Type: "gauge", Labels: []string{"md", "state"}},
+		{Name: "node_md_disks_required", Help: "Number of devices required for the md array to be fully in sync", Type: "gauge", Labels: []string{"md"}},
+		{Name: "node_md_syncing", Help: "Whether the md array is currently resyncing, recovering, reshaping or checking", Type: "gauge", Labels: []string{"md"}},
+		{Name: "node_md_sync_progress_percent", Help: "Progress of the current md array sync action", Type: "gauge", Unit: "percent", Labels: []string{"md", "action"}},
+		{Name: "node_md_sync_speed_bytes_per_second", Help: "Speed of the current md array sync action, in bytes per second", Type: "gauge", Unit: "bytes", Labels: []string{"md", "action"}},
+	},
+	"fileservices": {
+		{Name: "node_fileservice_up", Help: "Whether the file service daemon is running", Type: "gauge", Labels: []string{"protocol"}},
+		{Name: "node_fileservice_connections", Help: "Number of active client sessions (SMB) or established TCP connections (NFS, AFP)", Type: "gauge", Labels: []string{"protocol"}},
+	},
+	"smbprobe": {
+		{Name: "node_smb_probe_success", Help: "Whether a file could be written to and read back from the probe share over SMB", Type: "gauge", Labels: []string{"share"}},
+		{Name: "node_smb_probe_duration_seconds", Help: "Time taken to write, read back and delete a file on the probe share over SMB", Type: "gauge", Unit: "seconds", Labels: []string{"share"}},
+	},
+	"volumeprobe": {
+		{Name: "node_volume_probe_success", Help: "Whether the root of the volume could be probed", Type: "gauge", Labels: []string{"volume"}},
+		{Name: "node_volume_probe_duration_seconds", Help: "Time taken to probe the root of the volume", Type: "gauge", Unit: "seconds", Labels: []string{"volume"}},
+	},
+	"qpkg": {
+		{Name: "node_qpkg_enabled", Help: "Whether the QPKG app is enabled in the App Center", Type: "gauge", Labels: []string{"name", "version"}},
+		{Name: "node_qpkg_running", Help: "Whether the QPKG app is running, according to its init script (only for the enabled apps whose init script has a status command)", Type: "gauge", Labels: []string{"name", "version"}},
+	},
+	"eventlog": {
+		{Name: "node_event_log_events_total", Help: "Number of warning and error events in the QTS system event log", Type: "counter", Labels: []string{"severity", "category"}},
+		{Name: "node_event_log_last_error_timestamp_seconds", Help: "Time of the most recent error event in the QTS system event log", Type: "gauge", Unit: "seconds"},
+	},
+	"backupjobs": {
+		{Name: "node_backup_job_last_run_timestamp_seconds", Help: "Time at which the last run of the Hybrid Backup Sync job ended", Type: "gauge", Unit: "seconds", Labels: []string{"job"}},
+		{Name: "node_backup_job_last_run_success", Help: "Whether the last run of the Hybrid Backup Sync job succeeded", Type: "gauge", Labels: []string{"job"}},
+		{Name: "node_backup_job_last_success_timestamp_seconds", Help: "Time at which the last successful run of the Hybrid Backup Sync job ended", Type: "gauge", Unit: "seconds", Labels: []string{"job"}},
+		{Name: "node_backup_job_last_transferred_bytes", Help: "Amount of data transferred by the last run of the Hybrid Backup Sync job, when reported in the event log", Type: "gauge", Unit: "bytes", Labels: []string{"job"}},
+		{Name: "node_backup_job_runs_total", Help: "Number of runs of the Hybrid Backup Sync job found in the event log, by result", Type: "counter", Labels: []string{"job", "result"}},
+	},
+	"docker": {
+		{Name: "node_container_cpu_seconds_total", Help: "Total CPU time consumed by the container", Type: "counter", Unit: "seconds", Labels: []string{"name", "image"}},
+		{Name: "node_container_memory_usage_bytes", Help: "Memory used by the container, excluding the page cache", Type: "gauge", Unit: "bytes", Labels: []string{"name", "image"}},
+		{Name: "node_container_memory_limit_bytes", Help: "Memory limit of the container", Type: "gauge", Unit: "bytes", Labels: []string{"name", "image"}},
+		{Name: "node_container_network_receive_bytes_total", Help: "Total number of bytes received by the container", Type: "counter", Unit: "bytes", Labels: []string{"name", "image"}},
+		{Name: "node_container_network_transmit_bytes_total", Help: "Total number of bytes transmitted by the container", Type: "counter", Unit: "bytes", Labels: []string{"name", "image"}},
+		{Name: "node_container_restart_count", Help: "Number of times the container has been restarted by the Docker daemon", Type: "gauge", Labels: []string{"name", "image"}},
+	},
+	"dependencies": {
+		{Name: "qnapexporter_dependency_available", Help: "Whether an optional tool or service used by qnapexporter is available", Type: "gauge", Labels: []string{"name"}},
+		{Name: "qnapexporter_subsystem_present", Help: "Whether the hardware read by a collector is present (collectors of absent hardware are skipped)", Type: "gauge", Labels: []string{"subsystem"}},
+	},
+}
+
+// MetricCatalog returns the description of every metric family the exporter can produce,
+// in collector order. Every metric also carries the `node` label. The deprecated names of the renamed families
+// follow their new name.
+func MetricCatalog() []MetricDescription {
+	names := append([]string{exporterCollectorName}, CollectorNames()...)
+	var catalog []MetricDescription
+	for _, name := range names {
+		for _, d := range metricCatalog[name] {
+			d.Collector = name
+			d.Labels = append([]string{"node"}, d.Labels...)
+			catalog = append(catalog, d)
+			for _, r := range metricRenames {
+				if r.to == d.Name {
+					catalog = append(catalog, deprecatedDescription(d, r.from))
+				}
+			}
+		}
+	}
+
+	return catalog
+}
+
+// deprecatedDescription describes the metric family d under its deprecated name from
+func deprecatedDescription(d MetricDescription, from string) MetricDescription {
+	d.Deprecated = d.Name
+	d.Help = deprecatedHelp(d.Help, d.Name)
+	d.Name = from
+
+	return d
+}
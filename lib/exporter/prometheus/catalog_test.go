@@ -0,0 +1,105 @@
+package prometheus
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricCatalogCoversAllCollectors(t *testing.T) {
+	names := CollectorNames()
+	for _, name := range names {
+		assert.NotEmpty(t, metricCatalog[name], "collector %q has no catalog entries", name)
+	}
+	for name := range metricCatalog {
+		if name == exporterCollectorName {
+			continue
+		}
+		assert.Contains(t, names, name, "catalog entry %q does not match any collector", name)
+	}
+}
+
+func TestMetricCatalog(t *testing.T) {
+	catalog := MetricCatalog()
+
+	assert.NotEmpty(t, catalog)
+	for _, d := range catalog {
+		assert.NotEmpty(t, d.Name)
+		assert.NotEmpty(t, d.Help, "metric %q has no help", d.Name)
+		assert.NotEmpty(t, d.Collector)
+		assert.Equal(t, "node", d.Labels[0])
+	}
+}
+
+// TestMetricCatalogMatchesMockData checks that every metric family produced from the outputs captured in
+// testdata/mock is described in the catalog, with the same type and labels
+func TestMetricCatalogMatchesMockData(t *testing.T) {
+	catalog := map[string]MetricDescription{}
+	var patterns []MetricDescription
+	for _, d := range MetricCatalog() {
+		if strings.HasSuffix(d.Name, "*") {
+			patterns = append(patterns, d)
+			continue
+		}
+		catalog[d.Name] = d
+	}
+	describe := func(name string) (MetricDescription, bool) {
+		if d, found := catalog[name]; found {
+			return d, true
+		}
+		for _, d := range patterns {
+			if strings.HasPrefix(name, strings.TrimSuffix(d.Name, "*")) {
+				return d, true
+			}
+		}
+		return MetricDescription{}, false
+	}
+
+	for _, model := range []string{"ts-453d", "hs-264", "ts-219p"} {
+		t.Run(model, func(t *testing.T) {
+			var s exporter.Status
+			e := NewExporter(ExporterConfig{
+				Hostname:         "nas",
+				Backend:          utils.NewMockBackend(mockDataDir(t, model)),
+				WatchdogTimeout:  time.Minute,
+				TopProcesses:     3,
+				PowerDrawWatts:   30,
+				ElectricityPrice: 0.25,
+				Logger:           logging.Discard(),
+			}, &s).(*promExporter)
+			defer e.Close()
+
+			r := e.collectAll(context.Background())
+			require.NoError(t, r.err)
+
+			seen := map[string]bool{}
+			for _, m := range r.metrics {
+				names, _, err := parseAttr(m.attr)
+				require.NoError(t, err, m.name)
+				key := m.name + "{" + strings.Join(names, ",") + "}"
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				d, found := describe(m.name)
+				if !assert.True(t, found, "metric %q is not in the catalog", m.name) {
+					continue
+				}
+				metricType := m.metricType
+				if metricType == "" {
+					metricType = defaultMetricType
+				}
+				assert.Equal(t, d.Type, metricType, "type of metric %q", m.name)
+				assert.Equal(t, d.Labels[1:], append([]string{}, names...), "labels of metric %q", m.name)
+			}
+		})
+	}
+}
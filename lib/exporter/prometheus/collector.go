@@ -0,0 +1,90 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	promclient "github.com/prometheus/client_golang/prometheus"
+)
+
+// Describe implements promclient.Collector. The set of metrics depends on the hardware
+// discovered at runtime, so no descriptors are sent and the collector is treated as unchecked.
+func (e *promExporter) Describe(chan<- *promclient.Desc) {
+}
+
+// Collect implements promclient.Collector, so that the exporter can be registered
+// in a promclient.Registry and embedded in other programs
+func (e *promExporter) Collect(ch chan<- promclient.Metric) {
+	defer e.releaseMemory()
+
+	// Errors are reported through qnapexporter_collector_error_info
+	r := e.collectShared(context.Background())
+	for _, m := range r.metrics {
+		ch <- toConstMetric(r.targetLabels, m)
+	}
+}
+
+func toConstMetric(targetLabels []label, m metric) promclient.Metric {
+	labelNames, labelValues, err := parseAttr(m.attr)
+	targetNames := make([]string, 0, len(targetLabels)+len(labelNames))
+	targetValues := make([]string, 0, len(targetLabels)+len(labelValues))
+	for _, l := range targetLabels {
+		targetNames = append(targetNames, l.name)
+		targetValues = append(targetValues, l.value)
+	}
+	labelNames = append(targetNames, labelNames...)
+	labelValues = append(targetValues, labelValues...)
+
+	desc := promclient.NewDesc(m.name, m.help, labelNames, nil)
+	if err != nil {
+		return promclient.NewInvalidMetric(desc, err)
+	}
+
+	var valueType promclient.ValueType
+	switch m.metricType {
+	case "counter":
+		valueType = promclient.CounterValue
+	case "gauge", "":
+		valueType = promclient.GaugeValue
+	default:
+		valueType = promclient.UntypedValue
+	}
+
+	pm, err := promclient.NewConstMetric(desc, valueType, m.value, labelValues...)
+	if err != nil {
+		return promclient.NewInvalidMetric(desc, err)
+	}
+	if !m.timestamp.IsZero() {
+		pm = promclient.NewMetricWithTimestamp(m.timestamp, pm)
+	}
+
+	return pm
+}
+
+// parseAttr splits a metric attribute string such as `device="sda",type="System"`
+// into its label names and values
+func parseAttr(attr string) (names []string, values []string, err error) {
+	for attr != "" {
+		name, rest, found := strings.Cut(attr, "=")
+		if !found {
+			return nil, nil, fmt.Errorf("parse attributes %q: missing '='", attr)
+		}
+
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse attributes %q: %w", attr, err)
+		}
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse attributes %q: %w", attr, err)
+		}
+
+		names = append(names, strings.TrimSpace(name))
+		values = append(values, value)
+		attr = strings.TrimPrefix(rest[len(quoted):], ",")
+	}
+
+	return names, values, nil
+}
@@ -0,0 +1,125 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	promclient "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAttr(t *testing.T) {
+	names, values, err := parseAttr(`device="sda",type="System \"1\"",fan="1"`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"device", "type", "fan"}, names)
+	assert.Equal(t, []string{"sda", `System "1"`, "1"}, values)
+
+	names, values, err = parseAttr("")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+	assert.Empty(t, values)
+
+	_, _, err = parseAttr(`device=sda`)
+	assert.Error(t, err)
+}
+
+func TestCollect(t *testing.T) {
+	e := &promExporter{
+		ExporterConfig: ExporterConfig{Logger: logging.Discard()},
+		hostname:       "nas",
+		envExpiry:      time.Now().Add(time.Hour),
+		watchdog:       newWatchdog(0, nil, nil),
+	}
+	e.fns = []collector{
+		{
+			name: "test",
+			fn: func(context.Context) ([]metric, error) {
+				return []metric{
+					{name: "node_test_total", attr: `device="sda"`, value: 42, help: "Test metric", metricType: "counter"},
+				}, nil
+			},
+		},
+		{
+			name: "failing",
+			fn: func(context.Context) ([]metric, error) {
+				return nil, errors.New("test error")
+			},
+		},
+	}
+
+	registry := promclient.NewRegistry()
+	require.NoError(t, registry.Register(e))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 9)
+
+	family := families[0]
+	assert.Equal(t, "node_test_total", family.GetName())
+	assert.Equal(t, "Test metric", family.GetHelp())
+	require.Len(t, family.GetMetric(), 1)
+	assert.Equal(t, 42.0, family.GetMetric()[0].GetCounter().GetValue())
+	require.Len(t, family.GetMetric()[0].GetLabel(), 2)
+	assert.Equal(t, "device", family.GetMetric()[0].GetLabel()[0].GetName())
+	assert.Equal(t, "node", family.GetMetric()[0].GetLabel()[1].GetName())
+	assert.Equal(t, "nas", family.GetMetric()[0].GetLabel()[1].GetValue())
+
+	family = families[4]
+	assert.Equal(t, "qnapexporter_collector_data_age_seconds", family.GetName())
+	require.Len(t, family.GetMetric(), 1)
+	assert.Equal(t, "test", family.GetMetric()[0].GetLabel()[0].GetValue())
+	assert.Equal(t, dto.MetricType_GAUGE, family.GetType())
+	assert.Zero(t, family.GetMetric()[0].GetGauge().GetValue())
+
+	family = families[5]
+	assert.Equal(t, "qnapexporter_collector_error_info", family.GetName())
+	require.Len(t, family.GetMetric(), 1)
+	assert.Equal(t, 1.0, family.GetMetric()[0].GetGauge().GetValue())
+	require.Len(t, family.GetMetric()[0].GetLabel(), 3)
+	assert.Equal(t, "failing", family.GetMetric()[0].GetLabel()[0].GetValue())
+	assert.Equal(t, "other", family.GetMetric()[0].GetLabel()[1].GetValue())
+}
+
+func TestCollectWithCollectorTimeout(t *testing.T) {
+	e := &promExporter{
+		ExporterConfig: ExporterConfig{
+			CollectorTimeout: 10 * time.Millisecond,
+			Logger:           logging.Discard(),
+		},
+		envExpiry: time.Now().Add(time.Hour),
+		watchdog:  newWatchdog(0, nil, nil),
+	}
+	e.fns = []collector{
+		{
+			name: "slow",
+			fn: func(ctx context.Context) ([]metric, error) {
+				<-ctx.Done()
+				return []metric{{name: "node_partial"}}, ctx.Err()
+			},
+		},
+	}
+
+	var names []string
+	err := e.collect(
+		context.Background(),
+		func(metrics []metric) {
+			for _, m := range metrics {
+				names = append(names, m.name)
+				if m.name == "qnap_exporter_collector_success" {
+					assert.Equal(t, 0.0, m.value)
+				}
+			}
+		},
+		func(error) {},
+	)
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, names, "node_partial")
+	assert.Contains(t, names, "qnap_exporter_collector_success")
+}
@@ -0,0 +1,100 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigSetting is a setting of the effective configuration, as reported by the configuration summary
+type ConfigSetting struct {
+	Name  string
+	Value string
+}
+
+// Summary returns the settings which tell what the exporter is configured to do, leaving out the unset ones and the
+// secrets (e.g. passwords)
+func (c ExporterConfig) Summary() []ConfigSetting {
+	c = c.withLowMemory()
+	e := &promExporter{ExporterConfig: c}
+	enabled := e.enabledCollectors()
+	names := make([]string, 0, len(enabled))
+	for _, collector := range enabled {
+		names = append(names, collector.name)
+	}
+
+	settings := []ConfigSetting{
+		{Name: "listen_addresses", Value: strings.Join(c.ListenAddresses, ",")},
+		{Name: "endpoints", Value: strings.Join(c.Endpoints, ",")},
+		{Name: "collectors", Value: strings.Join(names, ",")},
+		{Name: "safe_mode", Value: strconv.FormatBool(c.SafeMode)},
+		{Name: "low_memory", Value: strconv.FormatBool(c.LowMemory)},
+		{Name: "collector_timeout", Value: durationSetting(c.CollectorTimeout)},
+		{Name: "watchdog_timeout", Value: durationSetting(c.WatchdogTimeout)},
+		{Name: "scrape_cache_ttl", Value: durationSetting(c.ScrapeCacheTTL)},
+		{Name: "ups_cache_ttl", Value: durationSetting(c.UpsCacheTTL)},
+		{Name: "stale_value_max_age", Value: durationSetting(c.StaleValueMaxAge)},
+		{Name: "update_check_interval", Value: durationSetting(c.UpdateCheckInterval)},
+		{Name: "firmware_check_interval", Value: durationSetting(c.FirmwareCheckInterval)},
+		{Name: "temperature_trend_window", Value: durationSetting(c.TemperatureTrendWindow)},
+		{Name: "volume_forecast_window", Value: durationSetting(c.VolumeForecastWindow)},
+		{Name: "metric_namespace", Value: c.MetricNamespace},
+		{Name: "deprecated_names_until", Value: deprecatedNamesUntilSetting(c.DeprecatedNamesUntil)},
+		{Name: "hostname_source", Value: c.HostnameSource},
+		{Name: "ping_targets", Value: strings.Join(c.PingTargets, ",")},
+		{Name: "ping_mode", Value: c.PingMode},
+		{Name: "ups_address", Value: c.UpsAddress},
+		{Name: "ups_names", Value: strings.Join(c.UpsNames, ",")},
+		{Name: "state_file", Value: c.StateFile},
+		{Name: "quirks_file", Value: c.QuirksFile},
+		{Name: "smb_probe_share", Value: c.SmbProbeShare},
+		{Name: "event_log_syslog", Value: c.EventLogSyslog},
+	}
+
+	summary := make([]ConfigSetting, 0, len(settings))
+	for _, s := range settings {
+		if s.Value != "" {
+			summary = append(summary, s)
+		}
+	}
+
+	return summary
+}
+
+// durationSetting formats d, with 0 meaning that the setting is disabled
+func durationSetting(d time.Duration) string {
+	if d <= 0 {
+		return "disabled"
+	}
+
+	return d.String()
+}
+
+// deprecatedNamesUntilSetting formats the end date of the deprecated metric names, with the zero date meaning that
+// they are emitted until they are dropped from the exporter
+func deprecatedNamesUntilSetting(until time.Time) string {
+	if until.IsZero() {
+		return ""
+	}
+
+	return until.Format(DeprecatedNamesDateLayout)
+}
+
+// getConfigMetrics reports the effective configuration, so that what the exporter is configured to do can be
+// answered from Prometheus when its configuration file isn't accessible
+func (e *promExporter) getConfigMetrics(ctx context.Context) ([]metric, error) {
+	settings := e.ExporterConfig.Summary()
+	metrics := make([]metric, 0, len(settings))
+	for _, s := range settings {
+		metrics = append(metrics, metric{
+			name:  "qnapexporter_config_info",
+			attr:  fmt.Sprintf("setting=%q,value=%q", s.Name, s.Value),
+			value: 1,
+			help:  "Setting of the effective configuration of the exporter",
+		})
+	}
+
+	return metrics, nil
+}
@@ -0,0 +1,71 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExporterConfigSummary(t *testing.T) {
+	tests := map[string]struct {
+		config           ExporterConfig
+		want             map[string]string
+		absent           []string
+		wantCollectors   []string
+		absentCollectors []string
+	}{
+		"defaults": {
+			config: ExporterConfig{
+				ListenAddresses: []string{":9094"},
+				Endpoints:       []string{"/metrics", "/healthz"},
+				UpsCacheTTL:     10 * time.Second,
+			},
+			want: map[string]string{
+				"listen_addresses":  ":9094",
+				"endpoints":         "/metrics,/healthz",
+				"safe_mode":         "false",
+				"collector_timeout": "disabled",
+				"ups_cache_ttl":     "10s",
+			},
+			absent: []string{"ping_targets", "state_file", "quirks_file"},
+		},
+		"safe mode with disabled collectors": {
+			config: ExporterConfig{
+				SafeMode:    true,
+				Collectors:  map[string]bool{"firmware": false, "fanpolicy": false},
+				PingTargets: []string{"1.1.1.1", "8.8.8.8"},
+			},
+			want: map[string]string{
+				"safe_mode":    "true",
+				"ping_targets": "1.1.1.1,8.8.8.8",
+			},
+			absent:           []string{"listen_addresses", "endpoints"},
+			wantCollectors:   []string{"version", "config", "uptime"},
+			absentCollectors: []string{"firmware", "fanpolicy", "smart", "volume"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			settings := make(map[string]string)
+			for _, s := range tc.config.Summary() {
+				settings[s.Name] = s.Value
+			}
+
+			for setting, value := range tc.want {
+				assert.Equal(t, value, settings[setting], setting)
+			}
+			for _, setting := range tc.absent {
+				assert.NotContains(t, settings, setting)
+			}
+			collectors := strings.Split(settings["collectors"], ",")
+			for _, collector := range tc.wantCollectors {
+				assert.Contains(t, collectors, collector)
+			}
+			for _, collector := range tc.absentCollectors {
+				assert.NotContains(t, collectors, collector)
+			}
+		})
+	}
+}
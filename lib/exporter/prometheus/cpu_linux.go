@@ -1,69 +1,54 @@
 package prometheus
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/shirou/gopsutil/v3/cpu"
 )
 
-func getCpuRatioMetrics() ([]metric, error) {
-	a, err := cpu.Times(false)
+func getCpuRatioMetrics(ctx context.Context) ([]metric, error) {
+	// Per-core times, as read from /proc/stat
+	times, err := cpu.TimesWithContext(ctx, true)
 	if err != nil {
 		return nil, err
 	}
-	s := a[0]
 
-	counts, err := cpu.Counts(false)
+	counts, err := cpu.CountsWithContext(ctx, false)
 	if err != nil {
 		return nil, err
 	}
 
-	metrics := []metric{
-		{
-			name:       "node_cpu_seconds_total",
-			attr:       `mode="user"`,
-			metricType: "counter",
-			value:      float64(s.User),
-		},
-		{
-			name:       "node_cpu_seconds_total",
-			attr:       `mode="nice"`,
-			metricType: "counter",
-			value:      float64(s.Nice),
-		},
-		{
-			name:       "node_cpu_seconds_total",
-			attr:       `mode="system"`,
-			metricType: "counter",
-			value:      float64(s.System),
-		},
-		{
-			name:       "node_cpu_seconds_total",
-			attr:       `mode="idle"`,
-			metricType: "counter",
-			value:      float64(s.Idle),
-		},
-		{
-			name:       "node_cpu_seconds_total",
-			attr:       `mode="iowait"`,
-			metricType: "counter",
-			value:      float64(s.Iowait),
-		},
-		{
-			name:       "node_cpu_seconds_total",
-			attr:       `mode="irq"`,
-			metricType: "counter",
-			value:      float64(s.Irq),
-		},
-		{
-			name:       "node_cpu_seconds_total",
-			attr:       `mode="softirq"`,
-			metricType: "counter",
-			value:      float64(s.Softirq),
-		},
-		{
-			name:  "node_cpu_count",
-			value: float64(counts),
-		},
+	metrics := make([]metric, 0, len(times)*8+1)
+	for idx, s := range times {
+		modes := []struct {
+			mode  string
+			value float64
+		}{
+			{mode: "user", value: s.User},
+			{mode: "nice", value: s.Nice},
+			{mode: "system", value: s.System},
+			{mode: "idle", value: s.Idle},
+			{mode: "iowait", value: s.Iowait},
+			{mode: "irq", value: s.Irq},
+			{mode: "softirq", value: s.Softirq},
+			{mode: "steal", value: s.Steal},
+		}
+
+		for _, m := range modes {
+			metrics = append(metrics, metric{
+				name:       "node_cpu_seconds_total",
+				attr:       fmt.Sprintf(`cpu="%d",mode=%q`, idx, m.mode),
+				metricType: "counter",
+				value:      m.value,
+			})
+		}
 	}
 
+	metrics = append(metrics, metric{
+		name:  "node_cpu_count",
+		value: float64(counts),
+	})
+
 	return metrics, nil
 }
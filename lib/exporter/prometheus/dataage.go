@@ -0,0 +1,91 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// dataAgeMetricName is the name of the metric holding the age of the data served for each collector
+const dataAgeMetricName = "qnapexporter_collector_data_age_seconds"
+
+type dataTimeKey struct{}
+
+// dataTime is the time at which the oldest data returned by a collector was read, when it serves data from a cache
+// rather than reading it during the scrape
+type dataTime struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+// withDataTime returns a context in which the collector records the time its data was read in d, with recordDataTime
+func withDataTime(ctx context.Context, d *dataTime) context.Context {
+	return context.WithValue(ctx, dataTimeKey{}, d)
+}
+
+// recordDataTime records that the collector running in ctx returns data read at t, e.g. from a cache
+func recordDataTime(ctx context.Context, t time.Time) {
+	d, found := ctx.Value(dataTimeKey{}).(*dataTime)
+	if !found || t.IsZero() {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.t.IsZero() || t.Before(d.t) {
+		d.t = t
+	}
+}
+
+// age returns the age of the data at now, which is 0 if it was read during the scrape
+func (d *dataTime) age(now time.Time) time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.t.IsZero() || now.Before(d.t) {
+		return 0
+	}
+
+	return now.Sub(d.t)
+}
+
+func dataAgeMetric(collector string, age time.Duration) metric {
+	return metric{
+		name:  dataAgeMetricName,
+		attr:  fmt.Sprintf("collector=%q", collector),
+		value: age.Seconds(),
+		help:  "Age of the data served for the collector, which is 0 unless it comes from a cache (e.g. the UPS cache, the last-known-good metrics or the scrape cache)",
+	}
+}
+
+// agedMetrics returns a copy of the metrics of a collection served age after it completed, with the data ages
+// increased accordingly
+func agedMetrics(metrics []metric, age time.Duration) []metric {
+	aged := make([]metric, len(metrics))
+	copy(aged, metrics)
+	for idx := range aged {
+		if aged[idx].name == dataAgeMetricName {
+			aged[idx].value += age.Seconds()
+		}
+	}
+
+	return aged
+}
+
+// getEnvironmentAgeMetrics reports the age of the environment (e.g. the disks, volumes and network interfaces found),
+// which is read again every envValidity
+func (e *promExporter) getEnvironmentAgeMetrics() []metric {
+	if e.envRead.IsZero() {
+		return nil
+	}
+
+	return []metric{
+		{
+			name:  "qnapexporter_environment_age_seconds",
+			value: time.Since(e.envRead).Seconds(),
+			help:  "Time since the environment (hostname, disks, volumes, network interfaces) was last discovered",
+		},
+	}
+}
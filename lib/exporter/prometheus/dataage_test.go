@@ -0,0 +1,77 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordDataTime(t *testing.T) {
+	now := time.Now()
+	tests := map[string]struct {
+		recorded []time.Time
+		want     time.Duration
+	}{
+		"fresh data":      {want: 0},
+		"cached data":     {recorded: []time.Time{now.Add(-time.Minute)}, want: time.Minute},
+		"oldest data":     {recorded: []time.Time{now.Add(-time.Minute), now.Add(-time.Hour), now}, want: time.Hour},
+		"never read":      {recorded: []time.Time{{}}, want: 0},
+		"read in advance": {recorded: []time.Time{now.Add(time.Second)}, want: 0},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := &dataTime{}
+			ctx := withDataTime(context.Background(), d)
+			for _, recorded := range tc.recorded {
+				recordDataTime(ctx, recorded)
+			}
+
+			assert.Equal(t, tc.want, d.age(now))
+		})
+	}
+
+	// Outside of a collector, nothing is recorded
+	recordDataTime(context.Background(), now)
+}
+
+func TestWriteMetricsDataAge(t *testing.T) {
+	e := &promExporter{
+		ExporterConfig: ExporterConfig{ScrapeCacheTTL: time.Hour, Logger: logging.Discard()},
+		hostname:       "nas",
+		envExpiry:      time.Now().Add(time.Hour),
+		watchdog:       newWatchdog(0, nil, nil),
+	}
+	e.fns = []collector{
+		{
+			name: "cached",
+			fn: func(ctx context.Context) ([]metric, error) {
+				recordDataTime(ctx, time.Now().Add(-time.Minute))
+				return []metric{{name: "node_cached", value: 1}}, nil
+			},
+		},
+		{
+			name: "fresh",
+			fn: func(ctx context.Context) ([]metric, error) {
+				return []metric{{name: "node_fresh", value: 1}}, nil
+			},
+		},
+	}
+
+	b := new(bytes.Buffer)
+	require.NoError(t, e.WriteMetrics(context.Background(), b))
+	assert.Regexp(t, `qnapexporter_collector_data_age_seconds\{node="nas",collector="cached"\} 60\.\d+\n`, b.String())
+	assert.Contains(t, b.String(), `qnapexporter_collector_data_age_seconds{node="nas",collector="fresh"} 0`+"\n")
+
+	// The collections served from the scrape cache are older
+	e.scrapes.last.time = e.scrapes.last.time.Add(-time.Minute)
+	b.Reset()
+	require.NoError(t, e.WriteMetrics(context.Background(), b))
+	assert.Regexp(t, `qnapexporter_collector_data_age_seconds\{node="nas",collector="cached"\} 120\.\d+\n`, b.String())
+	assert.Regexp(t, `qnapexporter_collector_data_age_seconds\{node="nas",collector="fresh"\} 60\.\d+\n`, b.String())
+}
@@ -0,0 +1,48 @@
+package prometheus
+
+import (
+	"fmt"
+
+	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+)
+
+// degradationReasons enumerates the reasons reported by qnapexporter_degraded_reason
+var degradationReasons = []string{"disabled", "timeout", "error"}
+
+// getDegradationMetrics summarizes whether the exporter is running with reduced coverage, i.e. with collectors
+// disabled by the configuration, timing out or failing. Collectors of absent hardware don't count as degraded.
+func getDegradationMetrics(disabled int, statuses []exporter.CollectorStatus) []metric {
+	reasons := map[string]bool{"disabled": disabled > 0}
+	for _, s := range statuses {
+		switch s.ErrorClass {
+		case "":
+		case "timeout", "in_progress", "canceled":
+			reasons["timeout"] = true
+		default:
+			reasons["error"] = true
+		}
+	}
+
+	metrics := make([]metric, 0, len(degradationReasons)+1)
+	var degraded float64
+	for _, reason := range degradationReasons {
+		var value float64
+		if reasons[reason] {
+			value = 1
+			degraded = 1
+		}
+
+		metrics = append(metrics, metric{
+			name:  "qnapexporter_degraded_reason",
+			attr:  fmt.Sprintf("reason=%q", reason),
+			value: value,
+			help:  "Whether the exporter is degraded for the given reason (disabled, timeout or error)",
+		})
+	}
+
+	return append(metrics, metric{
+		name:  "qnapexporter_degraded",
+		value: degraded,
+		help:  "Whether any collector is disabled, timing out or failing",
+	})
+}
@@ -0,0 +1,58 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDegradationMetrics(t *testing.T) {
+	testCases := map[string]struct {
+		disabled     int
+		statuses     []exporter.CollectorStatus
+		wantDegraded float64
+		wantReasons  map[string]float64
+	}{
+		"healthy": {
+			statuses: []exporter.CollectorStatus{
+				{Name: "cpu"},
+				{Name: "ups", Absent: true},
+			},
+			wantReasons: map[string]float64{"disabled": 0, "timeout": 0, "error": 0},
+		},
+		"disabled collectors": {
+			disabled:     2,
+			statuses:     []exporter.CollectorStatus{{Name: "cpu"}},
+			wantDegraded: 1,
+			wantReasons:  map[string]float64{"disabled": 1, "timeout": 0, "error": 0},
+		},
+		"timeout and error": {
+			statuses: []exporter.CollectorStatus{
+				{Name: "smart", Error: "context deadline exceeded", ErrorClass: "timeout"},
+				{Name: "ups", Error: "previous run is still in progress", ErrorClass: "in_progress"},
+				{Name: "docker", Error: "permission denied", ErrorClass: "permission"},
+			},
+			wantDegraded: 1,
+			wantReasons:  map[string]float64{"disabled": 0, "timeout": 1, "error": 1},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			metrics := getDegradationMetrics(tc.disabled, tc.statuses)
+
+			reasons := map[string]float64{}
+			for _, m := range metrics {
+				switch m.name {
+				case "qnapexporter_degraded":
+					assert.Equal(t, tc.wantDegraded, m.value)
+				case "qnapexporter_degraded_reason":
+					reasons[strings.TrimSuffix(strings.TrimPrefix(m.attr, `reason="`), `"`)] = m.value
+				}
+			}
+			assert.Equal(t, tc.wantReasons, reasons)
+		})
+	}
+}
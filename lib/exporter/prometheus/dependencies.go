@@ -0,0 +1,82 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+)
+
+func (e *promExporter) getDependencyMetrics(ctx context.Context) ([]metric, error) {
+	dmsetup, _ := e.sys.LookPath("dmsetup")
+	dependencies := []struct {
+		name      string
+		available bool
+	}{
+		{name: "getsysinfo", available: e.getsysinfo != ""},
+		{name: "hal_app", available: e.hal_app != ""},
+		{name: "smartctl", available: e.smartctl != ""},
+		{name: "qcli_snapshot", available: e.qcliSnapshot != ""},
+		{name: "repquota", available: e.repquota != ""},
+		{name: "hdparm", available: e.hdparm != ""},
+		{name: "smbstatus", available: e.smbstatus != ""},
+		{name: "smbclient", available: e.smbclient != ""},
+		{name: "sqlite3", available: e.sqlite3 != ""},
+		{name: "dmsetup", available: dmsetup != ""},
+		{name: "nut", available: e.isUpsConnected()},
+	}
+
+	metrics := make([]metric, 0, len(dependencies))
+	for _, d := range dependencies {
+		var value float64
+		if d.available {
+			value = 1
+		}
+
+		metrics = append(metrics, metric{
+			name:  "qnapexporter_dependency_available",
+			attr:  fmt.Sprintf("name=%q", d.name),
+			value: value,
+			help:  "Whether an optional tool or service used by qnapexporter is available",
+		})
+	}
+
+	return metrics, nil
+}
+
+// getSubsystemMetrics reports whether the hardware read by each enabled collector of fns is present. It is called
+// once every collector of the collection has reported, so that the subsystems found absent by this collection are
+// included.
+func (e *promExporter) getSubsystemMetrics(fns []collector) []metric {
+	absent := e.absent.names()
+	if e.status != nil {
+		e.status.AbsentSubsystems = absent
+	}
+
+	reported := false
+	for _, c := range fns {
+		reported = reported || c.name == "dependencies"
+	}
+	if !reported {
+		return nil
+	}
+
+	var metrics []metric
+	for _, c := range e.fns {
+		if !containsString(hardwareCollectors, c.name) {
+			continue
+		}
+
+		var value float64 = 1
+		if containsString(absent, c.name) {
+			value = 0
+		}
+
+		metrics = append(metrics, metric{
+			name:  "qnapexporter_subsystem_present",
+			attr:  fmt.Sprintf("subsystem=%q", c.name),
+			value: value,
+			help:  "Whether the hardware read by a collector is present (collectors of absent hardware are skipped)",
+		})
+	}
+
+	return metrics
+}
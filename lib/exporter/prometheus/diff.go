@@ -0,0 +1,113 @@
+package prometheus
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+)
+
+// SeriesDiff lists the differences between the series exported under two configurations
+type SeriesDiff struct {
+	Added   []string
+	Removed []string
+	Renamed []SeriesRename
+}
+
+// SeriesRename is a series which is exported under a different name, with the same labels and value
+type SeriesRename struct {
+	Old, New string
+}
+
+// Diff collects the metrics once under each configuration and compares the resulting series,
+// so that users can check how a configuration change affects their dashboards
+func Diff(ctx context.Context, oldConfig, newConfig ExporterConfig) SeriesDiff {
+	configs := []ExporterConfig{oldConfig, newConfig}
+	series := make([]map[string]float64, len(configs))
+
+	// Collect concurrently, so that the values of both sets of series are as close as possible
+	var wg sync.WaitGroup
+	for idx, config := range configs {
+		wg.Add(1)
+		go func(idx int, config ExporterConfig) {
+			defer wg.Done()
+
+			e := NewExporter(config, &exporter.Status{}).(*promExporter)
+			defer e.Close()
+
+			series[idx] = e.collectSeries(ctx)
+		}(idx, config)
+	}
+	wg.Wait()
+
+	return diffSeries(series[0], series[1])
+}
+
+// collectSeries returns the value of every series, keyed by its full name (e.g. `node_load1{node="nas"}`)
+func (e *promExporter) collectSeries(ctx context.Context) map[string]float64 {
+	series := map[string]float64{}
+	_ = e.collect(
+		ctx,
+		func(metrics []metric) {
+			for _, m := range metrics {
+				series[e.getMetricFullName(m)] = m.value
+			}
+		},
+		func(error) {},
+	)
+
+	return series
+}
+
+func diffSeries(oldSeries, newSeries map[string]float64) SeriesDiff {
+	var diff SeriesDiff
+	var removed, added []string
+	for s := range oldSeries {
+		if _, found := newSeries[s]; !found {
+			removed = append(removed, s)
+		}
+	}
+	for s := range newSeries {
+		if _, found := oldSeries[s]; !found {
+			added = append(added, s)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+
+	// A removed series is considered renamed if exactly one added series has the same labels and value
+	renamedTo := map[string]bool{}
+	for _, o := range removed {
+		var candidates []string
+		for _, a := range added {
+			if seriesLabels(a) == seriesLabels(o) && newSeries[a] == oldSeries[o] && !renamedTo[a] {
+				candidates = append(candidates, a)
+			}
+		}
+
+		if len(candidates) == 1 {
+			renamedTo[candidates[0]] = true
+			diff.Renamed = append(diff.Renamed, SeriesRename{Old: o, New: candidates[0]})
+		} else {
+			diff.Removed = append(diff.Removed, o)
+		}
+	}
+	for _, a := range added {
+		if !renamedTo[a] {
+			diff.Added = append(diff.Added, a)
+		}
+	}
+
+	return diff
+}
+
+// seriesLabels returns the label part of a series full name, e.g. `{node="nas"}`
+func seriesLabels(series string) string {
+	if idx := strings.Index(series, "{"); idx != -1 {
+		return series[idx:]
+	}
+
+	return ""
+}
@@ -0,0 +1,35 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSeries(t *testing.T) {
+	oldSeries := map[string]float64{
+		`node_load1{node="nas"}`:                       0.5,
+		`node_cputmp_C{node="nas"}`:                    42,
+		`node_sysfan_RPM{node="nas",fan="1"}`:          1200,
+		`node_hdtmp_C{node="nas",hd="1",smart="GOOD"}`: 35,
+		`node_hdtmp_C{node="nas",hd="2",smart="GOOD"}`: 35,
+	}
+	newSeries := map[string]float64{
+		`node_load1{node="nas"}`:                                        0.5,
+		`qnap_cpu_temperature_celsius{node="nas"}`:                      42,
+		`qnap_disk_temperature_celsius{node="nas",hd="1",smart="GOOD"}`: 35,
+		`node_memory_MemFree_bytes{node="nas"}`:                         1024,
+	}
+
+	diff := diffSeries(oldSeries, newSeries)
+
+	assert.Equal(t, []SeriesRename{
+		{Old: `node_cputmp_C{node="nas"}`, New: `qnap_cpu_temperature_celsius{node="nas"}`},
+		{Old: `node_hdtmp_C{node="nas",hd="1",smart="GOOD"}`, New: `qnap_disk_temperature_celsius{node="nas",hd="1",smart="GOOD"}`},
+	}, diff.Renamed)
+	assert.Equal(t, []string{
+		`node_hdtmp_C{node="nas",hd="2",smart="GOOD"}`,
+		`node_sysfan_RPM{node="nas",fan="1"}`,
+	}, diff.Removed)
+	assert.Equal(t, []string{`node_memory_MemFree_bytes{node="nas"}`}, diff.Added)
+}
@@ -0,0 +1,103 @@
+package prometheus
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+
+	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+)
+
+// discoveryResult is the outcome of discovering one kind of item while reading the environment,
+// so that e.g. an empty device list caused by a permissions problem can be told apart from a diskless unit
+type discoveryResult struct {
+	item  string
+	count int
+	err   error
+}
+
+func (e *promExporter) recordDiscovery(item string, count int, err error) {
+	if err != nil {
+		e.Logger.Error("Error discovering item", "item", item, "err", err)
+	}
+	if count < 0 {
+		count = 0
+	}
+
+	e.discovery = append(e.discovery, discoveryResult{item: item, count: count, err: err})
+}
+
+// discoverTool looks for a tool under each of names in turn, unless path is already set.
+// A missing tool is not a discovery error, since all the tools are optional.
+// No tool is used in safe mode, so that no command is ever run.
+func (e *promExporter) discoverTool(path *string, names ...string) {
+	item := names[0]
+	if e.SafeMode {
+		*path = ""
+		return
+	}
+	if *path != "" {
+		e.recordDiscovery(item, 1, nil)
+		return
+	}
+
+	var err error
+	for _, name := range names {
+		if *path, err = e.sys.LookPath(name); err == nil {
+			break
+		}
+	}
+
+	switch {
+	case err == nil:
+		e.Logger.Debug("Found tool", "tool", item, "path", *path)
+		e.recordDiscovery(item, 1, nil)
+	case errors.Is(err, exec.ErrNotFound), errors.Is(err, fs.ErrNotExist):
+		e.Logger.Debug("Tool not found", "tool", item, "err", err)
+		e.recordDiscovery(item, 0, nil)
+	default:
+		e.recordDiscovery(item, 0, err)
+	}
+}
+
+func (e *promExporter) discoveryStatuses() []exporter.DiscoveryStatus {
+	statuses := make([]exporter.DiscoveryStatus, 0, len(e.discovery))
+	for _, r := range e.discovery {
+		s := exporter.DiscoveryStatus{Item: r.item, Count: r.count}
+		if r.err != nil {
+			s.Error = r.err.Error()
+		}
+		statuses = append(statuses, s)
+	}
+
+	return statuses
+}
+
+func getDiscoveryMetrics(results []discoveryResult) []metric {
+	metrics := make([]metric, 0, 2*len(results))
+	for _, r := range results {
+		attr := fmt.Sprintf("item=%q", r.item)
+
+		var success float64 = 1
+		if r.err != nil {
+			success = 0
+		}
+		metrics = append(metrics,
+			metric{
+				name:  "qnapexporter_discovery_success",
+				attr:  attr,
+				value: success,
+				help:  "Whether the last discovery of the item succeeded (0 means its list may be incomplete, see the log)",
+			},
+			metric{
+				name:  "qnapexporter_discovery_items",
+				attr:  attr,
+				value: float64(r.count),
+				help:  "Number of items found by the last discovery",
+			},
+		)
+	}
+
+	return metrics
+}
@@ -0,0 +1,75 @@
+package prometheus
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoverTool(t *testing.T) {
+	dir := t.TempDir()
+	executable := filepath.Join(dir, "tool")
+	require.NoError(t, os.WriteFile(executable, []byte("#!/bin/sh\n"), 0755))
+	notExecutable := filepath.Join(dir, "data")
+	require.NoError(t, os.WriteFile(notExecutable, nil, 0644))
+
+	tests := map[string]struct {
+		path          string
+		names         []string
+		expectedPath  string
+		expectedCount int
+		expectedErr   bool
+	}{
+		"already known": {path: "/opt/tool", names: []string{"tool"}, expectedPath: "/opt/tool", expectedCount: 1},
+		"found":         {names: []string{filepath.Join(dir, "missing"), executable}, expectedPath: executable, expectedCount: 1},
+		"missing":       {names: []string{filepath.Join(dir, "missing")}},
+		"not runnable":  {names: []string{notExecutable}, expectedErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := &promExporter{ExporterConfig: ExporterConfig{Logger: logging.Discard()}}
+
+			path := tc.path
+			e.discoverTool(&path, tc.names...)
+
+			assert.Equal(t, tc.expectedPath, path)
+			require.Len(t, e.discovery, 1)
+			assert.Equal(t, tc.names[0], e.discovery[0].item)
+			assert.Equal(t, tc.expectedCount, e.discovery[0].count)
+			assert.Equal(t, tc.expectedErr, e.discovery[0].err != nil)
+		})
+	}
+}
+
+func TestGetDiscoveryMetrics(t *testing.T) {
+	e := &promExporter{ExporterConfig: ExporterConfig{Logger: logging.Discard()}}
+	e.recordDiscovery("devices", 0, errors.New("open /sys/block: permission denied"))
+	e.recordDiscovery("system_fans", -1, nil)
+	e.recordDiscovery("interfaces", 2, nil)
+
+	metrics := getDiscoveryMetrics(e.discovery)
+	require.Len(t, metrics, 6)
+	assert.Equal(t, metric{
+		name:  "qnapexporter_discovery_success",
+		attr:  `item="devices"`,
+		value: 0,
+		help:  "Whether the last discovery of the item succeeded (0 means its list may be incomplete, see the log)",
+	}, metrics[0])
+	assert.Equal(t, 0.0, metrics[3].value)
+	assert.Equal(t, "qnapexporter_discovery_items", metrics[5].name)
+	assert.Equal(t, `item="interfaces"`, metrics[5].attr)
+	assert.Equal(t, 2.0, metrics[5].value)
+
+	assert.Equal(t, []exporter.DiscoveryStatus{
+		{Item: "devices", Error: "open /sys/block: permission denied"},
+		{Item: "system_fans"},
+		{Item: "interfaces", Count: 2},
+	}, e.discoveryStatuses())
+}
@@ -1,16 +1,16 @@
 package prometheus
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 
 	"github.com/pedropombeiro/qnapexporter/lib/utils"
-	"github.com/shirou/gopsutil/v3/disk"
 )
 
-func (e *promExporter) getSysInfoHdMetrics() ([]metric, error) {
+func (e *promExporter) getSysInfoHdMetrics(ctx context.Context) ([]metric, error) {
 	if e.getsysinfo == "" {
 		return nil, nil
 	}
@@ -20,22 +20,22 @@ func (e *promExporter) getSysInfoHdMetrics() ([]metric, error) {
 
 	for hdnum := 1; hdnum <= e.syshdnum; hdnum++ {
 		hdnumStr := strconv.Itoa(hdnum)
-		tempStr, err := utils.ExecCommand(e.getsysinfo, "hdtmp", hdnumStr)
+		tempStr, err := e.sys.ExecCommand(ctx, e.getsysinfo, "hdtmp", hdnumStr)
 		if err != nil {
-			return nil, err
+			return metrics, err
 		}
 		if strings.HasPrefix(tempStr, "--") {
 			continue
 		}
 
-		smart, err := utils.ExecCommand(e.getsysinfo, "hdsmart", hdnumStr)
+		smart, err := e.sys.ExecCommand(ctx, e.getsysinfo, "hdsmart", hdnumStr)
 		if err != nil {
-			return nil, err
+			return metrics, err
 		}
 
-		temp, err := strconv.ParseFloat(strings.SplitN(tempStr, " ", 2)[0], 64)
-		if err != nil {
-			return metrics, err
+		temp, ok := e.parseValue(ctx, strings.SplitN(tempStr, " ", 2)[0])
+		if !ok {
+			continue
 		}
 
 		metrics = append(metrics, metric{
@@ -52,16 +52,15 @@ func (e *promExporter) getSysInfoHdMetrics() ([]metric, error) {
 	return metrics, nil
 }
 
-func (e *promExporter) getFlashCacheStatsMetrics() ([]metric, error) {
+func (e *promExporter) getFlashCacheStatsMetrics(ctx context.Context) ([]metric, error) {
 	if e.kernelVersion >= 5 {
-		return nil, nil
+		return nil, subsystemAbsentError{"flashcache is not used since kernel 5"}
 	}
 
-	lines, err := utils.ReadFileLines(flashcacheStatsPath)
+	lines, err := e.sys.ReadFileLines(flashcacheStatsPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			// Ignore if the file does not exist
-			return nil, nil
+			return nil, subsystemAbsentError{"no flashcache statistics found"}
 		}
 
 		return nil, err
@@ -85,13 +84,13 @@ func (e *promExporter) getFlashCacheStatsMetrics() ([]metric, error) {
 	return metrics, nil
 }
 
-func (e *promExporter) getDmCacheStatsMetrics() ([]metric, error) {
+func (e *promExporter) getDmCacheStatsMetrics(ctx context.Context) ([]metric, error) {
 	if len(e.dmCacheClients) == 0 {
-		return nil, nil
+		return nil, subsystemAbsentError{"no dm-cache devices found"}
 	}
 
 	args := append([]string{"status", "--noflush"}, e.dmCacheClients...)
-	lines, err := utils.ExecCommandGetLines("dmsetup", args...)
+	lines, err := e.sys.ExecCommandGetLines(ctx, "dmsetup", args...)
 	if err != nil {
 		return nil, fmt.Errorf("get dm-cache status (dmsetup %s): %w", args, err)
 	}
@@ -121,7 +120,7 @@ func (e *promExporter) appendDmCacheHitMetrics(metrics []metric) ([]metric, erro
 	cache := fmt.Sprintf("dm-%s", e.dmCacheDeviceMinorNumber)
 	dmCacheStatsFilePath := fmt.Sprintf(dmCacheStatsFilePathFormat, cache)
 
-	lines, err := utils.ReadFileLines(dmCacheStatsFilePath)
+	lines, err := e.sys.ReadFileLines(dmCacheStatsFilePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Ignore if the file does not exist
@@ -253,7 +252,7 @@ func getTokenValue(token string) float64 {
 }
 
 func appendFloatMetric(metrics []metric, metricName string, valueStr string, factor float64, attr string, help string) []metric {
-	value, err := strconv.ParseFloat(valueStr, 64)
+	value, err := utils.ParseFloat(valueStr)
 	if err != nil {
 		return metrics
 	}
@@ -266,77 +265,3 @@ func appendFloatMetric(metrics []metric, metricName string, valueStr string, fac
 		metricType: "counter",
 	})
 }
-
-func (e *promExporter) getDiskStatsMetrics() ([]metric, error) {
-	stats, err := disk.IOCounters(e.devices...)
-	if err != nil {
-		return nil, err
-	}
-
-	metrics := make([]metric, 0, len(e.devices)*2)
-	for _, s := range stats {
-		attr := fmt.Sprintf(`device=%q`, s.Name)
-
-		metrics = append(
-			metrics,
-			metric{
-				name:       "node_disk_read_bytes_total",
-				attr:       attr,
-				value:      float64(s.ReadBytes),
-				help:       "Total number of bytes read",
-				metricType: "counter",
-			},
-			metric{
-				name:       "node_disk_written_bytes_total",
-				attr:       attr,
-				value:      float64(s.WriteBytes),
-				help:       "Total number of bytes written",
-				metricType: "counter",
-			},
-			metric{
-				name:       "node_disk_read_ops_total",
-				attr:       attr,
-				value:      float64(s.ReadCount),
-				help:       "Total number of read operations",
-				metricType: "counter",
-			},
-			metric{
-				name:       "node_disk_write_ops_total",
-				attr:       attr,
-				value:      float64(s.WriteCount),
-				help:       "Total number of write operations",
-				metricType: "counter",
-			},
-			metric{
-				name:       "node_disk_read_time_msec",
-				attr:       attr,
-				value:      float64(s.ReadTime),
-				help:       "# of milliseconds spent reading",
-				metricType: "counter",
-			},
-			metric{
-				name:       "node_disk_write_time_msec",
-				attr:       attr,
-				value:      float64(s.WriteTime),
-				help:       "# of milliseconds spent writing",
-				metricType: "counter",
-			},
-			metric{
-				name:       "node_disk_iops_in_progress",
-				attr:       attr,
-				value:      float64(s.IopsInProgress),
-				help:       "# of I/Os currently in progress",
-				metricType: "gauge",
-			},
-			metric{
-				name:       "node_disk_iotime_msec",
-				attr:       attr,
-				value:      float64(s.IoTime),
-				help:       "# of milliseconds spent doing I/Os",
-				metricType: "counter",
-			},
-		)
-	}
-
-	return metrics, nil
-}
@@ -0,0 +1,186 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// diskPowerMaxGap is the longest interval between two power state checks which is accounted to the disk state,
+// so that the time during which the exporter was stopped is not counted
+const diskPowerMaxGap = time.Hour
+
+// diskPowerSampler keeps the time spent by each disk in standby and active since the exporter started, from the
+// power state found by the periodic checks
+type diskPowerSampler struct {
+	mu    sync.Mutex
+	disks map[string]*diskPowerStats
+}
+
+type diskPowerStats struct {
+	standby        bool
+	checked        time.Time
+	standbySeconds float64
+	activeSeconds  float64
+	// spinUps counts the checks finding the disk active after it was found in standby
+	spinUps float64
+}
+
+// record accounts the time since the previous check to the state found then, and returns the updated statistics
+func (s *diskPowerSampler) record(dev string, standby bool, now time.Time) diskPowerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.disks == nil {
+		s.disks = make(map[string]*diskPowerStats)
+	}
+	stats, found := s.disks[dev]
+	if !found {
+		stats = &diskPowerStats{}
+		s.disks[dev] = stats
+	} else if elapsed := now.Sub(stats.checked); elapsed > 0 && elapsed <= diskPowerMaxGap {
+		if stats.standby {
+			stats.standbySeconds += elapsed.Seconds()
+		} else {
+			stats.activeSeconds += elapsed.Seconds()
+		}
+		if stats.standby && !standby {
+			stats.spinUps++
+		}
+	}
+	stats.standby, stats.checked = standby, now
+
+	return *stats
+}
+
+// getDiskPowerMetrics checks whether each disk is in standby, without waking it up, and reports the time spent in
+// standby and active, so that the effect of the disk sleep settings can be measured
+func (e *promExporter) getDiskPowerMetrics(ctx context.Context) ([]metric, error) {
+	if e.hdparm == "" && e.smartctl == "" {
+		return nil, subsystemAbsentError{"neither hdparm nor smartctl found"}
+	}
+
+	var metrics []metric
+	for _, dev := range e.devices {
+		// NVMe drives don't spin down
+		if strings.HasPrefix(dev, "nvme") {
+			continue
+		}
+
+		standby, known, err := e.readDiskStandby(ctx, dev)
+		if err != nil {
+			return metrics, err
+		}
+		if !known {
+			continue
+		}
+
+		stats := e.diskPower.record(dev, standby, time.Now())
+		metrics = append(metrics, diskPowerMetrics(fmt.Sprintf("device=%q", dev), stats)...)
+	}
+
+	return metrics, nil
+}
+
+// readDiskStandby reports whether the disk dev is in standby, through `hdparm -C` or else `smartctl -n standby`,
+// neither of which wakes up the disk. It returns false if the power state could not be determined.
+func (e *promExporter) readDiskStandby(ctx context.Context, dev string) (bool, bool, error) {
+	if e.hdparm != "" {
+		output, err := e.sys.ExecCommand(ctx, e.hdparm, "-C", path.Join(devDir, dev))
+		if err != nil {
+			return false, false, err
+		}
+
+		state, known := parseHdparmPowerState(output)
+		return state, known, nil
+	}
+
+	output, exitCode, err := e.sys.ExecCommandWithExitCode(ctx, e.smartctl, "-n", "standby", "-i", path.Join(devDir, dev))
+	if err != nil {
+		return false, false, err
+	}
+	switch {
+	case exitCode == 0:
+		return false, true, nil
+	case exitCode&smartctlFatalExitMask != 0 && (strings.Contains(output, "STANDBY mode") || strings.Contains(output, "SLEEP mode")):
+		return true, true, nil
+	default:
+		return false, false, nil
+	}
+}
+
+// parseHdparmPowerState parses the output of `hdparm -C`, e.g.:
+//
+//	/dev/sda:
+//	 drive state is:  standby
+//
+// It returns whether the disk is in standby (or sleeping), and false if the state is unknown.
+func parseHdparmPowerState(output string) (bool, bool) {
+	for _, line := range strings.Split(output, "\n") {
+		_, state, found := strings.Cut(line, "drive state is:")
+		if !found {
+			continue
+		}
+
+		switch strings.TrimSpace(state) {
+		case "standby", "sleeping":
+			return true, true
+		case "active/idle", "active", "idle":
+			return false, true
+		default:
+			return false, false
+		}
+	}
+
+	return false, false
+}
+
+func diskPowerMetrics(attr string, stats diskPowerStats) []metric {
+	var standby float64
+	if stats.standby {
+		standby = 1
+	}
+
+	metrics := []metric{
+		{
+			name:  "node_disk_standby",
+			attr:  attr,
+			value: standby,
+			help:  "Whether the disk was in standby (spun down) at the last check",
+		},
+		{
+			name:       "node_disk_standby_seconds_total",
+			attr:       attr,
+			value:      stats.standbySeconds,
+			help:       "Time spent by the disk in standby since the exporter started, as found by the periodic checks",
+			metricType: "counter",
+		},
+		{
+			name:       "node_disk_active_seconds_total",
+			attr:       attr,
+			value:      stats.activeSeconds,
+			help:       "Time spent by the disk spun up since the exporter started, as found by the periodic checks",
+			metricType: "counter",
+		},
+		{
+			name:       "node_disk_spinups_total",
+			attr:       attr,
+			value:      stats.spinUps,
+			help:       "Number of times the disk was found spun up after being in standby",
+			metricType: "counter",
+		},
+	}
+	if total := stats.standbySeconds + stats.activeSeconds; total > 0 {
+		metrics = append(metrics, metric{
+			name:  "node_disk_standby_ratio",
+			attr:  attr,
+			value: stats.standbySeconds / total,
+			help:  "Ratio of the time spent by the disk in standby since the exporter started",
+		})
+	}
+
+	return metrics
+}
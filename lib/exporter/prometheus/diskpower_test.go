@@ -0,0 +1,113 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHdparmPowerState(t *testing.T) {
+	tests := map[string]struct {
+		output      string
+		wantStandby bool
+		wantKnown   bool
+	}{
+		"standby": {
+			output:      "\n/dev/sda:\n drive state is:  standby",
+			wantStandby: true,
+			wantKnown:   true,
+		},
+		"sleeping": {
+			output:      "\n/dev/sda:\n drive state is:  sleeping",
+			wantStandby: true,
+			wantKnown:   true,
+		},
+		"active": {
+			output:    "\n/dev/sda:\n drive state is:  active/idle",
+			wantKnown: true,
+		},
+		"unknown": {
+			output: "\n/dev/sda:\n drive state is:  unknown",
+		},
+		"no state": {
+			output: "\n/dev/sda:\n SG_IO: bad/missing sense data",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			standby, known := parseHdparmPowerState(tc.output)
+
+			assert.Equal(t, tc.wantStandby, standby)
+			assert.Equal(t, tc.wantKnown, known)
+		})
+	}
+}
+
+func TestDiskPowerSamplerRecord(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	type check struct {
+		standby bool
+		at      time.Duration
+	}
+	tests := map[string]struct {
+		checks []check
+		want   diskPowerStats
+	}{
+		"first check": {
+			checks: []check{{standby: true}},
+			want:   diskPowerStats{standby: true, checked: start},
+		},
+		"standby then spin up": {
+			checks: []check{
+				{standby: false},
+				{standby: true, at: time.Minute},
+				{standby: true, at: 11 * time.Minute},
+				{standby: false, at: 31 * time.Minute},
+				{standby: false, at: 32 * time.Minute},
+			},
+			want: diskPowerStats{
+				checked:        start.Add(32 * time.Minute),
+				standbySeconds: 30 * 60,
+				activeSeconds:  2 * 60,
+				spinUps:        1,
+			},
+		},
+		"gap while stopped": {
+			checks: []check{
+				{standby: true},
+				{standby: true, at: time.Minute},
+				{standby: false, at: 3 * time.Hour},
+			},
+			want: diskPowerStats{
+				checked:        start.Add(3 * time.Hour),
+				standbySeconds: 60,
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var s diskPowerSampler
+			var stats diskPowerStats
+			for _, c := range tc.checks {
+				stats = s.record("sda", c.standby, start.Add(c.at))
+			}
+
+			assert.Equal(t, tc.want, stats)
+		})
+	}
+}
+
+func TestDiskPowerMetrics(t *testing.T) {
+	metrics := diskPowerMetrics(`device="sda"`, diskPowerStats{standby: true, standbySeconds: 300, activeSeconds: 100, spinUps: 2})
+
+	assert.Equal(t, map[string]float64{`device="sda"`: 1}, metricValues("node_disk_standby")(metrics))
+	assert.Equal(t, map[string]float64{`device="sda"`: 300}, metricValues("node_disk_standby_seconds_total")(metrics))
+	assert.Equal(t, map[string]float64{`device="sda"`: 100}, metricValues("node_disk_active_seconds_total")(metrics))
+	assert.Equal(t, map[string]float64{`device="sda"`: 2}, metricValues("node_disk_spinups_total")(metrics))
+	assert.Equal(t, map[string]float64{`device="sda"`: 0.75}, metricValues("node_disk_standby_ratio")(metrics))
+
+	assert.Empty(t, metricValues("node_disk_standby_ratio")(diskPowerMetrics(`device="sda"`, diskPowerStats{})))
+}
@@ -0,0 +1,244 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// diskSectorSize is the unit of the sector counts in /proc/diskstats, regardless of the device sector size
+const diskSectorSize = 512
+
+type diskStats struct {
+	name            string
+	readsCompleted  float64
+	readsMerged     float64
+	sectorsRead     float64
+	readTimeMs      float64
+	writesCompleted float64
+	writesMerged    float64
+	sectorsWritten  float64
+	writeTimeMs     float64
+	iosInProgress   float64
+	ioTimeMs        float64
+	weightedIoTime  float64
+}
+
+func (e *promExporter) getDiskStatsMetrics(ctx context.Context) ([]metric, error) {
+	lines, err := e.sys.ReadFileLines(diskstatsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Ignore if the file does not exist
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	stats, err := parseDiskStats(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make([]metric, 0, len(stats)*11)
+	for _, s := range stats {
+		if !isDiskOrPartition(e.devices, s.name) {
+			continue
+		}
+
+		attr := fmt.Sprintf(`device=%q`, s.name)
+		metrics = append(
+			metrics,
+			metric{
+				name:       "node_disk_read_bytes_total",
+				attr:       attr,
+				value:      s.sectorsRead * diskSectorSize,
+				help:       "Total number of bytes read",
+				metricType: "counter",
+			},
+			metric{
+				name:       "node_disk_written_bytes_total",
+				attr:       attr,
+				value:      s.sectorsWritten * diskSectorSize,
+				help:       "Total number of bytes written",
+				metricType: "counter",
+			},
+			metric{
+				name:       "node_disk_read_ops_total",
+				attr:       attr,
+				value:      s.readsCompleted,
+				help:       "Total number of read operations",
+				metricType: "counter",
+			},
+			metric{
+				name:       "node_disk_write_ops_total",
+				attr:       attr,
+				value:      s.writesCompleted,
+				help:       "Total number of write operations",
+				metricType: "counter",
+			},
+			metric{
+				name:       "node_disk_reads_merged_total",
+				attr:       attr,
+				value:      s.readsMerged,
+				help:       "Total number of adjacent reads merged into a single operation",
+				metricType: "counter",
+			},
+			metric{
+				name:       "node_disk_writes_merged_total",
+				attr:       attr,
+				value:      s.writesMerged,
+				help:       "Total number of adjacent writes merged into a single operation",
+				metricType: "counter",
+			},
+			metric{
+				name:       "node_disk_read_time_msec",
+				attr:       attr,
+				value:      s.readTimeMs,
+				help:       "# of milliseconds spent reading",
+				metricType: "counter",
+			},
+			metric{
+				name:       "node_disk_write_time_msec",
+				attr:       attr,
+				value:      s.writeTimeMs,
+				help:       "# of milliseconds spent writing",
+				metricType: "counter",
+			},
+			metric{
+				name:       "node_disk_iops_in_progress",
+				attr:       attr,
+				value:      s.iosInProgress,
+				help:       "# of I/Os currently in progress",
+				metricType: "gauge",
+			},
+			metric{
+				name:       "node_disk_iotime_msec",
+				attr:       attr,
+				value:      s.ioTimeMs,
+				help:       "# of milliseconds spent doing I/Os",
+				metricType: "counter",
+			},
+			metric{
+				name:       "node_disk_io_time_weighted_seconds_total",
+				attr:       attr,
+				value:      s.weightedIoTime / 1000,
+				help:       "Time spent doing I/Os weighted by the number of I/Os in progress (its rate is the average queue depth)",
+				metricType: "counter",
+			},
+		)
+	}
+
+	return metrics, nil
+}
+
+// isDiskOrPartition returns whether name is one of devices, or a partition of one of them (e.g. sda3 or nvme0n1p1)
+func isDiskOrPartition(devices []string, name string) bool {
+	for _, dev := range devices {
+		if name == dev {
+			return true
+		}
+
+		if !strings.HasPrefix(name, dev) {
+			continue
+		}
+		partition := strings.TrimPrefix(name, dev)
+		// Partitions of devices whose name ends with a digit have a "p" separator
+		if last := dev[len(dev)-1]; last >= '0' && last <= '9' {
+			if !strings.HasPrefix(partition, "p") {
+				continue
+			}
+			partition = strings.TrimPrefix(partition, "p")
+		}
+		if _, err := strconv.Atoi(partition); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseDiskStats parses the contents of /proc/diskstats, e.g.:
+//
+//	8       0 sda 120583 3478 19523370 1083421 246032 187539 24189560 2975823 0 1187560 4059244 0 0 0 0
+//	8       3 sda3 110583 19423370 240032 24089560
+//
+// The second line is the shorter format of the partitions on the old kernels of some legacy QTS firmware
+func parseDiskStats(lines []string) ([]diskStats, error) {
+	stats := make([]diskStats, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+
+		var s diskStats
+		var err error
+		switch {
+		case len(fields) >= 14:
+			s, err = parseDiskStatsFields(fields[2], fields[3:])
+		case len(fields) == 7:
+			s, err = parseLegacyPartitionStatsFields(fields[2], fields[3:])
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+
+	return stats, nil
+}
+
+// parseLegacyPartitionStatsFields parses the reads, sectors read, writes and sectors written of the partition name,
+// which are the only statistics reported for the partitions by the kernels older than 2.6.25
+func parseLegacyPartitionStatsFields(name string, fields []string) (diskStats, error) {
+	values := make([]float64, len(fields))
+	for idx := range values {
+		value, err := strconv.ParseFloat(fields[idx], 64)
+		if err != nil {
+			return diskStats{}, fmt.Errorf("parse %s statistics: %w", name, err)
+		}
+		values[idx] = value
+	}
+
+	return diskStats{
+		name:            name,
+		readsCompleted:  values[0],
+		sectorsRead:     values[1],
+		writesCompleted: values[2],
+		sectorsWritten:  values[3],
+	}, nil
+}
+
+// parseDiskStatsFields parses the statistics of the device name, as found after the device name in /proc/diskstats
+// and in /sys/block/<device>/stat
+func parseDiskStatsFields(name string, fields []string) (diskStats, error) {
+	if len(fields) < 11 {
+		return diskStats{}, fmt.Errorf("parse %s statistics: expected at least 11 fields, found %d", name, len(fields))
+	}
+
+	values := make([]float64, 11)
+	for idx := range values {
+		value, err := strconv.ParseFloat(fields[idx], 64)
+		if err != nil {
+			return diskStats{}, fmt.Errorf("parse %s statistics: %w", name, err)
+		}
+		values[idx] = value
+	}
+
+	return diskStats{
+		name:            name,
+		readsCompleted:  values[0],
+		readsMerged:     values[1],
+		sectorsRead:     values[2],
+		readTimeMs:      values[3],
+		writesCompleted: values[4],
+		writesMerged:    values[5],
+		sectorsWritten:  values[6],
+		writeTimeMs:     values[7],
+		iosInProgress:   values[8],
+		ioTimeMs:        values[9],
+		weightedIoTime:  values[10],
+	}, nil
+}
@@ -0,0 +1,69 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDiskStats(t *testing.T) {
+	lines := []string{
+		"   8       0 sda 120583 3478 19523370 1083421 246032 187539 24189560 2975823 0 1187560 4059244 0 0 0 0",
+		" 259       1 nvme0n1p1 42 0 336 5 0 0 0 0 2 10 5",
+		"   8       3 sda3 110583 19423370 240032 24089560",
+		"   1       0 ram0",
+	}
+
+	stats, err := parseDiskStats(lines)
+	require.NoError(t, err)
+	require.Len(t, stats, 3)
+
+	assert.Equal(t, diskStats{
+		name:            "sda",
+		readsCompleted:  120583,
+		readsMerged:     3478,
+		sectorsRead:     19523370,
+		readTimeMs:      1083421,
+		writesCompleted: 246032,
+		writesMerged:    187539,
+		sectorsWritten:  24189560,
+		writeTimeMs:     2975823,
+		iosInProgress:   0,
+		ioTimeMs:        1187560,
+		weightedIoTime:  4059244,
+	}, stats[0])
+	assert.Equal(t, "nvme0n1p1", stats[1].name)
+	assert.Equal(t, 2.0, stats[1].iosInProgress)
+	assert.Equal(t, diskStats{
+		name:            "sda3",
+		readsCompleted:  110583,
+		sectorsRead:     19423370,
+		writesCompleted: 240032,
+		sectorsWritten:  24089560,
+	}, stats[2])
+
+	_, err = parseDiskStats([]string{"8 0 sda x 0 0 0 0 0 0 0 0 0 0"})
+	assert.Error(t, err)
+}
+
+func TestIsDiskOrPartition(t *testing.T) {
+	devices := []string{"sda", "nvme0n1"}
+
+	testCases := map[string]bool{
+		"sda":       true,
+		"sda3":      true,
+		"sdaa":      false,
+		"sdb1":      false,
+		"nvme0n1":   true,
+		"nvme0n1p2": true,
+		"nvme0n12":  false,
+		"dm-0":      false,
+	}
+
+	for name, expected := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, expected, isDiskOrPartition(devices, name))
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+func (e *promExporter) getDockerMetrics(ctx context.Context) ([]metric, error) {
+	if e.dockerClient == nil {
+		if os.Getenv(client.EnvOverrideHost) == "" {
+			if _, err := e.sys.Stat(dockerSocketPath); os.IsNotExist(err) {
+				// Container Station is not installed/running
+				return nil, nil
+			}
+		}
+
+		cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+		if err != nil {
+			return nil, err
+		}
+		e.dockerClient = cli
+	}
+
+	containers, err := e.dockerClient.ContainerList(ctx, types.ContainerListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make([]metric, 0, len(containers)*6)
+	for _, c := range containers {
+		m, err := e.getContainerMetrics(ctx, c)
+		if err != nil {
+			return metrics, err
+		}
+
+		metrics = append(metrics, m...)
+	}
+
+	return metrics, nil
+}
+
+func (e *promExporter) getContainerMetrics(ctx context.Context, c types.Container) ([]metric, error) {
+	name := c.ID
+	if len(c.Names) > 0 {
+		name = strings.TrimPrefix(c.Names[0], "/")
+	}
+	attr := fmt.Sprintf("name=%q,image=%q", name, c.Image)
+
+	info, err := e.dockerClient.ContainerInspect(ctx, c.ID)
+	if err != nil {
+		return nil, fmt.Errorf("inspect container %s: %w", name, err)
+	}
+
+	resp, err := e.dockerClient.ContainerStatsOneShot(ctx, c.ID)
+	if err != nil {
+		return nil, fmt.Errorf("retrieve stats for container %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	var stats types.StatsJSON
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("decode stats for container %s: %w", name, err)
+	}
+
+	var rxBytes, txBytes uint64
+	for _, n := range stats.Networks {
+		rxBytes += n.RxBytes
+		txBytes += n.TxBytes
+	}
+
+	return []metric{
+		{
+			name:  "node_container_cpu_seconds_total",
+			attr:  attr,
+			value: float64(stats.CPUStats.CPUUsage.TotalUsage) / 1e9,
+			help:  "Total CPU time consumed by the container",
+		},
+		{
+			name:  "node_container_memory_usage_bytes",
+			attr:  attr,
+			value: float64(containerMemoryUsage(stats.MemoryStats)),
+			help:  "Memory used by the container, excluding the page cache",
+		},
+		{
+			name:  "node_container_memory_limit_bytes",
+			attr:  attr,
+			value: float64(stats.MemoryStats.Limit),
+			help:  "Memory limit of the container",
+		},
+		{
+			name:  "node_container_network_receive_bytes_total",
+			attr:  attr,
+			value: float64(rxBytes),
+			help:  "Total number of bytes received by the container",
+		},
+		{
+			name:  "node_container_network_transmit_bytes_total",
+			attr:  attr,
+			value: float64(txBytes),
+			help:  "Total number of bytes transmitted by the container",
+		},
+		{
+			name:  "node_container_restart_count",
+			attr:  attr,
+			value: float64(info.RestartCount),
+			help:  "Number of times the container has been restarted by the Docker daemon",
+		},
+	}, nil
+}
+
+// containerMemoryUsage computes the memory usage the same way as `docker stats`,
+// i.e. excluding the page cache (reported as `cache` by cgroup v1 and `inactive_file` by cgroup v2)
+func containerMemoryUsage(s types.MemoryStats) uint64 {
+	cache, found := s.Stats["total_inactive_file"]
+	if !found {
+		cache, found = s.Stats["inactive_file"]
+	}
+	if !found {
+		cache = s.Stats["cache"]
+	}
+	if cache > s.Usage {
+		return s.Usage
+	}
+
+	return s.Usage - cache
+}
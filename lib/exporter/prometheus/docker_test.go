@@ -0,0 +1,42 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerMemoryUsage(t *testing.T) {
+	testCases := map[string]struct {
+		stats    types.MemoryStats
+		expected uint64
+	}{
+		"cgroup v1": {
+			stats:    types.MemoryStats{Usage: 1000, Stats: map[string]uint64{"total_inactive_file": 300, "cache": 400}},
+			expected: 700,
+		},
+		"cgroup v2": {
+			stats:    types.MemoryStats{Usage: 1000, Stats: map[string]uint64{"inactive_file": 100}},
+			expected: 900,
+		},
+		"legacy cache only": {
+			stats:    types.MemoryStats{Usage: 1000, Stats: map[string]uint64{"cache": 400}},
+			expected: 600,
+		},
+		"no stats": {
+			stats:    types.MemoryStats{Usage: 1000},
+			expected: 1000,
+		},
+		"cache larger than usage": {
+			stats:    types.MemoryStats{Usage: 100, Stats: map[string]uint64{"cache": 400}},
+			expected: 100,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, containerMemoryUsage(tc.stats))
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package prometheus
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// Formats of the metric reference written by WriteMetricDocs
+const (
+	DocsFormatMarkdown = "markdown"
+	DocsFormatHTML     = "html"
+)
+
+// docsCollector holds the metric families of a collector, in the order of the reference
+type docsCollector struct {
+	Name    string
+	Metrics []MetricDescription
+}
+
+var docsHTMLTemplate = template.Must(template.New("docs").Funcs(template.FuncMap{
+	"labels": docsLabels,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>qnapexporter metrics</title>
+</head>
+<body>
+<h1>qnapexporter metrics</h1>
+<p>Generated by <code>qnapexporter docs</code> from the metric catalog. Every metric also carries the <code>node</code> label.</p>
+{{- range .}}
+<h2 id="{{.Name}}">{{.Name}}</h2>
+<table>
+<tr><th>Metric</th><th>Type</th><th>Unit</th><th>Labels</th><th>Description</th></tr>
+{{- range .Metrics}}
+<tr><td><code>{{.Name}}</code></td><td>{{.Type}}</td><td>{{.Unit}}</td><td>{{range $i, $l := labels .}}{{if $i}}, {{end}}<code>{{$l}}</code>{{end}}</td><td>{{.Help}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+// WriteMetricDocs writes a reference of every metric family the exporter can produce, grouped by collector,
+// in the given format (markdown or html). It is generated from the metric catalog, which TestMetricCatalogMatchesMockData
+// checks against the metrics produced by the collectors.
+func WriteMetricDocs(w io.Writer, format string) error {
+	var collectors []docsCollector
+	for _, d := range MetricCatalog() {
+		if len(collectors) == 0 || collectors[len(collectors)-1].Name != d.Collector {
+			collectors = append(collectors, docsCollector{Name: d.Collector})
+		}
+		last := &collectors[len(collectors)-1]
+		last.Metrics = append(last.Metrics, d)
+	}
+
+	switch format {
+	case DocsFormatMarkdown:
+		return writeMarkdownDocs(w, collectors)
+	case DocsFormatHTML:
+		return docsHTMLTemplate.Execute(w, collectors)
+	default:
+		return fmt.Errorf("unknown docs format %q", format)
+	}
+}
+
+func writeMarkdownDocs(w io.Writer, collectors []docsCollector) error {
+	var sb strings.Builder
+	sb.WriteString("# qnapexporter metrics\n\n")
+	sb.WriteString("Generated by `qnapexporter docs` from the metric catalog. Every metric also carries the `node` label.\n")
+
+	for _, c := range collectors {
+		fmt.Fprintf(&sb, "\n## %s\n\n", c.Name)
+		sb.WriteString("| Metric | Type | Unit | Labels | Description |\n")
+		sb.WriteString("|--------|------|------|--------|-------------|\n")
+
+		for _, d := range c.Metrics {
+			labels := docsLabels(d)
+			for idx, label := range labels {
+				labels[idx] = "`" + label + "`"
+			}
+
+			fmt.Fprintf(&sb, "| `%s` | %s | %s | %s | %s |\n",
+				d.Name, d.Type, d.Unit, strings.Join(labels, ", "), strings.ReplaceAll(d.Help, "|", `\|`))
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// docsLabels returns the labels of the metric family, except for the node label carried by every metric
+func docsLabels(d MetricDescription) []string {
+	labels := make([]string, 0, len(d.Labels))
+	for _, label := range d.Labels {
+		if label != "node" {
+			labels = append(labels, label)
+		}
+	}
+
+	return labels
+}
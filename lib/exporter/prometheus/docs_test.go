@@ -0,0 +1,52 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteMetricDocs(t *testing.T) {
+	testCases := map[string]struct {
+		format      string
+		wantHeading string
+		wantRows    []string
+	}{
+		"markdown": {
+			format:      DocsFormatMarkdown,
+			wantHeading: "\n## smart\n",
+			wantRows: []string{
+				"| `node_disk_smart_healthy` | gauge |  | `device`, `serial`, `model` | Whether the device passed the S.M.A.R.T. overall-health self-assessment test |\n",
+				"| `node_network_monthly_receive_bytes` | gauge | bytes | `device` |",
+			},
+		},
+		"html": {
+			format:      DocsFormatHTML,
+			wantHeading: `<h2 id="smart">smart</h2>`,
+			wantRows: []string{
+				"<tr><td><code>node_disk_smart_healthy</code></td><td>gauge</td><td></td><td><code>device</code>, <code>serial</code>, <code>model</code></td>",
+				"<tr><td><code>node_network_monthly_receive_bytes</code></td><td>gauge</td><td>bytes</td><td><code>device</code></td>",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var sb strings.Builder
+			require.NoError(t, WriteMetricDocs(&sb, tc.format))
+
+			docs := sb.String()
+			assert.Contains(t, docs, tc.wantHeading)
+			for _, row := range tc.wantRows {
+				assert.Contains(t, docs, row)
+			}
+			for _, d := range MetricCatalog() {
+				assert.Contains(t, docs, d.Name)
+			}
+		})
+	}
+
+	assert.EqualError(t, WriteMetricDocs(&strings.Builder{}, "pdf"), `unknown docs format "pdf"`)
+}
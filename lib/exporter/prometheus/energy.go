@@ -0,0 +1,105 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// upsPowerMaxAge is how long the power read from the UPS is used before falling back to the estimate
+	upsPowerMaxAge = 5 * time.Minute
+	// energyMaxGap is the longest interval between two power samples over which the energy is integrated,
+	// so that the time during which the exporter was stopped is not counted
+	energyMaxGap = time.Hour
+)
+
+// energyState holds the cumulative energy and cost, along with the last power sample they were integrated up to
+type energyState struct {
+	KWh  float64     `json:"kwh"`
+	Cost float64     `json:"cost"`
+	Last stateSample `json:"last"`
+}
+
+// powerDraw returns the power drawn by the NAS and its source: the UPS when it reports its power, else the
+// configured estimate or the one of the model quirks. It returns false if the power is neither measured nor estimated.
+func (e *promExporter) powerDraw() (float64, string, bool) {
+	if watts, ok := e.lastUpsPower(upsPowerMaxAge); ok {
+		return watts, "ups", true
+	}
+	if e.PowerDrawWatts > 0 {
+		return e.PowerDrawWatts, "estimate", true
+	}
+	if e.quirk.PowerWatts != nil {
+		return *e.quirk.PowerWatts, "estimate", true
+	}
+
+	return 0, "", false
+}
+
+// getEnergyMetrics integrates the power drawn by the NAS into cumulative energy and cost counters, which are
+// kept in the state file so that they survive restarts
+func (e *promExporter) getEnergyMetrics(ctx context.Context) ([]metric, error) {
+	watts, source, ok := e.powerDraw()
+	if !ok {
+		return nil, subsystemAbsentError{"no UPS reporting its power and no power draw estimate configured"}
+	}
+
+	now := time.Now()
+	var energy energyState
+	err := e.state.update(func(state *exporterState) bool {
+		energy = integrateEnergy(state, watts, e.ElectricityPrice, now)
+
+		if now.Sub(e.energySaved) < stateSaveInterval {
+			return false
+		}
+		e.energySaved = now
+		return true
+	})
+
+	metrics := []metric{
+		{
+			name:  "node_power_watts",
+			attr:  fmt.Sprintf("source=%q", source),
+			value: watts,
+			help:  "Power drawn by the NAS, as measured by the UPS or estimated",
+		},
+		{
+			name:       "node_energy_kwh_total",
+			value:      energy.KWh,
+			help:       "Energy consumed by the NAS, integrated from its power draw",
+			metricType: "counter",
+		},
+	}
+	if e.ElectricityPrice > 0 {
+		metrics = append(metrics, metric{
+			name:       "node_energy_cost_total",
+			attr:       fmt.Sprintf("currency=%q", e.ElectricityCurrency),
+			value:      energy.Cost,
+			help:       "Estimated cost of the energy consumed by the NAS, at the configured electricity price",
+			metricType: "counter",
+		})
+	}
+
+	return metrics, err
+}
+
+// integrateEnergy adds the energy consumed since the previous sample to the counters of state, at price per kWh,
+// using the average of the previous and current power draw, and returns the updated counters
+func integrateEnergy(state *exporterState, watts float64, price float64, now time.Time) energyState {
+	if state.Energy == nil {
+		state.Energy = &energyState{}
+	}
+	energy := state.Energy
+
+	if last := energy.Last; !last.Time.IsZero() {
+		if elapsed := now.Sub(last.Time); elapsed > 0 && elapsed <= energyMaxGap {
+			kwh := (last.Value + watts) / 2 * elapsed.Hours() / 1000
+			energy.KWh += kwh
+			energy.Cost += kwh * price
+		}
+	}
+	energy.Last = stateSample{Value: watts, Time: now}
+
+	return *energy
+}
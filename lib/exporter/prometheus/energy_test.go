@@ -0,0 +1,131 @@
+package prometheus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrateEnergy(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		samples  []stateSample
+		wantKWh  float64
+		wantCost float64
+	}{
+		"first sample": {
+			samples: []stateSample{{Value: 40, Time: start}},
+		},
+		"constant power": {
+			samples: []stateSample{
+				{Value: 40, Time: start},
+				{Value: 40, Time: start.Add(30 * time.Minute)},
+				{Value: 40, Time: start.Add(time.Hour)},
+			},
+			wantKWh:  0.04,
+			wantCost: 0.01,
+		},
+		"changing power": {
+			samples: []stateSample{
+				{Value: 20, Time: start},
+				{Value: 60, Time: start.Add(time.Hour)},
+			},
+			wantKWh:  0.04,
+			wantCost: 0.01,
+		},
+		"gap while stopped": {
+			samples: []stateSample{
+				{Value: 40, Time: start},
+				{Value: 40, Time: start.Add(30 * time.Minute)},
+				{Value: 40, Time: start.Add(5 * time.Hour)},
+				{Value: 40, Time: start.Add(5*time.Hour + 30*time.Minute)},
+			},
+			wantKWh:  0.04,
+			wantCost: 0.01,
+		},
+		"clock going backwards": {
+			samples: []stateSample{
+				{Value: 40, Time: start},
+				{Value: 40, Time: start.Add(-time.Hour)},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var state exporterState
+			var energy energyState
+			for _, s := range tc.samples {
+				energy = integrateEnergy(&state, s.Value, 0.25, s.Time)
+			}
+
+			assert.InDelta(t, tc.wantKWh, energy.KWh, 1e-9)
+			assert.InDelta(t, tc.wantCost, energy.Cost, 1e-9)
+			assert.Equal(t, energy, *state.Energy)
+		})
+	}
+}
+
+func TestGetEnergyMetrics(t *testing.T) {
+	watts := 32.0
+	tests := map[string]struct {
+		config       ExporterConfig
+		quirk        sensorQuirk
+		upsWatts     float64
+		wantAbsent   bool
+		wantSource   string
+		wantWatts    float64
+		wantCurrency string
+	}{
+		"no power": {
+			wantAbsent: true,
+		},
+		"model estimate": {
+			quirk:      sensorQuirk{PowerWatts: &watts},
+			wantSource: "estimate",
+			wantWatts:  32,
+		},
+		"configured estimate": {
+			config:     ExporterConfig{PowerDrawWatts: 45},
+			quirk:      sensorQuirk{PowerWatts: &watts},
+			wantSource: "estimate",
+			wantWatts:  45,
+		},
+		"measured by the UPS": {
+			config:       ExporterConfig{PowerDrawWatts: 45, ElectricityPrice: 0.25, ElectricityCurrency: "EUR"},
+			upsWatts:     51,
+			wantSource:   "ups",
+			wantWatts:    51,
+			wantCurrency: "EUR",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tc.config.Logger = logging.Discard()
+			e := &promExporter{ExporterConfig: tc.config, quirk: tc.quirk, state: newStateStore("")}
+			if tc.upsWatts > 0 {
+				e.upsState.powerWatts, e.upsState.powerTime = tc.upsWatts, time.Now()
+			}
+
+			metrics, err := e.getEnergyMetrics(context.Background())
+			if tc.wantAbsent {
+				assert.ErrorAs(t, err, &subsystemAbsentError{})
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, map[string]float64{`source="` + tc.wantSource + `"`: tc.wantWatts}, metricValues("node_power_watts")(metrics))
+			assert.Equal(t, map[string]float64{"": 0}, metricValues("node_energy_kwh_total")(metrics))
+			if tc.wantCurrency != "" {
+				assert.Equal(t, map[string]float64{`currency="` + tc.wantCurrency + `"`: 0}, metricValues("node_energy_cost_total")(metrics))
+			} else {
+				assert.Empty(t, metricValues("node_energy_cost_total")(metrics))
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+var errPreviousRunInProgress = errors.New("previous run is still in progress")
+
+// classifyError maps a collector error to a coarse class with bounded cardinality,
+// suitable for use as a label value. The full error message is only logged.
+func classifyError(err error) string {
+	var netErr net.Error
+	var exitErr *exec.ExitError
+	var numErr *strconv.NumError
+
+	switch {
+	case errors.Is(err, errPreviousRunInProgress):
+		return "in_progress"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, os.ErrNotExist), errors.Is(err, exec.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, os.ErrPermission):
+		return "permission"
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE), netErr != nil:
+		return "connection"
+	case errors.As(err, &exitErr):
+		return "command"
+	case errors.As(err, &numErr):
+		return "parse"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,39 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyError(t *testing.T) {
+	_, numErr := strconv.Atoi("abc")
+
+	testCases := map[string]struct {
+		err      error
+		expected string
+	}{
+		"previous run in progress": {err: errPreviousRunInProgress, expected: "in_progress"},
+		"deadline exceeded":        {err: fmt.Errorf("wrapped: %w", context.DeadlineExceeded), expected: "timeout"},
+		"canceled":                 {err: context.Canceled, expected: "canceled"},
+		"missing file":             {err: &os.PathError{Op: "open", Path: "/proc/x", Err: syscall.ENOENT}, expected: "not_found"},
+		"missing executable":       {err: &exec.Error{Name: "getsysinfo", Err: exec.ErrNotFound}, expected: "not_found"},
+		"permission denied":        {err: &os.PathError{Op: "open", Path: "/dev/sda", Err: syscall.EACCES}, expected: "permission"},
+		"connection refused":       {err: os.NewSyscallError("connect", syscall.ECONNREFUSED), expected: "connection"},
+		"parse":                    {err: numErr, expected: "parse"},
+		"other":                    {err: errors.New("boom"), expected: "other"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, classifyError(tc.err))
+		})
+	}
+}
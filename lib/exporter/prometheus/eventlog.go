@@ -0,0 +1,259 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"log/syslog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+const (
+	// eventLogPath is the SQLite database where QTS keeps the system event log
+	eventLogPath = "/etc/logs/event.log"
+	// eventLogSeparator separates the columns returned by sqlite3, as it doesn't appear in event descriptions
+	eventLogSeparator = "\x1f"
+	eventLogSyslogTag = "qts"
+)
+
+// eventLogSeverities maps the event_type column of the event log to a severity
+var eventLogSeverities = map[int]string{0: "information", 1: "warning", 2: "error"}
+
+type eventLogEntry struct {
+	id       int64
+	severity string
+	time     time.Time
+	category string
+	desc     string
+}
+
+type eventLogKey struct {
+	severity string
+	category string
+}
+
+// eventForwarder sends events to a syslog server (implemented by *syslog.Writer)
+type eventForwarder interface {
+	Info(m string) error
+	Warning(m string) error
+	Err(m string) error
+	Close() error
+}
+
+// eventLogState holds the event counts, which are kept across scrapes to only read new events
+type eventLogState struct {
+	mu            sync.Mutex
+	loaded        bool
+	lastID        int64
+	counts        map[eventLogKey]float64
+	lastErrorTime time.Time
+	forwarder     eventForwarder
+}
+
+// ParseSyslogAddress splits a syslog server address such as udp://192.168.1.10:514 into network and address.
+// The network defaults to udp.
+func ParseSyslogAddress(addr string) (string, string, error) {
+	network, address, found := strings.Cut(addr, "://")
+	if !found {
+		network, address = "udp", addr
+	}
+
+	switch network {
+	case "udp", "tcp":
+	default:
+		return "", "", fmt.Errorf("unsupported syslog network %q in %q", network, addr)
+	}
+	if address == "" {
+		return "", "", fmt.Errorf("missing syslog server address in %q", addr)
+	}
+
+	return network, address, nil
+}
+
+func (e *promExporter) getEventLogMetrics(ctx context.Context) ([]metric, error) {
+	if e.sqlite3 == "" {
+		return nil, subsystemAbsentError{"sqlite3 not found"}
+	}
+	if _, err := e.sys.Stat(eventLogPath); os.IsNotExist(err) {
+		return nil, subsystemAbsentError{fmt.Sprintf("%s not found", eventLogPath)}
+	}
+
+	s := &e.eventLog
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := readEventLog(e.sys, ctx, e.sqlite3, eventLogPath, s.lastID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only forward the events which happen while the exporter is running, not the whole history
+	if s.loaded && e.EventLogSyslog != "" {
+		if err := s.forward(e.EventLogSyslog, entries); err != nil {
+			e.Logger.Error("Error forwarding events to syslog", "address", e.EventLogSyslog, "err", err)
+		}
+	}
+	s.add(entries)
+
+	return s.metrics(), nil
+}
+
+// readEventLog returns the entries of the event log at path whose ID is greater than afterID
+func readEventLog(sys utils.System, ctx context.Context, sqlite3 string, path string, afterID int64) ([]eventLogEntry, error) {
+	query := fmt.Sprintf(
+		"SELECT event_id, event_type, event_date || ' ' || event_time, event_comp, "+
+			"replace(replace(event_desc, char(13), ' '), char(10), ' ') "+
+			"FROM NASLOG_EVENT WHERE event_id > %d ORDER BY event_id;", afterID)
+	output, err := sys.ExecCommand(ctx, sqlite3, "-readonly", "-batch", "-noheader", "-separator", eventLogSeparator, path, query)
+	if err != nil {
+		return nil, fmt.Errorf("read event log: %w", err)
+	}
+
+	return parseEventLog(output), nil
+}
+
+// parseEventLog parses the rows returned by readEventLog, e.g.:
+//
+//	1234\x1f1\x1f2023-01-01 12:00:00\x1fStorage & Snapshots\x1f[Storage & Snapshots] Disk 2 was removed.
+func parseEventLog(output string) []eventLogEntry {
+	var entries []eventLogEntry
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, eventLogSeparator, 5)
+		if len(fields) != 5 {
+			continue
+		}
+
+		id, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		eventType, _ := strconv.Atoi(fields[1])
+		severity, found := eventLogSeverities[eventType]
+		if !found {
+			severity = "unknown"
+		}
+		t, _ := time.ParseInLocation("2006-01-02 15:04:05", fields[2], time.Local)
+		category := strings.TrimSpace(fields[3])
+		if category == "" || category == "-" {
+			category = "other"
+		}
+
+		entries = append(entries, eventLogEntry{
+			id:       id,
+			severity: severity,
+			time:     t,
+			category: category,
+			desc:     strings.TrimSpace(fields[4]),
+		})
+	}
+
+	return entries
+}
+
+func (s *eventLogState) add(entries []eventLogEntry) {
+	s.loaded = true
+	if s.counts == nil {
+		s.counts = make(map[eventLogKey]float64)
+	}
+
+	for _, entry := range entries {
+		if entry.id > s.lastID {
+			s.lastID = entry.id
+		}
+		if entry.severity != "warning" && entry.severity != "error" {
+			continue
+		}
+
+		s.counts[eventLogKey{severity: entry.severity, category: entry.category}]++
+		if entry.severity == "error" && entry.time.After(s.lastErrorTime) {
+			s.lastErrorTime = entry.time
+		}
+	}
+}
+
+func (s *eventLogState) forward(addr string, entries []eventLogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if s.forwarder == nil {
+		network, address, err := ParseSyslogAddress(addr)
+		if err != nil {
+			return err
+		}
+
+		w, err := syslog.Dial(network, address, syslog.LOG_DAEMON|syslog.LOG_INFO, eventLogSyslogTag)
+		if err != nil {
+			return err
+		}
+		s.forwarder = w
+	}
+
+	for _, entry := range entries {
+		msg := fmt.Sprintf("[%s] %s", entry.category, entry.desc)
+
+		var err error
+		switch entry.severity {
+		case "error":
+			err = s.forwarder.Err(msg)
+		case "warning":
+			err = s.forwarder.Warning(msg)
+		default:
+			err = s.forwarder.Info(msg)
+		}
+		if err != nil {
+			// Reconnect on the next scrape
+			s.closeForwarder()
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *eventLogState) closeForwarder() {
+	if s.forwarder != nil {
+		_ = s.forwarder.Close()
+		s.forwarder = nil
+	}
+}
+
+func (s *eventLogState) metrics() []metric {
+	keys := make([]eventLogKey, 0, len(s.counts))
+	for key := range s.counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].severity != keys[j].severity {
+			return keys[i].severity < keys[j].severity
+		}
+		return keys[i].category < keys[j].category
+	})
+
+	metrics := make([]metric, 0, len(keys)+1)
+	for _, key := range keys {
+		metrics = append(metrics, metric{
+			name:       "node_event_log_events_total",
+			attr:       fmt.Sprintf("severity=%q,category=%q", key.severity, key.category),
+			value:      s.counts[key],
+			help:       "Number of warning and error events in the QTS system event log",
+			metricType: "counter",
+		})
+	}
+
+	if !s.lastErrorTime.IsZero() {
+		metrics = append(metrics, metric{
+			name:  "node_event_log_last_error_timestamp_seconds",
+			value: float64(s.lastErrorTime.Unix()),
+			help:  "Time of the most recent error event in the QTS system event log",
+		})
+	}
+
+	return metrics
+}
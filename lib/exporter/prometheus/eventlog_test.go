@@ -0,0 +1,153 @@
+package prometheus
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEventForwarder struct {
+	messages []string
+}
+
+func (f *fakeEventForwarder) Info(m string) error {
+	f.messages = append(f.messages, "info: "+m)
+	return nil
+}
+
+func (f *fakeEventForwarder) Warning(m string) error {
+	f.messages = append(f.messages, "warning: "+m)
+	return nil
+}
+
+func (f *fakeEventForwarder) Err(m string) error {
+	f.messages = append(f.messages, "err: "+m)
+	return nil
+}
+
+func (f *fakeEventForwarder) Close() error {
+	return nil
+}
+
+func TestReadEventLog(t *testing.T) {
+	sqlite3 := filepath.Join(t.TempDir(), "sqlite3")
+	script := `#!/bin/sh
+case "$*" in
+  *"WHERE event_id > 12 "*)
+    printf '13\0371\0372023-01-01 12:00:00\037Storage & Snapshots\037Disk 2 was removed.\n'
+    printf '14\0372\0372023-01-01 12:05:00\037-\037Fan 1 failed.\n'
+    printf 'invalid line\n'
+    ;;
+esac
+`
+	require.NoError(t, os.WriteFile(sqlite3, []byte(script), 0o755))
+
+	entries, err := readEventLog(utils.System{}, context.Background(), sqlite3, "/etc/logs/event.log", 12)
+	require.NoError(t, err)
+
+	assert.Equal(t, []eventLogEntry{
+		{
+			id:       13,
+			severity: "warning",
+			time:     time.Date(2023, 1, 1, 12, 0, 0, 0, time.Local),
+			category: "Storage & Snapshots",
+			desc:     "Disk 2 was removed.",
+		},
+		{
+			id:       14,
+			severity: "error",
+			time:     time.Date(2023, 1, 1, 12, 5, 0, 0, time.Local),
+			category: "other",
+			desc:     "Fan 1 failed.",
+		},
+	}, entries)
+}
+
+func TestEventLogState(t *testing.T) {
+	errorTime := time.Date(2023, 1, 1, 12, 5, 0, 0, time.UTC)
+	forwarder := &fakeEventForwarder{}
+	s := eventLogState{forwarder: forwarder}
+
+	s.add([]eventLogEntry{
+		{id: 1, severity: "information", category: "System", desc: "System started."},
+		{id: 2, severity: "warning", category: "Hardware", desc: "Fan 1 is slow."},
+	})
+	newEntries := []eventLogEntry{
+		{id: 3, severity: "error", time: errorTime, category: "Hardware", desc: "Fan 1 failed."},
+		{id: 4, severity: "warning", category: "Hardware", desc: "Fan 1 is slow."},
+	}
+	require.NoError(t, s.forward("udp://127.0.0.1:514", newEntries))
+	s.add(newEntries)
+
+	assert.Equal(t, int64(4), s.lastID)
+	assert.Equal(t, []string{"err: [Hardware] Fan 1 failed.", "warning: [Hardware] Fan 1 is slow."}, forwarder.messages)
+	assert.Equal(t, []metric{
+		{
+			name:       "node_event_log_events_total",
+			attr:       `severity="error",category="Hardware"`,
+			value:      1,
+			help:       "Number of warning and error events in the QTS system event log",
+			metricType: "counter",
+		},
+		{
+			name:       "node_event_log_events_total",
+			attr:       `severity="warning",category="Hardware"`,
+			value:      2,
+			help:       "Number of warning and error events in the QTS system event log",
+			metricType: "counter",
+		},
+		{
+			name:  "node_event_log_last_error_timestamp_seconds",
+			value: float64(errorTime.Unix()),
+			help:  "Time of the most recent error event in the QTS system event log",
+		},
+	}, s.metrics())
+}
+
+func TestParseSyslogAddress(t *testing.T) {
+	testCases := map[string]struct {
+		addr        string
+		wantNetwork string
+		wantAddress string
+		wantErr     string
+	}{
+		"default network": {
+			addr:        "192.168.1.10:514",
+			wantNetwork: "udp",
+			wantAddress: "192.168.1.10:514",
+		},
+		"tcp": {
+			addr:        "tcp://syslog.example.com:514",
+			wantNetwork: "tcp",
+			wantAddress: "syslog.example.com:514",
+		},
+		"unsupported network": {
+			addr:    "http://syslog.example.com",
+			wantErr: `unsupported syslog network "http" in "http://syslog.example.com"`,
+		},
+		"missing address": {
+			addr:    "udp://",
+			wantErr: `missing syslog server address in "udp://"`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			network, address, err := ParseSyslogAddress(tc.addr)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantNetwork, network)
+			assert.Equal(t, tc.wantAddress, address)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package prometheus
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// labelValueReplacer escapes label values, for which the text format only supports \\, \" and \n
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	// helpReplacer escapes HELP lines, for which the text format only supports \\ and \n
+	helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+// metricFamily groups the samples sharing a metric name, which must be written under a single HELP/TYPE block
+type metricFamily struct {
+	name       string
+	help       string
+	metricType string
+	samples    []sample
+}
+
+type sample struct {
+	labels    string
+	value     float64
+	timestamp string
+}
+
+// groupMetricFamilies groups metrics by name, rendering their labels after the target labels.
+// Families are sorted by name and samples by labels, so that the output is deterministic.
+func groupMetricFamilies(targetLabels []label, metrics []metric) []*metricFamily {
+	byName := make(map[string]*metricFamily)
+	for _, m := range metrics {
+		f, found := byName[m.name]
+		if !found {
+			f = &metricFamily{name: m.name}
+			byName[m.name] = f
+		}
+		// Only some samples of a family may carry its metadata
+		if f.help == "" {
+			f.help = m.help
+		}
+		if f.metricType == "" {
+			f.metricType = m.metricType
+		}
+
+		s := sample{labels: formatExpositionLabels(targetLabels, m.attr), value: m.value}
+		if !m.timestamp.IsZero() {
+			s.timestamp = strconv.FormatInt(m.timestamp.UnixMilli(), 10)
+		}
+		f.samples = append(f.samples, s)
+	}
+
+	families := make([]*metricFamily, 0, len(byName))
+	for _, f := range byName {
+		if f.metricType == "" {
+			f.metricType = defaultMetricType
+		}
+		sort.SliceStable(f.samples, func(i, j int) bool { return f.samples[i].labels < f.samples[j].labels })
+		families = append(families, f)
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	return families
+}
+
+// formatExpositionLabels renders the target labels followed by the metric attributes, escaped as
+// required by the text format (attributes are formatted with %q, which uses Go escape sequences)
+func formatExpositionLabels(targetLabels []label, attr string) string {
+	names, values, err := parseAttr(attr)
+	if err != nil {
+		// Keep the attributes as they are rather than dropping the sample
+		return strings.TrimPrefix(formatLabels(targetLabels)+","+attr, ",")
+	}
+
+	parts := make([]string, 0, len(targetLabels)+len(names))
+	for _, l := range targetLabels {
+		parts = append(parts, l.name+`="`+labelValueReplacer.Replace(l.value)+`"`)
+	}
+	for idx, name := range names {
+		parts = append(parts, name+`="`+labelValueReplacer.Replace(values[idx])+`"`)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func writeMetricFamilies(w io.Writer, families []*metricFamily) {
+	for _, f := range families {
+		if f.help != "" {
+			fmt.Fprintf(w, "# HELP %s %s\n", f.name, helpReplacer.Replace(f.help))
+		}
+		if f.metricType != "" {
+			fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.metricType)
+		}
+
+		for _, s := range f.samples {
+			name := f.name
+			if s.labels != "" {
+				name += "{" + s.labels + "}"
+			}
+			if s.timestamp != "" {
+				fmt.Fprintf(w, "%s %s %s\n", name, formatSampleValue(s.value), s.timestamp)
+			} else {
+				fmt.Fprintf(w, "%s %s\n", name, formatSampleValue(s.value))
+			}
+		}
+	}
+}
+
+func formatSampleValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
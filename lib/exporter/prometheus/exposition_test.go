@@ -0,0 +1,78 @@
+package prometheus
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteMetricFamilies(t *testing.T) {
+	tests := map[string]struct {
+		targetLabels []label
+		metrics      []metric
+		expected     string
+	}{
+		"groups samples under a single metadata block": {
+			targetLabels: []label{{name: "node", value: "nas"}},
+			metrics: []metric{
+				{name: "node_sysfan_RPM", attr: `fan="2"`, value: 900, help: "Fan speed", metricType: "gauge"},
+				{name: "node_load1", value: 0.5},
+				{name: "node_sysfan_RPM", attr: `fan="1"`, value: 1000, help: "Fan speed", metricType: "gauge"},
+			},
+			expected: `# TYPE node_load1 gauge
+node_load1{node="nas"} 0.5
+# HELP node_sysfan_RPM Fan speed
+# TYPE node_sysfan_RPM gauge
+node_sysfan_RPM{node="nas",fan="1"} 1000
+node_sysfan_RPM{node="nas",fan="2"} 900
+`,
+		},
+		"metadata from any sample": {
+			metrics: []metric{
+				{name: "node_errors_total", attr: `device="a"`, value: 1},
+				{name: "node_errors_total", attr: `device="b"`, value: 2, help: "Errors", metricType: "counter"},
+			},
+			expected: `# HELP node_errors_total Errors
+# TYPE node_errors_total counter
+node_errors_total{device="a"} 1
+node_errors_total{device="b"} 2
+`,
+		},
+		"escaping": {
+			targetLabels: []label{{name: "node", value: `nas\1`}},
+			metrics: []metric{
+				{name: "node_info", attr: `name="a \"b\"\nc\td"`, value: 1, help: "Line 1\nC:\\path"},
+			},
+			expected: `# HELP node_info Line 1\nC:\\path
+# TYPE node_info gauge
+node_info{node="nas\\1",name="a \"b\"\nc` + "\t" + `d"} 1
+`,
+		},
+		"timestamps in milliseconds": {
+			metrics: []metric{
+				{name: "node_ping_rtt_seconds", value: 0.01, timestamp: time.Unix(1700000000, 123456789)},
+			},
+			expected: "# TYPE node_ping_rtt_seconds gauge\nnode_ping_rtt_seconds 0.01 1700000000123\n",
+		},
+		"special values": {
+			metrics: []metric{
+				{name: "a", value: math.Inf(1)},
+				{name: "b", value: math.NaN()},
+				{name: "c", value: 1e21},
+			},
+			expected: "# TYPE a gauge\na +Inf\n# TYPE b gauge\nb NaN\n# TYPE c gauge\nc 1e+21\n",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := new(bytes.Buffer)
+			writeMetricFamilies(b, groupMetricFamilies(tc.targetLabels, tc.metrics))
+
+			assert.Equal(t, tc.expected, b.String())
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+const (
+	mountsPath = "/proc/mounts"
+	// externalShareDir is where QTS mounts the external USB and eSATA disks, e.g. /share/external/DEV3301_1
+	externalShareDir = "/share/external/"
+)
+
+type externalDisk struct {
+	device string
+	bus    string
+	model  string
+	size   float64
+}
+
+func (e *promExporter) getExternalDiskMetrics(ctx context.Context) ([]metric, error) {
+	mounts, err := e.sys.ReadFileLines(mountsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	disks := findExternalDisks(e.sys, e.devices, e.blockDir, mounts)
+	metrics := make([]metric, 0, 1+len(disks)*3)
+	metrics = append(metrics, metric{
+		name:  "node_external_disks",
+		value: float64(len(disks)),
+		help:  "Number of external USB and eSATA disks connected",
+	})
+
+	for _, disk := range disks {
+		attr := fmt.Sprintf("device=%q", disk.device)
+		metrics = append(metrics,
+			metric{
+				name:  "node_external_disk_info",
+				attr:  fmt.Sprintf("%s,bus=%q,model=%q", attr, disk.bus, disk.model),
+				value: 1,
+				help:  "External disk connected through USB or eSATA",
+			},
+			metric{
+				name:  "node_external_disk_size_bytes",
+				attr:  attr,
+				value: disk.size,
+				help:  "Capacity of the external disk",
+			},
+		)
+
+		if e.smartctl == "" {
+			continue
+		}
+
+		// Use `-n standby` so that we don't wake up sleeping disks
+		output, exitCode, err := e.sys.ExecCommandWithExitCode(ctx, e.smartctl, "-n", "standby", "-A", path.Join(devDir, disk.device))
+		if err != nil {
+			return metrics, err
+		}
+		if exitCode&smartctlFatalExitMask != 0 {
+			// e.g. a USB bridge which doesn't pass S.M.A.R.T. commands through
+			continue
+		}
+
+		if temperature := parseSmartctlOutput(output).temperature; temperature != nil {
+			metrics = append(metrics, metric{
+				name:  "node_external_disk_temperature_celsius",
+				attr:  attr,
+				value: *temperature,
+				help:  "Temperature of the external disk as reported by S.M.A.R.T.",
+			})
+		}
+	}
+
+	return metrics, nil
+}
+
+// findExternalDisks returns the devices which are attached through USB, according to their sysfs path in blockDir,
+// or which are mounted under externalShareDir by QTS (e.g. an eSATA disk)
+func findExternalDisks(sys utils.System, devices []string, blockDir string, mounts []string) []externalDisk {
+	mountedExternally := make(map[string]bool)
+	for _, line := range mounts {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.HasPrefix(fields[1], externalShareDir) {
+			continue
+		}
+
+		// e.g. /dev/sdc1 is a partition of sdc
+		mountedExternally[strings.TrimRight(path.Base(fields[0]), "0123456789")] = true
+	}
+
+	var disks []externalDisk
+	for _, dev := range devices {
+		if !strings.HasPrefix(dev, "sd") {
+			continue
+		}
+
+		var bus string
+		link, err := sys.Readlink(filepath.Join(blockDir, dev))
+		if err != nil {
+			// In the deprecated sysfs layout, the disk directory is not a link, but its device is
+			link, err = sys.EvalSymlinks(filepath.Join(blockDir, dev, "device"))
+		}
+		switch {
+		case err == nil && strings.Contains(link, "/usb"):
+			bus = "usb"
+		case mountedExternally[dev]:
+			bus = "esata"
+		default:
+			continue
+		}
+
+		disk := externalDisk{device: dev, bus: bus}
+		if model, err := sys.ReadFile(filepath.Join(blockDir, dev, "device", "model")); err == nil {
+			disk.model = strings.TrimSpace(model)
+		}
+		if sectors, err := sys.ReadFile(filepath.Join(blockDir, dev, "size")); err == nil {
+			// The size is always expressed in 512-byte sectors
+			if value, err := utils.ParseFloat(strings.TrimSpace(sectors)); err == nil {
+				disk.size = value * 512
+			}
+		}
+
+		disks = append(disks, disk)
+	}
+
+	return disks
+}
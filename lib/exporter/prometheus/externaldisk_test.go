@@ -0,0 +1,41 @@
+package prometheus
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindExternalDisks(t *testing.T) {
+	root := t.TempDir()
+	blockDir := filepath.Join(root, "class", "block")
+	require.NoError(t, os.MkdirAll(blockDir, 0o755))
+
+	addDisk := func(dev, parent, model, sectors string) {
+		dir := filepath.Join(root, "devices", parent, "block", dev)
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "device"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "device", "model"), []byte(model+"\n"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "size"), []byte(sectors+"\n"), 0o644))
+		require.NoError(t, os.Symlink(filepath.Join("..", "..", "devices", parent, "block", dev), filepath.Join(blockDir, dev)))
+	}
+	addDisk("sda", "pci0000:00/0000:00:17.0/ata1/host0/target0:0:0/0:0:0:0", "WDC WD40EFRX", "7814037168")
+	addDisk("sdb", "pci0000:00/0000:00:17.0/ata2/host1/target1:0:0/1:0:0:0", "ST4000VN008", "7814037168")
+	addDisk("sdc", "pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/host6/target6:0:0/6:0:0:0", "Expansion HDD", "3907029168")
+
+	mounts := []string{
+		"/dev/md9 /mnt/HDA_ROOT ext4 rw,relatime 0 0",
+		"/dev/sdb1 /share/external/DEV3302_1 ext4 rw,relatime 0 0",
+		"/dev/sdc1 /share/external/DEV3301_1 ufsd rw,relatime 0 0",
+	}
+
+	disks := findExternalDisks(utils.System{}, []string{"nvme0n1", "sda", "sdb", "sdc"}, blockDir, mounts)
+
+	assert.Equal(t, []externalDisk{
+		{device: "sdb", bus: "esata", model: "ST4000VN008", size: 7814037168 * 512},
+		{device: "sdc", bus: "usb", model: "Expansion HDD", size: 3907029168 * 512},
+	}, disks)
+}
@@ -0,0 +1,106 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+// fanProfiles maps the Fan Mode values of uLinux.conf to the profiles of the smart fan control in QTS
+var fanProfiles = map[string]string{
+	"0": "quiet",
+	"1": "normal",
+	"2": "performance",
+}
+
+// fanPolicy is the fan control configured in QTS
+type fanPolicy struct {
+	// mode is smart when QTS adjusts the fan speed to the temperatures, else manual
+	mode string
+	// profile is the smart fan profile (e.g. quiet), or the fan speed level in manual mode
+	profile string
+	// thresholds maps the temperature settings (e.g. system_temp_high) to their value in °C
+	thresholds map[string]float64
+}
+
+// getFanPolicyMetrics reports the fan control configured in QTS, so that a change of fan behavior can be correlated
+// with a configuration change
+func (e *promExporter) getFanPolicyMetrics(ctx context.Context) ([]metric, error) {
+	if _, err := e.sys.Stat(qtsConfigPath); os.IsNotExist(err) {
+		return nil, subsystemAbsentError{fmt.Sprintf("%s not found", qtsConfigPath)}
+	}
+
+	lines, err := e.sys.ReadFileLines(qtsConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, found := parseFanPolicy(lines)
+	if !found {
+		return nil, subsystemAbsentError{fmt.Sprintf("no fan settings in %s", qtsConfigPath)}
+	}
+
+	metrics := []metric{
+		{
+			name:  "qnap_fan_policy_info",
+			attr:  fmt.Sprintf("mode=%q,profile=%q", policy.mode, policy.profile),
+			value: 1,
+			help:  "Fan control configured in QTS: smart (driven by the temperatures) or manual, and its profile or speed level",
+		},
+	}
+
+	settings := make([]string, 0, len(policy.thresholds))
+	for setting := range policy.thresholds {
+		settings = append(settings, setting)
+	}
+	sort.Strings(settings)
+	for _, setting := range settings {
+		metrics = append(metrics, metric{
+			name:  "qnap_fan_policy_threshold_celsius",
+			attr:  fmt.Sprintf("setting=%q", setting),
+			value: policy.thresholds[setting],
+			help:  "Temperature threshold of the fan control configured in QTS",
+		})
+	}
+
+	return metrics, nil
+}
+
+// parseFanPolicy extracts the fan control from the [Misc] section of uLinux.conf, e.g.:
+//
+//	[Misc]
+//	Smart Fan = TRUE
+//	Fan Mode = 1
+//	System Temp High = 55
+//	System Temp Low = 40
+//
+// It returns false if no fan setting is present.
+func parseFanPolicy(lines []string) (fanPolicy, bool) {
+	misc := parseQtsConfigSection(lines, "Misc")
+	smartFan, hasSmartFan := misc["Smart Fan"]
+	fanMode, hasFanMode := misc["Fan Mode"]
+
+	policy := fanPolicy{mode: "smart", thresholds: make(map[string]float64)}
+	if hasSmartFan && !strings.EqualFold(smartFan, "TRUE") {
+		policy.mode = "manual"
+	}
+	policy.profile = fanMode
+	if profile, known := fanProfiles[fanMode]; known && policy.mode == "smart" {
+		policy.profile = profile
+	}
+
+	for key, value := range misc {
+		if !strings.Contains(strings.ToLower(key), "temp") {
+			continue
+		}
+		if celsius, err := utils.ParseFloat(value); err == nil {
+			policy.thresholds[strings.ToLower(strings.Join(strings.Fields(key), "_"))] = celsius
+		}
+	}
+
+	return policy, hasSmartFan || hasFanMode || len(policy.thresholds) > 0
+}
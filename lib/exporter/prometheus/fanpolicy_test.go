@@ -0,0 +1,62 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFanPolicy(t *testing.T) {
+	tests := map[string]struct {
+		conf      string
+		want      fanPolicy
+		wantFound bool
+	}{
+		"smart fan": {
+			conf: `[System]
+Version = 5.1.0
+[Misc]
+Smart Fan = TRUE
+Fan Mode = 0
+System Temp High = 55
+System Temp Low = 40
+Buzzer Quiet Mode = FALSE`,
+			want: fanPolicy{
+				mode:       "smart",
+				profile:    "quiet",
+				thresholds: map[string]float64{"system_temp_high": 55, "system_temp_low": 40},
+			},
+			wantFound: true,
+		},
+		"manual speed": {
+			conf: `[Misc]
+Smart Fan = FALSE
+Fan Mode = 3`,
+			want:      fanPolicy{mode: "manual", profile: "3", thresholds: map[string]float64{}},
+			wantFound: true,
+		},
+		"unknown profile": {
+			conf: `[Misc]
+Fan Mode = 7`,
+			want:      fanPolicy{mode: "smart", profile: "7", thresholds: map[string]float64{}},
+			wantFound: true,
+		},
+		"no fan settings": {
+			conf: `[System]
+Fan Mode = 1
+[Misc]
+Buzzer Quiet Mode = FALSE`,
+			want: fanPolicy{mode: "smart", thresholds: map[string]float64{}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			policy, found := parseFanPolicy(strings.Split(tc.conf, "\n"))
+
+			assert.Equal(t, tc.wantFound, found)
+			assert.Equal(t, tc.want, policy)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// errInjectedFault is the error returned by a collector set to fail by a FaultInjector
+var errInjectedFault = errors.New("injected fault")
+
+// Fault makes a collector slow or failing, to check that the alerts on the exporter metrics fire
+type Fault struct {
+	Collector string `json:"collector"`
+	// Delay is added before the collector runs. It counts towards the collector timeout, so that a delay
+	// longer than the timeout makes the collector time out.
+	Delay time.Duration `json:"delay,omitempty"`
+	// Fail makes the collector return an error instead of its metrics
+	Fail bool `json:"fail,omitempty"`
+	// Expires is when the fault is removed (zero keeps it until it is cleared)
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+// FaultInjector holds the faults injected in the collectors, for testing the alert rules. It is safe for concurrent use.
+type FaultInjector struct {
+	mu     sync.Mutex
+	faults map[string]Fault
+}
+
+func NewFaultInjector() *FaultInjector {
+	return &FaultInjector{faults: make(map[string]Fault)}
+}
+
+// Set injects f, replacing the fault previously injected in the same collector
+func (fi *FaultInjector) Set(f Fault) error {
+	if !isCollectorName(f.Collector) {
+		return fmt.Errorf("unknown collector %q", f.Collector)
+	}
+	if f.Delay < 0 {
+		return fmt.Errorf("negative delay %s", f.Delay)
+	}
+	if f.Delay == 0 && !f.Fail {
+		return errors.New("the fault must either delay or fail the collector")
+	}
+
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+
+	fi.faults[f.Collector] = f
+	return nil
+}
+
+// Clear removes the fault injected in collector, or every fault if collector is empty
+func (fi *FaultInjector) Clear(collector string) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+
+	if collector == "" {
+		fi.faults = make(map[string]Fault)
+		return
+	}
+	delete(fi.faults, collector)
+}
+
+// Faults returns the faults currently injected, sorted by collector
+func (fi *FaultInjector) Faults() []Fault {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+
+	now := time.Now()
+	faults := make([]Fault, 0, len(fi.faults))
+	for _, f := range fi.faults {
+		if f.Expires.IsZero() || now.Before(f.Expires) {
+			faults = append(faults, f)
+		}
+	}
+	sort.Slice(faults, func(i, j int) bool { return faults[i].Collector < faults[j].Collector })
+
+	return faults
+}
+
+// inject delays the collector and returns errInjectedFault if it is set to fail. It does nothing if fi is nil.
+func (fi *FaultInjector) inject(ctx context.Context, collector string) error {
+	if fi == nil {
+		return nil
+	}
+
+	fi.mu.Lock()
+	f, found := fi.faults[collector]
+	if found && !f.Expires.IsZero() && !time.Now().Before(f.Expires) {
+		delete(fi.faults, collector)
+		found = false
+	}
+	fi.mu.Unlock()
+	if !found {
+		return nil
+	}
+
+	if f.Delay > 0 {
+		timer := time.NewTimer(f.Delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if f.Fail {
+		return errInjectedFault
+	}
+
+	return nil
+}
+
+func isCollectorName(name string) bool {
+	for _, n := range CollectorNames() {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,87 @@
+package prometheus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultInjectorSet(t *testing.T) {
+	tests := map[string]struct {
+		fault   Fault
+		wantErr string
+	}{
+		"fail": {
+			fault: Fault{Collector: "smart", Fail: true},
+		},
+		"delay": {
+			fault: Fault{Collector: "ups", Delay: time.Second},
+		},
+		"unknown collector": {
+			fault:   Fault{Collector: "foo", Fail: true},
+			wantErr: `unknown collector "foo"`,
+		},
+		"negative delay": {
+			fault:   Fault{Collector: "smart", Delay: -time.Second},
+			wantErr: "negative delay -1s",
+		},
+		"no effect": {
+			fault:   Fault{Collector: "smart"},
+			wantErr: "the fault must either delay or fail the collector",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			fi := NewFaultInjector()
+
+			err := fi.Set(tc.fault)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				assert.Empty(t, fi.Faults())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, []Fault{tc.fault}, fi.Faults())
+		})
+	}
+}
+
+func TestFaultInjectorInject(t *testing.T) {
+	ctx := context.Background()
+	fi := NewFaultInjector()
+	require.NoError(t, fi.Set(Fault{Collector: "smart", Fail: true}))
+	require.NoError(t, fi.Set(Fault{Collector: "ups", Delay: time.Hour}))
+	require.NoError(t, fi.Set(Fault{Collector: "zfs", Fail: true, Expires: time.Now().Add(-time.Second)}))
+
+	assert.ErrorIs(t, fi.inject(ctx, "smart"), errInjectedFault)
+	assert.NoError(t, fi.inject(ctx, "cpu"))
+	assert.NoError(t, fi.inject(ctx, "zfs"), "expired faults are ignored")
+
+	// The delay is cut short by the collector timeout
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, fi.inject(timeoutCtx, "ups"), context.DeadlineExceeded)
+
+	assert.Equal(t, []string{"smart", "ups"}, faultCollectors(fi.Faults()))
+	fi.Clear("smart")
+	assert.Equal(t, []string{"ups"}, faultCollectors(fi.Faults()))
+	fi.Clear("")
+	assert.Empty(t, fi.Faults())
+
+	var disabled *FaultInjector
+	assert.NoError(t, disabled.inject(ctx, "smart"))
+}
+
+func faultCollectors(faults []Fault) []string {
+	collectors := make([]string, 0, len(faults))
+	for _, f := range faults {
+		collectors = append(collectors, f.Collector)
+	}
+
+	return collectors
+}
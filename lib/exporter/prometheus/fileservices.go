@@ -0,0 +1,160 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+const (
+	procDir = "/proc"
+
+	// qnapSmbstatusPath is where QTS installs smbstatus, which is usually not in the PATH
+	qnapSmbstatusPath = "/usr/local/samba/bin/smbstatus"
+
+	// tcpStateEstablished is the TCP_ESTABLISHED state as reported in /proc/net/tcp
+	tcpStateEstablished = "01"
+)
+
+type fileService struct {
+	protocol string
+	daemons  []string
+	port     uint64
+}
+
+var fileServices = []fileService{
+	{protocol: "smb", daemons: []string{"smbd"}, port: 445},
+	{protocol: "nfs", daemons: []string{"nfsd"}, port: 2049},
+	{protocol: "afp", daemons: []string{"afpd"}, port: 548},
+}
+
+func (e *promExporter) getFileServiceMetrics(ctx context.Context) ([]metric, error) {
+	running, err := runningProcessNames(e.sys)
+	if err != nil {
+		return nil, err
+	}
+
+	var tcpLines []string
+	for _, f := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		lines, err := e.sys.ReadFileLines(f)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		tcpLines = append(tcpLines, lines...)
+	}
+
+	metrics := make([]metric, 0, len(fileServices)*2)
+	for _, s := range fileServices {
+		attr := fmt.Sprintf("protocol=%q", s.protocol)
+
+		var up float64
+		for _, d := range s.daemons {
+			if running[d] {
+				up = 1
+			}
+		}
+
+		connections := countEstablishedConnections(tcpLines, s.port)
+		if s.protocol == "smb" && e.smbstatus != "" && up == 1 {
+			output, err := e.sys.ExecCommand(ctx, e.smbstatus, "-b")
+			if err != nil {
+				return metrics, err
+			}
+			connections = parseSmbStatusSessions(output)
+		}
+
+		metrics = append(
+			metrics,
+			metric{
+				name:  "node_fileservice_up",
+				attr:  attr,
+				value: up,
+				help:  "Whether the file service daemon is running",
+			},
+			metric{
+				name:  "node_fileservice_connections",
+				attr:  attr,
+				value: float64(connections),
+				help:  "Number of active client sessions (SMB) or established TCP connections (NFS, AFP)",
+			},
+		)
+	}
+
+	return metrics, nil
+}
+
+// runningProcessNames returns the command names of all the running processes, including kernel threads
+func runningProcessNames(sys utils.System) (map[string]bool, error) {
+	paths, err := sys.Glob(filepath.Join(procDir, "[0-9]*", "comm"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		// Processes may exit while we iterate
+		comm, err := sys.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		names[strings.TrimSpace(comm)] = true
+	}
+
+	return names, nil
+}
+
+// countEstablishedConnections counts the established connections to the local port in the contents of /proc/net/tcp{,6}, e.g.:
+//
+//	sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+//	 0: 0100007F:0801 0100007F:C350 01 00000000:00000000 00:00000000 00000000     0        0 12345
+func countEstablishedConnections(lines []string, port uint64) int {
+	var count int
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[3] != tcpStateEstablished {
+			continue
+		}
+
+		_, localPort, found := strings.Cut(fields[1], ":")
+		if !found {
+			continue
+		}
+		p, err := strconv.ParseUint(localPort, 16, 16)
+		if err == nil && p == port {
+			count++
+		}
+	}
+
+	return count
+}
+
+// parseSmbStatusSessions counts the sessions listed by `smbstatus -b`, e.g.:
+//
+//	Samba version 4.15.13
+//	PID     Username     Group        Machine                                   Protocol Version  Encryption           Signing
+//	----------------------------------------------------------------------------------------------------------------------------------------
+//	12345   alice        everyone     192.168.1.10 (ipv4:192.168.1.10:51234)    SMB3_11           -                    partial(AES-128-CMAC)
+func parseSmbStatusSessions(output string) int {
+	var count int
+	var inTable bool
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "---"):
+			inTable = true
+		case line == "":
+			if inTable && count > 0 {
+				return count
+			}
+		case inTable:
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,70 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountEstablishedConnections(t *testing.T) {
+	lines := []string{
+		"  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode",
+		"   0: 00000000:0801 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1234 1 0000000000000000 100 0 0 10 0",
+		"   1: 0A00A8C0:0801 0B00A8C0:03FF 01 00000000:00000000 00:00000000 00000000     0        0 1235 1 0000000000000000 20 4 30 10 -1",
+		"   2: 0A00A8C0:0801 0C00A8C0:0400 01 00000000:00000000 00:00000000 00000000     0        0 1236 1 0000000000000000 20 4 30 10 -1",
+		"   3: 0A00A8C0:01BD 0C00A8C0:C350 01 00000000:00000000 00:00000000 00000000     0        0 1237 1 0000000000000000 20 4 30 10 -1",
+		"   0: 00000000000000000000000000000000:0224 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1238 1",
+		"   1: 0000000000000000FFFF00000A00A8C0:0224 0000000000000000FFFF00000B00A8C0:D431 01 00000000:00000000 00:00000000 00000000     0        0 1239 1",
+	}
+
+	testCases := map[string]struct {
+		port     uint64
+		expected int
+	}{
+		"nfs": {port: 2049, expected: 2},
+		"smb": {port: 445, expected: 1},
+		"afp": {port: 548, expected: 1},
+		"ssh": {port: 22, expected: 0},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, countEstablishedConnections(lines, tc.port))
+		})
+	}
+}
+
+func TestParseSmbStatusSessions(t *testing.T) {
+	testCases := map[string]struct {
+		output   string
+		expected int
+	}{
+		"no sessions": {
+			output: `
+Samba version 4.15.13
+PID     Username     Group        Machine                                   Protocol Version  Encryption           Signing
+----------------------------------------------------------------------------------------------------------------------------------------
+`,
+		},
+		"sessions followed by share table": {
+			output: `
+Samba version 4.15.13
+PID     Username     Group        Machine                                   Protocol Version  Encryption           Signing
+----------------------------------------------------------------------------------------------------------------------------------------
+12345   alice        everyone     192.168.1.10 (ipv4:192.168.1.10:51234)    SMB3_11           -                    partial(AES-128-CMAC)
+12346   bob          everyone     192.168.1.11 (ipv4:192.168.1.11:51235)    SMB3_11           -                    partial(AES-128-CMAC)
+
+Service      pid     Machine       Connected at                     Encryption   Signing
+---------------------------------------------------------------------------------------------
+Public       12345   192.168.1.10  Sun Jan  1 00:00:00 2023 WET     -            -
+`,
+			expected: 2,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, parseSmbStatusSessions(tc.output))
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// ignoredFsTypes are pseudo and overlay file systems which don't hold user data
+var ignoredFsTypes = map[string]bool{
+	"autofs": true, "binfmt_misc": true, "bpf": true, "cgroup": true, "cgroup2": true, "configfs": true,
+	"debugfs": true, "devpts": true, "devtmpfs": true, "efivarfs": true, "fusectl": true, "hugetlbfs": true,
+	"mqueue": true, "nfsd": true, "nsfs": true, "overlay": true, "proc": true, "pstore": true, "ramfs": true,
+	"rpc_pipefs": true, "securityfs": true, "selinuxfs": true, "squashfs": true, "sysfs": true, "tmpfs": true,
+	"tracefs": true,
+}
+
+func getFilesystemMetrics(ctx context.Context) ([]metric, error) {
+	// Include file systems without a backing device, such as ecryptfs for encrypted shares
+	partitions, err := disk.PartitionsWithContext(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(partitions))
+	metrics := make([]metric, 0, len(partitions)*4)
+	for _, p := range partitions {
+		if ignoredFsTypes[p.Fstype] || seen[p.Mountpoint] {
+			continue
+		}
+		seen[p.Mountpoint] = true
+
+		usage, err := disk.UsageWithContext(ctx, p.Mountpoint)
+		if err != nil {
+			// The file system may have been unmounted in the meantime (e.g. an ejected USB drive)
+			continue
+		}
+
+		attr := fmt.Sprintf("device=%q,mountpoint=%q,fstype=%q", p.Device, p.Mountpoint, p.Fstype)
+		metrics = append(
+			metrics,
+			metric{
+				name:  "node_filesystem_size_bytes",
+				attr:  attr,
+				value: float64(usage.Total),
+				help:  "Filesystem size in bytes",
+			},
+			metric{
+				name:  "node_filesystem_avail_bytes",
+				attr:  attr,
+				value: float64(usage.Free),
+				help:  "Filesystem space available to non-root users in bytes",
+			},
+			metric{
+				name:  "node_filesystem_files",
+				attr:  attr,
+				value: float64(usage.InodesTotal),
+				help:  "Filesystem total file nodes",
+			},
+			metric{
+				name:  "node_filesystem_files_free",
+				attr:  attr,
+				value: float64(usage.InodesFree),
+				help:  "Filesystem total free file nodes",
+			},
+		)
+	}
+
+	return metrics, nil
+}
@@ -0,0 +1,20 @@
+package prometheus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetFilesystemMetrics(t *testing.T) {
+	metrics, err := getFilesystemMetrics(context.Background())
+	require.NoError(t, err)
+
+	for _, m := range metrics {
+		assert.NotContains(t, m.attr, `fstype="proc"`)
+		assert.NotContains(t, m.attr, `fstype="sysfs"`)
+		assert.Contains(t, m.attr, "mountpoint=")
+	}
+}
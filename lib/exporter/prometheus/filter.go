@@ -0,0 +1,80 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// LabelFilter restricts the metrics written to the ones with the given label values.
+// A metric matches if, for every label in the filter, it has that label set to one of the listed values.
+type LabelFilter map[string][]string
+
+type labelFilterKey struct{}
+
+// ParseLabelFilter parses filter specifications such as `volume:DataVol1` or `device:sda,device:sdb`
+func ParseLabelFilter(specs []string) (LabelFilter, error) {
+	f := LabelFilter{}
+	for _, spec := range specs {
+		for _, s := range strings.Split(spec, ",") {
+			if s == "" {
+				continue
+			}
+
+			name, value, found := strings.Cut(s, ":")
+			if !found || name == "" {
+				return nil, fmt.Errorf("invalid label filter %q, expected label:value", s)
+			}
+			f[name] = append(f[name], value)
+		}
+	}
+
+	return f, nil
+}
+
+// WithLabelFilter returns a context which makes WriteMetrics only write the metrics matching f
+func WithLabelFilter(ctx context.Context, f LabelFilter) context.Context {
+	return context.WithValue(ctx, labelFilterKey{}, f)
+}
+
+func labelFilterFromContext(ctx context.Context) LabelFilter {
+	f, _ := ctx.Value(labelFilterKey{}).(LabelFilter)
+	return f
+}
+
+func (f LabelFilter) matches(targetLabels []label, m metric) bool {
+	if len(f) == 0 {
+		return true
+	}
+
+	names, values, err := parseAttr(m.attr)
+	if err != nil {
+		return false
+	}
+	labels := make(map[string]string, len(targetLabels)+len(names))
+	for _, l := range targetLabels {
+		labels[l.name] = l.value
+	}
+	for idx, name := range names {
+		labels[name] = values[idx]
+	}
+
+	for name, allowed := range f {
+		value, found := labels[name]
+		if !found || !containsString(allowed, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
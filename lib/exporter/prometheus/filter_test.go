@@ -0,0 +1,162 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLabelFilter(t *testing.T) {
+	testCases := map[string]struct {
+		specs       []string
+		expected    LabelFilter
+		expectedErr bool
+	}{
+		"empty": {
+			expected: LabelFilter{},
+		},
+		"single label": {
+			specs:    []string{"volume:DataVol1"},
+			expected: LabelFilter{"volume": {"DataVol1"}},
+		},
+		"repeated and comma-separated": {
+			specs:    []string{"device:sda,device:sdb", "volume:DataVol1"},
+			expected: LabelFilter{"device": {"sda", "sdb"}, "volume": {"DataVol1"}},
+		},
+		"missing value separator": {
+			specs:       []string{"volume"},
+			expectedErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			f, err := ParseLabelFilter(tc.specs)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, f)
+		})
+	}
+}
+
+func TestWriteMetricsWithLabelFilter(t *testing.T) {
+	e := &promExporter{
+		ExporterConfig: ExporterConfig{Logger: logging.Discard()},
+		hostname:       "nas",
+		envExpiry:      time.Now().Add(time.Hour),
+		watchdog:       newWatchdog(0, nil, nil),
+	}
+	e.fns = []collector{
+		{
+			name: "test",
+			fn: func(context.Context) ([]metric, error) {
+				return []metric{
+					{name: "node_disk_read_bytes_total", attr: `device="sda"`, value: 1},
+					{name: "node_disk_read_bytes_total", attr: `device="sdb"`, value: 2},
+					{name: "node_load1", value: 3},
+				}, nil
+			},
+		},
+	}
+
+	b := new(bytes.Buffer)
+	ctx := WithLabelFilter(context.Background(), LabelFilter{"device": {"sdb"}})
+	require.NoError(t, e.WriteMetrics(ctx, b))
+
+	assert.Equal(t, "# TYPE node_disk_read_bytes_total gauge\nnode_disk_read_bytes_total{node=\"nas\",device=\"sdb\"} 2\n", b.String())
+}
+
+func TestWriteMetricsWithTargetLabels(t *testing.T) {
+	testCases := map[string]struct {
+		config   ExporterConfig
+		expected []string
+	}{
+		"default": {
+			expected: []string{
+				"\nnode_load1{node=\"nas\"} 3\n",
+				"\nnode_disk_read_bytes_total{node=\"nas\",device=\"sda\"} 1\n",
+			},
+		},
+		"renamed node label with static labels": {
+			config: ExporterConfig{NodeLabel: "instance_name", StaticLabels: map[string]string{"site": "home", "rack": "1"}},
+			expected: []string{
+				"\nnode_load1{instance_name=\"nas\",rack=\"1\",site=\"home\"} 3\n",
+				"\nnode_disk_read_bytes_total{instance_name=\"nas\",rack=\"1\",site=\"home\",device=\"sda\"} 1\n",
+			},
+		},
+		"dropped node label": {
+			config: ExporterConfig{DropNodeLabel: true},
+			expected: []string{
+				"\nnode_load1 3\n",
+				"\nnode_disk_read_bytes_total{device=\"sda\"} 1\n",
+			},
+		},
+		"collector labels": {
+			config: ExporterConfig{CollectorLabels: map[string]map[string]string{
+				"test":  {"circuit": "office", "bay": "2"},
+				"other": {"circuit": "rack"},
+			}},
+			expected: []string{
+				"\nnode_load1{node=\"nas\",bay=\"2\",circuit=\"office\"} 3\n",
+				"\nnode_disk_read_bytes_total{node=\"nas\",bay=\"2\",circuit=\"office\",device=\"sda\"} 1\n",
+				"\nqnap_exporter_collector_success{node=\"nas\",collector=\"test\"} 1\n",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tc.config.Logger = logging.Discard()
+			e := &promExporter{
+				ExporterConfig: tc.config,
+				hostname:       "nas",
+				envExpiry:      time.Now().Add(time.Hour),
+				watchdog:       newWatchdog(0, nil, nil),
+			}
+			e.fns = []collector{
+				{
+					name: "test",
+					fn: func(context.Context) ([]metric, error) {
+						return []metric{
+							{name: "node_load1", value: 3},
+							{name: "node_disk_read_bytes_total", attr: `device="sda"`, value: 1},
+						}, nil
+					},
+				},
+			}
+
+			b := new(bytes.Buffer)
+			b.WriteString("\n")
+			require.NoError(t, e.WriteMetrics(context.Background(), b))
+
+			for _, line := range tc.expected {
+				assert.Contains(t, b.String(), line)
+			}
+		})
+	}
+}
+
+func TestValidateLabels(t *testing.T) {
+	assert.NoError(t, ValidateLabels("", map[string]string{"site": "home"}))
+	assert.NoError(t, ValidateLabels("instance_name", map[string]string{"node": "nas"}))
+	assert.Error(t, ValidateLabels("", map[string]string{"node": "nas"}))
+	assert.Error(t, ValidateLabels("instance-name", nil))
+	assert.Error(t, ValidateLabels("", map[string]string{"1site": "home"}))
+}
+
+func TestValidateCollectorLabels(t *testing.T) {
+	assert.NoError(t, ValidateCollectorLabels("", map[string]string{"site": "home"}, map[string]map[string]string{"ups": {"circuit": "office"}}))
+	assert.Error(t, ValidateCollectorLabels("", nil, map[string]map[string]string{"ups": {"node": "nas"}}))
+	assert.Error(t, ValidateCollectorLabels("", map[string]string{"site": "home"}, map[string]map[string]string{"ups": {"site": "office"}}))
+	assert.Error(t, ValidateCollectorLabels("", nil, map[string]map[string]string{"ups": {"ups": "office"}}))
+	assert.Error(t, ValidateCollectorLabels("", nil, map[string]map[string]string{"ups": {"circuit-name": "office"}}))
+}
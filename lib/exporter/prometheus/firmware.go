@@ -0,0 +1,76 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pedropombeiro/qnapexporter/lib/update"
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+// readQtsFirmware reads the installed firmware version from the [System] section of uLinux.conf, e.g.:
+//
+//	[System]
+//	Version = 5.1.0
+//	Build Number = 20230822
+func readQtsFirmware(sys utils.System) (update.Firmware, error) {
+	lines, err := sys.ReadFileLines(qtsConfigPath)
+	if err != nil {
+		return update.Firmware{}, err
+	}
+
+	f := update.Firmware{
+		Version: parseQtsSystemValue(lines, "Version"),
+		Build:   parseQtsSystemValue(lines, "Build Number"),
+	}
+	if f.Version == "" {
+		return update.Firmware{}, fmt.Errorf("firmware version not found in %s", qtsConfigPath)
+	}
+
+	return f, nil
+}
+
+func (e *promExporter) getFirmwareMetrics(ctx context.Context) ([]metric, error) {
+	if _, err := e.sys.Stat(qtsConfigPath); os.IsNotExist(err) {
+		return nil, subsystemAbsentError{fmt.Sprintf("%s not found", qtsConfigPath)}
+	}
+
+	current, err := readQtsFirmware(e.sys)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := []metric{
+		{
+			name:  "qnap_firmware_info",
+			attr:  fmt.Sprintf("version=%q,build=%q", current.Version, current.Build),
+			value: 1,
+			help:  "Version of the installed QTS firmware",
+		},
+	}
+	if e.FirmwareCheckInterval <= 0 || e.firmwareUpdates == nil || e.model == "" {
+		return metrics, nil
+	}
+
+	// The check runs in the background, so the result of the previous check is reported
+	e.firmwareUpdates.Refresh(e.model, e.FirmwareCheckInterval, e.Logger)
+	latest, checked := e.firmwareUpdates.Latest()
+	if !checked {
+		return metrics, nil
+	}
+	recordDataTime(ctx, e.firmwareUpdates.CheckedAt())
+
+	var available float64
+	if update.IsNewerFirmware(current, latest) {
+		available = 1
+	}
+
+	return append(metrics, metric{
+		name: "qnap_firmware_update_available",
+		attr: fmt.Sprintf("current_version=%q,current_build=%q,latest_version=%q,latest_build=%q",
+			current.Version, current.Build, latest.Version, latest.Build),
+		value: available,
+		help:  "Whether a newer QTS firmware is available for the model of the NAS",
+	}), nil
+}
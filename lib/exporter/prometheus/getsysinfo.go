@@ -0,0 +1,63 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// getGetsysinfoMetrics runs the getsysinfo subcommands configured in GetsysinfoCommands,
+// so that capabilities of newer QTS versions can be exported without code changes
+func (e *promExporter) getGetsysinfoMetrics(ctx context.Context) ([]metric, error) {
+	if e.getsysinfo == "" || len(e.GetsysinfoCommands) == 0 {
+		return nil, nil
+	}
+
+	var err error
+	metrics := make([]metric, 0, len(e.GetsysinfoCommands))
+	for _, command := range e.GetsysinfoCommands {
+		args := strings.Fields(command)
+		if len(args) == 0 {
+			continue
+		}
+		command = strings.Join(args, " ")
+
+		output, cmdErr := e.sys.ExecCommand(ctx, e.getsysinfo, args...)
+		if cmdErr != nil {
+			err = fmt.Errorf("getsysinfo %s: %w", command, cmdErr)
+			continue
+		}
+
+		// e.g. "42 C/107 F" or "1234 RPM"
+		value := parseLeadingNumber(strings.TrimSpace(output))
+		if value == nil {
+			err = fmt.Errorf("getsysinfo %s: non-numeric output %q", command, output)
+			continue
+		}
+
+		metrics = append(metrics, metric{
+			name:  "node_getsysinfo_value",
+			attr:  fmt.Sprintf("command=%q", command),
+			value: *value,
+			help:  "Numeric output of a getsysinfo subcommand configured by the user",
+		})
+	}
+
+	return metrics, err
+}
+
+// readSysInfoCount runs a getsysinfo subcommand reporting a number of items (e.g. hdnum), returning -1 on error
+func (e *promExporter) readSysInfoCount(ctx context.Context, command string) (int, error) {
+	output, err := e.sys.ExecCommand(ctx, e.getsysinfo, command)
+	if err != nil {
+		return -1, fmt.Errorf("getsysinfo %s: %w", command, err)
+	}
+
+	count, err := strconv.Atoi(output)
+	if err != nil {
+		return -1, fmt.Errorf("getsysinfo %s: %w", command, err)
+	}
+
+	return count, nil
+}
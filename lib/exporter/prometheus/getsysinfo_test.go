@@ -0,0 +1,37 @@
+package prometheus
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetGetsysinfoMetrics(t *testing.T) {
+	getsysinfo := filepath.Join(t.TempDir(), "getsysinfo")
+	script := `#!/bin/sh
+case "$*" in
+  "cputmp") echo "42 C/107 F" ;;
+  "sysfan 3") echo "1234 RPM" ;;
+  *) echo "--" ;;
+esac
+`
+	require.NoError(t, os.WriteFile(getsysinfo, []byte(script), 0o755))
+
+	e := &promExporter{
+		ExporterConfig: ExporterConfig{GetsysinfoCommands: []string{"cputmp", " sysfan  3 ", "unknown"}},
+		getsysinfo:     getsysinfo,
+	}
+
+	metrics, err := e.getGetsysinfoMetrics(context.Background())
+	assert.EqualError(t, err, `getsysinfo unknown: non-numeric output "--"`)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "node_getsysinfo_value", metrics[0].name)
+	assert.Equal(t, `command="cputmp"`, metrics[0].attr)
+	assert.Equal(t, 42.0, metrics[0].value)
+	assert.Equal(t, `command="sysfan 3"`, metrics[1].attr)
+	assert.Equal(t, 1234.0, metrics[1].value)
+}
@@ -0,0 +1,158 @@
+package prometheus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// defaultHealthWeights are the weights of the components of qnap_health_score, keyed by component
+var defaultHealthWeights = map[string]float64{
+	"raid":        3,
+	"smart":       3,
+	"temperature": 2,
+	"volume":      2,
+	"ups":         1,
+}
+
+// healthThresholds are the values between which a component score decreases linearly from 1 to 0
+type healthThresholds struct {
+	warning  float64
+	critical float64
+}
+
+var (
+	diskTemperatureThresholds   = healthThresholds{warning: 45, critical: 60}
+	cpuTemperatureThresholds    = healthThresholds{warning: 80, critical: 95}
+	systemTemperatureThresholds = healthThresholds{warning: 50, critical: 65}
+	volumeUsageThresholds       = healthThresholds{warning: 0.8, critical: 0.95}
+)
+
+// score returns 1 below the warning threshold, 0 above the critical threshold, and a linear interpolation in between
+func (t healthThresholds) score(value float64) float64 {
+	switch {
+	case value <= t.warning:
+		return 1
+	case value >= t.critical:
+		return 0
+	default:
+		return (t.critical - value) / (t.critical - t.warning)
+	}
+}
+
+// ValidateHealthWeights checks that the weights refer to known components of the health score and are not negative
+func ValidateHealthWeights(weights map[string]float64) error {
+	for component, weight := range weights {
+		if _, found := defaultHealthWeights[component]; !found {
+			return fmt.Errorf("unknown health score component %q", component)
+		}
+		if weight < 0 {
+			return fmt.Errorf("negative weight %v for health score component %q", weight, component)
+		}
+	}
+
+	return nil
+}
+
+// healthScore accumulates the state of each component of the health score from the metrics of a scrape.
+// Each component scores from 0 (failed) to 1 (healthy), and is as healthy as its worst item (e.g. the hottest disk).
+type healthScore struct {
+	components  map[string]float64
+	volumeAvail map[string]float64
+	volumeSize  map[string]float64
+}
+
+func newHealthScore() *healthScore {
+	return &healthScore{
+		components:  make(map[string]float64),
+		volumeAvail: make(map[string]float64),
+		volumeSize:  make(map[string]float64),
+	}
+}
+
+func (h *healthScore) set(component string, score float64) {
+	if current, found := h.components[component]; !found || score < current {
+		h.components[component] = score
+	}
+}
+
+// observe updates the components from the metrics of a collector
+func (h *healthScore) observe(metrics []metric) {
+	for _, m := range metrics {
+		switch m.name {
+		case "node_md_degraded":
+			h.set("raid", 1-m.value)
+		case "node_zfs_pool_healthy":
+			h.set("raid", m.value)
+		case "node_disk_smart_healthy":
+			h.set("smart", m.value)
+		case "node_hdtmp_C", "node_disk_smart_temperature_celsius":
+			h.set("temperature", diskTemperatureThresholds.score(m.value))
+		case "node_cputmp_C":
+			h.set("temperature", cpuTemperatureThresholds.score(m.value))
+		case "node_systmp_C":
+			h.set("temperature", systemTemperatureThresholds.score(m.value))
+		case "node_volume_avail_bytes":
+			h.volumeAvail[m.attr] = m.value
+		case "node_volume_size_bytes":
+			h.volumeSize[m.attr] = m.value
+		case "node_ups_status_flag":
+			switch {
+			case strings.Contains(m.attr, `flag="LB"`):
+				h.set("ups", 1-m.value)
+			case strings.Contains(m.attr, `flag="OB"`):
+				h.set("ups", 1-m.value/2)
+			case strings.Contains(m.attr, `flag="OL"`):
+				h.set("ups", 1)
+			}
+		}
+	}
+}
+
+// metrics returns the overall score, as the weighted average of the components which were observed, and the
+// score of each component, all from 0 to 100. Nothing is returned if no weighted component was observed.
+func (h *healthScore) metrics(weights map[string]float64) []metric {
+	for attr, size := range h.volumeSize {
+		if avail, found := h.volumeAvail[attr]; found && size > 0 {
+			h.set("volume", volumeUsageThresholds.score(1-avail/size))
+		}
+	}
+
+	components := make([]string, 0, len(h.components))
+	for component := range h.components {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+
+	metrics := make([]metric, 0, len(components)+1)
+	var weightedSum, totalWeight float64
+	for _, component := range components {
+		weight, found := weights[component]
+		if !found {
+			weight = defaultHealthWeights[component]
+		}
+		if weight <= 0 {
+			continue
+		}
+
+		score := h.components[component]
+		weightedSum += weight * score
+		totalWeight += weight
+
+		metrics = append(metrics, metric{
+			name:  "qnap_health_component_score",
+			attr:  fmt.Sprintf("component=%q", component),
+			value: 100 * score,
+			help:  "Health score of the component, from 0 (failed) to 100 (healthy)",
+		})
+	}
+	if totalWeight == 0 {
+		return nil
+	}
+
+	return append(metrics, metric{
+		name:  "qnap_health_score",
+		value: 100 * weightedSum / totalWeight,
+		help:  "Overall health of the NAS, from 0 to 100, as the weighted average of the component scores",
+	})
+}
@@ -0,0 +1,94 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthScore(t *testing.T) {
+	testCases := map[string]struct {
+		metrics        []metric
+		weights        map[string]float64
+		wantScore      *float64
+		wantComponents map[string]float64
+	}{
+		"nothing observed": {
+			metrics: []metric{{name: "node_load1", value: 3}},
+		},
+		"healthy": {
+			metrics: []metric{
+				{name: "node_md_degraded", attr: `md="md1"`, value: 0},
+				{name: "node_disk_smart_healthy", attr: `device="sda"`, value: 1},
+				{name: "node_hdtmp_C", attr: `hd="1"`, value: 38},
+				{name: "node_volume_avail_bytes", attr: `volume="DataVol1"`, value: 50},
+				{name: "node_volume_size_bytes", attr: `volume="DataVol1"`, value: 100},
+				{name: "node_ups_status_flag", attr: `flag="OL",ups="qnapups"`, value: 1},
+				{name: "node_ups_status_flag", attr: `flag="OB",ups="qnapups"`, value: 0},
+			},
+			wantScore:      floatPtr(100),
+			wantComponents: map[string]float64{"raid": 100, "smart": 100, "temperature": 100, "volume": 100, "ups": 100},
+		},
+		"degraded array and hot disk": {
+			metrics: []metric{
+				{name: "node_md_degraded", attr: `md="md1"`, value: 1},
+				{name: "node_md_degraded", attr: `md="md2"`, value: 0},
+				{name: "node_disk_smart_healthy", attr: `device="sda"`, value: 1},
+				{name: "node_hdtmp_C", attr: `hd="1"`, value: 40},
+				{name: "node_hdtmp_C", attr: `hd="2"`, value: 55},
+			},
+			// (3*0 + 3*100 + 2*33.3) / 8
+			wantScore:      floatPtr(45.833),
+			wantComponents: map[string]float64{"raid": 0, "smart": 100, "temperature": 33.333},
+		},
+		"custom weights": {
+			metrics: []metric{
+				{name: "node_zfs_pool_healthy", attr: `pool="zpool1"`, value: 1},
+				{name: "node_volume_avail_bytes", attr: `volume="DataVol1"`, value: 10},
+				{name: "node_volume_size_bytes", attr: `volume="DataVol1"`, value: 100},
+				{name: "node_ups_status_flag", attr: `flag="LB",ups="qnapups"`, value: 1},
+			},
+			weights:        map[string]float64{"volume": 1, "ups": 0},
+			wantScore:      floatPtr(83.333),
+			wantComponents: map[string]float64{"raid": 100, "volume": 33.333},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			h := newHealthScore()
+			h.observe(tc.metrics)
+
+			components := map[string]float64{}
+			var score *float64
+			for _, m := range h.metrics(tc.weights) {
+				switch m.name {
+				case "qnap_health_score":
+					value := m.value
+					score = &value
+				case "qnap_health_component_score":
+					components[m.attr[len(`component="`):len(m.attr)-1]] = m.value
+				}
+			}
+
+			if tc.wantScore == nil {
+				assert.Nil(t, score)
+				assert.Empty(t, components)
+				return
+			}
+			if assert.NotNil(t, score) {
+				assert.InDelta(t, *tc.wantScore, *score, 0.001)
+			}
+			assert.Len(t, components, len(tc.wantComponents))
+			for component, want := range tc.wantComponents {
+				assert.InDelta(t, want, components[component], 0.001, component)
+			}
+		})
+	}
+}
+
+func TestValidateHealthWeights(t *testing.T) {
+	assert.NoError(t, ValidateHealthWeights(map[string]float64{"raid": 5, "ups": 0}))
+	assert.EqualError(t, ValidateHealthWeights(map[string]float64{"fans": 1}), `unknown health score component "fans"`)
+	assert.EqualError(t, ValidateHealthWeights(map[string]float64{"ups": -1}), `negative weight -1 for health score component "ups"`)
+}
@@ -0,0 +1,87 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// HostnameSourceOS uses the HOSTNAME environment variable or the output of `hostname`
+	HostnameSourceOS = "os"
+	// HostnameSourceQTS uses the server name configured in QTS
+	HostnameSourceQTS = "qts"
+
+	qtsConfigPath = "/etc/config/uLinux.conf"
+)
+
+// resolveHostname determines the value of the `node` label, which is re-resolved on every environment refresh
+func (e *promExporter) resolveHostname(ctx context.Context) (string, error) {
+	if e.Hostname != "" {
+		return e.Hostname, nil
+	}
+
+	if e.HostnameSource == HostnameSourceQTS {
+		lines, err := e.sys.ReadFileLines(qtsConfigPath)
+		if err == nil {
+			if name := parseQtsServerName(lines); name != "" {
+				return name, nil
+			}
+			err = fmt.Errorf("server name not found in %s", qtsConfigPath)
+		}
+		e.Logger.Warn("Failed to read QTS server name, falling back to OS hostname", "err", err)
+	}
+
+	if hostname := os.Getenv("HOSTNAME"); hostname != "" {
+		return hostname, nil
+	}
+	if e.SafeMode {
+		// Read the hostname from procfs instead of running `hostname`
+		return os.Hostname()
+	}
+
+	return e.sys.ExecCommand(ctx, "hostname")
+}
+
+// parseQtsServerName extracts the server name from the [System] section of uLinux.conf, e.g.:
+//
+//	[System]
+//	Server Name = NAS123456
+func parseQtsServerName(lines []string) string {
+	return parseQtsSystemValue(lines, "Server Name")
+}
+
+// parseQtsSystemValue extracts the value of key from the [System] section of uLinux.conf
+func parseQtsSystemValue(lines []string, key string) string {
+	return parseQtsConfigSection(lines, "System")[key]
+}
+
+// parseQtsConfigSection returns the keys and values of section in uLinux.conf
+func parseQtsConfigSection(lines []string, section string) map[string]string {
+	values := make(map[string]string)
+	var current string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = line[1 : len(line)-1]
+			continue
+		}
+		if current != section {
+			continue
+		}
+
+		k, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		// The first occurrence of a key wins
+		if k = strings.TrimSpace(k); k != "" {
+			if _, seen := values[k]; !seen {
+				values[k] = strings.TrimSpace(value)
+			}
+		}
+	}
+
+	return values
+}
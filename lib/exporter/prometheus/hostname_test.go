@@ -0,0 +1,53 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQtsServerName(t *testing.T) {
+	testCases := map[string]struct {
+		lines    []string
+		expected string
+	}{
+		"server name in System section": {
+			lines: []string{
+				"[Network]",
+				"Server Name = other",
+				"[System]",
+				"Version = 5.0.1",
+				"Server Name = NAS123456",
+				"Time Zone = Europe/Lisbon",
+			},
+			expected: "NAS123456",
+		},
+		"server name in other section": {
+			lines: []string{
+				"[Network]",
+				"Server Name = other",
+			},
+		},
+		"empty": {},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, parseQtsServerName(tc.lines))
+		})
+	}
+}
+
+func TestParseQtsSystemValue(t *testing.T) {
+	lines := []string{
+		"[System]",
+		"Version = 5.1.0",
+		"Build Number = 20230822",
+		"[Network]",
+		"Version = 2",
+	}
+
+	assert.Equal(t, "5.1.0", parseQtsSystemValue(lines, "Version"))
+	assert.Equal(t, "20230822", parseQtsSystemValue(lines, "Build Number"))
+	assert.Empty(t, parseQtsSystemValue(lines, "Model"))
+}
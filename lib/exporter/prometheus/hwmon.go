@@ -0,0 +1,146 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+var hwmonInputRe = regexp.MustCompile(`^(temp|fan|in)(\d+)_input$`)
+
+// hwmonCPUChips are the hwmon drivers reporting the CPU temperature, used when getsysinfo is not available
+var hwmonCPUChips = map[string]bool{"coretemp": true, "k10temp": true, "cpu_thermal": true}
+
+type hwmonSensor struct {
+	chip   string
+	device string
+	// kind is one of temp, fan or in (voltage)
+	kind  string
+	label string
+	value float64
+}
+
+func (e *promExporter) getHwmonMetrics(ctx context.Context) ([]metric, error) {
+	sensors, err := readHwmonSensors(e.sys, hwmonDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(sensors) == 0 {
+		return nil, subsystemAbsentError{"no hwmon sensors found"}
+	}
+
+	metrics := make([]metric, 0, len(sensors)+1)
+	cpuTemp := -1.0
+	for _, s := range sensors {
+		attr := fmt.Sprintf("chip=%q,device=%q,sensor=%q", s.chip, s.device, s.label)
+		switch s.kind {
+		case "temp":
+			metrics = append(metrics, metric{
+				name:  "node_hwmon_temp_celsius",
+				attr:  attr,
+				value: s.value / 1000,
+				help:  "Temperature reported by a hardware monitoring sensor",
+			})
+			if hwmonCPUChips[s.chip] && s.value/1000 > cpuTemp {
+				cpuTemp = s.value / 1000
+			}
+		case "fan":
+			metrics = append(metrics, metric{
+				name:  "node_hwmon_fan_rpm",
+				attr:  attr,
+				value: s.value,
+				help:  "Fan speed reported by a hardware monitoring sensor",
+			})
+		case "in":
+			metrics = append(metrics, metric{
+				name:  "node_hwmon_in_volts",
+				attr:  attr,
+				value: s.value / 1000,
+				help:  "Voltage reported by a hardware monitoring sensor",
+			})
+		}
+	}
+
+	if e.getsysinfo == "" && cpuTemp >= 0 {
+		// Keep the CPU temperature panels working on systems without getsysinfo (e.g. in a container)
+		metrics = append(metrics, metric{
+			name:  "node_cputmp_C",
+			value: cpuTemp,
+		})
+	}
+
+	return metrics, nil
+}
+
+// readHwmonSensors reads the temperature, fan and voltage sensors under root (e.g. /sys/class/hwmon)
+func readHwmonSensors(sys utils.System, root string) ([]hwmonSensor, error) {
+	entries, err := sys.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var sensors []hwmonSensor
+	for _, entry := range entries {
+		dir := filepath.Join(root, entry.Name())
+		chip := readSysfsString(sys, filepath.Join(dir, "name"))
+		if chip == "" {
+			continue
+		}
+		device := entry.Name()
+		if target, err := sys.EvalSymlinks(filepath.Join(dir, "device")); err == nil {
+			device = filepath.Base(target)
+		}
+
+		files, err := sys.ReadDir(dir)
+		if err != nil {
+			return sensors, err
+		}
+		for _, f := range files {
+			m := hwmonInputRe.FindStringSubmatch(f.Name())
+			if m == nil {
+				continue
+			}
+
+			value, err := strconv.ParseFloat(readSysfsString(sys, filepath.Join(dir, f.Name())), 64)
+			if err != nil {
+				// Some drivers fail reading sensors which are not connected
+				continue
+			}
+
+			label := readSysfsString(sys, filepath.Join(dir, m[1]+m[2]+"_label"))
+			if label == "" {
+				label = m[1] + m[2]
+			}
+
+			sensors = append(sensors, hwmonSensor{chip: chip, device: device, kind: m[1], label: label, value: value})
+		}
+	}
+
+	sort.SliceStable(sensors, func(i, j int) bool {
+		if sensors[i].device != sensors[j].device {
+			return sensors[i].device < sensors[j].device
+		}
+		return sensors[i].label < sensors[j].label
+	})
+
+	return sensors, nil
+}
+
+func readSysfsString(sys utils.System, path string) string {
+	contents, err := sys.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	return contents
+}
@@ -0,0 +1,50 @@
+package prometheus
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadHwmonSensors(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"hwmon0/name":        "coretemp\n",
+		"hwmon0/temp1_input": "45000\n",
+		"hwmon0/temp1_label": "Package id 0\n",
+		"hwmon0/temp2_input": "43000\n",
+		"hwmon0/temp2_max":   "100000\n",
+		"hwmon1/name":        "nct6775\n",
+		"hwmon1/fan1_input":  "1250\n",
+		"hwmon1/in0_input":   "1104\n",
+		"hwmon1/in1_input":   "invalid\n",
+		"hwmon2/temp1_input": "38000\n",
+	}
+	for name, contents := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "devices", "nvme0"), 0o755))
+	require.NoError(t, os.Symlink(filepath.Join(root, "devices", "nvme0"), filepath.Join(root, "hwmon1", "device")))
+
+	sensors, err := readHwmonSensors(utils.System{}, root)
+	require.NoError(t, err)
+
+	assert.Equal(t, []hwmonSensor{
+		{chip: "coretemp", device: "hwmon0", kind: "temp", label: "Package id 0", value: 45000},
+		{chip: "coretemp", device: "hwmon0", kind: "temp", label: "temp2", value: 43000},
+		{chip: "nct6775", device: "nvme0", kind: "fan", label: "fan1", value: 1250},
+		{chip: "nct6775", device: "nvme0", kind: "in", label: "in0", value: 1104},
+	}, sensors)
+}
+
+func TestReadHwmonSensorsMissingDir(t *testing.T) {
+	sensors, err := readHwmonSensors(utils.System{}, filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, sensors)
+}
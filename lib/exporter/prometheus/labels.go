@@ -0,0 +1,138 @@
+package prometheus
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultNodeLabel is the name of the label holding the hostname, unless overridden by ExporterConfig.NodeLabel
+const DefaultNodeLabel = "node"
+
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+type label struct {
+	name, value string
+}
+
+// ValidateLabels checks that the node label and static labels have valid and distinct names
+func ValidateLabels(nodeLabel string, staticLabels map[string]string) error {
+	if nodeLabel == "" {
+		nodeLabel = DefaultNodeLabel
+	}
+	if !labelNameRe.MatchString(nodeLabel) {
+		return fmt.Errorf("invalid node label name %q", nodeLabel)
+	}
+
+	for name := range staticLabels {
+		if !labelNameRe.MatchString(name) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+		if name == nodeLabel {
+			return fmt.Errorf("label %q conflicts with the node label", name)
+		}
+	}
+
+	return nil
+}
+
+// ValidateCollectorLabels checks that the labels added to the metrics of each collector have valid names
+// which don't conflict with the node label, the static labels or the labels of the collector metrics
+func ValidateCollectorLabels(nodeLabel string, staticLabels map[string]string, collectorLabels map[string]map[string]string) error {
+	if nodeLabel == "" {
+		nodeLabel = DefaultNodeLabel
+	}
+
+	for collector, labels := range collectorLabels {
+		reserved := map[string]bool{nodeLabel: true}
+		for name := range staticLabels {
+			reserved[name] = true
+		}
+		for _, d := range metricCatalog[collector] {
+			for _, name := range d.Labels {
+				reserved[name] = true
+			}
+		}
+
+		for name := range labels {
+			if !labelNameRe.MatchString(name) {
+				return fmt.Errorf("invalid label name %q for collector %q", name, collector)
+			}
+			if reserved[name] {
+				return fmt.Errorf("label %q of collector %q conflicts with an existing label", name, collector)
+			}
+		}
+	}
+
+	return nil
+}
+
+// withCollectorLabels returns a copy of metrics with the labels configured for collector prepended
+// to their attributes. The metrics are copied, since some collectors keep the slice they return.
+func (e *promExporter) withCollectorLabels(collector string, metrics []metric) []metric {
+	labels := e.CollectorLabels[collector]
+	if len(labels) == 0 {
+		return metrics
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sorted := make([]label, 0, len(names))
+	for _, name := range names {
+		sorted = append(sorted, label{name: name, value: labels[name]})
+	}
+	attr := formatLabels(sorted)
+
+	labeled := make([]metric, 0, len(metrics))
+	for _, m := range metrics {
+		if m.attr == "" {
+			m.attr = attr
+		} else {
+			m.attr = attr + "," + m.attr
+		}
+		labeled = append(labeled, m)
+	}
+
+	return labeled
+}
+
+// targetLabels returns the labels added to every metric: the node label (unless dropped) and the machine ID label
+// (if enabled), followed by the static labels
+func (e *promExporter) targetLabels() []label {
+	labels := make([]label, 0, len(e.StaticLabels)+1)
+	if !e.DropNodeLabel {
+		name := e.NodeLabel
+		if name == "" {
+			name = DefaultNodeLabel
+		}
+		labels = append(labels, label{name: name, value: e.hostname})
+	}
+	if e.MachineIDLabel != "" && e.machineID != "" {
+		labels = append(labels, label{name: e.MachineIDLabel, value: e.machineID})
+	}
+
+	names := make([]string, 0, len(e.StaticLabels))
+	for name := range e.StaticLabels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		labels = append(labels, label{name: name, value: e.StaticLabels[name]})
+	}
+
+	return labels
+}
+
+// formatLabels formats labels the way metric attributes are written, e.g. `node="nas",site="home"`
+func formatLabels(labels []label) string {
+	parts := make([]string, 0, len(labels))
+	for _, l := range labels {
+		parts = append(parts, fmt.Sprintf("%s=%q", l.name, l.value))
+	}
+
+	return strings.Join(parts, ",")
+}
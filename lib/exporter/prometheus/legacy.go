@@ -0,0 +1,63 @@
+package prometheus
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+// legacySysBlockDir is where the block devices are found on the kernels built with the deprecated sysfs layout,
+// such as the ones of QTS 4.2 and 4.3 on the ARM models (e.g. TS-219), which lack /sys/class/block. The partitions
+// are then nested in the directory of their disk (e.g. /sys/block/sda/sda3) instead of being listed alongside it.
+const legacySysBlockDir = "/sys/block"
+
+// isLegacyQtsVersion reports whether version (e.g. "4.3.6") is a QTS release older than 4.4, whose layout of
+// procfs, sysfs and md arrays differs from the one of the current firmware
+func isLegacyQtsVersion(version string) bool {
+	fields := strings.SplitN(version, ".", 3)
+	if len(fields) < 2 {
+		return false
+	}
+	major, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return false
+	}
+	minor, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return false
+	}
+
+	return major < 4 || major == 4 && minor < 4
+}
+
+// resolveSysBlockDir returns the directory listing the block devices: sysBlockDir, or legacySysBlockDir on the
+// kernels lacking it
+func resolveSysBlockDir(sys utils.System) string {
+	if _, err := sys.Stat(sysBlockDir); err != nil {
+		if _, err := sys.Stat(legacySysBlockDir); err == nil {
+			return legacySysBlockDir
+		}
+	}
+
+	return sysBlockDir
+}
+
+// blockDevicePath returns the directory of dev in root, looking for it in the directory of each disk when root
+// has the deprecated layout, in which the partitions are not listed. It returns an empty string if dev is not found.
+func blockDevicePath(sys utils.System, root string, dev string) string {
+	path := filepath.Join(root, dev)
+	if _, err := sys.Stat(path); err == nil {
+		return path
+	}
+
+	matches, _ := sys.Glob(filepath.Join(root, "*", dev))
+	for _, m := range matches {
+		if _, err := sys.Stat(filepath.Join(m, "partition")); err == nil {
+			return m
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,29 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsLegacyQtsVersion(t *testing.T) {
+	tests := map[string]struct {
+		version string
+		want    bool
+	}{
+		"QTS 4.2":   {version: "4.2.6", want: true},
+		"QTS 4.3":   {version: "4.3.6", want: true},
+		"QTS 4.1":   {version: "4.1", want: true},
+		"QTS 4.4":   {version: "4.4.3"},
+		"QTS 5":     {version: "5.1.0"},
+		"QuTS hero": {version: "h5.1.0"},
+		"no minor":  {version: "4"},
+		"empty":     {version: ""},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, isLegacyQtsVersion(tc.version))
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package prometheus
+
+import "runtime/debug"
+
+const (
+	// metricsChannelSize is the number of metric batches the collectors can hand over before waiting for the
+	// collection to consume them
+	metricsChannelSize = 4
+	// lowMemoryMetricsChannelSize is metricsChannelSize in low-memory mode
+	lowMemoryMetricsChannelSize = 1
+)
+
+// withLowMemory returns c with the caches disabled in low-memory mode, so that no metrics are kept in memory between
+// two scrapes
+func (c ExporterConfig) withLowMemory() ExporterConfig {
+	if !c.LowMemory {
+		return c
+	}
+
+	c.UpsCacheTTL = 0
+	c.ScrapeCacheTTL = 0
+	c.StaleValueMaxAge = 0
+
+	return c
+}
+
+// metricsChannelSize returns the size of the channel through which the collectors hand over their metrics
+func (e *promExporter) metricsChannelSize() int {
+	if e.LowMemory {
+		return lowMemoryMetricsChannelSize
+	}
+
+	return metricsChannelSize
+}
+
+// releaseMemory returns the memory freed by a collection to the OS in low-memory mode, at the cost of a garbage
+// collection per scrape
+func (e *promExporter) releaseMemory() {
+	if e.LowMemory {
+		debug.FreeOSMemory()
+	}
+}
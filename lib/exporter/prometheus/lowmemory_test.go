@@ -0,0 +1,94 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLowMemory(t *testing.T) {
+	config := ExporterConfig{
+		UpsCacheTTL:      10 * time.Second,
+		ScrapeCacheTTL:   time.Minute,
+		StaleValueMaxAge: time.Hour,
+		CollectorTimeout: 30 * time.Second,
+	}
+
+	tests := map[string]struct {
+		lowMemory bool
+		want      ExporterConfig
+	}{
+		"default": {
+			want: config,
+		},
+		"low memory": {
+			lowMemory: true,
+			want:      ExporterConfig{LowMemory: true, CollectorTimeout: 30 * time.Second},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := config
+			c.LowMemory = tc.lowMemory
+
+			assert.Equal(t, tc.want, c.withLowMemory())
+		})
+	}
+}
+
+func TestWriteMetricsInLowMemoryMode(t *testing.T) {
+	tests := map[string]struct {
+		lowMemory   bool
+		wantRunning int64
+	}{
+		"concurrent collectors": {wantRunning: 3},
+		"low memory":            {lowMemory: true, wantRunning: 1},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var running, maxRunning atomic.Int64
+			e := &promExporter{
+				ExporterConfig: ExporterConfig{LowMemory: tc.lowMemory, Logger: logging.Discard()},
+				hostname:       "nas",
+				envExpiry:      time.Now().Add(time.Hour),
+				watchdog:       newWatchdog(0, nil, nil),
+			}
+			for idx := 0; idx < 3; idx++ {
+				name := fmt.Sprintf("test%d", idx)
+				e.fns = append(e.fns, collector{
+					name: name,
+					fn: func(context.Context) ([]metric, error) {
+						n := running.Add(1)
+						defer running.Add(-1)
+						for {
+							m := maxRunning.Load()
+							if n <= m || maxRunning.CompareAndSwap(m, n) {
+								break
+							}
+						}
+						time.Sleep(50 * time.Millisecond)
+
+						return []metric{{name: "node_" + name, value: 1}}, nil
+					},
+				})
+			}
+
+			b := new(bytes.Buffer)
+			require.NoError(t, e.WriteMetrics(context.Background(), b))
+
+			assert.Equal(t, tc.wantRunning, maxRunning.Load())
+			for idx := 0; idx < 3; idx++ {
+				assert.Contains(t, b.String(), fmt.Sprintf("node_test%d{node=\"nas\"} 1\n", idx))
+			}
+		})
+	}
+}
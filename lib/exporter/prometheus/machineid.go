@@ -0,0 +1,68 @@
+package prometheus
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// hwSerialCommand prints the serial number of the NAS on QTS
+const hwSerialCommand = "get_hwsn"
+
+// machineIDFiles are read in turn for a stable identifier when the serial number can't be read through hwSerialCommand
+var machineIDFiles = []string{"/sys/class/dmi/id/product_serial", "/etc/machine-id"}
+
+// resolveMachineID returns a stable identifier of the NAS, which survives renaming it. It is derived from the hardware
+// serial number (or the machine ID on non-QNAP hosts) through a hash, so that the serial number isn't exposed.
+func (e *promExporter) resolveMachineID(ctx context.Context) (string, error) {
+	serial, err := e.readHardwareSerial(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(serial))
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+func (e *promExporter) readHardwareSerial(ctx context.Context) (string, error) {
+	if !e.SafeMode {
+		if path, err := e.sys.LookPath(hwSerialCommand); err == nil {
+			serial, err := e.sys.ExecCommand(ctx, path)
+			if err == nil && serial != "" {
+				return serial, nil
+			}
+			e.Logger.Debug("Failed to read the hardware serial number", "command", hwSerialCommand, "err", err)
+		}
+	}
+
+	for _, path := range machineIDFiles {
+		if serial, err := e.sys.ReadFile(path); err == nil && strings.TrimSpace(serial) != "" {
+			return serial, nil
+		}
+	}
+
+	return "", errors.New("no hardware serial number or machine ID found")
+}
+
+// ValidateMachineIDLabel checks that the name of the machine ID label, if any, is valid and distinct
+// from the node label and static labels
+func ValidateMachineIDLabel(machineIDLabel, nodeLabel string, staticLabels map[string]string) error {
+	if machineIDLabel == "" {
+		return nil
+	}
+	if nodeLabel == "" {
+		nodeLabel = DefaultNodeLabel
+	}
+
+	if !labelNameRe.MatchString(machineIDLabel) {
+		return fmt.Errorf("invalid machine ID label name %q", machineIDLabel)
+	}
+	if _, found := staticLabels[machineIDLabel]; found || machineIDLabel == nodeLabel {
+		return fmt.Errorf("machine ID label %q conflicts with an existing label", machineIDLabel)
+	}
+
+	return nil
+}
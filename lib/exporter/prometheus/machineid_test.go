@@ -0,0 +1,72 @@
+package prometheus
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveMachineID(t *testing.T) {
+	dir := t.TempDir()
+	dmiSerial := filepath.Join(dir, "product_serial")
+	machineID := filepath.Join(dir, "machine-id")
+	require.NoError(t, os.WriteFile(machineID, []byte("0123456789abcdef\n"), 0o644))
+
+	saved := machineIDFiles
+	t.Cleanup(func() { machineIDFiles = saved })
+	machineIDFiles = []string{dmiSerial, machineID}
+
+	// Safe mode doesn't run get_hwsn, leaving out any binary of the test host
+	e := &promExporter{ExporterConfig: ExporterConfig{SafeMode: true, Logger: logging.Discard()}}
+
+	id, err := e.resolveMachineID(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, id, 16)
+	assert.NotContains(t, id, "0123456789abcdef")
+
+	require.NoError(t, os.WriteFile(dmiSerial, []byte("Q123A45678\n"), 0o644))
+	serialID, err := e.resolveMachineID(context.Background())
+	require.NoError(t, err)
+	assert.NotEqual(t, id, serialID, "the serial number takes precedence over the machine ID")
+
+	again, err := e.resolveMachineID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, serialID, again)
+
+	machineIDFiles = []string{filepath.Join(dir, "missing")}
+	_, err = e.resolveMachineID(context.Background())
+	assert.Error(t, err)
+}
+
+func TestValidateMachineIDLabel(t *testing.T) {
+	assert.NoError(t, ValidateMachineIDLabel("", "", nil))
+	assert.NoError(t, ValidateMachineIDLabel("machine_id", "", map[string]string{"site": "home"}))
+	assert.Error(t, ValidateMachineIDLabel("machine-id", "", nil))
+	assert.Error(t, ValidateMachineIDLabel("node", "", nil))
+	assert.Error(t, ValidateMachineIDLabel("host", "host", nil))
+	assert.Error(t, ValidateMachineIDLabel("site", "", map[string]string{"site": "home"}))
+}
+
+func TestTargetLabelsWithMachineID(t *testing.T) {
+	e := &promExporter{
+		ExporterConfig: ExporterConfig{MachineIDLabel: "machine_id", StaticLabels: map[string]string{"site": "home"}},
+		hostname:       "nas",
+		machineID:      "0123456789abcdef",
+	}
+	assert.Equal(t, []label{
+		{name: "node", value: "nas"},
+		{name: "machine_id", value: "0123456789abcdef"},
+		{name: "site", value: "home"},
+	}, e.targetLabels())
+
+	e.DropNodeLabel = true
+	assert.Equal(t, []label{
+		{name: "machine_id", value: "0123456789abcdef"},
+		{name: "site", value: "home"},
+	}, e.targetLabels())
+}
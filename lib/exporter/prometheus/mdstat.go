@@ -0,0 +1,217 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	mdStatusRe    = regexp.MustCompile(`\[(\d+)/(\d+)\]\s+\[[U_]+\]`)
+	mdSyncRe      = regexp.MustCompile(`(resync|recovery|reshape|check)\s*=\s*([\d.]+)%`)
+	mdSyncSpeedRe = regexp.MustCompile(`speed=(\d+)K/sec`)
+
+	// mdSyncQueuedRe matches the sync actions waiting for the sync of another array sharing the same disks,
+	// which the old kernels of some legacy QTS firmware report after a reboot
+	mdSyncQueuedRe = regexp.MustCompile(`(resync|recovery|reshape|check)\s*=\s*(DELAYED|PENDING)`)
+
+	// QNAP system arrays are RAID1 arrays sized for the maximum number of bays,
+	// so they always report fewer in-sync devices than slots. md8 is the swap array created by the legacy QTS firmware,
+	// which is still found on the NAS upgraded since.
+	qnapSystemMdArrays = map[string]bool{"md8": true, "md9": true, "md13": true}
+)
+
+type mdArray struct {
+	name            string
+	state           string
+	level           string
+	activeDevices   int
+	failedDevices   int
+	spareDevices    int
+	requiredDevices int
+	inSyncDevices   int
+	syncAction      string
+	syncProgress    float64
+	syncSpeedBytes  float64
+}
+
+func (e *promExporter) getMdStatMetrics(ctx context.Context) ([]metric, error) {
+	lines, err := e.sys.ReadFileLines(mdstatPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Ignore if the file does not exist
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	arrays := parseMdStat(lines)
+	metrics := make([]metric, 0, len(arrays)*8)
+	for _, a := range arrays {
+		attr := fmt.Sprintf("md=%q", a.name)
+
+		var active, degraded, syncing float64
+		if a.state == "active" {
+			active = 1
+		}
+		switch {
+		case qnapSystemMdArrays[a.name]:
+			if a.failedDevices > 0 {
+				degraded = 1
+			}
+		case a.inSyncDevices < a.requiredDevices:
+			degraded = 1
+		}
+		if a.syncAction != "" {
+			syncing = 1
+		}
+
+		metrics = append(
+			metrics,
+			metric{
+				name:  "node_md_active",
+				attr:  fmt.Sprintf("%s,level=%q", attr, a.level),
+				value: active,
+				help:  "Whether the md array is active",
+			},
+			metric{
+				name:  "node_md_degraded",
+				attr:  attr,
+				value: degraded,
+				help:  "Whether the md array has fewer in-sync devices than required",
+			},
+			metric{
+				name:  "node_md_disks",
+				attr:  attr + `,state="active"`,
+				value: float64(a.activeDevices),
+				help:  "Number of member devices in the md array, by state",
+			},
+			metric{
+				name:  "node_md_disks",
+				attr:  attr + `,state="failed"`,
+				value: float64(a.failedDevices),
+				help:  "Number of member devices in the md array, by state",
+			},
+			metric{
+				name:  "node_md_disks",
+				attr:  attr + `,state="spare"`,
+				value: float64(a.spareDevices),
+				help:  "Number of member devices in the md array, by state",
+			},
+			metric{
+				name:  "node_md_disks_required",
+				attr:  attr,
+				value: float64(a.requiredDevices),
+				help:  "Number of devices required for the md array to be fully in sync",
+			},
+			metric{
+				name:  "node_md_syncing",
+				attr:  attr,
+				value: syncing,
+				help:  "Whether the md array is currently resyncing, recovering, reshaping or checking",
+			},
+		)
+
+		if a.syncAction != "" {
+			syncAttr := fmt.Sprintf("%s,action=%q", attr, a.syncAction)
+			metrics = append(
+				metrics,
+				metric{
+					name:  "node_md_sync_progress_percent",
+					attr:  syncAttr,
+					value: a.syncProgress,
+					help:  "Progress of the current md array sync action",
+				},
+				metric{
+					name:  "node_md_sync_speed_bytes_per_second",
+					attr:  syncAttr,
+					value: a.syncSpeedBytes,
+					help:  "Speed of the current md array sync action, in bytes per second",
+				},
+			)
+		}
+	}
+
+	return metrics, nil
+}
+
+// parseMdStat parses the contents of /proc/mdstat, e.g.:
+//
+//	md1 : active raid5 sda3[0] sdc3[2](F) sdb3[1]
+//	      7795118592 blocks super 1.0 level 5, 512k chunk, algorithm 2 [3/2] [UU_]
+//	      [==>..................]  recovery = 12.3% (480123456/3897559296) finish=321.1min speed=177423K/sec
+func parseMdStat(lines []string) []mdArray {
+	var arrays []mdArray
+	var current *mdArray
+
+	for _, line := range lines {
+		if strings.HasPrefix(line, "md") {
+			name, desc, found := strings.Cut(line, " : ")
+			if !found {
+				continue
+			}
+
+			arrays = append(arrays, mdArray{name: strings.TrimSpace(name)})
+			current = &arrays[len(arrays)-1]
+
+			fields := strings.Fields(desc)
+			if len(fields) == 0 {
+				continue
+			}
+			current.state = fields[0]
+			fields = fields[1:]
+			if len(fields) > 0 && strings.HasPrefix(fields[0], "(") {
+				// e.g. "(auto-read-only)"
+				fields = fields[1:]
+			}
+			if len(fields) > 0 && !strings.Contains(fields[0], "[") {
+				current.level = fields[0]
+				fields = fields[1:]
+			}
+			for _, dev := range fields {
+				switch {
+				case strings.HasSuffix(dev, "(F)"):
+					current.failedDevices++
+				case strings.HasSuffix(dev, "(S)"):
+					current.spareDevices++
+				default:
+					current.activeDevices++
+				}
+			}
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		if m := mdStatusRe.FindStringSubmatch(line); m != nil {
+			current.requiredDevices, _ = strconv.Atoi(m[1])
+			current.inSyncDevices, _ = strconv.Atoi(m[2])
+		}
+		if m := mdSyncRe.FindStringSubmatch(line); m != nil {
+			current.syncAction = m[1]
+			current.syncProgress, _ = strconv.ParseFloat(m[2], 64)
+		} else if m := mdSyncQueuedRe.FindStringSubmatch(line); m != nil {
+			current.syncAction = m[1]
+		}
+		if m := mdSyncSpeedRe.FindStringSubmatch(line); m != nil {
+			speed, _ := strconv.ParseFloat(m[1], 64)
+			current.syncSpeedBytes = speed * 1024
+		}
+	}
+
+	for idx, a := range arrays {
+		if a.requiredDevices == 0 {
+			// Arrays such as raid0/linear don't report the [n/m] status
+			arrays[idx].requiredDevices = a.activeDevices
+			arrays[idx].inSyncDevices = a.activeDevices
+		}
+	}
+
+	return arrays
+}
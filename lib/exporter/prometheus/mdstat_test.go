@@ -0,0 +1,60 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMdStat(t *testing.T) {
+	mdstat := `Personalities : [linear] [raid0] [raid1] [raid10] [raid6] [raid5] [raid4] [multipath]
+md1 : active raid5 sda3[0] sdc3[2](F) sdb3[1] sdd3[3](S)
+      7795118592 blocks super 1.0 level 5, 512k chunk, algorithm 2 [3/2] [UU_]
+      [==>..................]  recovery = 12.3% (480123456/3897559296) finish=321.1min speed=177423K/sec
+
+md9 : active raid1 sda1[0] sdc1[2] sdb1[1]
+      530048 blocks super 1.0 [24/3] [UUU_____________________]
+      bitmap: 1/1 pages [4KB], 65536KB chunk
+
+md2 : active raid0 sde3[0] sdf3[1]
+      1953260544 blocks super 1.0 512k chunks
+
+md8 : active raid1 sdb2[2](S) sda2[0] sdc2[1]
+      530048 blocks [2/2] [UU]
+      	resync=DELAYED
+
+unused devices: <none>`
+
+	arrays := parseMdStat(strings.Split(mdstat, "\n"))
+	require.Len(t, arrays, 4)
+
+	assert.Equal(t, mdArray{
+		name:            "md1",
+		state:           "active",
+		level:           "raid5",
+		activeDevices:   2,
+		failedDevices:   1,
+		spareDevices:    1,
+		requiredDevices: 3,
+		inSyncDevices:   2,
+		syncAction:      "recovery",
+		syncProgress:    12.3,
+		syncSpeedBytes:  177423 * 1024,
+	}, arrays[0])
+
+	assert.Equal(t, "md9", arrays[1].name)
+	assert.Equal(t, 24, arrays[1].requiredDevices)
+	assert.Equal(t, 3, arrays[1].inSyncDevices)
+	assert.Empty(t, arrays[1].syncAction)
+
+	assert.Equal(t, "raid0", arrays[2].level)
+	assert.Equal(t, 2, arrays[2].requiredDevices)
+	assert.Equal(t, 2, arrays[2].inSyncDevices)
+
+	assert.Equal(t, "md8", arrays[3].name)
+	assert.Equal(t, 2, arrays[3].requiredDevices)
+	assert.Equal(t, "resync", arrays[3].syncAction)
+	assert.Zero(t, arrays[3].syncProgress)
+}
@@ -1,22 +1,56 @@
 package prometheus
 
-import "github.com/shirou/gopsutil/v3/mem"
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
 
-func getMemInfoMetrics() ([]metric, error) {
-	s, err := mem.VirtualMemory()
+const meminfoPath = "/proc/meminfo"
+
+// getMemInfoMetrics exports every field of /proc/meminfo, named like node_exporter does
+// (e.g. node_memory_Active_anon_bytes for Active(anon)), so that its dashboards and alerts can be reused
+func (e *promExporter) getMemInfoMetrics(ctx context.Context) ([]metric, error) {
+	lines, err := e.sys.ReadFileLines(meminfoPath)
 	if err != nil {
 		return nil, err
 	}
 
-	metrics := []metric{
-		{name: "node_memory_MemTotal_bytes", value: float64(s.Total)},
-		{name: "node_memory_MemFree_bytes", value: float64(s.Free)},
-		{name: "node_memory_Cached_bytes", value: float64(s.Cached)},
-		{name: "node_memory_Active_bytes", value: float64(s.Active)},
-		{name: "node_memory_Inactive_bytes", value: float64(s.Inactive)},
-		{name: "node_memory_SwapTotal_bytes", value: float64(s.SwapTotal)},
-		{name: "node_memory_SwapFree_bytes", value: float64(s.SwapFree)},
-		{name: "node_memory_MemAvailable_bytes", value: float64(s.Available)},
+	return parseMemInfo(lines)
+}
+
+// parseMemInfo parses /proc/meminfo lines, e.g.:
+//
+//	MemTotal:        8039012 kB
+//	Active(anon):     812344 kB
+//	HugePages_Total:       0
+func parseMemInfo(lines []string) ([]metric, error) {
+	metrics := make([]metric, 0, len(lines))
+	for _, line := range lines {
+		key, rest, found := strings.Cut(line, ":")
+		fields := strings.Fields(rest)
+		if !found || len(fields) == 0 {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s value %q: %w", key, fields[0], err)
+		}
+
+		key = strings.NewReplacer("(", "_", ")", "").Replace(strings.TrimSpace(key))
+		name := "node_memory_" + key
+		if len(fields) == 2 && fields[1] == "kB" {
+			name += "_bytes"
+			value *= 1024
+		}
+
+		metrics = append(metrics, metric{
+			name:  name,
+			value: value,
+			help:  "Memory information field " + key,
+		})
 	}
 
 	return metrics, nil
@@ -0,0 +1,47 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMemInfo(t *testing.T) {
+	testCases := map[string]struct {
+		lines   []string
+		want    []metric
+		wantErr string
+	}{
+		"fields": {
+			lines: []string{
+				"MemTotal:        8039012 kB",
+				"Active(anon):     812344 kB",
+				"HugePages_Total:       0",
+				"",
+			},
+			want: []metric{
+				{name: "node_memory_MemTotal_bytes", value: 8039012 * 1024, help: "Memory information field MemTotal"},
+				{name: "node_memory_Active_anon_bytes", value: 812344 * 1024, help: "Memory information field Active_anon"},
+				{name: "node_memory_HugePages_Total", value: 0, help: "Memory information field HugePages_Total"},
+			},
+		},
+		"invalid value": {
+			lines:   []string{"MemTotal: lots kB"},
+			wantErr: `parse MemTotal value "lots": strconv.ParseFloat: parsing "lots": invalid syntax`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			metrics, err := parseMemInfo(tc.lines)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, metrics)
+		})
+	}
+}
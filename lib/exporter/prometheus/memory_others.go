@@ -3,11 +3,12 @@
 package prometheus
 
 import (
+	"context"
 	"github.com/shirou/gopsutil/v3/mem"
 )
 
-func getMemInfoMetrics() ([]metric, error) {
-	s, err := mem.VirtualMemory()
+func (e *promExporter) getMemInfoMetrics(ctx context.Context) ([]metric, error) {
+	s, err := mem.VirtualMemoryWithContext(ctx)
 	if err != nil {
 		return nil, err
 	}
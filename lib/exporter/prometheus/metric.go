@@ -3,10 +3,14 @@ package prometheus
 import "time"
 
 type metric struct {
-	name       string
-	attr       string
-	timestamp  time.Time
-	value      float64
-	help       string
+	name      string
+	attr      string
+	timestamp time.Time
+	value     float64
+	help      string
+	// metricType is the type of the metric family, e.g. counter. Families without a type are exposed as gauges.
 	metricType string
 }
+
+// defaultMetricType is the type of the metric families which don't set one
+const defaultMetricType = "gauge"
@@ -2,20 +2,24 @@
 
 package prometheus
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // mockFetchMetricFn is an autogenerated mock type for the fetchMetricFn type
 type mockFetchMetricFn struct {
 	mock.Mock
 }
 
-// Execute provides a mock function with given fields:
-func (_m *mockFetchMetricFn) Execute() ([]metric, error) {
-	ret := _m.Called()
+// Execute provides a mock function with given fields: ctx
+func (_m *mockFetchMetricFn) Execute(ctx context.Context) ([]metric, error) {
+	ret := _m.Called(ctx)
 
 	var r0 []metric
-	if rf, ok := ret.Get(0).(func() []metric); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(context.Context) []metric); ok {
+		r0 = rf(ctx)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]metric)
@@ -23,8 +27,8 @@ func (_m *mockFetchMetricFn) Execute() ([]metric, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
 	} else {
 		r1 = ret.Error(1)
 	}
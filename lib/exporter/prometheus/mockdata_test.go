@@ -0,0 +1,132 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMockData runs the collectors against the outputs captured from each model in testdata/mock
+func TestMockData(t *testing.T) {
+	// The subsystems of which none of the models captured has any trace
+	absent := []string{"backupjobs", "diskpower", "dmcache", "energy", "eventlog", "fanpolicy", "flashcache", "qpkg", "quota", "ssdcache", "ups", "zfs"}
+	tests := map[string]struct {
+		wantLines    []string
+		notWantLines []string
+		wantAbsent   []string
+	}{
+		"ts-453d": {
+			wantLines: []string{
+				`node_cputmp_C{node="nas"} 45`,
+				`node_systmp_C{node="nas"} 38`,
+				`node_sysfan_RPM{node="nas",fan="1",type="System"} 812`,
+				`node_hdtmp_C{node="nas",hd="3",smart="GOOD"} 36`,
+				`node_volume_avail_bytes{node="nas",volume="DataVol1",filesystem="ext4",status="Ready"} 3.848290697216e+12`,
+				`node_disk_read_ops_total{node="nas",device="sda3"} 110583`,
+				`node_block_device_write_ops_total{node="nas",device="dm-0",name="cachedev1",volume=""} 690000`,
+				`node_block_device_read_ops_total{node="nas",device="md1",name="",volume=""} 330000`,
+				`node_hwmon_temp_celsius{node="nas",chip="coretemp",device="hwmon0",sensor="Package id 0"} 45`,
+				`node_md_disks_required{node="nas",md="md1"} 3`,
+				`node_cpu_count{node="nas"} 4`,
+				`node_load1{node="nas"} 0.42`,
+				`node_network_receive_drop_total{node="nas",device="eth0"} 12`,
+				`node_volume_device_info{node="nas",volume="",mountpoint="/share/CACHEDEV1_DATA",device="sdb3"} 1`,
+				`qnap_firmware_info{node="nas",version="5.1.0",build="20230822"} 1`,
+			},
+			notWantLines: []string{
+				`hd="4"`,
+				`node_disk_read_ops_total{node="nas",device="md1"}`,
+			},
+			wantAbsent: absent,
+		},
+		"hs-264": {
+			wantLines: []string{
+				`node_cputmp_C{node="nas"} 52`,
+				`node_hdtmp_C{node="nas",hd="2",smart="GOOD"} 41`,
+				`node_volume_size_bytes{node="nas",volume="Media",filesystem="ext4",status="Ready"} 3.848290697216e+12`,
+				`node_md_degraded{node="nas",md="md1"} 1`,
+				`node_disk_read_ops_total{node="nas",device="sdb3"} 2011`,
+				`node_block_device_read_ops_total{node="nas",device="md1",name="",volume=""} 82000`,
+				`node_memory_MemTotal_bytes{node="nas"} 8.202887168e+09`,
+				`qnap_firmware_info{node="nas",version="5.0.1",build="20230512"} 1`,
+			},
+			notWantLines: []string{
+				"node_systmp_C",
+				"node_sysfan_RPM",
+				"node_hwmon_",
+			},
+			wantAbsent: append([]string{"sysfan", "hwmon"}, absent...),
+		},
+		"ts-219p": {
+			// QTS 4.2 on a kernel without /sys/class/block, with the md8 swap array of the legacy firmware
+			wantLines: []string{
+				`node_sysfan_RPM{node="nas",fan="1",type="System"} 650`,
+				`node_disk_read_ops_total{node="nas",device="sdb3"} 43102`,
+				`node_block_device_read_ops_total{node="nas",device="md0",name="",volume=""} 87313`,
+				`node_md_degraded{node="nas",md="md8"} 0`,
+				`node_md_syncing{node="nas",md="md8"} 1`,
+				`node_load5{node="nas"} 0.97`,
+				`node_memory_MemTotal_bytes{node="nas"} 5.19806976e+08`,
+				`node_network_receive_errs_total{node="nas",device="eth0"} 3`,
+				`qnap_firmware_info{node="nas",version="4.2.6",build="20210327"} 1`,
+			},
+			notWantLines: []string{
+				`node_md_degraded{node="nas",md="md9"} 1`,
+			},
+			wantAbsent: append([]string{"hwmon"}, absent...),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := mockDataDir(t, name)
+
+			var s exporter.Status
+			logs := new(bytes.Buffer)
+			config := ExporterConfig{
+				Hostname:      "nas",
+				Backend:       utils.NewMockBackend(dir),
+				Logger:        logging.New(logs, logging.LevelError, logging.FormatText, nil),
+				ErrorComments: true,
+			}
+			e := NewExporter(config, &s)
+			defer e.Close()
+
+			b := new(bytes.Buffer)
+			err := e.WriteMetrics(context.Background(), b)
+			assert.NotContains(t, b.String(), "\n## ", "every collector succeeds")
+			require.NoError(t, err)
+
+			output := "\n" + b.String()
+			for _, line := range tc.wantLines {
+				assert.Contains(t, output, "\n"+line+"\n")
+			}
+			for _, line := range tc.notWantLines {
+				assert.NotContains(t, output, line)
+			}
+			assert.ElementsMatch(t, tc.wantAbsent, s.AbsentSubsystems)
+			assert.Empty(t, logs.String())
+		})
+	}
+}
+
+// mockDataDir returns the directory of the outputs captured from model, to which it also points the collectors reading
+// procfs and sysfs through gopsutil (e.g. cpu and loadavg), which don't go through the backend (see newMockBackend in
+// main)
+func mockDataDir(t *testing.T, model string) string {
+	t.Helper()
+
+	dir := filepath.Join("testdata", "mock", model)
+	for env, subdir := range map[string]string{"HOST_PROC": "proc", "HOST_SYS": "sys", "HOST_ETC": "etc", "HOST_DEV": "dev"} {
+		t.Setenv(env, filepath.Join(dir, subdir))
+	}
+
+	return dir
+}
@@ -0,0 +1,71 @@
+package prometheus
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// MetricNamespaceNode keeps the historical names (mostly node_*), for compatibility with existing dashboards and rules
+	MetricNamespaceNode = "node"
+	// MetricNamespaceQNAP moves the node_* and ups_* metrics under the qnap_ namespace,
+	// so that they don't collide with node_exporter running on the same host
+	MetricNamespaceQNAP = "qnap"
+)
+
+// qnapMetricRenames holds the names which don't follow the Prometheus naming conventions, and are given a proper
+// name instead of just a new prefix in the qnap namespace
+var qnapMetricRenames = map[string]string{
+	"node_time_seconds": "qnap_system_uptime_seconds",
+	"node_cputmp_C":     "qnap_cpu_temperature_celsius",
+	"node_cputmp_C_max": "qnap_cpu_temperature_celsius_max",
+	"node_systmp_C":     "qnap_system_temperature_celsius",
+	"node_hdtmp_C":      "qnap_disk_temperature_celsius",
+	"node_sysfan_RPM":   "qnap_system_fan_speed_rpm",
+}
+
+// ValidateMetricNamespace checks that namespace is empty or one of the known metric namespaces
+func ValidateMetricNamespace(namespace string) error {
+	switch namespace {
+	case "", MetricNamespaceNode, MetricNamespaceQNAP:
+		return nil
+	default:
+		return fmt.Errorf("unknown metric namespace %q", namespace)
+	}
+}
+
+// qnapMetricName returns the name of the metric family in the qnap namespace.
+// The metrics of the exporter itself (qnap_* and qnapexporter_*) keep their names.
+func qnapMetricName(name string) string {
+	if renamed, found := qnapMetricRenames[name]; found {
+		return renamed
+	}
+
+	switch {
+	case strings.HasPrefix(name, "node_"):
+		return "qnap_" + strings.TrimPrefix(name, "node_")
+	case strings.HasPrefix(name, "ups_"):
+		return "qnap_" + name
+	default:
+		return name
+	}
+}
+
+// inNamespace returns metrics with the names of the configured metric namespace, along with the deprecated names
+// of the renamed metrics during their transition period
+func (e *promExporter) inNamespace(metrics []metric) []metric {
+	deprecated := e.deprecatedNames(time.Now())
+	if e.MetricNamespace != MetricNamespaceQNAP {
+		return withDeprecatedNames(metrics, deprecated)
+	}
+
+	// The collectors may keep the metrics they return (e.g. as last-known-good values), so they are copied
+	renamed := make([]metric, len(metrics))
+	for idx, m := range metrics {
+		m.name = qnapMetricName(m.name)
+		renamed[idx] = m
+	}
+
+	return withDeprecatedNames(renamed, deprecated)
+}
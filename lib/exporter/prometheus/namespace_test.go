@@ -0,0 +1,68 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQnapMetricName(t *testing.T) {
+	tests := map[string]struct {
+		name string
+		want string
+	}{
+		"renamed":          {name: "node_cputmp_C", want: "qnap_cpu_temperature_celsius"},
+		"node prefix":      {name: "node_disk_read_bytes_total", want: "qnap_disk_read_bytes_total"},
+		"ups prefix":       {name: "ups_battery_charge", want: "qnap_ups_battery_charge"},
+		"exporter metric":  {name: "qnapexporter_degraded", want: "qnapexporter_degraded"},
+		"already in qnap":  {name: "qnap_health_score", want: "qnap_health_score"},
+		"other namespaces": {name: "go_program", want: "go_program"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, qnapMetricName(tc.name))
+		})
+	}
+}
+
+func TestValidateMetricNamespace(t *testing.T) {
+	assert.NoError(t, ValidateMetricNamespace(""))
+	assert.NoError(t, ValidateMetricNamespace(MetricNamespaceNode))
+	assert.NoError(t, ValidateMetricNamespace(MetricNamespaceQNAP))
+	assert.Error(t, ValidateMetricNamespace("nas"))
+}
+
+func TestWriteMetricsInQnapNamespace(t *testing.T) {
+	returned := []metric{
+		{name: "node_cputmp_C", value: 42},
+		{name: "node_load1", value: 3},
+	}
+	e := &promExporter{
+		ExporterConfig: ExporterConfig{MetricNamespace: MetricNamespaceQNAP, Logger: logging.Discard()},
+		hostname:       "nas",
+		envExpiry:      time.Now().Add(time.Hour),
+		watchdog:       newWatchdog(0, nil, nil),
+	}
+	e.fns = []collector{
+		{
+			name: "test",
+			fn: func(context.Context) ([]metric, error) {
+				return returned, nil
+			},
+		},
+	}
+
+	b := new(bytes.Buffer)
+	require.NoError(t, e.WriteMetrics(context.Background(), b))
+
+	assert.Contains(t, b.String(), "qnap_cpu_temperature_celsius{node=\"nas\"} 42\n")
+	assert.Contains(t, b.String(), "\nqnap_load1{node=\"nas\"} 3\n")
+	assert.NotContains(t, b.String(), "node_")
+	assert.Equal(t, "node_cputmp_C", returned[0].name, "the metrics returned by the collector are left untouched")
+}
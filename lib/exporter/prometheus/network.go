@@ -1,37 +1,204 @@
 package prometheus
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"math"
+	"net"
+	"os"
 	"path"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-ping/ping"
 	"github.com/pedropombeiro/qnapexporter/lib/utils"
 )
 
-func (e *promExporter) getNetworkStatsMetrics() ([]metric, error) {
+const (
+	PingModeICMP = "icmp"
+	// PingModeUDP sends ICMP echo requests through an unprivileged datagram socket, which doesn't require raw sockets
+	// but is only permitted to the groups in the net.ipv4.ping_group_range sysctl
+	PingModeUDP = "udp"
+	PingModeTCP = "tcp"
+	PingModeTLS = "tls"
+
+	pingTimeout      = 2 * time.Second
+	pingInterval     = 200 * time.Millisecond
+	pingCount        = 5
+	defaultProbePort = "443"
+)
+
+func (e *promExporter) getNetworkStatsMetrics(ctx context.Context) ([]metric, error) {
 	metrics := make([]metric, 0, len(e.ifaces)*2)
+	traffic := make([]trafficSample, 0, len(e.ifaces))
 	for _, iface := range e.ifaces {
-		rxMetric, err := getNetworkStatMetric("node_network_receive_bytes_total", "Total number of bytes received", iface, "rx")
+		rxMetric, err := getNetworkStatMetric(e.sys, "node_network_receive_bytes_total", "Total number of bytes received", iface, "rx")
 		if err != nil {
 			return nil, err
 		}
 		metrics = append(metrics, rxMetric)
 
-		txMetric, err := getNetworkStatMetric("node_network_transmit_bytes_total", "Total number of bytes transmitted", iface, "tx")
+		txMetric, err := getNetworkStatMetric(e.sys, "node_network_transmit_bytes_total", "Total number of bytes transmitted", iface, "tx")
 		if err != nil {
 			return nil, err
 		}
 		metrics = append(metrics, txMetric)
+		traffic = append(traffic, trafficSample{iface: iface, rxBytes: rxMetric.value, txBytes: txMetric.value})
+
+		metrics = append(metrics, readNetworkLinkMetrics(e.sys, netDir, iface)...)
+
+		if strings.HasPrefix(iface, "bond") {
+			bondMetrics, err := readBondingMetrics(e.sys, netDir, iface)
+			if err != nil {
+				return metrics, err
+			}
+			metrics = append(metrics, bondMetrics...)
+		}
+	}
+
+	for _, bridge := range e.bridgeIfaces {
+		typeAttr := fmt.Sprintf(",type=%q", containerBridgeType(bridge))
+
+		rxMetric, err := getNetworkStatMetric(e.sys, "node_network_container_receive_bytes_total", "Total number of bytes received by the container bridge", bridge, "rx")
+		if err != nil {
+			return metrics, err
+		}
+		rxMetric.attr += typeAttr
+
+		txMetric, err := getNetworkStatMetric(e.sys, "node_network_container_transmit_bytes_total", "Total number of bytes transmitted by the container bridge", bridge, "tx")
+		if err != nil {
+			return metrics, err
+		}
+		txMetric.attr += typeAttr
+
+		metrics = append(metrics, rxMetric, txMetric)
+
+		metrics = append(metrics, readNetworkLinkMetrics(e.sys, netDir, bridge)...)
+	}
+
+	trafficMetrics, err := e.getTrafficMetrics(traffic)
+	return append(metrics, trafficMetrics...), err
+}
+
+// networkErrorCounters are the statistics of /sys/class/net/<iface>/statistics exported along with the byte counts
+var networkErrorCounters = []struct {
+	name string
+	help string
+	file string
+}{
+	{name: "node_network_receive_errs_total", help: "Total number of receive errors", file: "rx_errors"},
+	{name: "node_network_transmit_errs_total", help: "Total number of transmit errors", file: "tx_errors"},
+	{name: "node_network_receive_drop_total", help: "Total number of received packets dropped", file: "rx_dropped"},
+	{name: "node_network_transmit_drop_total", help: "Total number of packets dropped before transmission", file: "tx_dropped"},
+}
+
+// readNetworkLinkMetrics reads the error and drop counters, carrier state and link speed of iface in root (e.g. /sys/class/net)
+func readNetworkLinkMetrics(sys utils.System, root string, iface string) []metric {
+	attr := fmt.Sprintf("device=%q", iface)
+	metrics := make([]metric, 0, len(networkErrorCounters)+2)
+	for _, c := range networkErrorCounters {
+		// Some drivers don't provide every counter, which are left out rather than failing the whole collector
+		str, err := sys.ReadFile(path.Join(root, iface, "statistics", c.file))
+		if err != nil {
+			continue
+		}
+		value, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			continue
+		}
+
+		metrics = append(metrics, metric{
+			name:       c.name,
+			attr:       attr,
+			value:      value,
+			help:       c.help,
+			metricType: "counter",
+		})
+	}
+
+	// carrier can't be read while the interface is administratively down
+	var carrier float64
+	if str, err := sys.ReadFile(path.Join(root, iface, "carrier")); err == nil && str == "1" {
+		carrier = 1
+	}
+	metrics = append(metrics, metric{
+		name:  "node_network_carrier",
+		attr:  attr,
+		value: carrier,
+		help:  "Whether the interface has a link (carrier)",
+	})
+
+	// speed is only known for physical interfaces with a link, and reported in Mbit/s
+	if str, err := sys.ReadFile(path.Join(root, iface, "speed")); err == nil {
+		if speed, err := strconv.ParseFloat(str, 64); err == nil && speed > 0 {
+			metrics = append(metrics, metric{
+				name:  "node_network_speed_bytes",
+				attr:  attr,
+				value: speed * 1000 * 1000 / 8,
+				help:  "Negotiated link speed, in bytes per second",
+			})
+		}
+	}
+
+	return metrics
+}
+
+// readBondingMetrics reads the number of slaves of the bond interface in root (e.g. /sys/class/net),
+// and how many of them have a link
+func readBondingMetrics(sys utils.System, root string, bond string) ([]metric, error) {
+	str, err := sys.ReadFile(path.Join(root, bond, "bonding", "slaves"))
+	if err != nil {
+		return nil, err
+	}
+
+	slaves := strings.Fields(str)
+	active := 0
+	for _, slave := range slaves {
+		status, err := sys.ReadFile(path.Join(root, slave, "bonding_slave", "mii_status"))
+		if err == nil && status == "up" {
+			active++
+		}
 	}
 
-	return metrics, nil
+	attr := fmt.Sprintf("master=%q", bond)
+	return []metric{
+		{
+			name:  "node_bonding_slaves",
+			attr:  attr,
+			value: float64(len(slaves)),
+			help:  "Number of interfaces enslaved to the bond",
+		},
+		{
+			name:  "node_bonding_active",
+			attr:  attr,
+			value: float64(active),
+			help:  "Number of interfaces enslaved to the bond which have a link",
+		},
+	}, nil
+}
+
+// containerBridgeType returns the kind of container bridge the interface is
+// (lxc, docker or qvs), or an empty string if it is not a container bridge
+func containerBridgeType(iface string) string {
+	switch {
+	case strings.HasPrefix(iface, "lxcbr"):
+		return "lxc"
+	case strings.HasPrefix(iface, "docker"), strings.HasPrefix(iface, "br-"):
+		// docker0 is the default bridge, br-<id> are user-defined bridge networks
+		return "docker"
+	case strings.HasPrefix(iface, "qvs"):
+		return "qvs"
+	default:
+		return ""
+	}
 }
 
-func getNetworkStatMetric(name string, help string, iface string, direction string) (metric, error) {
-	str, err := utils.ReadFile(path.Join(netDir, iface, "statistics", direction+"_bytes"))
+func getNetworkStatMetric(sys utils.System, name string, help string, iface string, direction string) (metric, error) {
+	str, err := sys.ReadFile(path.Join(netDir, iface, "statistics", direction+"_bytes"))
 	if err != nil {
 		return metric{}, err
 	}
@@ -50,35 +217,158 @@ func getNetworkStatMetric(name string, help string, iface string, direction stri
 	}, nil
 }
 
-func (e *promExporter) getPingMetrics() ([]metric, error) {
-	if e.PingTarget == "" {
+func (e *promExporter) getPingMetrics(ctx context.Context) ([]metric, error) {
+	if len(e.PingTargets) == 0 {
 		return nil, nil
 	}
 
-	pinger, err := ping.NewPinger(e.PingTarget)
+	results := make([]probeResult, len(e.PingTargets))
+	errs := make([]error, len(e.PingTargets))
+	var wg sync.WaitGroup
+	for idx, target := range e.PingTargets {
+		wg.Add(1)
+		go func(idx int, target string) {
+			defer wg.Done()
+
+			switch e.PingMode {
+			case PingModeTCP, PingModeTLS:
+				results[idx], errs[idx] = dialProbe(ctx, e.PingMode, target)
+			case PingModeICMP, "":
+				results[idx], errs[idx] = e.icmpProbe(target)
+			case PingModeUDP:
+				results[idx], errs[idx] = icmpProbe(target, false)
+			default:
+				errs[idx] = fmt.Errorf("unknown ping mode %q", e.PingMode)
+			}
+		}(idx, target)
+	}
+	wg.Wait()
+
+	var err error
+	metrics := make([]metric, 0, 2*len(results))
+	for idx, r := range results {
+		if errs[idx] != nil {
+			err = fmt.Errorf("probe %s: %w", e.PingTargets[idx], errs[idx])
+			continue
+		}
+
+		value := float64(r.rtt.Seconds()) * 1000.0
+		if r.rtt < 0 {
+			value = math.NaN()
+		}
+		attr := fmt.Sprintf("target=%q", r.target)
+		metrics = append(
+			metrics,
+			metric{
+				name:      "node_network_external_roundtrip_time_ms",
+				attr:      attr,
+				value:     value,
+				timestamp: time.Now(),
+			},
+			metric{
+				name:  "node_network_external_packet_loss_ratio",
+				attr:  attr,
+				value: r.packetLoss,
+				help:  "Ratio of probes to the ping target which got no reply",
+			},
+		)
+	}
+
+	return metrics, err
+}
+
+type probeResult struct {
+	// target is the resolved target address in ICMP mode, or the configured target in TCP and TLS modes
+	target string
+	// rtt is the average round trip time, or a negative duration if all the probes were lost
+	rtt time.Duration
+	// packetLoss is the ratio of lost probes, between 0 and 1
+	packetLoss float64
+}
+
+// icmpProbe probes host through a raw socket, falling back to an unprivileged datagram socket for good
+// once raw sockets turn out not to be permitted (e.g. when not running as root or in an unprivileged container)
+func (e *promExporter) icmpProbe(host string) (probeResult, error) {
+	if !e.pingUnprivileged.Load() {
+		r, err := icmpProbe(host, true)
+		if !errors.Is(err, os.ErrPermission) {
+			return r, err
+		}
+
+		if !e.pingUnprivileged.Swap(true) {
+			e.Logger.Warn("Raw sockets not permitted, falling back to unprivileged ping", "err", err)
+		}
+	}
+
+	return icmpProbe(host, false)
+}
+
+// icmpProbe sends pingCount ICMP echo requests to host, through a raw socket if privileged is true,
+// or else through a datagram socket
+func icmpProbe(host string, privileged bool) (probeResult, error) {
+	pinger, err := ping.NewPinger(host)
 	if err != nil {
-		return nil, err
+		return probeResult{}, err
 	}
 
-	pinger.SetPrivileged(true)
-	pinger.Timeout = 2 * time.Second
-	pinger.Count = 1
+	pinger.SetPrivileged(privileged)
+	pinger.Timeout = pingTimeout
+	pinger.Interval = pingInterval
+	pinger.Count = pingCount
 	err = pinger.Run() // Blocks until finished.
 	if err != nil {
-		return nil, err
+		if !privileged && errors.Is(err, os.ErrPermission) {
+			return probeResult{}, fmt.Errorf("%w (allow the group of the exporter in the net.ipv4.ping_group_range sysctl, or use the tcp ping mode)", err)
+		}
+		return probeResult{}, err
 	}
 
 	stats := pinger.Statistics() // get send/receive/rtt stats
-	value := float64(stats.AvgRtt.Seconds()) * 1000.0
-	if stats.PacketLoss > 0 {
-		value = math.NaN()
+	r := probeResult{target: pinger.IPAddr().String(), rtt: stats.AvgRtt, packetLoss: 1}
+	if stats.PacketsSent > 0 {
+		r.packetLoss = float64(stats.PacketsSent-stats.PacketsRecv) / float64(stats.PacketsSent)
 	}
-	m := metric{
-		name:      "node_network_external_roundtrip_time_ms",
-		attr:      fmt.Sprintf("target=%q", pinger.IPAddr().String()),
-		value:     value,
-		timestamp: time.Now(),
+	if stats.PacketsRecv == 0 {
+		r.rtt = -1
+	}
+
+	return r, nil
+}
+
+// dialProbe opens a TCP connection (and performs a TLS handshake, in TLS mode) to target,
+// counting a target which could not be reached as a lost packet. The result is labeled with target as configured,
+// whether it was reached or not, so that the series of a target does not change when it goes down.
+func dialProbe(ctx context.Context, mode string, target string) (probeResult, error) {
+	address := target
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, defaultProbePort)
+	}
+
+	dialer := &net.Dialer{Timeout: pingTimeout}
+	start := time.Now()
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	switch mode {
+	case PingModeTLS:
+		tlsDialer := &tls.Dialer{NetDialer: dialer}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", address)
+	default:
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			// Unreachable target is reported the same way as a lost ICMP packet
+			return probeResult{target: target, rtt: -1, packetLoss: 1}, nil
+		}
+
+		return probeResult{}, err
 	}
+	rtt := time.Since(start)
+	conn.Close()
 
-	return []metric{m}, nil
+	return probeResult{target: target, rtt: rtt}, nil
 }
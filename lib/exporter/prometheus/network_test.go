@@ -0,0 +1,199 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialProbe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	addr := l.Addr().String()
+	r, err := dialProbe(context.Background(), PingModeTCP, addr)
+	require.NoError(t, err)
+	assert.Equal(t, addr, r.target)
+	assert.GreaterOrEqual(t, r.rtt.Nanoseconds(), int64(0))
+	assert.Zero(t, r.packetLoss)
+
+	l.Close()
+
+	r, err = dialProbe(context.Background(), PingModeTCP, addr)
+	require.NoError(t, err)
+	assert.Equal(t, addr, r.target)
+	assert.Negative(t, r.rtt.Nanoseconds())
+	assert.Equal(t, 1.0, r.packetLoss)
+
+	r, err = dialProbe(context.Background(), PingModeTCP, "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", r.target, "the target is reported as configured, without the default port")
+}
+
+func TestICMPProbeUnprivileged(t *testing.T) {
+	e := &promExporter{ExporterConfig: ExporterConfig{Logger: logging.Discard()}}
+	e.pingUnprivileged.Store(true)
+
+	r, err := e.icmpProbe("127.0.0.1")
+	if errors.Is(err, os.ErrPermission) {
+		t.Skip("unprivileged ping is not permitted by net.ipv4.ping_group_range")
+	}
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", r.target)
+	assert.Zero(t, r.packetLoss)
+}
+
+func TestGetPingMetricsWithMultipleTargets(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	e := &promExporter{
+		ExporterConfig: ExporterConfig{
+			PingTargets: []string{l.Addr().String(), closedAddr},
+			PingMode:    PingModeTCP,
+		},
+	}
+
+	metrics, err := e.getPingMetrics(context.Background())
+	require.NoError(t, err)
+	require.Len(t, metrics, 4)
+
+	assert.Equal(t, fmt.Sprintf("target=%q", l.Addr().String()), metrics[1].attr)
+	assert.Equal(t, "node_network_external_packet_loss_ratio", metrics[1].name)
+	assert.Zero(t, metrics[1].value)
+	assert.Equal(t, fmt.Sprintf("target=%q", closedAddr), metrics[3].attr)
+	assert.True(t, math.IsNaN(metrics[2].value))
+	assert.Equal(t, 1.0, metrics[3].value)
+}
+
+func TestContainerBridgeType(t *testing.T) {
+	testCases := map[string]string{
+		"eth0":            "",
+		"lo":              "",
+		"lxcbr0":          "lxc",
+		"docker0":         "docker",
+		"br-3c1e2f9a7b6d": "docker",
+		"qvs0":            "qvs",
+	}
+
+	for iface, expected := range testCases {
+		t.Run(iface, func(t *testing.T) {
+			assert.Equal(t, expected, containerBridgeType(iface))
+		})
+	}
+}
+
+func writeSysfsFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	for name, contents := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+	}
+}
+
+func TestReadNetworkLinkMetrics(t *testing.T) {
+	root := t.TempDir()
+	writeSysfsFiles(t, root, map[string]string{
+		"eth0/statistics/rx_errors":  "1\n",
+		"eth0/statistics/tx_errors":  "2\n",
+		"eth0/statistics/rx_dropped": "3\n",
+		"eth0/statistics/tx_dropped": "4\n",
+		"eth0/carrier":               "1\n",
+		"eth0/speed":                 "1000\n",
+		"eth1/statistics/rx_errors":  "0\n",
+		"eth1/statistics/tx_errors":  "0\n",
+		"eth1/statistics/rx_dropped": "0\n",
+		"eth1/statistics/tx_dropped": "0\n",
+		"eth1/speed":                 "-1\n",
+		"eth3/statistics/rx_errors":  "n/a\n",
+		"eth3/statistics/tx_errors":  "5\n",
+		"eth3/carrier":               "1\n",
+	})
+
+	testCases := map[string]struct {
+		iface string
+		want  map[string]float64
+	}{
+		"link up": {
+			iface: "eth0",
+			want: map[string]float64{
+				"node_network_receive_errs_total":  1,
+				"node_network_transmit_errs_total": 2,
+				"node_network_receive_drop_total":  3,
+				"node_network_transmit_drop_total": 4,
+				"node_network_carrier":             1,
+				"node_network_speed_bytes":         125000000,
+			},
+		},
+		"interface down": {
+			iface: "eth1",
+			want: map[string]float64{
+				"node_network_receive_errs_total":  0,
+				"node_network_transmit_errs_total": 0,
+				"node_network_receive_drop_total":  0,
+				"node_network_transmit_drop_total": 0,
+				"node_network_carrier":             0,
+			},
+		},
+		"missing and unparsable counters": {
+			iface: "eth3",
+			want: map[string]float64{
+				"node_network_transmit_errs_total": 5,
+				"node_network_carrier":             1,
+			},
+		},
+		"missing interface": {
+			iface: "eth2",
+			want: map[string]float64{
+				"node_network_carrier": 0,
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			metrics := readNetworkLinkMetrics(utils.System{}, root, tc.iface)
+			values := map[string]float64{}
+			for _, m := range metrics {
+				assert.Equal(t, fmt.Sprintf("device=%q", tc.iface), m.attr)
+				values[m.name] = m.value
+			}
+			assert.Equal(t, tc.want, values)
+		})
+	}
+}
+
+func TestReadBondingMetrics(t *testing.T) {
+	root := t.TempDir()
+	writeSysfsFiles(t, root, map[string]string{
+		"bond0/bonding/slaves":          "eth0 eth1\n",
+		"eth0/bonding_slave/mii_status": "up\n",
+		"eth1/bonding_slave/mii_status": "down\n",
+	})
+
+	metrics, err := readBondingMetrics(utils.System{}, root, "bond0")
+	require.NoError(t, err)
+	assert.Equal(t, []metric{
+		{name: "node_bonding_slaves", attr: `master="bond0"`, value: 2, help: "Number of interfaces enslaved to the bond"},
+		{name: "node_bonding_active", attr: `master="bond0"`, value: 1, help: "Number of interfaces enslaved to the bond which have a link"},
+	}, metrics)
+}
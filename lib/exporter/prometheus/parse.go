@@ -0,0 +1,60 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+type collectorNameKey struct{}
+
+// withCollectorName records the name of the running collector in ctx, so that parse failures are attributed to it
+func withCollectorName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, collectorNameKey{}, name)
+}
+
+// parseFailures counts, per collector, the values which could not be parsed from command output
+type parseFailures struct {
+	mu     sync.Mutex
+	counts map[string]float64
+}
+
+func (p *parseFailures) add(collector string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.counts == nil {
+		p.counts = make(map[string]float64)
+	}
+	p.counts[collector]++
+}
+
+func (p *parseFailures) metric(collector string) metric {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return metric{
+		name:       "qnapexporter_parse_failures_total",
+		attr:       fmt.Sprintf("collector=%q", collector),
+		value:      p.counts[collector],
+		help:       "Number of values which could not be parsed from command output, and were skipped",
+		metricType: "counter",
+	}
+}
+
+// parseValue parses a number from command output. If it cannot be parsed, the failure is counted against
+// the collector running in ctx and false is returned, so that only that value is skipped.
+func (e *promExporter) parseValue(ctx context.Context, s string) (float64, bool) {
+	value, err := utils.ParseFloat(s)
+	if err != nil {
+		name, _ := ctx.Value(collectorNameKey{}).(string)
+		e.parseFailures.add(name)
+		e.Logger.Debug("Skipping unparsable value", "collector", name, "value", s, "err", err)
+
+		return 0, false
+	}
+
+	return value, true
+}
@@ -0,0 +1,27 @@
+package prometheus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseValue(t *testing.T) {
+	e := &promExporter{ExporterConfig: ExporterConfig{Logger: logging.Discard()}}
+	ctx := withCollectorName(context.Background(), "hdtemp")
+
+	value, ok := e.parseValue(ctx, "38,5")
+	assert.True(t, ok)
+	assert.Equal(t, 38.5, value)
+
+	_, ok = e.parseValue(ctx, "Unbekannt")
+	assert.False(t, ok)
+	_, ok = e.parseValue(ctx, "")
+	assert.False(t, ok)
+
+	assert.Equal(t, 2.0, e.parseFailures.metric("hdtemp").value)
+	assert.Equal(t, `collector="hdtemp"`, e.parseFailures.metric("hdtemp").attr)
+	assert.Equal(t, 0.0, e.parseFailures.metric("sysfan").value)
+}
@@ -0,0 +1,221 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+// clockTicksPerSecond is the unit of the CPU times in /proc/[pid]/stat (USER_HZ), which is 100 on every Linux platform QTS runs on
+const clockTicksPerSecond = 100
+
+// processKey identifies a process, telling apart processes which reused the PID of a previous one
+type processKey struct {
+	pid       int
+	startTime uint64
+}
+
+type processSample struct {
+	key     processKey
+	comm    string
+	threads int
+	// cpuTicks is the user and system CPU time used by the process, in clock ticks
+	cpuTicks uint64
+	rssBytes float64
+}
+
+// processUsage is the resource usage of all the processes sharing a command name
+type processUsage struct {
+	comm      string
+	processes int
+	cpuRatio  float64
+	rssBytes  float64
+}
+
+// processSampler keeps the CPU times seen on the previous scrape, from which the CPU usage of each process is computed
+type processSampler struct {
+	mu       sync.Mutex
+	cpuTicks map[processKey]uint64
+	lastTime time.Time
+}
+
+// getProcessMetrics reports the CPU and resident memory used by the top processes, grouped by command name,
+// as well as the number of processes and threads
+func (e *promExporter) getProcessMetrics(ctx context.Context) ([]metric, error) {
+	if e.TopProcesses <= 0 {
+		return nil, nil
+	}
+
+	samples, err := readProcesses(e.sys, procDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, subsystemAbsentError{"/proc not found"}
+		}
+
+		return nil, err
+	}
+
+	usages, cpuKnown := e.processes.usages(samples, time.Now())
+	threads := 0
+	for _, s := range samples {
+		threads += s.threads
+	}
+
+	metrics := make([]metric, 0, 2*e.TopProcesses+2)
+	metrics = append(
+		metrics,
+		metric{name: "node_processes_pids", value: float64(len(samples)), help: "Number of processes"},
+		metric{name: "node_processes_threads", value: float64(threads), help: "Number of threads in all the processes"},
+	)
+
+	if cpuKnown {
+		sort.SliceStable(usages, func(i, j int) bool { return usages[i].cpuRatio > usages[j].cpuRatio })
+		for _, u := range topProcessUsages(usages, e.TopProcesses) {
+			metrics = append(metrics, metric{
+				name:  "node_process_top_cpu_ratio",
+				attr:  fmt.Sprintf("comm=%q", u.comm),
+				value: u.cpuRatio,
+				help:  "CPU used since the previous scrape by the processes with the command name, among the top processes by CPU usage (1 is one full CPU)",
+			})
+		}
+	}
+
+	sort.SliceStable(usages, func(i, j int) bool { return usages[i].rssBytes > usages[j].rssBytes })
+	for _, u := range topProcessUsages(usages, e.TopProcesses) {
+		metrics = append(
+			metrics,
+			metric{
+				name:  "node_process_top_resident_memory_bytes",
+				attr:  fmt.Sprintf("comm=%q", u.comm),
+				value: u.rssBytes,
+				help:  "Resident memory used by the processes with the command name, among the top processes by memory usage",
+			},
+			metric{
+				name:  "node_process_top_count",
+				attr:  fmt.Sprintf("comm=%q", u.comm),
+				value: float64(u.processes),
+				help:  "Number of processes with the command name, among the top processes by memory usage",
+			},
+		)
+	}
+
+	return metrics, nil
+}
+
+func topProcessUsages(usages []processUsage, n int) []processUsage {
+	if len(usages) > n {
+		return usages[:n]
+	}
+
+	return usages
+}
+
+// usages groups samples by command name, computing their CPU usage since the previous call.
+// It returns whether the CPU usage is known, which is only the case from the second call on.
+func (s *processSampler) usages(samples []processSample, now time.Time) ([]processUsage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cpuKnown := !s.lastTime.IsZero()
+	elapsed := now.Sub(s.lastTime).Seconds()
+	byComm := map[string]*processUsage{}
+	cpuTicks := make(map[processKey]uint64, len(samples))
+	for _, p := range samples {
+		cpuTicks[p.key] = p.cpuTicks
+
+		u, found := byComm[p.comm]
+		if !found {
+			u = &processUsage{comm: p.comm}
+			byComm[p.comm] = u
+		}
+		u.processes++
+		u.rssBytes += p.rssBytes
+		// Processes started since the previous call only count from the next one
+		if previous, found := s.cpuTicks[p.key]; found && cpuKnown && elapsed > 0 && p.cpuTicks >= previous {
+			u.cpuRatio += float64(p.cpuTicks-previous) / clockTicksPerSecond / elapsed
+		}
+	}
+	s.cpuTicks = cpuTicks
+	s.lastTime = now
+
+	usages := make([]processUsage, 0, len(byComm))
+	for _, u := range byComm {
+		usages = append(usages, *u)
+	}
+	// Sort by name first, so that ties are ranked deterministically
+	sort.Slice(usages, func(i, j int) bool { return usages[i].comm < usages[j].comm })
+
+	return usages, cpuKnown
+}
+
+// readProcesses reads the stat file of every process under root
+func readProcesses(sys utils.System, root string) ([]processSample, error) {
+	entries, err := sys.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := float64(os.Getpagesize())
+	samples := make([]processSample, 0, len(entries))
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		contents, err := sys.ReadFile(filepath.Join(root, entry.Name(), "stat"))
+		if err != nil {
+			// The process exited since the directory was listed
+			continue
+		}
+
+		if s, ok := parseProcessStat(pid, contents, pageSize); ok {
+			samples = append(samples, s)
+		}
+	}
+
+	return samples, nil
+}
+
+// parseProcessStat parses the contents of /proc/[pid]/stat (see proc(5))
+func parseProcessStat(pid int, stat string, pageSize float64) (processSample, bool) {
+	// The command name is enclosed in parentheses and may itself contain spaces and parentheses
+	start := strings.IndexByte(stat, '(')
+	end := strings.LastIndexByte(stat, ')')
+	if start < 0 || end < start {
+		return processSample{}, false
+	}
+
+	// fields[0] is the 3rd field of the file (state)
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 22 {
+		return processSample{}, false
+	}
+
+	utime, err1 := strconv.ParseUint(fields[11], 10, 64)
+	stime, err2 := strconv.ParseUint(fields[12], 10, 64)
+	threads, err3 := strconv.Atoi(fields[17])
+	startTime, err4 := strconv.ParseUint(fields[19], 10, 64)
+	rss, err5 := strconv.ParseInt(fields[21], 10, 64)
+	for _, err := range []error{err1, err2, err3, err4, err5} {
+		if err != nil {
+			return processSample{}, false
+		}
+	}
+
+	return processSample{
+		key:      processKey{pid: pid, startTime: startTime},
+		comm:     stat[start+1 : end],
+		threads:  threads,
+		cpuTicks: utime + stime,
+		rssBytes: float64(rss) * pageSize,
+	}, true
+}
@@ -0,0 +1,116 @@
+package prometheus
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProcessStat(t *testing.T) {
+	tests := map[string]struct {
+		pid      int
+		stat     string
+		expected processSample
+		ok       bool
+	}{
+		"simple": {
+			pid:  1234,
+			stat: "1234 (smbd) S 1 1234 1234 0 -1 4194624 1000 0 0 0 150 50 0 0 20 0 3 0 987654 123456789 250 18446744073709551615",
+			expected: processSample{
+				key:      processKey{pid: 1234, startTime: 987654},
+				comm:     "smbd",
+				threads:  3,
+				cpuTicks: 200,
+				rssBytes: 250 * 4096,
+			},
+			ok: true,
+		},
+		"command with spaces and parentheses": {
+			pid:  42,
+			stat: "42 (my (odd) app) R 1 42 42 0 -1 0 0 0 0 0 1 2 0 0 20 0 1 0 5 1000 10 0",
+			expected: processSample{
+				key:      processKey{pid: 42, startTime: 5},
+				comm:     "my (odd) app",
+				threads:  1,
+				cpuTicks: 3,
+				rssBytes: 10 * 4096,
+			},
+			ok: true,
+		},
+		"truncated": {pid: 42, stat: "42 (app) R 1 42"},
+		"garbage":   {pid: 42, stat: "garbage"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			sample, ok := parseProcessStat(tc.pid, tc.stat, 4096)
+
+			assert.Equal(t, tc.ok, ok)
+			if tc.ok {
+				assert.Equal(t, tc.expected, sample)
+			}
+		})
+	}
+}
+
+func TestReadProcesses(t *testing.T) {
+	root := t.TempDir()
+	for pid, stat := range map[string]string{
+		"1":   "1 (init) S 0 1 1 0 -1 0 0 0 0 0 10 5 0 0 20 0 1 0 1 1000 100 0",
+		"200": "200 (nginx) S 1 200 200 0 -1 0 0 0 0 0 20 10 0 0 20 0 4 0 50 1000 300 0",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, pid), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, pid, "stat"), []byte(stat), 0o644))
+	}
+	// Entries which are not processes, or processes which exited, are skipped
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sys"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "300"), 0o755))
+
+	samples, err := readProcesses(utils.System{}, root)
+	require.NoError(t, err)
+
+	require.Len(t, samples, 2)
+	assert.Equal(t, "init", samples[0].comm)
+	assert.Equal(t, "nginx", samples[1].comm)
+	assert.Equal(t, 4, samples[1].threads)
+}
+
+func TestProcessSamplerUsages(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	var s processSampler
+
+	usages, cpuKnown := s.usages([]processSample{
+		{key: processKey{pid: 10, startTime: 1}, comm: "smbd", cpuTicks: 100, rssBytes: 1000},
+		{key: processKey{pid: 11, startTime: 1}, comm: "smbd", cpuTicks: 100, rssBytes: 2000},
+		{key: processKey{pid: 20, startTime: 1}, comm: "nginx", cpuTicks: 500, rssBytes: 500},
+	}, now)
+	assert.False(t, cpuKnown)
+	assert.Equal(t, []processUsage{
+		{comm: "nginx", processes: 1, rssBytes: 500},
+		{comm: "smbd", processes: 2, rssBytes: 3000},
+	}, usages)
+
+	usages, cpuKnown = s.usages([]processSample{
+		{key: processKey{pid: 10, startTime: 1}, comm: "smbd", cpuTicks: 300, rssBytes: 1000},
+		{key: processKey{pid: 11, startTime: 1}, comm: "smbd", cpuTicks: 200, rssBytes: 2000},
+		// The PID was reused by a new process, whose CPU time only counts from the next call
+		{key: processKey{pid: 20, startTime: 7}, comm: "nginx", cpuTicks: 50, rssBytes: 500},
+	}, now.Add(10*time.Second))
+	assert.True(t, cpuKnown)
+	require.Len(t, usages, 2)
+	assert.Equal(t, processUsage{comm: "nginx", processes: 1, rssBytes: 500}, usages[0])
+	assert.Equal(t, "smbd", usages[1].comm)
+	assert.InDelta(t, 0.3, usages[1].cpuRatio, 1e-9)
+}
+
+func TestTopProcessUsages(t *testing.T) {
+	usages := []processUsage{{comm: "a"}, {comm: "b"}, {comm: "c"}}
+
+	assert.Equal(t, usages[:2], topProcessUsages(usages, 2))
+	assert.Equal(t, usages, topProcessUsages(usages, 5))
+}
@@ -1,31 +1,49 @@
 package prometheus
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
-	"os"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/docker/docker/client"
 	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/pedropombeiro/qnapexporter/lib/update"
 	"github.com/pedropombeiro/qnapexporter/lib/utils"
+	promclient "github.com/prometheus/client_golang/prometheus"
 )
 
 const (
 	devDir                     = "/dev"
 	netDir                     = "/sys/class/net"
+	hwmonDir                   = "/sys/class/hwmon"
+	sysBlockDir                = "/sys/class/block"
+	shareDir                   = "/share"
 	flashcacheStatsPath        = "/proc/flashcache/CG0/flashcache_stats"
+	flashcacheGroupsGlob       = "/proc/flashcache/*/flashcache_stats"
+	mdstatPath                 = "/proc/mdstat"
+	diskstatsPath              = "/proc/diskstats"
 	dmCacheStatsFilePathFormat = "/sys/block/%s/dm/cache/curr_stats"
+	dockerSocketPath           = "/var/run/docker.sock"
+	zfsArcStatsPath            = "/proc/spl/kstat/zfs/arcstats"
+	kernelReleasePath          = "/proc/sys/kernel/osrelease"
 
 	envValidity    = time.Duration(5 * time.Minute)
 	volumeValidity = time.Duration(1 * time.Minute)
+
+	// collectorGracePeriod is how long to wait for a partial result from a collector which timed out
+	collectorGracePeriod = 100 * time.Millisecond
 )
 
-type fetchMetricFn func() ([]metric, error)
+type fetchMetricFn func(ctx context.Context) ([]metric, error)
 
 type qnapEnclosure struct {
 	id        string
@@ -38,92 +56,461 @@ type qnapEnclosure struct {
 type promExporter struct {
 	ExporterConfig
 
-	status *exporter.Status
+	status    *exporter.Status
+	startTime time.Time
 
 	hostname      string
+	machineID     string
 	kernelVersion int
 
 	upsState upsState
 
-	getsysinfo string
-	syshdnum   int
-	sysfannum  int
-	ifaces     []string
-	devices    []string
-	hal_app    string
-	enclosures []qnapEnclosure
-	envExpiry  time.Time
-
+	getsysinfo   string
+	model        string
+	syshdnum     int
+	sysfannum    int
+	quirk        sensorQuirk
+	ifaces       []string
+	bridgeIfaces []string
+	devices      []string
+	hal_app      string
+	smartctl     string
+	qcliSnapshot string
+	repquota     string
+	hdparm       string
+	smbstatus    string
+	sqlite3      string
+	smbclient    string
+	zpool        string
+	zfs          string
+	enclosures   []qnapEnclosure
+	envExpiry    time.Time
+	// sys runs the commands and reads the files of the collectors, through the configured backend
+	sys utils.System
+	// envRead is when the environment was last read
+	envRead time.Time
+
+	// volumesMu guards the free space of the volumes, updated by the volume collector while others read the volumes
+	volumesMu       sync.Mutex
 	volumes         []volumeInfo
 	volumeLastFetch time.Time
 
+	blockDir                 string
+	virtualDevices           []string
 	dmCacheClients           []string
 	dmCacheDeviceMinorNumber string
 
-	fns     []fetchMetricFn
-	fetchMu sync.Mutex
+	dockerClient *client.Client
+
+	eventLog   eventLogState
+	backupJobs backupJobState
+
+	blockDevices blockDeviceSampler
+
+	processes processSampler
+
+	diskPower diskPowerSampler
+
+	// energySaved is when the energy counters were last written to the state file
+	energySaved time.Time
+	// trafficSaved is when the monthly traffic counters were last written to the state file
+	trafficSaved time.Time
+
+	state *stateStore
+
+	fns           []collector
+	absent        absentSubsystems
+	stale         staleCache
+	parseFailures parseFailures
+	discovery     []discoveryResult
+	updates       *update.Checker
+	fetchMu       sync.Mutex
+	watchdog      *watchdog
+	scrapes       scrapeCoordinator
+
+	// firmwareUpdates checks the firmware release feed for a newer QTS firmware
+	firmwareUpdates *update.FirmwareChecker
+
+	// pingUnprivileged is set once raw sockets turned out not to be permitted in the icmp ping mode
+	pingUnprivileged atomic.Bool
 }
 
 type ExporterConfig struct {
-	PingTarget string
-	Logger     *log.Logger
+	PingTargets []string
+	// PingMode is one of PingModeICMP (default), PingModeUDP, PingModeTCP or PingModeTLS
+	PingMode   string
+	UpsAddress string
+	// UpsNames restricts the UPS metrics to these UPS devices (empty exports every UPS known to the UPS daemon)
+	UpsNames []string
+	// UpsCacheTTL is how long the UPS metrics are served from the cache before being refreshed (0 disables the cache)
+	UpsCacheTTL time.Duration
+	// ScrapeCacheTTL is how long the metrics of a collection are served to the following scrapes (0 disables the cache).
+	// The scrapes arriving while a collection runs always share it.
+	ScrapeCacheTTL time.Duration
+	// Hostname overrides the value of the node label
+	Hostname string
+	// NodeLabel is the name of the label holding the hostname (DefaultNodeLabel if empty)
+	NodeLabel string
+	// DropNodeLabel removes the node label from every metric
+	DropNodeLabel bool
+	// MachineIDLabel is the name of a label holding a stable identifier derived from the hardware serial number,
+	// which is added to every metric (empty disables it)
+	MachineIDLabel string
+	// StaticLabels are added to every metric (e.g. site or rack)
+	StaticLabels map[string]string
+	// CollectorLabels maps collector names to labels added to the metrics of that collector only (e.g. circuit for ups)
+	CollectorLabels map[string]map[string]string
+	// MetricNamespace is one of MetricNamespaceNode (default) or MetricNamespaceQNAP
+	MetricNamespace string
+	// DeprecatedNamesUntil is the last day on which the old names of the renamed metrics are emitted along with the new
+	// ones (zero means until they are dropped from the exporter)
+	DeprecatedNamesUntil time.Time
+	// HostnameSource is one of HostnameSourceOS (default) or HostnameSourceQTS, and is ignored if Hostname is set
+	HostnameSource string
+	// GetsysinfoCommands are extra getsysinfo subcommands (e.g. "sysfan 3") whose numeric output is exported
+	GetsysinfoCommands []string
+	// ExecPaths are searched for the tools (e.g. getsysinfo) before the inherited PATH
+	ExecPaths []string
+	// ExecEnv holds extra environment variables of the commands run by the collectors
+	ExecEnv map[string]string
+	// Backend runs the commands and reads the files of the collectors, e.g. a utils.MockBackend serving captured data
+	// (nil uses the local system). The collectors reading procfs and sysfs through gopsutil (cpu, loadavg, uptime,
+	// filesystem and volumedevices) don't use it, and follow the HOST_PROC, HOST_SYS, HOST_ETC and HOST_DEV environment
+	// variables of the process instead.
+	Backend utils.Backend
+	// Collectors maps collector names to whether they are enabled. Collectors not present are enabled.
+	Collectors map[string]bool
+	// CollectorTimeout is the maximum time each collector may take (0 disables the timeout)
+	CollectorTimeout time.Duration
+	// WatchdogTimeout is the time after which a running collector is considered hung (0 disables the watchdog)
+	WatchdogTimeout time.Duration
+	// OnHungCollector is called by the watchdog when a collector is considered hung
+	OnHungCollector func(collector string)
+	// StateFile is the path of the file where the state which must survive restarts is kept (empty keeps it in memory)
+	StateFile string
+	// SmbProbeShare is the share (e.g. //127.0.0.1/probe) to which the SMB probe writes a file (empty disables the probe)
+	SmbProbeShare    string
+	SmbProbeUser     string
+	SmbProbePassword string
+	// EventLogSyslog is the address of a syslog server to forward new QTS events to (e.g. udp://192.168.1.10:514)
+	EventLogSyslog string
+	// StaleValueMaxAge is how long the last-known-good metrics of a failing collector are served (0 disables it)
+	StaleValueMaxAge time.Duration
+	// ErrorComments restores the legacy `## error` comment lines in the exposition
+	ErrorComments bool
+	// UpdateCheckInterval is how often the release feed is checked for a newer version (0 disables the check)
+	UpdateCheckInterval time.Duration
+	// FirmwareCheckInterval is how often the firmware release feed is checked for a newer QTS firmware (0 disables the check)
+	FirmwareCheckInterval time.Duration
+	// FirmwareReleaseURL is the firmware release feed (update.DefaultFirmwareReleaseURL if empty)
+	FirmwareReleaseURL string
+	// SafeMode only enables the collectors reading procfs and sysfs, which neither run commands nor wake the disks
+	SafeMode bool
+	// LowMemory trades CPU for memory on the models with little RAM: the caches are disabled, the collectors run one
+	// at a time and the memory is returned to the OS after each collection
+	LowMemory bool
+	// TopProcesses is the number of processes reported by the processes collector, by CPU and by memory usage (0 disables it)
+	TopProcesses int
+	// QuirksFile is the path of a YAML file mapping NAS models to sensor quirks, which take precedence over the built-in ones
+	QuirksFile string
+	// HealthWeights overrides the weights of the components of qnap_health_score (e.g. raid or ups), 0 excluding a component
+	HealthWeights map[string]float64
+	// TemperatureTrendWindow is the period over which the disk temperature slope is computed (0 disables it)
+	TemperatureTrendWindow time.Duration
+	// VolumeForecastWindow is the period over which the volume usage growth is computed to estimate when each volume
+	// will be full (0 disables it)
+	VolumeForecastWindow time.Duration
+	// PowerDrawWatts is the estimated power draw of the NAS, used for the energy metrics when no UPS reports its power
+	// (0 falls back to the power draw of the model quirks)
+	PowerDrawWatts float64
+	// ElectricityPrice is the price of a kWh, from which the energy cost is estimated (0 disables it)
+	ElectricityPrice float64
+	// ElectricityCurrency is the value of the currency label of the energy cost, e.g. EUR
+	ElectricityCurrency string
+	// VolumeProbe enables the volume probe, which times the read of the root directory of each volume
+	VolumeProbe bool
+	// VolumeProbeWrite makes the volume probe time the synchronous write of a small file at the root of each volume
+	// instead, which wakes the disks up on every scrape
+	VolumeProbeWrite bool
+	// TrafficResetDay is the day of the month on which the monthly traffic counters are reset
+	TrafficResetDay int
+	// ListenAddresses and Endpoints are the addresses and paths served by the HTTP server, reported in the
+	// configuration summary
+	ListenAddresses []string
+	Endpoints       []string
+	// Faults holds the faults injected in the collectors to test the alert rules (nil disables fault injection)
+	Faults *FaultInjector
+	Logger *logging.Logger
+}
+
+// ConfigurableExporter is an exporter whose configuration can be updated while it is running
+type ConfigurableExporter interface {
+	exporter.Exporter
+	promclient.Collector
+
+	ApplyConfig(config ExporterConfig)
 }
 
-func NewExporter(config ExporterConfig, status *exporter.Status) exporter.Exporter {
+type collector struct {
+	name string
+	fn   fetchMetricFn
+}
+
+func NewExporter(config ExporterConfig, status *exporter.Status) ConfigurableExporter {
 	now := time.Now()
+	config = config.withLowMemory()
 	e := &promExporter{
 		ExporterConfig: config,
 		status:         status,
+		startTime:      now,
 		envExpiry:      now,
+		state:          newStateStore(config.StateFile),
+		watchdog:       newWatchdog(config.WatchdogTimeout, config.OnHungCollector, config.Logger),
+		updates:        update.NewChecker(update.DefaultReleaseURL, utils.VERSION),
 	}
-	e.fns = []fetchMetricFn{
-		e.getVersionMetrics,           // #1
-		getUptimeMetrics,              // #2
-		getLoadAvgMetrics,             // #3
-		getCpuRatioMetrics,            // #4
-		getMemInfoMetrics,             // #5
-		e.getUpsStatsMetricsWithRetry, // #6
-		e.getSysInfoTempMetrics,       // #7
-		e.getSysInfoFanMetrics,        // #8
-		e.getEnclosureFanMetrics,      // #9
-		e.getSysInfoHdMetrics,         // #10
-		e.getSysInfoVolMetrics,        // #11
-		e.getDiskStatsMetrics,         // #12
-		e.getFlashCacheStatsMetrics,   // #13
-		e.getDmCacheStatsMetrics,      // #14
-		e.getNetworkStatsMetrics,      // #15
-		e.getPingMetrics,              // #16
-	}
+	e.firmwareUpdates = update.NewFirmwareChecker(e.firmwareReleaseURL())
+	e.fns = e.enabledCollectors()
+	e.applyExecEnvironment()
+	go e.runWatchdog()
 
 	if status != nil {
-		status.Uptime = now
+		status.StartTime = now
 	}
 
 	return e
 }
 
-func (e *promExporter) WriteMetrics(w io.Writer) error {
+// CollectorNames returns the names of all the available collectors
+func CollectorNames() []string {
+	e := &promExporter{}
+	collectors := e.collectors()
+	names := make([]string, 0, len(collectors))
+	for _, c := range collectors {
+		names = append(names, c.name)
+	}
+
+	return names
+}
+
+func (e *promExporter) collectors() []collector {
+	return []collector{
+		{name: "version", fn: e.getVersionMetrics},
+		{name: "config", fn: e.getConfigMetrics},
+		{name: "firmware", fn: e.getFirmwareMetrics},
+		{name: "uptime", fn: e.getUptimeMetrics},
+		{name: "loadavg", fn: getLoadAvgMetrics},
+		{name: "cpu", fn: getCpuRatioMetrics},
+		{name: "meminfo", fn: e.getMemInfoMetrics},
+		{name: "ups", fn: e.getCachedUpsMetrics},
+		{name: "energy", fn: e.getEnergyMetrics},
+		{name: "systemp", fn: e.getSysInfoTempMetrics},
+		{name: "sysfan", fn: e.getSysInfoFanMetrics},
+		{name: "enclosurefan", fn: e.getEnclosureFanMetrics},
+		{name: "fanpolicy", fn: e.getFanPolicyMetrics},
+		{name: "hwmon", fn: e.getHwmonMetrics},
+		{name: "hdtemp", fn: e.getSysInfoHdMetrics},
+		{name: "volume", fn: e.getSysInfoVolMetrics},
+		{name: "volumedevices", fn: e.getVolumeDeviceMetrics},
+		{name: "getsysinfo", fn: e.getGetsysinfoMetrics},
+		{name: "filesystem", fn: getFilesystemMetrics},
+		{name: "diskstats", fn: e.getDiskStatsMetrics},
+		{name: "blockdevices", fn: e.getBlockDeviceMetrics},
+		{name: "flashcache", fn: e.getFlashCacheStatsMetrics},
+		{name: "dmcache", fn: e.getDmCacheStatsMetrics},
+		{name: "ssdcache", fn: e.getSsdCacheMetrics},
+		{name: "zfs", fn: e.getZfsMetrics},
+		{name: "network", fn: e.getNetworkStatsMetrics},
+		{name: "ping", fn: e.getPingMetrics},
+		{name: "smart", fn: e.getSmartMetrics},
+		{name: "diskpower", fn: e.getDiskPowerMetrics},
+		{name: "externaldisk", fn: e.getExternalDiskMetrics},
+		{name: "processes", fn: e.getProcessMetrics},
+		{name: "mdstat", fn: e.getMdStatMetrics},
+		{name: "snapshot", fn: e.getSnapshotMetrics},
+		{name: "quota", fn: e.getQuotaMetrics},
+		{name: "fileservices", fn: e.getFileServiceMetrics},
+		{name: "smbprobe", fn: e.getSmbProbeMetrics},
+		{name: "volumeprobe", fn: e.getVolumeProbeMetrics},
+		{name: "qpkg", fn: e.getQpkgMetrics},
+		{name: "eventlog", fn: e.getEventLogMetrics},
+		{name: "backupjobs", fn: e.getBackupJobMetrics},
+		{name: "docker", fn: e.getDockerMetrics},
+		{name: "dependencies", fn: e.getDependencyMetrics},
+	}
+}
+
+func (e *promExporter) enabledCollectors() []collector {
+	var enabled []collector
+	for _, c := range e.collectors() {
+		if on, found := e.Collectors[c.name]; found && !on {
+			continue
+		}
+		if e.SafeMode && !safeCollectors[c.name] {
+			continue
+		}
+
+		enabled = append(enabled, c)
+	}
+
+	return enabled
+}
+
+// disabledCollectorCount returns the number of collectors disabled by the configuration, including safe mode
+func (e *promExporter) disabledCollectorCount() int {
+	return len(e.collectors()) - len(e.enabledCollectors())
+}
+
+// ApplyConfig replaces the exporter configuration, taking effect on the next scrape
+func (e *promExporter) ApplyConfig(config ExporterConfig) {
 	e.fetchMu.Lock()
 	defer e.fetchMu.Unlock()
 
+	if config.Logger == nil {
+		config.Logger = e.Logger
+	}
+	config = config.withLowMemory()
+	upsAddressChanged := config.UpsAddress != e.UpsAddress
+	upsNamesChanged := strings.Join(config.UpsNames, ",") != strings.Join(e.UpsNames, ",")
+	hostnameChanged := config.Hostname != e.Hostname || config.HostnameSource != e.HostnameSource ||
+		config.MachineIDLabel != e.MachineIDLabel
+	safeModeChanged := config.SafeMode != e.SafeMode
+	stateFileChanged := config.StateFile != e.StateFile
+	eventLogSyslogChanged := config.EventLogSyslog != e.EventLogSyslog
+	staleValueMaxAgeChanged := config.StaleValueMaxAge != e.StaleValueMaxAge
+	firmwareReleaseURLChanged := config.FirmwareReleaseURL != e.FirmwareReleaseURL
+	execPathsChanged := strings.Join(config.ExecPaths, ":") != strings.Join(e.ExecPaths, ":") || config.Backend != e.Backend
+
+	e.ExporterConfig = config
+	e.fns = e.enabledCollectors()
+	e.scrapes.reset()
+	e.applyExecEnvironment()
+	e.watchdog.configure(config.WatchdogTimeout, config.OnHungCollector)
+
+	if upsAddressChanged {
+		// Force a reconnection to the new UPS daemon on the next scrape
+		e.resetUpsClient()
+		e.invalidateUpsCache()
+	} else if upsNamesChanged {
+		e.invalidateUpsCache()
+	}
+	if hostnameChanged || safeModeChanged || execPathsChanged {
+		// Force the environment to be read again on the next scrape
+		e.envExpiry = time.Now()
+	}
+	if stateFileChanged {
+		e.state = newStateStore(config.StateFile)
+	}
+	if firmwareReleaseURLChanged {
+		e.firmwareUpdates = update.NewFirmwareChecker(e.firmwareReleaseURL())
+	}
+	if staleValueMaxAgeChanged {
+		e.stale.reset()
+	}
+	if eventLogSyslogChanged {
+		e.eventLog.mu.Lock()
+		e.eventLog.closeForwarder()
+		e.eventLog.mu.Unlock()
+	}
+}
+
+func (e *promExporter) firmwareReleaseURL() string {
+	if e.FirmwareReleaseURL == "" {
+		return update.DefaultFirmwareReleaseURL
+	}
+
+	return e.FirmwareReleaseURL
+}
+
+// applyExecEnvironment hands the search paths and environment variables of the commands to utils, and sets up the
+// System through which the collectors reach the configured backend
+func (e *promExporter) applyExecEnvironment() {
+	names := make([]string, 0, len(e.ExecEnv))
+	for name := range e.ExecEnv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := make([]string, 0, len(names))
+	for _, name := range names {
+		env = append(env, name+"="+e.ExecEnv[name])
+	}
+	utils.SetExecEnvironment(e.ExecPaths, env)
+	e.sys = utils.NewSystem(e.Backend)
+}
+
+// WriteMetrics writes the metrics in the Prometheus text format. The metrics are buffered until all
+// collectors complete, so that the samples of each family are written together, in a deterministic order.
+func (e *promExporter) WriteMetrics(ctx context.Context, w io.Writer) error {
+	defer e.releaseMemory()
+
+	filter := labelFilterFromContext(ctx)
+	r := e.collectShared(ctx)
+
+	metrics := make([]metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		if filter.matches(r.targetLabels, m) {
+			metrics = append(metrics, m)
+		}
+	}
+
+	writeMetricFamilies(w, groupMetricFamilies(r.targetLabels, metrics))
+	if e.ErrorComments {
+		for _, err := range r.errs {
+			_, _ = fmt.Fprintf(w, "## %v\n", err)
+		}
+	}
+
+	return r.err
+}
+
+// collect runs the enabled collectors selected by ctx concurrently, calling onMetrics for each batch of
+// metrics retrieved and onError for each collector failure. It returns the last error seen.
+func (e *promExporter) collect(ctx context.Context, onMetrics func([]metric), onError func(error)) error {
+	e.fetchMu.Lock()
+	defer e.fetchMu.Unlock()
+
+	start := time.Now()
 	if e.status != nil {
 		e.status.MetricCount = 0
-		e.status.LastFetch = time.Now()
+		e.status.LastFetch = start
 		defer func() {
 			e.status.LastFetchDuration = time.Since(e.status.LastFetch)
 		}()
 	}
 
 	if time.Now().After(e.envExpiry) {
-		e.readEnvironment()
+		e.readEnvironment(ctx)
 	}
 
+	selection := collectorSelectionFromContext(ctx)
+	fns := selection.filter(e.fns)
+
 	var wg sync.WaitGroup
-	metricsCh := make(chan interface{}, 4)
-	for idx, fn := range e.fns {
+	metricsCh := make(chan interface{}, e.metricsChannelSize())
+	statuses := make([]exporter.CollectorStatus, len(fns))
+	var queue []int
+	for idx, c := range fns {
+		statuses[idx].Name = c.name
+		if e.absent.contains(c.name) {
+			statuses[idx].Absent = true
+			continue
+		}
+
 		wg.Add(1)
+		if e.LowMemory {
+			queue = append(queue, idx)
+			continue
+		}
 
-		go fetchMetricsWorker(&wg, metricsCh, idx, fn)
+		go e.fetchMetricsWorker(ctx, &wg, metricsCh, c, &statuses[idx])
+	}
+	if len(queue) > 0 {
+		// In low-memory mode, the collectors run one at a time, so that the output of a single command is held at once
+		go func() {
+			for _, idx := range queue {
+				e.fetchMetricsWorker(ctx, &wg, metricsCh, fns[idx], &statuses[idx])
+			}
+		}()
 	}
 
 	go func() {
@@ -132,114 +519,287 @@ func (e *promExporter) WriteMetrics(w io.Writer) error {
 		close(metricsCh)
 	}()
 
-	// Retrieve metrics from channel and write them to the response
+	// Retrieve metrics from channel and hand them to the caller
 	var err error
+	health := newHealthScore()
 	for m := range metricsCh {
 		switch v := m.(type) {
 		case []metric:
 			if e.status != nil {
 				e.status.MetricCount += len(v)
 			}
-			for _, m := range v {
-				writeMetricMetadata(w, m)
-
-				var timestamp string
-				if !m.timestamp.IsZero() {
-					timestamp = strconv.Itoa(int(m.timestamp.UnixNano() / 1000000))
-				}
-				_, _ = fmt.Fprintf(w, "%s %g %s\n", e.getMetricFullName(m), m.value, timestamp)
-			}
+			health.observe(v)
+			onMetrics(e.inNamespace(v))
 		case error:
 			err = v
-			e.Logger.Println(v.Error())
+			onError(v)
+		}
+	}
 
-			_, _ = fmt.Fprintf(w, "## %v\n", v)
+	if e.status != nil {
+		if selection.isEmpty() {
+			e.status.Collectors = statuses
+		} else {
+			e.status.Collectors = mergeCollectorStatuses(e.status.Collectors, statuses)
 		}
 	}
 
+	metrics := e.watchdog.metrics(fns)
+	metrics = append(metrics, getDegradationMetrics(e.disabledCollectorCount(), statuses)...)
+	metrics = append(metrics, getDiscoveryMetrics(e.discovery)...)
+	metrics = append(metrics, e.getSubsystemMetrics(fns)...)
+	metrics = append(metrics, e.getEnvironmentAgeMetrics()...)
+	if selection.isEmpty() {
+		// The score of a subset of the collectors would be misleading
+		metrics = append(metrics, health.metrics(e.HealthWeights)...)
+	}
+	onMetrics(e.inNamespace(append(
+		metrics,
+		metric{
+			name:  "qnap_exporter_scrape_duration_seconds",
+			value: time.Since(start).Seconds(),
+			help:  "Time taken to collect all the metrics",
+		},
+	)))
+
 	return err
 }
 
-func fetchMetricsWorker(wg *sync.WaitGroup, metricsCh chan<- interface{}, idx int, fetchMetricsFn fetchMetricFn) {
+// fetchMetricsWorker runs collector c, sending its metrics and error to metricsCh, and recording its outcome in status
+func (e *promExporter) fetchMetricsWorker(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	metricsCh chan<- interface{},
+	c collector,
+	status *exporter.CollectorStatus,
+) {
 	defer wg.Done()
 
-	metrics, err := fetchMetricsFn()
-	if err != nil {
-		metricsCh <- fmt.Errorf("retrieve metric #%d: %w", 1+idx, err)
+	type result struct {
+		metrics []metric
+		err     error
+	}
+
+	start := time.Now()
+	var r result
+	data := &dataTime{}
+	if e.watchdog.isRunning(c.name) {
+		// Don't run a collector concurrently with a previous run which timed out
+		r.err = errPreviousRunInProgress
+	} else {
+		if e.CollectorTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, e.CollectorTimeout)
+			defer cancel()
+		}
+
+		resultCh := make(chan result, 1)
+		e.watchdog.start(c.name)
+		go func() {
+			defer e.watchdog.stop(c.name)
+
+			if err := e.Faults.inject(ctx, c.name); err != nil {
+				resultCh <- result{err: err}
+				return
+			}
+
+			metrics, err := c.fn(withDataTime(withCollectorName(ctx, c.name), data))
+			resultCh <- result{metrics: metrics, err: err}
+		}()
+
+		select {
+		case r = <-resultCh:
+		case <-ctx.Done():
+			// Give context-aware collectors a chance to return a partial result
+			select {
+			case r = <-resultCh:
+			case <-time.After(collectorGracePeriod):
+				r.err = ctx.Err()
+			}
+		}
+	}
+	duration := time.Since(start)
+	status.Duration = duration
+
+	var absentErr subsystemAbsentError
+	if errors.As(r.err, &absentErr) {
+		if e.absent.add(c.name) {
+			e.Logger.Info("Skipping collector of absent hardware", "collector", c.name, "reason", absentErr)
+		}
+		status.Absent = true
 		return
 	}
+	if r.err != nil {
+		status.Error = r.err.Error()
+		status.ErrorClass = classifyError(r.err)
+	}
+
+	attr := fmt.Sprintf("collector=%q", c.name)
+	metrics := r.metrics
+	var success float64 = 1
+	var staleAge time.Duration
+	dataAge, served := data.age(time.Now()), true
+	if r.err != nil {
+		success = 0
+		e.Logger.Error("Collector failed", "collector", c.name, "error_class", status.ErrorClass, "duration", duration, "err", r.err)
+
+		cached, age, found := e.stale.lookup(c.name, e.StaleValueMaxAge, time.Now())
+		if found {
+			// Serve the last-known-good metrics instead of dropping the series
+			e.Logger.Warn("Serving last-known-good metrics", "collector", c.name, "age", age.Round(time.Second))
+			metrics = cached
+			staleAge, dataAge = age, age
+		} else {
+			metricsCh <- fmt.Errorf("retrieve %s metrics: %w", c.name, r.err)
+			served = false
+		}
+
+		metrics = e.withCollectorLabels(c.name, metrics)
+		metrics = append(metrics, metric{
+			name:  "qnapexporter_collector_error_info",
+			attr:  fmt.Sprintf("%s,error_class=%q", attr, status.ErrorClass),
+			value: 1,
+			help:  "Class of the error which occurred while retrieving the collector metrics (details are logged)",
+		})
+	} else {
+		watermarks, err := e.getWatermarkMetrics(ctx, c.name, metrics)
+		if err != nil {
+			e.Logger.Error("Error updating watermarks", "collector", c.name, "err", err)
+		}
+		metrics = append(metrics, watermarks...)
+
+		if e.StaleValueMaxAge > 0 {
+			e.stale.store(c.name, metrics, time.Now())
+		}
+		metrics = e.withCollectorLabels(c.name, metrics)
+	}
+	if e.StaleValueMaxAge > 0 {
+		metrics = append(metrics, staleMetric(c.name, staleAge))
+	}
+	if served {
+		metrics = append(metrics, dataAgeMetric(c.name, dataAge))
+	}
 
-	metricsCh <- metrics
+	metricsCh <- append(
+		metrics,
+		metric{
+			name:  "qnap_exporter_collector_duration_seconds",
+			attr:  attr,
+			value: duration.Seconds(),
+			help:  "Time taken by the collector to retrieve its metrics",
+		},
+		metric{
+			name:  "qnap_exporter_collector_success",
+			attr:  attr,
+			value: success,
+			help:  "Whether the collector succeeded in retrieving its metrics",
+		},
+		e.parseFailures.metric(c.name),
+	)
 }
 
 func (e *promExporter) Close() {
-	if e.upsState.upsClient.ProtocolVersion != "" {
-		e.upsState.upsLock.Lock()
-		_, _ = e.upsState.upsClient.Disconnect()
-		e.upsState.upsLock.Unlock()
+	e.watchdog.shutdown()
+	e.resetUpsClient()
+	e.eventLog.mu.Lock()
+	e.eventLog.closeForwarder()
+	e.eventLog.mu.Unlock()
+	if e.dockerClient != nil {
+		_ = e.dockerClient.Close()
 	}
 }
 
-func (e *promExporter) readEnvironment() {
-	e.Logger.Println("Reading environment...")
+func (e *promExporter) readEnvironment(ctx context.Context) {
+	e.Logger.Info("Reading environment")
+
+	// Look for hardware which was absent again, in case it was plugged in since
+	e.absent.reset()
+
+	e.discovery = nil
 
 	var err error
-	e.hostname = os.Getenv("HOSTNAME")
-	if e.hostname == "" {
-		e.hostname, err = utils.ExecCommand("hostname")
+	e.hostname, err = e.resolveHostname(ctx)
+	e.Logger.Debug("Resolved hostname", "hostname", e.hostname, "err", err)
+
+	if e.MachineIDLabel != "" {
+		e.machineID, err = e.resolveMachineID(ctx)
+		e.Logger.Debug("Resolved machine ID", "machine_id", e.machineID, "err", err)
+		var count int
+		if e.machineID != "" {
+			count = 1
+		}
+		e.recordDiscovery("machine_id", count, err)
 	}
-	e.Logger.Printf("Hostname: %s, err=%v", e.hostname, err)
 
-	e.Logger.Println("Retrieving QTS version")
-	kernelVersionStr, err := utils.ExecCommand("uname", "-r")
+	e.Logger.Debug("Retrieving QTS version")
+	var kernelVersionStr string
+	if e.SafeMode {
+		kernelVersionStr, err = e.sys.ReadFile(kernelReleasePath)
+	} else {
+		kernelVersionStr, err = e.sys.ExecCommand(ctx, "uname", "-r")
+	}
 	if err == nil {
 		e.kernelVersion, err = strconv.Atoi(strings.SplitN(kernelVersionStr, ".", 2)[0])
 	}
 	if err != nil {
 		e.kernelVersion = 4
 	}
+	e.recordDiscovery("kernel_version", 1, err)
 
-	if e.getsysinfo == "" {
-		e.getsysinfo, _ = exec.LookPath("getsysinfo")
-		if err == nil {
-			e.Logger.Printf("Retrieved getsysinfo path: %q", e.getsysinfo)
-		} else {
-			e.Logger.Printf("Failed to find getsysinfo: %v", err)
+	var firmwareVersion string
+	if firmware, err := readQtsFirmware(e.sys); err == nil {
+		firmwareVersion = firmware.Version
+		if isLegacyQtsVersion(firmware.Version) {
+			e.Logger.Info("Detected a legacy QTS firmware", "version", firmware.Version, "kernel", e.kernelVersion)
 		}
 	}
-	if e.getsysinfo != "" {
-		hdnumOutput, err := utils.ExecCommand(e.getsysinfo, "hdnum")
-		if err == nil {
-			e.syshdnum, _ = strconv.Atoi(hdnumOutput)
-		} else {
-			e.syshdnum = -1
-		}
-		e.Logger.Printf("Retrieved sysdhnum: %d", e.syshdnum)
+	if e.status != nil {
+		e.status.Firmware = firmwareVersion
+	}
 
-		sysfannumOutput, err := utils.ExecCommand(e.getsysinfo, "sysfannum")
-		if err == nil {
-			e.sysfannum, _ = strconv.Atoi(sysfannumOutput)
-		} else {
-			e.sysfannum = -1
+	e.discoverTool(&e.getsysinfo, "getsysinfo")
+	if e.getsysinfo != "" {
+		e.syshdnum, err = e.readSysInfoCount(ctx, "hdnum")
+		e.Logger.Debug("Retrieved disk slot count", "hdnum", e.syshdnum)
+		e.recordDiscovery("disk_slots", e.syshdnum, err)
+
+		e.sysfannum, err = e.readSysInfoCount(ctx, "sysfannum")
+		e.Logger.Debug("Retrieved system fan count", "sysfannum", e.sysfannum)
+		e.recordDiscovery("system_fans", e.sysfannum, err)
+
+		var model string
+		model, err = e.sys.ExecCommand(ctx, e.getsysinfo, "model")
+		e.Logger.Debug("Retrieved model", "model", model)
+		e.recordDiscovery("model", 1, err)
+		e.model = model
+		if e.status != nil {
+			e.status.Model = model
 		}
-		e.Logger.Printf("Retrieved sysfannum: %d", e.sysfannum)
+		e.applyQuirks(model)
 
-		e.readSysVolInfo()
-		e.Logger.Printf("Retrieved sysvolinfo")
+		err = e.readSysVolInfo(ctx)
+		e.Logger.Debug("Retrieved sysvolinfo")
+		e.recordDiscovery("volumes", len(e.volumes), err)
 	}
 
-	if e.hal_app == "" {
-		e.hal_app, _ = exec.LookPath("hal_app")
-		if err != nil {
-			e.Logger.Printf("Failed to find hal_app: %v", err)
-		}
-		e.Logger.Printf("Retrieved hal_app path: %q", e.hal_app)
-	}
+	e.discoverTool(&e.hal_app, "hal_app")
+	e.discoverTool(&e.smartctl, "smartctl")
+	e.discoverTool(&e.qcliSnapshot, "qcli_snapshot")
+	e.discoverTool(&e.repquota, "repquota")
+	e.discoverTool(&e.hdparm, "hdparm")
+	e.discoverTool(&e.smbstatus, "smbstatus", qnapSmbstatusPath)
+	e.discoverTool(&e.smbclient, "smbclient", qnapSmbclientPath)
+	e.discoverTool(&e.sqlite3, "sqlite3")
+	e.discoverTool(&e.zpool, "zpool")
+	e.discoverTool(&e.zfs, "zfs")
+
 	e.enclosures = nil
-	e.status.Enclosures = nil
+	if e.status != nil {
+		e.status.Enclosures = nil
+	}
 	if e.hal_app != "" {
-		e.Logger.Println("Retrieving QM2 enclosures")
-		seEnumOutput, err := utils.ExecCommand(e.hal_app, "--se_enum")
+		e.Logger.Debug("Retrieving QM2 enclosures")
+		seEnumOutput, err := e.sys.ExecCommand(ctx, e.hal_app, "--se_enum")
 		if err == nil {
 			lines := utils.FindMatchingLines("qm2_", seEnumOutput)
 			if len(lines) != 0 {
@@ -254,27 +814,38 @@ func (e *promExporter) readEnvironment() {
 					enc.tempCount, _ = strconv.Atoi(fields[10])
 					if enc.fanCount != 0 {
 						e.enclosures = append(e.enclosures, enc)
-						e.status.Enclosures = append(e.status.Enclosures, enc.name)
+						if e.status != nil {
+							e.status.Enclosures = append(e.status.Enclosures, enc.name)
+						}
 					}
 				}
 			}
 		}
+		e.recordDiscovery("enclosures", len(e.enclosures), err)
 	}
 
-	e.Logger.Printf("Retrieving network interfaces in %q...", netDir)
-	info, _ := os.ReadDir(netDir)
+	e.Logger.Debug("Retrieving network interfaces", "dir", netDir)
+	info, err := e.sys.ReadDir(netDir)
 	e.ifaces = make([]string, 0, len(info))
+	e.bridgeIfaces = nil
 	for _, d := range info {
 		iface := d.Name()
-		if !strings.HasPrefix(iface, "eth") {
+		if containerBridgeType(iface) != "" {
+			e.bridgeIfaces = append(e.bridgeIfaces, iface)
+			continue
+		}
+		// bonding_masters is a file listing the bond interfaces
+		if !strings.HasPrefix(iface, "eth") && !strings.HasPrefix(iface, "bond") || iface == "bonding_masters" {
 			continue
 		}
 
 		e.ifaces = append(e.ifaces, iface)
 	}
+	e.Logger.Debug("Found container bridges", "bridges", e.bridgeIfaces)
+	e.recordDiscovery("interfaces", len(e.ifaces), err)
 
-	e.Logger.Printf("Retrieving devices in %q...", devDir)
-	info, _ = os.ReadDir(devDir)
+	e.Logger.Debug("Retrieving devices", "dir", devDir)
+	info, err = e.sys.ReadDir(devDir)
 	e.devices = make([]string, 0, len(info))
 	for _, d := range info {
 		dev := d.Name()
@@ -290,25 +861,39 @@ func (e *promExporter) readEnvironment() {
 
 		e.devices = append(e.devices, dev)
 	}
-	e.Logger.Printf("Found devices: %v", e.devices)
+	e.Logger.Debug("Found devices", "devices", e.devices)
+	e.recordDiscovery("devices", len(e.devices), err)
+
+	e.blockDir = resolveSysBlockDir(e.sys)
+	if e.blockDir != sysBlockDir {
+		e.Logger.Info("Reading the block devices from the deprecated sysfs layout", "dir", e.blockDir)
+	}
+	e.virtualDevices, err = discoverVirtualBlockDevices(e.sys, e.blockDir)
+	e.Logger.Debug("Found virtual block devices", "devices", e.virtualDevices)
+	e.recordDiscovery("virtual_devices", len(e.virtualDevices), err)
 
 	e.dmCacheClients = []string{}
-	if e.kernelVersion >= 5 {
-		e.Logger.Print("Retrieving dm-cache devices...")
+	if e.kernelVersion >= 5 && !e.SafeMode {
+		e.Logger.Debug("Retrieving dm-cache devices")
 
-		table, err := utils.ExecCommand("dmsetup", "table")
+		table, err := e.sys.ExecCommand(ctx, "dmsetup", "table")
 		if err == nil {
 			cacheClients := utils.FindMatchingLines("cache_client", table)
 			for _, cacheClient := range cacheClients {
 				e.dmCacheClients = append(e.dmCacheClients, strings.SplitN(cacheClient, ":", 2)[0])
 			}
 		}
-		e.Logger.Printf("Found cache clients: %v", e.dmCacheClients)
+		e.Logger.Debug("Found cache clients", "clients", e.dmCacheClients)
+		if errors.Is(err, exec.ErrNotFound) {
+			// dm-cache is not used without dmsetup
+			err = nil
+		}
+		e.recordDiscovery("dm_caches", len(e.dmCacheClients), err)
 
-		table, err = utils.ExecCommand("dmsetup", "ls")
+		table, err = e.sys.ExecCommand(ctx, "dmsetup", "ls")
 		if err == nil {
 			cacheDevices := utils.FindMatchingLines("vg256-lv256\t", table)
-			e.Logger.Printf("Found cache volumes: %v", cacheDevices)
+			e.Logger.Debug("Found cache volumes", "volumes", cacheDevices)
 			if len(cacheDevices) == 1 {
 				e.dmCacheDeviceMinorNumber = strings.Split(cacheDevices[0], ":")[1]
 				e.dmCacheDeviceMinorNumber = strings.TrimRight(e.dmCacheDeviceMinorNumber, ")")
@@ -317,11 +902,14 @@ func (e *promExporter) readEnvironment() {
 	}
 
 	e.envExpiry = e.envExpiry.Add(envValidity)
+	e.envRead = time.Now()
 
 	if e.status != nil {
 		e.status.Devices = e.devices
 		e.status.Interfaces = e.ifaces
 		e.status.DmCaches = e.dmCacheClients
+		e.status.Discovery = e.discoveryStatuses()
+		e.status.EnvironmentRead = time.Now()
 		if e.dmCacheDeviceMinorNumber != "" {
 			e.status.DmCacheDevice = fmt.Sprintf("dm-%s", e.dmCacheDeviceMinorNumber)
 		} else {
@@ -331,18 +919,18 @@ func (e *promExporter) readEnvironment() {
 }
 
 func (e *promExporter) getMetricFullName(m metric) string {
-	if m.attr != "" {
-		return fmt.Sprintf(`%s{node=%q,%s}`, m.name, e.hostname, m.attr)
-	}
-
-	return fmt.Sprintf(`%s{node=%q}`, m.name, e.hostname)
+	return getMetricFullName(formatLabels(e.targetLabels()), m)
 }
 
-func writeMetricMetadata(w io.Writer, m metric) {
-	if m.help != "" {
-		fmt.Fprintln(w, "# HELP "+m.name+" "+m.help)
-	}
-	if m.metricType != "" {
-		fmt.Fprintln(w, "# TYPE "+m.name+" "+m.metricType)
+func getMetricFullName(targetAttr string, m metric) string {
+	switch {
+	case targetAttr == "" && m.attr == "":
+		return m.name
+	case targetAttr == "":
+		return fmt.Sprintf(`%s{%s}`, m.name, m.attr)
+	case m.attr == "":
+		return fmt.Sprintf(`%s{%s}`, m.name, targetAttr)
+	default:
+		return fmt.Sprintf(`%s{%s,%s}`, m.name, targetAttr, m.attr)
 	}
 }
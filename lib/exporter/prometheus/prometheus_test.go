@@ -2,20 +2,20 @@ package prometheus
 
 import (
 	"bytes"
-	"io"
-	"log"
+	"context"
 	"testing"
 	"time"
 
 	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestNewExporter(t *testing.T) {
 	config := ExporterConfig{
-		PingTarget: "1.1.1.1",
-		Logger:     log.New(io.Discard, "", 0),
+		PingTargets: []string{"1.1.1.1"},
+		Logger:      logging.Discard(),
 	}
 	e := NewExporter(config, nil)
 
@@ -27,35 +27,81 @@ func TestWriteMetrics(t *testing.T) {
 	var s exporter.Status
 	startTime := time.Now()
 	config := ExporterConfig{
-		PingTarget: "8.8.8.8",
-		Logger:     log.New(io.Discard, "", 0),
+		PingTargets: []string{"8.8.8.8"},
+		Logger:      logging.Discard(),
 	}
 	e := NewExporter(config, &s)
 	b := new(bytes.Buffer)
 	defer e.Close()
 
-	err := e.WriteMetrics(b)
-	require.Error(t, err)
+	err := e.WriteMetrics(context.Background(), b)
+	require.NoError(t, err)
 
 	output := b.String()
 	assert.Contains(t, output, "\nnode_time_seconds{node=\"")
-	assert.Contains(t, output, "dial tcp 127.0.0.1:3493: connect: connection refused")
-	assert.True(t, s.Uptime.After(startTime))
-	assert.True(t, s.LastFetch.After(s.Uptime))
+	// No UPS daemon is running, so the UPS is absent rather than failing
+	assert.NotContains(t, output, `collector="ups"`)
+	assert.NotContains(t, output, "## ")
+	assert.True(t, s.StartTime.After(startTime))
+	assert.True(t, s.LastFetch.After(s.StartTime))
+	assert.True(t, s.BootTime.Before(s.StartTime))
 	assert.NotZero(t, s.LastFetchDuration.Microseconds())
 	assert.NotZero(t, s.MetricCount)
 }
 
+func TestWriteMetricsSkipsAbsentSubsystems(t *testing.T) {
+	var s exporter.Status
+	config := ExporterConfig{
+		Collectors: map[string]bool{"ping": false},
+		Logger:     logging.Discard(),
+	}
+	e := NewExporter(config, &s)
+	defer e.Close()
+
+	// The subsystems found absent by a scrape are reported by that same scrape
+	b := new(bytes.Buffer)
+	require.NoError(t, e.WriteMetrics(context.Background(), b))
+	assert.Contains(t, b.String(), `subsystem="ups"} 0`)
+	assert.Contains(t, s.AbsentSubsystems, "ups")
+
+	b = new(bytes.Buffer)
+	require.NoError(t, e.WriteMetrics(context.Background(), b))
+
+	output := b.String()
+	assert.Contains(t, output, `subsystem="ups"} 0`)
+	assert.NotContains(t, output, `collector="ups"`)
+	assert.NotContains(t, output, "qnap_exporter_ups_cache_age_seconds")
+	assert.Contains(t, s.AbsentSubsystems, "ups")
+}
+
+func TestWriteMetricsWithErrorComments(t *testing.T) {
+	var s exporter.Status
+	config := ExporterConfig{
+		PingTargets:   []string{"8.8.8.8"},
+		UpsAddress:    "ups.invalid",
+		ErrorComments: true,
+		Logger:        logging.Discard(),
+	}
+	e := NewExporter(config, &s)
+	b := new(bytes.Buffer)
+	defer e.Close()
+
+	err := e.WriteMetrics(context.Background(), b)
+	require.Error(t, err)
+
+	assert.Contains(t, b.String(), "## retrieve ups metrics: lookup ups.invalid")
+}
+
 func BenchmarkWriteMetrics(b *testing.B) {
 	config := ExporterConfig{
-		PingTarget: "8.8.8.8",
-		Logger:     log.New(io.Discard, "", 0),
+		PingTargets: []string{"8.8.8.8"},
+		Logger:      logging.Discard(),
 	}
 	e := NewExporter(config, nil)
 	defer e.Close()
 
 	for i := 0; i < b.N; i++ {
 		buf := new(bytes.Buffer)
-		_ = e.WriteMetrics(buf)
+		_ = e.WriteMetrics(context.Background(), buf)
 	}
 }
@@ -0,0 +1,129 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+// qpkgConfigPath is where QTS keeps the list of installed QPKG apps
+const qpkgConfigPath = "/etc/config/qpkg.conf"
+
+// qpkgStatusCaseRe matches the status branch of the case statement of a QPKG init script
+var qpkgStatusCaseRe = regexp.MustCompile(`(?m)^\s*["']?status["']?\s*\)`)
+
+type qpkgApp struct {
+	name    string
+	version string
+	enabled bool
+	shell   string
+}
+
+// getQpkgMetrics reports whether each installed QPKG app is enabled and, for the apps whose init script
+// supports the status command, whether it is running
+func (e *promExporter) getQpkgMetrics(ctx context.Context) ([]metric, error) {
+	lines, err := e.sys.ReadFileLines(qpkgConfigPath)
+	if os.IsNotExist(err) {
+		return nil, subsystemAbsentError{fmt.Sprintf("%s not found", qpkgConfigPath)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	apps := parseQpkgConfig(lines)
+	metrics := make([]metric, 0, len(apps)*2)
+	for _, app := range apps {
+		attr := fmt.Sprintf("name=%q,version=%q", app.name, app.version)
+
+		var enabled float64
+		if app.enabled {
+			enabled = 1
+		}
+		metrics = append(metrics, metric{
+			name:  "node_qpkg_enabled",
+			attr:  attr,
+			value: enabled,
+			help:  "Whether the QPKG app is enabled in the App Center",
+		})
+
+		if !app.enabled || !qpkgSupportsStatus(e.sys, app.shell) {
+			continue
+		}
+
+		// The init scripts exit with 0 when the app is running
+		_, exitCode, err := e.sys.ExecCommandWithExitCode(ctx, app.shell, "status")
+		if err != nil {
+			return metrics, fmt.Errorf("retrieve status of QPKG %s: %w", app.name, err)
+		}
+
+		var running float64
+		if exitCode == 0 {
+			running = 1
+		}
+		metrics = append(metrics, metric{
+			name:  "node_qpkg_running",
+			attr:  attr,
+			value: running,
+			help:  "Whether the QPKG app is running, according to its init script (only for the enabled apps whose init script has a status command)",
+		})
+	}
+
+	return metrics, nil
+}
+
+// parseQpkgConfig parses the sections of qpkg.conf, e.g.:
+//
+//	[PlexMediaServer]
+//	Name = PlexMediaServer
+//	Version = 1.32.5.7349
+//	Enable = TRUE
+//	Shell = /share/CACHEDEV1_DATA/.qpkg/PlexMediaServer/plex.sh
+func parseQpkgConfig(lines []string) []qpkgApp {
+	var apps []qpkgApp
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			apps = append(apps, qpkgApp{name: line[1 : len(line)-1]})
+			continue
+		}
+		if len(apps) == 0 {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		app := &apps[len(apps)-1]
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "Version":
+			app.version = value
+		case "Enable":
+			app.enabled = strings.EqualFold(value, "TRUE")
+		case "Shell":
+			app.shell = value
+		}
+	}
+
+	return apps
+}
+
+// qpkgSupportsStatus returns whether the init script at path handles the status command,
+// since many scripts only handle start, stop and restart
+func qpkgSupportsStatus(sys utils.System, path string) bool {
+	if path == "" {
+		return false
+	}
+
+	contents, err := sys.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	return qpkgStatusCaseRe.MatchString(contents)
+}
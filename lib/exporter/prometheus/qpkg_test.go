@@ -0,0 +1,61 @@
+package prometheus
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQpkgConfig(t *testing.T) {
+	lines := []string{
+		"[PlexMediaServer]",
+		"Name = PlexMediaServer",
+		"Version = 1.32.5.7349",
+		"Enable = TRUE",
+		"Shell = /share/CACHEDEV1_DATA/.qpkg/PlexMediaServer/plex.sh",
+		"",
+		"[HybridBackup]",
+		"Version = 23.1.0",
+		"Enable = FALSE",
+	}
+
+	assert.Equal(t, []qpkgApp{
+		{name: "PlexMediaServer", version: "1.32.5.7349", enabled: true, shell: "/share/CACHEDEV1_DATA/.qpkg/PlexMediaServer/plex.sh"},
+		{name: "HybridBackup", version: "23.1.0"},
+	}, parseQpkgConfig(lines))
+}
+
+func TestQpkgSupportsStatus(t *testing.T) {
+	testCases := map[string]struct {
+		script string
+		want   bool
+	}{
+		"status command": {
+			script: "case \"$1\" in\n  start)\n    ;;\n  status)\n    pidof plex\n    ;;\nesac\n",
+			want:   true,
+		},
+		"quoted status command": {
+			script: "case \"$1\" in\n  'status')\n    ;;\nesac\n",
+			want:   true,
+		},
+		"no status command": {
+			script: "case \"$1\" in\n  start)\n    ;;\n  stop)\n    ;;\nesac\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.sh")
+			require.NoError(t, os.WriteFile(path, []byte(tc.script), 0o755))
+
+			assert.Equal(t, tc.want, qpkgSupportsStatus(utils.System{}, path))
+		})
+	}
+
+	assert.False(t, qpkgSupportsStatus(utils.System{}, ""))
+	assert.False(t, qpkgSupportsStatus(utils.System{}, filepath.Join(t.TempDir(), "missing.sh")))
+}
@@ -0,0 +1,125 @@
+package prometheus
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sensorQuirk adjusts the sensors reported by getsysinfo on a NAS model whose firmware gets them wrong
+type sensorQuirk struct {
+	// SystemFans overrides the number of system fans reported by `getsysinfo sysfannum`
+	SystemFans *int `yaml:"system_fans"`
+	// DiskSlots overrides the number of disk slots reported by `getsysinfo hdnum`
+	DiskSlots *int `yaml:"disk_slots"`
+	// FanNames maps a system fan number to the value of the fan label (e.g. "1": "cpu")
+	FanNames map[string]string `yaml:"fan_names"`
+	// Ignore lists the bogus readings which are not exported, e.g. "systmp" or "sysfan 2"
+	Ignore []string `yaml:"ignore"`
+	// PowerWatts is the typical power draw of the model, from which the energy is estimated without a UPS
+	PowerWatts *float64 `yaml:"power_watts"`
+}
+
+// builtinQuirks are the known sensor quirks, keyed by model. A key ending with "*" matches any model with that prefix.
+var builtinQuirks = map[string]sensorQuirk{
+	// The HS series is fanless, but some firmware versions report a phantom system fan
+	"HS-*": {SystemFans: intPtr(0)},
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// loadQuirks reads a YAML file mapping models to sensor quirks
+func loadQuirks(path string) (map[string]sensorQuirk, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read quirks file: %w", err)
+	}
+
+	var quirks map[string]sensorQuirk
+	if err := yaml.Unmarshal(contents, &quirks); err != nil {
+		return nil, fmt.Errorf("parse quirks file %s: %w", path, err)
+	}
+
+	return quirks, nil
+}
+
+// lookupQuirk returns the quirk of model from the first table holding it, so that the tables given first take precedence.
+// An exact match is preferred over a prefix match, and the longest prefix wins.
+func lookupQuirk(model string, tables ...map[string]sensorQuirk) (sensorQuirk, bool) {
+	model = strings.ToUpper(strings.TrimSpace(model))
+	if model == "" {
+		return sensorQuirk{}, false
+	}
+
+	for _, table := range tables {
+		var best string
+		var quirk sensorQuirk
+		found := false
+		for key, q := range table {
+			key = strings.ToUpper(strings.TrimSpace(key))
+			if key == model {
+				return q, true
+			}
+
+			prefix := strings.TrimSuffix(key, "*")
+			if prefix != key && strings.HasPrefix(model, prefix) && (!found || len(prefix) > len(best)) {
+				best, quirk, found = prefix, q, true
+			}
+		}
+		if found {
+			return quirk, true
+		}
+	}
+
+	return sensorQuirk{}, false
+}
+
+// ignores reports whether the reading (e.g. "cputmp" or "sysfan 2") is known to be bogus
+func (q sensorQuirk) ignores(reading string) bool {
+	for _, ignored := range q.Ignore {
+		if strings.Join(strings.Fields(ignored), " ") == reading {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fanName returns the value of the fan label for the system fan fannum
+func (q sensorQuirk) fanName(fannum string) string {
+	if name, ok := q.FanNames[fannum]; ok && name != "" {
+		return name
+	}
+
+	return fannum
+}
+
+// applyQuirks looks up the quirks of the NAS model, in QuirksFile first, and adjusts the sensor counts read from getsysinfo
+func (e *promExporter) applyQuirks(model string) {
+	tables := []map[string]sensorQuirk{builtinQuirks}
+	if e.QuirksFile != "" {
+		quirks, err := loadQuirks(e.QuirksFile)
+		e.recordDiscovery("quirks_file", len(quirks), err)
+		if err == nil {
+			tables = []map[string]sensorQuirk{quirks, builtinQuirks}
+		}
+	}
+
+	var found bool
+	e.quirk, found = lookupQuirk(model, tables...)
+	if !found {
+		return
+	}
+	e.Logger.Info("Applying sensor quirks", "model", model)
+
+	if e.quirk.SystemFans != nil {
+		e.sysfannum = *e.quirk.SystemFans
+	}
+	if e.quirk.DiskSlots != nil {
+		e.syshdnum = *e.quirk.DiskSlots
+	}
+}
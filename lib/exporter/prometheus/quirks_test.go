@@ -0,0 +1,111 @@
+package prometheus
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupQuirk(t *testing.T) {
+	user := map[string]sensorQuirk{
+		"TS-453D": {Ignore: []string{"systmp"}},
+		"hs-2*":   {SystemFans: intPtr(1)},
+	}
+	builtin := map[string]sensorQuirk{
+		"HS-*":    {SystemFans: intPtr(0)},
+		"HS-453*": {SystemFans: intPtr(2)},
+	}
+
+	tests := map[string]struct {
+		model     string
+		wantFound bool
+		wantQuirk sensorQuirk
+	}{
+		"exact match": {
+			model:     "TS-453D",
+			wantFound: true,
+			wantQuirk: sensorQuirk{Ignore: []string{"systmp"}},
+		},
+		"user table takes precedence": {
+			model:     "HS-251+",
+			wantFound: true,
+			wantQuirk: sensorQuirk{SystemFans: intPtr(1)},
+		},
+		"longest prefix": {
+			model:     "hs-453DX",
+			wantFound: true,
+			wantQuirk: sensorQuirk{SystemFans: intPtr(2)},
+		},
+		"prefix": {
+			model:     "HS-1",
+			wantFound: true,
+			wantQuirk: sensorQuirk{SystemFans: intPtr(0)},
+		},
+		"unknown model": {
+			model: "TS-251",
+		},
+		"empty model": {
+			model: "",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			quirk, found := lookupQuirk(tc.model, user, builtin)
+
+			assert.Equal(t, tc.wantFound, found)
+			assert.Equal(t, tc.wantQuirk, quirk)
+		})
+	}
+}
+
+func TestSensorQuirk(t *testing.T) {
+	q := sensorQuirk{
+		FanNames: map[string]string{"1": "cpu"},
+		Ignore:   []string{"systmp", "sysfan  2"},
+	}
+
+	assert.True(t, q.ignores("systmp"))
+	assert.True(t, q.ignores("sysfan 2"))
+	assert.False(t, q.ignores("cputmp"))
+	assert.False(t, q.ignores("sysfan 1"))
+	assert.Equal(t, "cpu", q.fanName("1"))
+	assert.Equal(t, "2", q.fanName("2"))
+}
+
+func TestApplyQuirks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quirks.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+TS-453D:
+  disk_slots: 4
+  fan_names:
+    "1": rear
+`), 0o600))
+
+	e := &promExporter{ExporterConfig: ExporterConfig{
+		QuirksFile: path,
+		Logger:     logging.Discard(),
+	}}
+	e.syshdnum, e.sysfannum = 6, 1
+
+	e.applyQuirks("TS-453D")
+	assert.Equal(t, 4, e.syshdnum)
+	assert.Equal(t, 1, e.sysfannum)
+	assert.Equal(t, "rear", e.quirk.fanName("1"))
+
+	// The built-in quirks still apply to the models missing from the file
+	e.applyQuirks("HS-264")
+	assert.Equal(t, 0, e.sysfannum)
+	assert.Equal(t, "1", e.quirk.fanName("1"))
+
+	e.QuirksFile = filepath.Join(t.TempDir(), "missing.yml")
+	e.discovery = nil
+	e.applyQuirks("TS-453D")
+	assert.Equal(t, sensorQuirk{}, e.quirk)
+	require.Len(t, e.discovery, 1)
+	assert.Error(t, e.discovery[0].err)
+}
@@ -0,0 +1,185 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+// quotaBlockSize is the unit of the block counts printed by repquota
+const quotaBlockSize = 1024
+
+type quotaEntry struct {
+	// device is the device of the file system holding the quota, e.g. /dev/mapper/cachedev1
+	device string
+	// name is the user, or the project of a shared folder quota
+	name           string
+	usedBytes      float64
+	softLimitBytes float64
+	hardLimitBytes float64
+}
+
+// getQuotaMetrics reports the usage and limits of the user quotas and of the shared folder quotas, which are
+// implemented as project quotas named after the shared folder, read through repquota
+func (e *promExporter) getQuotaMetrics(ctx context.Context) ([]metric, error) {
+	if e.repquota == "" {
+		return nil, subsystemAbsentError{"repquota not found"}
+	}
+
+	userQuotas, err := e.readQuotaReport(ctx, "-u")
+	if err != nil {
+		return nil, err
+	}
+	// Project quotas are not supported by every kernel and file system, so that a failure only means that
+	// no shared folder quota is set
+	shareQuotas, _ := e.readQuotaReport(ctx, "-P")
+	if userQuotas == nil && shareQuotas == nil {
+		return nil, subsystemAbsentError{"no file system with quotas enabled"}
+	}
+
+	volumes := make(map[string]string)
+	for volume, stack := range e.volumeDeviceStacks(ctx) {
+		volumes[stack[0]] = volume
+	}
+
+	metrics := quotaMetrics(e.sys, "user", userQuotas, volumes)
+	metrics = append(metrics, quotaMetrics(e.sys, "share", shareQuotas, volumes)...)
+
+	return metrics, nil
+}
+
+// readQuotaReport returns the quotas of the given kind (-u for the users, -P for the projects) on every file system
+// with quotas enabled, or nil if there is none
+func (e *promExporter) readQuotaReport(ctx context.Context, kind string) ([]quotaEntry, error) {
+	output, exitCode, err := e.sys.ExecCommandWithExitCode(ctx, e.repquota, "-a", kind)
+	if err != nil {
+		return nil, err
+	}
+	if exitCode != 0 {
+		if output == "" {
+			// repquota fails when no file system has quotas enabled
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("repquota %s exited with status %d", kind, exitCode)
+	}
+
+	return parseRepquota(output), nil
+}
+
+// parseRepquota parses the output of repquota, e.g.:
+//
+//	*** Report for user quotas on device /dev/mapper/cachedev1
+//	Block grace time: 7days; Inode grace time: 7days
+//	                        Block limits                File limits
+//	User            used    soft    hard  grace    used  soft  hard  grace
+//	----------------------------------------------------------------------
+//	admin     --  1048576       0       0           1203     0     0
+//	alice     +-  5243904 5000000 5242880  6days     342     0     0
+func parseRepquota(output string) []quotaEntry {
+	var entries []quotaEntry
+	var device string
+	inTable := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "***"):
+			fields := strings.Fields(line)
+			device = fields[len(fields)-1]
+			inTable = false
+			continue
+		case strings.HasPrefix(line, "---"):
+			inTable = true
+			continue
+		case line == "":
+			inTable = false
+			continue
+		case !inTable:
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 5 || len(fields[1]) != 2 {
+			continue
+		}
+
+		var values [3]float64
+		valid := true
+		for idx := range values {
+			value, err := utils.ParseFloat(fields[2+idx])
+			if err != nil {
+				valid = false
+				break
+			}
+			values[idx] = value * quotaBlockSize
+		}
+		if !valid {
+			continue
+		}
+
+		entries = append(entries, quotaEntry{
+			device:         device,
+			name:           fields[0],
+			usedBytes:      values[0],
+			softLimitBytes: values[1],
+			hardLimitBytes: values[2],
+		})
+	}
+
+	return entries
+}
+
+// quotaMetrics returns the metrics of the quotas with a limit, labeled by the volume holding them (or the device,
+// if it is not the one of a volume) and by kind (user or share)
+func quotaMetrics(sys utils.System, kind string, entries []quotaEntry, volumes map[string]string) []metric {
+	var metrics []metric
+	for _, q := range entries {
+		if q.softLimitBytes == 0 && q.hardLimitBytes == 0 {
+			continue
+		}
+
+		volume := q.device
+		if device, err := sys.EvalSymlinks(q.device); err == nil {
+			if v, found := volumes[filepath.Base(device)]; found {
+				volume = v
+			}
+		}
+
+		attr := fmt.Sprintf("volume=%q,%s=%q", volume, kind, q.name)
+		metrics = append(metrics, metric{
+			name:  fmt.Sprintf("node_%s_quota_used_bytes", kind),
+			attr:  attr,
+			value: q.usedBytes,
+			help:  fmt.Sprintf("Space used by the %s, as counted by its quota", quotaSubject(kind)),
+		})
+		if q.softLimitBytes > 0 {
+			metrics = append(metrics, metric{
+				name:  fmt.Sprintf("node_%s_quota_soft_limit_bytes", kind),
+				attr:  attr,
+				value: q.softLimitBytes,
+				help:  fmt.Sprintf("Soft limit of the quota of the %s, which may be exceeded during the grace period", quotaSubject(kind)),
+			})
+		}
+		if q.hardLimitBytes > 0 {
+			metrics = append(metrics, metric{
+				name:  fmt.Sprintf("node_%s_quota_hard_limit_bytes", kind),
+				attr:  attr,
+				value: q.hardLimitBytes,
+				help:  fmt.Sprintf("Hard limit of the quota of the %s, beyond which writes fail", quotaSubject(kind)),
+			})
+		}
+	}
+
+	return metrics
+}
+
+func quotaSubject(kind string) string {
+	if kind == "share" {
+		return "shared folder"
+	}
+
+	return kind
+}
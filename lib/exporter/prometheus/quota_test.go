@@ -0,0 +1,60 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRepquota(t *testing.T) {
+	output := `*** Report for user quotas on device /dev/mapper/cachedev1
+Block grace time: 7days; Inode grace time: 7days
+                        Block limits                File limits
+User            used    soft    hard  grace    used  soft  hard  grace
+----------------------------------------------------------------------
+admin     --  1048576       0       0           1203     0     0
+alice     +-  5243904 5000000 5242880  6days     342     0     0
+#1005     --      128       0 1048576              2     0     0
+
+*** Report for user quotas on device /dev/mapper/cachedev2
+Block grace time: 7days; Inode grace time: 7days
+                        Block limits                File limits
+User            used    soft    hard  grace    used  soft  hard  grace
+----------------------------------------------------------------------
+bob       --     2048 1048576 2097152                5     0     0
+`
+
+	assert.Equal(t, []quotaEntry{
+		{device: "/dev/mapper/cachedev1", name: "admin", usedBytes: 1048576 * 1024},
+		{device: "/dev/mapper/cachedev1", name: "alice", usedBytes: 5243904 * 1024, softLimitBytes: 5000000 * 1024, hardLimitBytes: 5242880 * 1024},
+		{device: "/dev/mapper/cachedev1", name: "#1005", usedBytes: 128 * 1024, hardLimitBytes: 1048576 * 1024},
+		{device: "/dev/mapper/cachedev2", name: "bob", usedBytes: 2048 * 1024, softLimitBytes: 1048576 * 1024, hardLimitBytes: 2097152 * 1024},
+	}, parseRepquota(output))
+
+	assert.Empty(t, parseRepquota(""))
+}
+
+func TestQuotaMetrics(t *testing.T) {
+	entries := []quotaEntry{
+		{device: "/dev/mapper/cachedev1", name: "admin", usedBytes: 1024},
+		{device: "/dev/mapper/cachedev1", name: "Public", usedBytes: 2048, hardLimitBytes: 4096},
+	}
+
+	metrics := quotaMetrics(utils.System{}, "share", entries, nil)
+
+	assert.Equal(t, []metric{
+		{
+			name:  "node_share_quota_used_bytes",
+			attr:  `volume="/dev/mapper/cachedev1",share="Public"`,
+			value: 2048,
+			help:  "Space used by the shared folder, as counted by its quota",
+		},
+		{
+			name:  "node_share_quota_hard_limit_bytes",
+			attr:  `volume="/dev/mapper/cachedev1",share="Public"`,
+			value: 4096,
+			help:  "Hard limit of the quota of the shared folder, beyond which writes fail",
+		},
+	}, metrics)
+}
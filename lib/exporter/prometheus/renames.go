@@ -0,0 +1,84 @@
+package prometheus
+
+import (
+	"fmt"
+	"time"
+)
+
+// DeprecatedNamesDateLayout is the layout of the date until which the deprecated metric names are emitted
+const DeprecatedNamesDateLayout = "2006-01-02"
+
+// metricRename is a metric family given a new name, e.g. to follow the Prometheus naming conventions
+type metricRename struct {
+	from string
+	to   string
+}
+
+// metricRenames lists the renamed metric families. The collectors return the new names, and the old ones are emitted
+// as well, as deprecated aliases, during the transition period, so that the dashboards and rules of the users can be
+// migrated before the old names disappear. Once a release drops an alias, its entry is removed.
+var metricRenames = []metricRename{}
+
+// ParseDeprecatedNamesUntil parses the last day (e.g. 2027-06-30) on which the deprecated metric names are emitted.
+// An empty value means that they are emitted until they are dropped from the exporter.
+func ParseDeprecatedNamesUntil(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	until, err := time.ParseInLocation(DeprecatedNamesDateLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse deprecated names end date %q: %w", value, err)
+	}
+
+	return until, nil
+}
+
+// deprecatedNames maps the new names to the old ones still emitted at now, in the configured metric namespace.
+// The end date is included.
+func (e *promExporter) deprecatedNames(now time.Time) map[string]string {
+	if len(metricRenames) == 0 {
+		return nil
+	}
+	if !e.DeprecatedNamesUntil.IsZero() && !now.Before(e.DeprecatedNamesUntil.AddDate(0, 0, 1)) {
+		return nil
+	}
+
+	names := make(map[string]string, len(metricRenames))
+	for _, r := range metricRenames {
+		from, to := r.from, r.to
+		if e.MetricNamespace == MetricNamespaceQNAP {
+			from, to = qnapMetricName(from), qnapMetricName(to)
+		}
+		if from != to {
+			names[to] = from
+		}
+	}
+
+	return names
+}
+
+// withDeprecatedNames returns metrics along with a copy of the renamed ones under their old name
+func withDeprecatedNames(metrics []metric, deprecated map[string]string) []metric {
+	if len(deprecated) == 0 {
+		return metrics
+	}
+
+	aliased := make([]metric, 0, len(metrics))
+	for _, m := range metrics {
+		aliased = append(aliased, m)
+		if from, found := deprecated[m.name]; found {
+			alias := m
+			alias.name = from
+			alias.help = deprecatedHelp(m.help, m.name)
+			aliased = append(aliased, alias)
+		}
+	}
+
+	return aliased
+}
+
+// deprecatedHelp returns the help of the old name of a metric family renamed to name
+func deprecatedHelp(help, name string) string {
+	return fmt.Sprintf("Deprecated, renamed to %s: %s", name, help)
+}
@@ -0,0 +1,99 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withMetricRenames replaces the renamed metric families for the duration of the test
+func withMetricRenames(t *testing.T, renames []metricRename) {
+	saved := metricRenames
+	metricRenames = renames
+	t.Cleanup(func() { metricRenames = saved })
+}
+
+func TestParseDeprecatedNamesUntil(t *testing.T) {
+	until, err := ParseDeprecatedNamesUntil("")
+	require.NoError(t, err)
+	assert.True(t, until.IsZero())
+
+	until, err = ParseDeprecatedNamesUntil("2027-06-30")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2027, time.June, 30, 0, 0, 0, 0, time.Local), until)
+
+	_, err = ParseDeprecatedNamesUntil("30/06/2027")
+	assert.Error(t, err)
+}
+
+func TestInNamespaceWithDeprecatedNames(t *testing.T) {
+	withMetricRenames(t, []metricRename{{from: "node_cputmp_C", to: "node_cpu_temperature_celsius"}})
+	metrics := []metric{
+		{name: "node_cpu_temperature_celsius", value: 45, help: "CPU temperature"},
+		{name: "node_load1", value: 0.5, help: "Load average"},
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	tests := map[string]struct {
+		namespace string
+		until     time.Time
+		want      []metric
+	}{
+		"transition period": {
+			want: []metric{
+				{name: "node_cpu_temperature_celsius", value: 45, help: "CPU temperature"},
+				{name: "node_cputmp_C", value: 45, help: "Deprecated, renamed to node_cpu_temperature_celsius: CPU temperature"},
+				{name: "node_load1", value: 0.5, help: "Load average"},
+			},
+		},
+		"last day of the transition period": {
+			until: today,
+			want: []metric{
+				{name: "node_cpu_temperature_celsius", value: 45, help: "CPU temperature"},
+				{name: "node_cputmp_C", value: 45, help: "Deprecated, renamed to node_cpu_temperature_celsius: CPU temperature"},
+				{name: "node_load1", value: 0.5, help: "Load average"},
+			},
+		},
+		"transition period over": {
+			until: today.AddDate(0, 0, -1),
+			want:  metrics,
+		},
+		"qnap namespace": {
+			// Both names are renamed to the same name in the qnap namespace
+			namespace: MetricNamespaceQNAP,
+			want: []metric{
+				{name: "qnap_cpu_temperature_celsius", value: 45, help: "CPU temperature"},
+				{name: "qnap_load1", value: 0.5, help: "Load average"},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := &promExporter{ExporterConfig: ExporterConfig{MetricNamespace: tc.namespace, DeprecatedNamesUntil: tc.until}}
+
+			assert.Equal(t, tc.want, e.inNamespace(metrics))
+		})
+	}
+}
+
+func TestMetricCatalogWithDeprecatedNames(t *testing.T) {
+	withMetricRenames(t, []metricRename{{from: "node_load_1m", to: "node_load1"}})
+
+	var found bool
+	for idx, d := range MetricCatalog() {
+		if d.Name != "node_load_1m" {
+			continue
+		}
+
+		found = true
+		assert.Equal(t, "node_load1", d.Deprecated)
+		assert.Equal(t, "loadavg", d.Collector)
+		assert.Contains(t, d.Help, "Deprecated, renamed to node_load1: ")
+		assert.Equal(t, "node_load1", MetricCatalog()[idx-1].Name)
+	}
+	assert.True(t, found)
+}
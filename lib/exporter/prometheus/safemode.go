@@ -0,0 +1,24 @@
+package prometheus
+
+// safeCollectors are the collectors enabled in safe mode: they only read procfs and sysfs,
+// so they neither run commands (e.g. getsysinfo or smartctl) nor access the disks
+var safeCollectors = map[string]bool{
+	"version":       true,
+	"config":        true,
+	"firmware":      true,
+	"uptime":        true,
+	"loadavg":       true,
+	"cpu":           true,
+	"meminfo":       true,
+	"fanpolicy":     true,
+	"hwmon":         true,
+	"volumedevices": true,
+	"diskstats":     true,
+	"blockdevices":  true,
+	"flashcache":    true,
+	"network":       true,
+	"externaldisk":  true,
+	"processes":     true,
+	"mdstat":        true,
+	"dependencies":  true,
+}
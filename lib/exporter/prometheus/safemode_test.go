@@ -0,0 +1,42 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSafeCollectorsExist(t *testing.T) {
+	names := map[string]bool{}
+	for _, name := range CollectorNames() {
+		names[name] = true
+	}
+
+	for name := range safeCollectors {
+		assert.True(t, names[name], "unknown safe collector %q", name)
+	}
+}
+
+func TestSafeMode(t *testing.T) {
+	e := &promExporter{ExporterConfig: ExporterConfig{
+		SafeMode:   true,
+		Collectors: map[string]bool{"mdstat": false},
+		Logger:     logging.Discard(),
+	}}
+
+	var names []string
+	for _, c := range e.enabledCollectors() {
+		names = append(names, c.name)
+		assert.True(t, safeCollectors[c.name], "collector %q is not safe", c.name)
+	}
+	assert.Contains(t, names, "meminfo")
+	assert.NotContains(t, names, "smart")
+	assert.NotContains(t, names, "mdstat")
+	assert.Equal(t, len(CollectorNames())-len(names), e.disabledCollectorCount())
+
+	// Tools are never looked up, even if found before safe mode was enabled
+	e.smartctl = "/usr/sbin/smartctl"
+	e.discoverTool(&e.smartctl, "smartctl")
+	assert.Empty(t, e.smartctl)
+}
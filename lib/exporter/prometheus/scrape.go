@@ -0,0 +1,142 @@
+package prometheus
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// scrapeResult holds the outcome of a collection, which may be shared by several scrapes
+type scrapeResult struct {
+	targetLabels []label
+	metrics      []metric
+	errs         []error
+	// err is the last error seen, as returned by collect
+	err  error
+	time time.Time
+}
+
+type scrapeCall struct {
+	done   chan struct{}
+	result scrapeResult
+	// cancelled is whether the collection was cut short by the scrape which started it, in which case it is neither
+	// served to the other scrapes nor cached
+	cancelled bool
+}
+
+// scrapeCoordinator shares a full collection between the scrapes which arrive while it runs (e.g. two Prometheus
+// servers scraping at the same time), and with the scrapes which arrive within ScrapeCacheTTL after it completed,
+// so that the commands run by the collectors aren't run several times over
+type scrapeCoordinator struct {
+	mu       sync.Mutex
+	inflight *scrapeCall
+	last     *scrapeResult
+}
+
+// detachedContext carries the values of its parent, but neither its deadline nor its cancellation
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+// collectShared returns the outcome of a collection, either running one or joining the one in progress.
+// Collections restricted to a selection of collectors are neither shared nor cached.
+func (e *promExporter) collectShared(ctx context.Context) scrapeResult {
+	if !collectorSelectionFromContext(ctx).isEmpty() {
+		return e.collectAll(ctx)
+	}
+
+	s := &e.scrapes
+	for {
+		s.mu.Lock()
+		if s.last != nil && time.Since(s.last.time) < e.ScrapeCacheTTL {
+			r := *s.last
+			s.mu.Unlock()
+			age := time.Since(r.time)
+			e.Logger.Debug("Serving cached metrics", "age", age)
+			r.metrics = agedMetrics(r.metrics, age)
+			return r
+		}
+		call := s.inflight
+		if call == nil {
+			call = e.startSharedCollection(ctx)
+		} else {
+			e.Logger.Debug("Joining the collection in progress")
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return scrapeResult{err: ctx.Err()}
+		}
+		if !call.cancelled {
+			return call.result
+		}
+		// The scrape which started the collection went away before it completed, run another one
+	}
+}
+
+// startSharedCollection starts a collection shared by the scrapes, which completes once call.done is closed.
+// e.scrapes.mu must be held.
+func (e *promExporter) startSharedCollection(ctx context.Context) *scrapeCall {
+	s := &e.scrapes
+	call := &scrapeCall{done: make(chan struct{})}
+	s.inflight = call
+
+	if e.CollectorTimeout > 0 {
+		// Each collector being bounded by the collector timeout, the collection can outlive the scrape which
+		// started it, so that the scrapes which joined it get all the metrics
+		ctx = detachedContext{parent: ctx}
+	}
+	go func() {
+		call.result = e.collectAll(ctx)
+		call.cancelled = ctx.Err() != nil
+
+		s.mu.Lock()
+		s.inflight = nil
+		if !call.cancelled && e.ScrapeCacheTTL > 0 {
+			s.last = &call.result
+		}
+		s.mu.Unlock()
+		close(call.done)
+	}()
+
+	return call
+}
+
+// collectAll runs the collectors selected by ctx, gathering all their metrics
+func (e *promExporter) collectAll(ctx context.Context) scrapeResult {
+	var r scrapeResult
+	r.err = e.collect(
+		ctx,
+		func(batch []metric) {
+			if r.targetLabels == nil {
+				// The hostname is only known once the environment has been read
+				r.targetLabels = e.targetLabels()
+			}
+			r.metrics = append(r.metrics, batch...)
+		},
+		func(err error) {
+			r.errs = append(r.errs, err)
+		},
+	)
+	r.time = time.Now()
+
+	return r
+}
+
+// reset drops the cached collection, e.g. when the configuration changes
+func (s *scrapeCoordinator) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.last = nil
+}
@@ -0,0 +1,123 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCountingExporter(cacheTTL time.Duration, delay time.Duration) (*promExporter, *atomic.Int64) {
+	var runs atomic.Int64
+	e := &promExporter{
+		ExporterConfig: ExporterConfig{ScrapeCacheTTL: cacheTTL, Logger: logging.Discard()},
+		hostname:       "nas",
+		envExpiry:      time.Now().Add(time.Hour),
+		watchdog:       newWatchdog(0, nil, nil),
+	}
+	e.fns = []collector{
+		{
+			name: "test",
+			fn: func(context.Context) ([]metric, error) {
+				runs.Add(1)
+				time.Sleep(delay)
+				return []metric{{name: "node_load1", value: 1}}, nil
+			},
+		},
+	}
+
+	return e, &runs
+}
+
+func TestWriteMetricsSharesConcurrentCollections(t *testing.T) {
+	e, runs := newCountingExporter(0, 100*time.Millisecond)
+
+	var wg sync.WaitGroup
+	outputs := make([]*bytes.Buffer, 3)
+	for idx := range outputs {
+		outputs[idx] = new(bytes.Buffer)
+		wg.Add(1)
+		go func(b *bytes.Buffer) {
+			defer wg.Done()
+			assert.NoError(t, e.WriteMetrics(context.Background(), b))
+		}(outputs[idx])
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(1), runs.Load())
+	for _, b := range outputs {
+		assert.Contains(t, b.String(), "node_load1{node=\"nas\"} 1\n")
+	}
+
+	// Without a cache, the next scrape runs a new collection
+	require.NoError(t, e.WriteMetrics(context.Background(), new(bytes.Buffer)))
+	assert.Equal(t, int64(2), runs.Load())
+}
+
+func TestWriteMetricsScrapeCache(t *testing.T) {
+	e, runs := newCountingExporter(time.Hour, 0)
+
+	require.NoError(t, e.WriteMetrics(context.Background(), new(bytes.Buffer)))
+	require.NoError(t, e.WriteMetrics(context.Background(), new(bytes.Buffer)))
+	assert.Equal(t, int64(1), runs.Load())
+
+	// Selections of collectors always run their own collection
+	ctx := WithCollectorSelection(context.Background(), CollectorSelection{Collect: []string{"test"}})
+	require.NoError(t, e.WriteMetrics(ctx, new(bytes.Buffer)))
+	assert.Equal(t, int64(2), runs.Load())
+
+	// Changing the configuration drops the cached metrics
+	fns := e.fns
+	e.ApplyConfig(e.ExporterConfig)
+	e.fns = fns
+	require.NoError(t, e.WriteMetrics(context.Background(), new(bytes.Buffer)))
+	assert.Equal(t, int64(3), runs.Load())
+}
+
+func TestWriteMetricsSharedCollectionOutlivesItsScrape(t *testing.T) {
+	tests := map[string]struct {
+		collectorTimeout time.Duration
+		expectedRuns     int64
+	}{
+		"collection completed for the joined scrapes": {
+			collectorTimeout: time.Minute,
+			expectedRuns:     1,
+		},
+		"joined scrapes run another collection without a collector timeout": {
+			expectedRuns: 2,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			e, runs := newCountingExporter(time.Hour, 100*time.Millisecond)
+			e.CollectorTimeout = tc.collectorTimeout
+
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() { _ = e.WriteMetrics(ctx, new(bytes.Buffer)) }()
+			require.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, time.Millisecond)
+
+			joined := make(chan *bytes.Buffer)
+			go func() {
+				b := new(bytes.Buffer)
+				assert.NoError(t, e.WriteMetrics(context.Background(), b))
+				joined <- b
+			}()
+			time.Sleep(20 * time.Millisecond)
+			cancel()
+
+			assert.Contains(t, (<-joined).String(), "node_load1{node=\"nas\"} 1\n")
+			assert.Equal(t, tc.expectedRuns, runs.Load())
+
+			// The complete collection is cached, not the one cut short
+			require.NoError(t, e.WriteMetrics(context.Background(), new(bytes.Buffer)))
+			assert.Equal(t, tc.expectedRuns, runs.Load())
+		})
+	}
+}
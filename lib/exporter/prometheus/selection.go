@@ -0,0 +1,91 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+)
+
+// CollectorSelection restricts the collectors run for a single scrape, e.g. so that a Prometheus job
+// scrapes the cheap collectors often and another one scrapes the slow collectors less often
+type CollectorSelection struct {
+	// Collect lists the collectors to run (empty runs every enabled collector)
+	Collect []string
+	// Exclude lists the collectors not to run
+	Exclude []string
+}
+
+type collectorSelectionKey struct{}
+
+// ParseCollectorSelection validates the collector names passed in the collect[] and exclude[] query parameters
+func ParseCollectorSelection(collect, exclude []string) (CollectorSelection, error) {
+	known := map[string]bool{}
+	for _, name := range CollectorNames() {
+		known[name] = true
+	}
+	for _, name := range append(append([]string(nil), collect...), exclude...) {
+		if !known[name] {
+			return CollectorSelection{}, fmt.Errorf("unknown collector %q", name)
+		}
+	}
+
+	return CollectorSelection{Collect: collect, Exclude: exclude}, nil
+}
+
+// WithCollectorSelection returns a context which makes WriteMetrics only run the collectors selected by s
+func WithCollectorSelection(ctx context.Context, s CollectorSelection) context.Context {
+	return context.WithValue(ctx, collectorSelectionKey{}, s)
+}
+
+func collectorSelectionFromContext(ctx context.Context) CollectorSelection {
+	s, _ := ctx.Value(collectorSelectionKey{}).(CollectorSelection)
+	return s
+}
+
+func (s CollectorSelection) isEmpty() bool {
+	return len(s.Collect) == 0 && len(s.Exclude) == 0
+}
+
+// filter returns the collectors of fns selected by s
+func (s CollectorSelection) filter(fns []collector) []collector {
+	if s.isEmpty() {
+		return fns
+	}
+
+	selected := make([]collector, 0, len(fns))
+	for _, c := range fns {
+		if len(s.Collect) > 0 && !containsString(s.Collect, c.name) || containsString(s.Exclude, c.name) {
+			continue
+		}
+
+		selected = append(selected, c)
+	}
+
+	return selected
+}
+
+// mergeCollectorStatuses replaces the statuses in previous with the ones of the collectors which just ran,
+// so that a scrape of a subset of the collectors doesn't hide the outcome of the others
+func mergeCollectorStatuses(previous []exporter.CollectorStatus, statuses []exporter.CollectorStatus) []exporter.CollectorStatus {
+	latest := make(map[string]exporter.CollectorStatus, len(statuses))
+	for _, s := range statuses {
+		latest[s.Name] = s
+	}
+
+	merged := make([]exporter.CollectorStatus, 0, len(previous)+len(statuses))
+	for _, s := range previous {
+		if l, found := latest[s.Name]; found {
+			s = l
+			delete(latest, s.Name)
+		}
+		merged = append(merged, s)
+	}
+	for _, s := range statuses {
+		if _, found := latest[s.Name]; found {
+			merged = append(merged, s)
+		}
+	}
+
+	return merged
+}
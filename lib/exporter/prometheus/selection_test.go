@@ -0,0 +1,95 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCollectorSelection(t *testing.T) {
+	tests := map[string]struct {
+		collect     []string
+		exclude     []string
+		expectedErr string
+	}{
+		"empty":             {},
+		"known collectors":  {collect: []string{"uptime", "cpu"}, exclude: []string{"ups"}},
+		"unknown collect":   {collect: []string{"uptime", "nope"}, expectedErr: `unknown collector "nope"`},
+		"unknown exclude":   {exclude: []string{"nope"}, expectedErr: `unknown collector "nope"`},
+		"exporter metadata": {collect: []string{exporterCollectorName}, expectedErr: `unknown collector "exporter"`},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := ParseCollectorSelection(tc.collect, tc.exclude)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, CollectorSelection{Collect: tc.collect, Exclude: tc.exclude}, s)
+		})
+	}
+}
+
+func TestCollectorSelectionFilter(t *testing.T) {
+	fns := []collector{{name: "uptime"}, {name: "cpu"}, {name: "ups"}}
+
+	tests := map[string]struct {
+		selection CollectorSelection
+		expected  []string
+	}{
+		"empty":           {expected: []string{"uptime", "cpu", "ups"}},
+		"collect":         {selection: CollectorSelection{Collect: []string{"ups", "cpu"}}, expected: []string{"cpu", "ups"}},
+		"exclude":         {selection: CollectorSelection{Exclude: []string{"ups"}}, expected: []string{"uptime", "cpu"}},
+		"collect+exclude": {selection: CollectorSelection{Collect: []string{"ups", "cpu"}, Exclude: []string{"ups"}}, expected: []string{"cpu"}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var names []string
+			for _, c := range tc.selection.filter(fns) {
+				names = append(names, c.name)
+			}
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
+
+func TestMergeCollectorStatuses(t *testing.T) {
+	previous := []exporter.CollectorStatus{{Name: "uptime"}, {Name: "cpu", Error: "failed"}, {Name: "ups"}}
+	statuses := []exporter.CollectorStatus{{Name: "cpu"}, {Name: "smart"}}
+
+	assert.Equal(
+		t,
+		[]exporter.CollectorStatus{{Name: "uptime"}, {Name: "cpu"}, {Name: "ups"}, {Name: "smart"}},
+		mergeCollectorStatuses(previous, statuses),
+	)
+}
+
+func TestWriteMetricsWithCollectorSelection(t *testing.T) {
+	var s exporter.Status
+	e := NewExporter(ExporterConfig{Logger: logging.Discard()}, &s)
+	defer e.Close()
+
+	require.NoError(t, e.WriteMetrics(context.Background(), io.Discard))
+	collectorCount := len(s.Collectors)
+
+	b := new(bytes.Buffer)
+	ctx := WithCollectorSelection(context.Background(), CollectorSelection{Collect: []string{"uptime", "loadavg"}})
+	require.NoError(t, e.WriteMetrics(ctx, b))
+
+	output := b.String()
+	assert.Contains(t, output, `collector="uptime"`)
+	assert.Contains(t, output, `collector="loadavg"`)
+	assert.NotContains(t, output, `collector="meminfo"`)
+	// The status still holds the outcome of the collectors which were not selected
+	assert.Len(t, s.Collectors, collectorCount)
+}
@@ -0,0 +1,254 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// smartctl exit status bits signaling that no data could be read from the device
+	// (bit 0: command line did not parse, bit 1: device open failed or device is in low-power mode)
+	smartctlFatalExitMask = 0x3
+
+	// smartDeltaMinPeriod is how long an attribute must be tracked before its daily rate of change is reported,
+	// to avoid huge rates right after the baseline is recorded
+	smartDeltaMinPeriod = time.Hour
+)
+
+// smartTrackedAttributes are the S.M.A.R.T. attributes whose growth is tracked, as it predicts disk failure
+var smartTrackedAttributes = []string{"reallocated_sectors", "pending_sectors"}
+
+type smartInfo struct {
+	model        string
+	serial       string
+	healthKnown  bool
+	healthy      bool
+	reallocated  *float64
+	pending      *float64
+	powerOnHours *float64
+	temperature  *float64
+}
+
+func (e *promExporter) getSmartMetrics(ctx context.Context) ([]metric, error) {
+	if e.smartctl == "" {
+		return nil, nil
+	}
+
+	metrics := make([]metric, 0, len(e.devices)*9)
+	var samples []smartSample
+	var temperatures []temperatureSample
+	for _, dev := range e.devices {
+		// Use `-n standby` so that we don't wake up sleeping disks
+		output, exitCode, err := e.sys.ExecCommandWithExitCode(ctx, e.smartctl, "-n", "standby", "-i", "-H", "-A", path.Join(devDir, dev))
+		if err != nil {
+			return metrics, err
+		}
+		if exitCode&smartctlFatalExitMask != 0 {
+			continue
+		}
+
+		info := parseSmartctlOutput(output)
+		attr := fmt.Sprintf("device=%q,serial=%q", dev, info.serial)
+
+		if info.healthKnown {
+			var value float64
+			if info.healthy {
+				value = 1
+			}
+			metrics = append(metrics, metric{
+				name:  "node_disk_smart_healthy",
+				attr:  fmt.Sprintf("%s,model=%q", attr, info.model),
+				value: value,
+				help:  "Whether the device passed the S.M.A.R.T. overall-health self-assessment test",
+			})
+		}
+
+		metrics = appendSmartMetric(metrics, "node_disk_smart_reallocated_sectors", attr, info.reallocated, "Number of reallocated sectors")
+		metrics = appendSmartMetric(metrics, "node_disk_smart_pending_sectors", attr, info.pending, "Number of sectors pending reallocation")
+		metrics = appendSmartMetric(metrics, "node_disk_smart_power_on_hours", attr, info.powerOnHours, "Number of hours the device has been powered on")
+		metrics = appendSmartMetric(metrics, "node_disk_smart_temperature_celsius", attr, info.temperature, "Device temperature as reported by S.M.A.R.T.")
+
+		disk := info.serial
+		if disk == "" {
+			disk = dev
+		}
+		for idx, value := range []*float64{info.reallocated, info.pending} {
+			if value != nil {
+				samples = append(samples, smartSample{disk: disk, attr: attr, attribute: smartTrackedAttributes[idx], value: *value})
+			}
+		}
+		if info.temperature != nil && e.TemperatureTrendWindow > 0 {
+			temperatures = append(temperatures, temperatureSample{disk: disk, attr: attr, value: *info.temperature})
+		}
+	}
+
+	if len(samples) == 0 && len(temperatures) == 0 {
+		return metrics, nil
+	}
+
+	var deltaMetrics, trendMetrics []metric
+	err := e.state.update(func(state *exporterState) bool {
+		now := time.Now()
+		var deltaChanged, trendChanged bool
+		deltaMetrics, deltaChanged = getSmartDeltaMetrics(state, samples, now)
+		if e.TemperatureTrendWindow > 0 {
+			trendMetrics, trendChanged = getTemperatureTrendMetrics(state, temperatures, e.TemperatureTrendWindow, now)
+		}
+		return deltaChanged || trendChanged
+	})
+
+	metrics = append(metrics, deltaMetrics...)
+	return append(metrics, trendMetrics...), err
+}
+
+type smartSample struct {
+	disk      string
+	attr      string
+	attribute string
+	value     float64
+}
+
+// getSmartDeltaMetrics computes the change of each sample since it was first seen, recording
+// the baseline of new samples in state. It returns whether state was changed.
+func getSmartDeltaMetrics(state *exporterState, samples []smartSample, now time.Time) ([]metric, bool) {
+	var changed bool
+	if state.SmartBaselines == nil {
+		state.SmartBaselines = make(map[string]stateSample)
+	}
+
+	metrics := make([]metric, 0, len(samples)*2)
+	for _, s := range samples {
+		key := s.disk + "/" + s.attribute
+		baseline, found := state.SmartBaselines[key]
+		if !found {
+			baseline = stateSample{Value: s.value, Time: now}
+			state.SmartBaselines[key] = baseline
+			changed = true
+		}
+
+		attr := fmt.Sprintf("%s,attribute=%q", s.attr, s.attribute)
+		delta := s.value - baseline.Value
+		metrics = append(metrics, metric{
+			name:  "node_disk_smart_attribute_delta",
+			attr:  attr,
+			value: delta,
+			help:  "Change of the S.M.A.R.T. attribute since it was first seen by the exporter",
+		})
+
+		if elapsed := now.Sub(baseline.Time); elapsed >= smartDeltaMinPeriod {
+			metrics = append(metrics, metric{
+				name:  "node_disk_smart_attribute_delta_per_day",
+				attr:  attr,
+				value: delta / (elapsed.Hours() / 24),
+				help:  "Average daily change of the S.M.A.R.T. attribute since it was first seen by the exporter",
+			})
+		}
+	}
+
+	return metrics, changed
+}
+
+func appendSmartMetric(metrics []metric, name string, attr string, value *float64, help string) []metric {
+	if value == nil {
+		return metrics
+	}
+
+	return append(metrics, metric{
+		name:  name,
+		attr:  attr,
+		value: *value,
+		help:  help,
+	})
+}
+
+// parseSmartctlOutput parses the output of `smartctl -i -H -A` for ATA, SCSI and NVMe devices
+func parseSmartctlOutput(output string) smartInfo {
+	var info smartInfo
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+
+		if key, value, found := strings.Cut(line, ":"); found {
+			value = strings.TrimSpace(value)
+
+			switch key {
+			case "Device Model", "Model Number", "Product":
+				info.model = value
+				continue
+			case "Serial Number", "Serial number":
+				info.serial = value
+				continue
+			case "SMART overall-health self-assessment test result", "SMART Health Status":
+				info.healthKnown = true
+				info.healthy = value == "PASSED" || value == "OK"
+				continue
+			case "Temperature", "Current Drive Temperature":
+				info.temperature = parseLeadingNumber(value)
+				continue
+			case "Power On Hours":
+				info.powerOnHours = parseLeadingNumber(value)
+				continue
+			case "Accumulated power on time, hours":
+				// e.g. "minutes 1234:56"
+				info.powerOnHours = parseLeadingNumber(strings.TrimPrefix(value, "minutes "))
+				continue
+			case "Elements in grown defect list":
+				info.reallocated = parseLeadingNumber(value)
+				continue
+			}
+		}
+
+		// ATA attribute table, e.g.:
+		// ID# ATTRIBUTE_NAME          FLAG     VALUE WORST THRESH TYPE      UPDATED  WHEN_FAILED RAW_VALUE
+		//   5 Reallocated_Sector_Ct   0x0033   100   100   010    Pre-fail  Always       -       0
+		fields := strings.Fields(line)
+		if len(fields) < 10 {
+			continue
+		}
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+
+		raw := parseLeadingNumber(fields[9])
+		switch id {
+		case 5:
+			info.reallocated = raw
+		case 9:
+			info.powerOnHours = raw
+		case 197:
+			info.pending = raw
+		case 194:
+			info.temperature = raw
+		case 190:
+			if info.temperature == nil {
+				info.temperature = raw
+			}
+		}
+	}
+
+	return info
+}
+
+// parseLeadingNumber parses the number at the start of s (e.g. "35 Celsius", "1,234", "12345h+12m"),
+// returning nil if s does not start with a number
+func parseLeadingNumber(s string) *float64 {
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != ','
+	})
+	if end == -1 {
+		end = len(s)
+	}
+
+	// Drop thousands separators
+	value, err := strconv.ParseFloat(strings.ReplaceAll(s[:end], ",", ""), 64)
+	if err != nil {
+		return nil
+	}
+
+	return &value
+}
@@ -0,0 +1,114 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSmartctlOutput(t *testing.T) {
+	testCases := map[string]struct {
+		output               string
+		expectedModel        string
+		expectedSerial       string
+		expectedHealthy      bool
+		expectedReallocated  float64
+		expectedPending      *float64
+		expectedPowerOnHours float64
+		expectedTemperature  float64
+	}{
+		"ATA device": {
+			output: `smartctl 6.5 2016-05-07 r4318 [x86_64-linux-5.10.60-qnap] (local build)
+
+=== START OF INFORMATION SECTION ===
+Device Model:     WDC WD40EFRX-68N32N0
+Serial Number:    WD-WCC7K1234567
+User Capacity:    4,000,787,030,016 bytes [4.00 TB]
+
+=== START OF READ SMART DATA SECTION ===
+SMART overall-health self-assessment test result: PASSED
+
+ID# ATTRIBUTE_NAME          FLAG     VALUE WORST THRESH TYPE      UPDATED  WHEN_FAILED RAW_VALUE
+  1 Raw_Read_Error_Rate     0x002f   200   200   051    Pre-fail  Always       -       0
+  5 Reallocated_Sector_Ct   0x0033   200   200   140    Pre-fail  Always       -       8
+  9 Power_On_Hours          0x0032   040   040   000    Old_age   Always       -       43817
+194 Temperature_Celsius     0x0022   116   101   000    Old_age   Always       -       34 (Min/Max 20/45)
+197 Current_Pending_Sector  0x0032   200   200   000    Old_age   Always       -       2`,
+			expectedModel:        "WDC WD40EFRX-68N32N0",
+			expectedSerial:       "WD-WCC7K1234567",
+			expectedHealthy:      true,
+			expectedReallocated:  8,
+			expectedPending:      func() *float64 { v := 2.0; return &v }(),
+			expectedPowerOnHours: 43817,
+			expectedTemperature:  34,
+		},
+		"NVMe device": {
+			output: `=== START OF INFORMATION SECTION ===
+Model Number:                       Samsung SSD 970 EVO Plus 500GB
+Serial Number:                      S4EVNX0N123456
+
+=== START OF SMART DATA SECTION ===
+SMART overall-health self-assessment test result: FAILED!
+Temperature:                        41 Celsius
+Power On Hours:                     12,345
+Media and Data Integrity Errors:    0`,
+			expectedModel:        "Samsung SSD 970 EVO Plus 500GB",
+			expectedSerial:       "S4EVNX0N123456",
+			expectedPowerOnHours: 12345,
+			expectedTemperature:  41,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			info := parseSmartctlOutput(tc.output)
+
+			assert.Equal(t, tc.expectedModel, info.model)
+			assert.Equal(t, tc.expectedSerial, info.serial)
+			assert.True(t, info.healthKnown)
+			assert.Equal(t, tc.expectedHealthy, info.healthy)
+			assert.Equal(t, tc.expectedPending, info.pending)
+			require.NotNil(t, info.powerOnHours)
+			assert.Equal(t, tc.expectedPowerOnHours, *info.powerOnHours)
+			require.NotNil(t, info.temperature)
+			assert.Equal(t, tc.expectedTemperature, *info.temperature)
+			if tc.expectedReallocated != 0 {
+				require.NotNil(t, info.reallocated)
+				assert.Equal(t, tc.expectedReallocated, *info.reallocated)
+			}
+		})
+	}
+}
+
+func TestGetSmartDeltaMetrics(t *testing.T) {
+	now := time.Date(2022, 1, 10, 0, 0, 0, 0, time.UTC)
+	samples := []smartSample{
+		{disk: "WD-1234", attr: `device="sda",serial="WD-1234"`, attribute: "reallocated_sectors", value: 12},
+		{disk: "sdb", attr: `device="sdb",serial=""`, attribute: "pending_sectors", value: 3},
+	}
+	state := exporterState{
+		SmartBaselines: map[string]stateSample{
+			"WD-1234/reallocated_sectors": {Value: 8, Time: now.Add(-48 * time.Hour)},
+		},
+	}
+
+	metrics, changed := getSmartDeltaMetrics(&state, samples, now)
+
+	assert.True(t, changed)
+	assert.Equal(t, stateSample{Value: 3, Time: now}, state.SmartBaselines["sdb/pending_sectors"])
+	require.Len(t, metrics, 3)
+	assert.Equal(t, "node_disk_smart_attribute_delta", metrics[0].name)
+	assert.Equal(t, `device="sda",serial="WD-1234",attribute="reallocated_sectors"`, metrics[0].attr)
+	assert.Equal(t, 4.0, metrics[0].value)
+	assert.Equal(t, "node_disk_smart_attribute_delta_per_day", metrics[1].name)
+	assert.Equal(t, 2.0, metrics[1].value)
+	// The daily rate is not reported for a baseline which was just recorded
+	assert.Equal(t, "node_disk_smart_attribute_delta", metrics[2].name)
+	assert.Equal(t, `device="sdb",serial="",attribute="pending_sectors"`, metrics[2].attr)
+	assert.Equal(t, 0.0, metrics[2].value)
+
+	_, changed = getSmartDeltaMetrics(&state, samples, now)
+	assert.False(t, changed)
+}
@@ -0,0 +1,106 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+const (
+	// qnapSmbclientPath is where QTS installs smbclient, which is usually not in the PATH
+	qnapSmbclientPath = "/usr/local/samba/bin/smbclient"
+
+	// smbProbeFile is the file written to and read back from the probe share
+	smbProbeFile = ".qnapexporter-probe"
+	smbProbeSize = 4096
+)
+
+// getSmbProbeMetrics writes a small file to the probe share through smbclient, reads it back and deletes it,
+// verifying the whole file serving path, from authentication to storage
+func (e *promExporter) getSmbProbeMetrics(ctx context.Context) ([]metric, error) {
+	if e.SmbProbeShare == "" {
+		return nil, nil
+	}
+	if e.smbclient == "" {
+		return nil, subsystemAbsentError{"smbclient not found"}
+	}
+
+	start := time.Now()
+	err := smbProbe(e.sys, ctx, e.smbclient, e.SmbProbeShare, e.SmbProbeUser, e.SmbProbePassword)
+	duration := time.Since(start)
+
+	var success float64 = 1
+	if err != nil {
+		success = 0
+		err = fmt.Errorf("probe %s: %w", e.SmbProbeShare, err)
+	}
+
+	attr := fmt.Sprintf("share=%q", e.SmbProbeShare)
+	return []metric{
+		{
+			name:  "node_smb_probe_success",
+			attr:  attr,
+			value: success,
+			help:  "Whether a file could be written to and read back from the probe share over SMB",
+		},
+		{
+			name:  "node_smb_probe_duration_seconds",
+			attr:  attr,
+			value: duration.Seconds(),
+			help:  "Time taken to write, read back and delete a file on the probe share over SMB",
+		},
+	}, err
+}
+
+func smbProbe(sys utils.System, ctx context.Context, smbclient string, share string, user string, password string) error {
+	dir, err := os.MkdirTemp("", "qnapexporter-smbprobe")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	data := make([]byte, smbProbeSize)
+	if _, err := rand.Read(data); err != nil {
+		return err
+	}
+	localPath := filepath.Join(dir, "probe.dat")
+	readbackPath := filepath.Join(dir, "readback.dat")
+	if err := os.WriteFile(localPath, data, 0o600); err != nil {
+		return err
+	}
+
+	args := []string{share, "-c", fmt.Sprintf(`put "%s" %s; get %s "%s"; del %s`, localPath, smbProbeFile, smbProbeFile, readbackPath, smbProbeFile)}
+	// smbclient reads the credentials from the environment, so that the password doesn't appear in the process list
+	var env []string
+	if user != "" {
+		env = []string{"USER=" + user, "PASSWD=" + password}
+	} else {
+		args = append(args, "-N")
+	}
+
+	output, err := sys.ExecCommandWithEnv(ctx, env, smbclient, args...)
+	if err != nil {
+		if lines := strings.Split(output, "\n"); output != "" {
+			return fmt.Errorf("%w: %s", err, lines[len(lines)-1])
+		}
+		return err
+	}
+
+	readback, err := os.ReadFile(readbackPath)
+	if err != nil {
+		return fmt.Errorf("read back probe file: %w", err)
+	}
+	if !bytes.Equal(readback, data) {
+		return errors.New("probe file read back differs from the one written")
+	}
+
+	return nil
+}
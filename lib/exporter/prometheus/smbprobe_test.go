@@ -0,0 +1,85 @@
+package prometheus
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSmbProbeMetrics(t *testing.T) {
+	// Fake smbclient, which copies the file written by the probe to the read back path
+	smbclient := filepath.Join(t.TempDir(), "smbclient")
+	script := `#!/bin/sh
+if [ "$1" != "//127.0.0.1/probe" ] || [ "$PASSWD" != "secret" ]; then
+  echo "session setup failed: NT_STATUS_LOGON_FAILURE"
+  exit 1
+fi
+dir=$(echo "$3" | sed -n 's|^put "\(.*\)/probe.dat".*|\1|p')
+if [ -n "$CORRUPT" ]; then
+  echo "corrupt" > "$dir/readback.dat"
+else
+  cp "$dir/probe.dat" "$dir/readback.dat"
+fi
+`
+	require.NoError(t, os.WriteFile(smbclient, []byte(script), 0o755))
+
+	testCases := map[string]struct {
+		share       string
+		password    string
+		corrupt     bool
+		wantSuccess float64
+		wantErr     string
+	}{
+		"success": {
+			share:       "//127.0.0.1/probe",
+			password:    "secret",
+			wantSuccess: 1,
+		},
+		"authentication failure": {
+			share:    "//127.0.0.1/probe",
+			password: "wrong",
+			wantErr:  "probe //127.0.0.1/probe: exit status 1: session setup failed: NT_STATUS_LOGON_FAILURE",
+		},
+		"corrupt read back": {
+			share:    "//127.0.0.1/probe",
+			password: "secret",
+			corrupt:  true,
+			wantErr:  "probe //127.0.0.1/probe: probe file read back differs from the one written",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if tc.corrupt {
+				t.Setenv("CORRUPT", "1")
+			}
+			e := &promExporter{
+				ExporterConfig: ExporterConfig{SmbProbeShare: tc.share, SmbProbeUser: "probe", SmbProbePassword: tc.password},
+				smbclient:      smbclient,
+			}
+
+			metrics, err := e.getSmbProbeMetrics(context.Background())
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			require.Len(t, metrics, 2)
+			assert.Equal(t, "node_smb_probe_success", metrics[0].name)
+			assert.Equal(t, `share="//127.0.0.1/probe"`, metrics[0].attr)
+			assert.Equal(t, tc.wantSuccess, metrics[0].value)
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		e := &promExporter{smbclient: smbclient}
+
+		metrics, err := e.getSmbProbeMetrics(context.Background())
+		assert.NoError(t, err)
+		assert.Empty(t, metrics)
+	})
+}
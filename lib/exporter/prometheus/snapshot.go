@@ -0,0 +1,180 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+type snapshotSpace struct {
+	count         int
+	reservedBytes *float64
+	usedBytes     *float64
+}
+
+func (e *promExporter) getSnapshotMetrics(ctx context.Context) ([]metric, error) {
+	if e.qcliSnapshot == "" {
+		return nil, nil
+	}
+
+	// List all the snapshots, e.g.:
+	//
+	//	SnapshotID VolumeID Name                   Created             Expires
+	//	1          1        GMT+01_2023-01-01_0000 2023/01/01 00:00:00 Never
+	listOutput, err := e.sys.ExecCommand(ctx, e.qcliSnapshot, "-l")
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := map[string]*snapshotSpace{}
+	for _, row := range parseTable(listOutput) {
+		volumeID := row["volumeid"]
+		if volumeID == "" {
+			continue
+		}
+		if volumes[volumeID] == nil {
+			volumes[volumeID] = &snapshotSpace{}
+		}
+		volumes[volumeID].count++
+	}
+
+	// Retrieve the snapshot space of each volume, e.g.:
+	//
+	//	VolumeID Reserved  Used
+	//	1        100.00 GB 12.50 GB
+	spaceOutput, err := e.sys.ExecCommand(ctx, e.qcliSnapshot, "-s")
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range parseTable(spaceOutput) {
+		volumeID := row["volumeid"]
+		if volumeID == "" {
+			continue
+		}
+		if volumes[volumeID] == nil {
+			volumes[volumeID] = &snapshotSpace{}
+		}
+		volumes[volumeID].reservedBytes = parseSnapshotSize(row["reserved"])
+		volumes[volumeID].usedBytes = parseSnapshotSize(row["used"])
+	}
+
+	volumeIDs := make([]string, 0, len(volumes))
+	for id := range volumes {
+		volumeIDs = append(volumeIDs, id)
+	}
+	sort.Strings(volumeIDs)
+
+	metrics := make([]metric, 0, len(volumes)*3)
+	for _, id := range volumeIDs {
+		v := volumes[id]
+		attr := fmt.Sprintf("volume_id=%q", id)
+
+		metrics = append(metrics, metric{
+			name:  "node_volume_snapshot_count",
+			attr:  attr,
+			value: float64(v.count),
+			help:  "Number of snapshots of the volume",
+		})
+		if v.reservedBytes != nil {
+			metrics = append(metrics, metric{
+				name:  "node_volume_snapshot_reserved_bytes",
+				attr:  attr,
+				value: *v.reservedBytes,
+				help:  "Space reserved for the snapshots of the volume",
+			})
+		}
+		if v.usedBytes != nil {
+			metrics = append(metrics, metric{
+				name:  "node_volume_snapshot_used_bytes",
+				attr:  attr,
+				value: *v.usedBytes,
+				help:  "Space used by the snapshots of the volume",
+			})
+		}
+	}
+
+	return metrics, nil
+}
+
+// parseTable parses the column-aligned tables printed by the QNAP qcli_* tools,
+// returning a map per row keyed by the lowercase column header.
+// Columns are delimited by the header positions, so that values may contain spaces.
+func parseTable(output string) []map[string]string {
+	lines := strings.Split(output, "\n")
+	headerIdx := -1
+	for idx, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			headerIdx = idx
+			break
+		}
+	}
+	if headerIdx == -1 {
+		return nil
+	}
+
+	type column struct {
+		name  string
+		start int
+	}
+	var columns []column
+	header := lines[headerIdx]
+	for idx := 0; idx < len(header); {
+		if header[idx] == ' ' || header[idx] == '\t' {
+			idx++
+			continue
+		}
+
+		end := strings.IndexAny(header[idx:], " \t")
+		if end == -1 {
+			end = len(header) - idx
+		}
+		columns = append(columns, column{name: strings.ToLower(header[idx : idx+end]), start: idx})
+		idx += end
+	}
+
+	var rows []map[string]string
+	for _, line := range lines[headerIdx+1:] {
+		if strings.TrimSpace(line) == "" || strings.Trim(line, "-= \t") == "" {
+			continue
+		}
+
+		row := make(map[string]string, len(columns))
+		for idx, c := range columns {
+			if c.start >= len(line) {
+				break
+			}
+			end := len(line)
+			if idx+1 < len(columns) && columns[idx+1].start < end {
+				end = columns[idx+1].start
+			}
+			row[c.name] = strings.TrimSpace(line[c.start:end])
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// parseSnapshotSize parses a size such as "12.50 GB" or a plain number of bytes,
+// returning nil if s is not a size
+func parseSnapshotSize(s string) *float64 {
+	if s == "" {
+		return nil
+	}
+
+	var size float64
+	var err error
+	if len(strings.Fields(s)) == 2 {
+		size, err = parseVolSize(s)
+	} else {
+		size, err = utils.ParseFloat(s)
+	}
+	if err != nil {
+		return nil
+	}
+
+	return &size
+}
@@ -0,0 +1,58 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTable(t *testing.T) {
+	testCases := map[string]struct {
+		output   string
+		expected []map[string]string
+	}{
+		"empty": {
+			output: "",
+		},
+		"snapshot list": {
+			output: `SnapshotID VolumeID Name                   Created
+1          1        GMT+01_2023-01-01_0000 2023/01/01 00:00:00
+2          2        manual snapshot        2023/01/02 00:00:00
+`,
+			expected: []map[string]string{
+				{"snapshotid": "1", "volumeid": "1", "name": "GMT+01_2023-01-01_0000", "created": "2023/01/01 00:00:00"},
+				{"snapshotid": "2", "volumeid": "2", "name": "manual snapshot", "created": "2023/01/02 00:00:00"},
+			},
+		},
+		"snapshot space with separator": {
+			output: `
+VolumeID Reserved  Used
+-------- --------- --------
+1        100.00 GB 12.50 GB
+`,
+			expected: []map[string]string{
+				{"volumeid": "1", "reserved": "100.00 GB", "used": "12.50 GB"},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, parseTable(tc.output))
+		})
+	}
+}
+
+func TestParseSnapshotSize(t *testing.T) {
+	assert.Nil(t, parseSnapshotSize(""))
+	assert.Nil(t, parseSnapshotSize("N/A"))
+
+	size := parseSnapshotSize("12.50 GB")
+	require.NotNil(t, size)
+	assert.Equal(t, 12.5*1024*1024*1024, *size)
+
+	size = parseSnapshotSize("4096")
+	require.NotNil(t, size)
+	assert.Equal(t, 4096.0, *size)
+}
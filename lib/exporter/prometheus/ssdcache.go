@@ -0,0 +1,237 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+// ssdCacheStats holds the statistics of an SSD cache group, whichever layout (flashcache or dm-cache) it uses.
+// The optional statistics are nil when the layout does not report them.
+type ssdCacheStats struct {
+	group  string
+	volume string
+
+	reads, readHits   float64
+	writes, writeHits float64
+
+	dirtyBlocks           *float64
+	promotions, demotions *float64
+	usedBlocks, allBlocks *float64
+}
+
+// getSsdCacheMetrics reports the statistics of every SSD cache group, from the flashcache statistics
+// used up to QTS 4 and the dm-cache targets used since
+func (e *promExporter) getSsdCacheMetrics(ctx context.Context) ([]metric, error) {
+	caches, err := readFlashcacheGroups(e.sys, flashcacheGroupsGlob)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only query dmsetup if there are device-mapper devices, since it fails without the dm driver
+	dmDevices := dmDeviceNames(e.sys, e.blockDir)
+	if len(dmDevices) > 0 {
+		dmCaches, err := readDmCacheTargets(e.sys, ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		stacks := e.volumeDeviceStacks(ctx)
+		for idx := range dmCaches {
+			dmCaches[idx].volume = volumeOfDevice(stacks, dmDevices[dmCaches[idx].group])
+		}
+		caches = append(caches, dmCaches...)
+	}
+
+	if len(caches) == 0 {
+		return nil, subsystemAbsentError{"no SSD cache found"}
+	}
+
+	metrics := make([]metric, 0, 13*len(caches))
+	for _, c := range caches {
+		metrics = append(metrics, c.metrics()...)
+	}
+
+	return metrics, nil
+}
+
+// readFlashcacheGroups reads the statistics of each flashcache cache group (e.g. CG0, CG1) matching pattern
+func readFlashcacheGroups(sys utils.System, pattern string) ([]ssdCacheStats, error) {
+	paths, err := sys.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	caches := make([]ssdCacheStats, 0, len(paths))
+	for _, path := range paths {
+		lines, err := sys.ReadFileLines(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// The cache group was removed
+				continue
+			}
+
+			return nil, err
+		}
+
+		caches = append(caches, parseFlashcacheStats(filepath.Base(filepath.Dir(path)), lines))
+	}
+
+	return caches, nil
+}
+
+// parseFlashcacheStats parses the `name:value` lines of a flashcache_stats file, ignoring unknown statistics
+func parseFlashcacheStats(group string, lines []string) ssdCacheStats {
+	s := ssdCacheStats{group: group}
+	for _, line := range lines {
+		name, valueStr, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value, err := utils.ParseFloat(valueStr)
+		if err != nil {
+			continue
+		}
+
+		switch strings.TrimSpace(name) {
+		case "reads":
+			s.reads = value
+		case "read_hits":
+			s.readHits = value
+		case "writes":
+			s.writes = value
+		case "write_hits":
+			s.writeHits = value
+		case "nr_dirty", "dirty_blocks":
+			s.dirtyBlocks = &value
+		}
+	}
+
+	return s
+}
+
+// readDmCacheTargets reads the statistics of every dm-cache target from dmsetup
+func readDmCacheTargets(sys utils.System, ctx context.Context) ([]ssdCacheStats, error) {
+	lines, err := sys.ExecCommandGetLines(ctx, "dmsetup", "status", "--target", "cache")
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("get dm-cache status (dmsetup status --target cache): %w", err)
+	}
+
+	var caches []ssdCacheStats
+	for _, line := range lines {
+		if s, ok := parseDmCacheStatus(line); ok {
+			caches = append(caches, s)
+		}
+	}
+
+	return caches, nil
+}
+
+// parseDmCacheStatus parses a line of `dmsetup status` for a dm-cache target, e.g.:
+// cachedev1: 0 209715200 cache 8 1234/5678 2048 100/1000 <read hits> <read misses> <write hits> <write misses> <demotions> <promotions> <dirty> ...
+func parseDmCacheStatus(line string) (ssdCacheStats, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 15 || !strings.HasSuffix(fields[0], ":") || fields[3] != "cache" {
+		return ssdCacheStats{}, false
+	}
+
+	s := ssdCacheStats{group: strings.TrimSuffix(fields[0], ":")}
+	values := make([]float64, 0, 7)
+	for _, f := range fields[8:15] {
+		value, err := utils.ParseFloat(f)
+		if err != nil {
+			return ssdCacheStats{}, false
+		}
+		values = append(values, value)
+	}
+	readMisses, writeMisses := values[1], values[3]
+	s.readHits, s.writeHits = values[0], values[2]
+	s.reads, s.writes = s.readHits+readMisses, s.writeHits+writeMisses
+	s.demotions, s.promotions, s.dirtyBlocks = &values[4], &values[5], &values[6]
+
+	if used, all, found := strings.Cut(fields[7], "/"); found {
+		usedBlocks, usedErr := utils.ParseFloat(used)
+		allBlocks, allErr := utils.ParseFloat(all)
+		if usedErr == nil && allErr == nil {
+			s.usedBlocks, s.allBlocks = &usedBlocks, &allBlocks
+		}
+	}
+
+	return s, true
+}
+
+// dmDeviceNames maps the name of each device-mapper device (e.g. cachedev1) to its kernel name (e.g. dm-0)
+func dmDeviceNames(sys utils.System, root string) map[string]string {
+	names := make(map[string]string)
+	paths, _ := sys.Glob(filepath.Join(root, "dm-*", "dm", "name"))
+	for _, path := range paths {
+		name, err := sys.ReadFile(path)
+		if err == nil {
+			names[name] = filepath.Base(filepath.Dir(filepath.Dir(path)))
+		}
+	}
+
+	return names
+}
+
+func volumeOfDevice(stacks map[string][]string, device string) string {
+	if device == "" {
+		return ""
+	}
+
+	for volume, stack := range stacks {
+		if containsString(stack, device) {
+			return volume
+		}
+	}
+
+	return ""
+}
+
+func (s ssdCacheStats) metrics() []metric {
+	attr := fmt.Sprintf("cache_group=%q,volume=%q", s.group, s.volume)
+	metrics := []metric{
+		{name: "node_ssd_cache_reads_total", attr: attr, value: s.reads, help: "Number of reads from the cached volume", metricType: "counter"},
+		{name: "node_ssd_cache_read_hits_total", attr: attr, value: s.readHits, help: "Number of reads served by the SSD cache", metricType: "counter"},
+		{name: "node_ssd_cache_writes_total", attr: attr, value: s.writes, help: "Number of writes to the cached volume", metricType: "counter"},
+		{name: "node_ssd_cache_write_hits_total", attr: attr, value: s.writeHits, help: "Number of writes to blocks resident in the SSD cache", metricType: "counter"},
+	}
+	if s.reads > 0 {
+		metrics = append(metrics, metric{name: "node_ssd_cache_read_hit_ratio", attr: attr, value: s.readHits / s.reads, help: "Ratio of reads served by the SSD cache"})
+	}
+	if s.writes > 0 {
+		metrics = append(metrics, metric{name: "node_ssd_cache_write_hit_ratio", attr: attr, value: s.writeHits / s.writes, help: "Ratio of writes to blocks resident in the SSD cache"})
+	}
+
+	optional := []struct {
+		name       string
+		value      *float64
+		help       string
+		metricType string
+	}{
+		{name: "node_ssd_cache_dirty_blocks", value: s.dirtyBlocks, help: "Number of cache blocks not yet written back to the volume"},
+		{name: "node_ssd_cache_promotions_total", value: s.promotions, help: "Number of blocks promoted to the SSD cache", metricType: "counter"},
+		{name: "node_ssd_cache_demotions_total", value: s.demotions, help: "Number of blocks demoted from the SSD cache", metricType: "counter"},
+		{name: "node_ssd_cache_used_blocks", value: s.usedBlocks, help: "Number of blocks resident in the SSD cache"},
+		{name: "node_ssd_cache_blocks", value: s.allBlocks, help: "Total number of blocks in the SSD cache"},
+	}
+	for _, o := range optional {
+		if o.value != nil {
+			metrics = append(metrics, metric{name: o.name, attr: attr, value: *o.value, help: o.help, metricType: o.metricType})
+		}
+	}
+
+	return metrics
+}
@@ -0,0 +1,100 @@
+package prometheus
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDmCacheStatus(t *testing.T) {
+	tests := map[string]struct {
+		line     string
+		expected *ssdCacheStats
+	}{
+		"cache target": {
+			line: "CG0cachedev1: 0 209715200 cache 8 1234/5678 2048 100/1000 30 10 5 15 2 7 4 1 writeback 2 migration_threshold 2048 smq 0 rw -",
+			expected: &ssdCacheStats{
+				group:       "CG0cachedev1",
+				reads:       40,
+				readHits:    30,
+				writes:      20,
+				writeHits:   5,
+				demotions:   floatPtr(2),
+				promotions:  floatPtr(7),
+				dirtyBlocks: floatPtr(4),
+				usedBlocks:  floatPtr(100),
+				allBlocks:   floatPtr(1000),
+			},
+		},
+		"other target": {line: "cachedev1: 0 209715200 linear"},
+		"no devices":   {line: "No devices found"},
+		"truncated":    {line: "cachedev1: 0 209715200 cache 8 1234/5678 2048 100/1000 30 10 5"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, ok := parseDmCacheStatus(tc.line)
+			if tc.expected == nil {
+				assert.False(t, ok)
+				return
+			}
+
+			require.True(t, ok)
+			assert.Equal(t, *tc.expected, s)
+		})
+	}
+}
+
+func TestReadFlashcacheGroups(t *testing.T) {
+	root := t.TempDir()
+	writeSysfsFiles(t, root, map[string]string{
+		"CG0/flashcache_stats": "reads:100\nread_hits:25\nwrites:50\nwrite_hits:10\nreplacement:3\n",
+		"CG1/flashcache_stats": "reads:0\nwrites:4\nwrite_hits:1\nnr_dirty:6\n",
+	})
+
+	caches, err := readFlashcacheGroups(utils.System{}, filepath.Join(root, "*", "flashcache_stats"))
+	require.NoError(t, err)
+	require.Len(t, caches, 2)
+	assert.Equal(t, ssdCacheStats{group: "CG0", reads: 100, readHits: 25, writes: 50, writeHits: 10}, caches[0])
+
+	metrics := caches[1].metrics()
+	names := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		names = append(names, m.name)
+		assert.Equal(t, `cache_group="CG1",volume=""`, m.attr)
+	}
+	// No read hit ratio without reads, and no dm-cache specific statistics
+	assert.Equal(t, []string{
+		"node_ssd_cache_reads_total",
+		"node_ssd_cache_read_hits_total",
+		"node_ssd_cache_writes_total",
+		"node_ssd_cache_write_hits_total",
+		"node_ssd_cache_write_hit_ratio",
+		"node_ssd_cache_dirty_blocks",
+	}, names)
+	assert.Equal(t, 0.25, metrics[4].value)
+	assert.Equal(t, 6.0, metrics[5].value)
+}
+
+func TestDmDeviceNames(t *testing.T) {
+	root := t.TempDir()
+	writeSysfsFiles(t, root, map[string]string{
+		"dm-0/dm/name": "vg1-lv1\n",
+		"dm-3/dm/name": "cachedev1\n",
+		"sda/size":     "100\n",
+	})
+
+	names := dmDeviceNames(utils.System{}, root)
+	assert.Equal(t, map[string]string{"vg1-lv1": "dm-0", "cachedev1": "dm-3"}, names)
+
+	stacks := map[string][]string{"DataVol1": {"dm-3", "dm-0", "md1", "sda3", "sda"}}
+	assert.Equal(t, "DataVol1", volumeOfDevice(stacks, names["cachedev1"]))
+	assert.Equal(t, "", volumeOfDevice(stacks, names["missing"]))
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,64 @@
+package prometheus
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// staleCache keeps the last metrics successfully retrieved by each collector, which are served for a bounded
+// time when the collector fails transiently, to avoid gaps in the series
+type staleCache struct {
+	mu      sync.Mutex
+	results map[string]staleResult
+}
+
+type staleResult struct {
+	metrics   []metric
+	timestamp time.Time
+}
+
+func (c *staleCache) store(collector string, metrics []metric, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.results == nil {
+		c.results = make(map[string]staleResult)
+	}
+	c.results[collector] = staleResult{metrics: append([]metric(nil), metrics...), timestamp: now}
+}
+
+// lookup returns the last metrics retrieved by collector, if they are not older than maxAge
+func (c *staleCache) lookup(collector string, maxAge time.Duration, now time.Time) ([]metric, time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, found := c.results[collector]
+	if !found {
+		return nil, 0, false
+	}
+
+	age := now.Sub(r.timestamp)
+	if age > maxAge {
+		return nil, 0, false
+	}
+
+	// Copy the metrics, since the caller appends to them
+	return append([]metric(nil), r.metrics...), age, true
+}
+
+func (c *staleCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.results = nil
+}
+
+func staleMetric(collector string, age time.Duration) metric {
+	return metric{
+		name:  "qnapexporter_collector_stale_seconds",
+		attr:  fmt.Sprintf("collector=%q", collector),
+		value: age.Seconds(),
+		help:  "Age of the metrics served for the collector, which is 0 unless the last-known-good metrics are served after a failure",
+	}
+}
@@ -0,0 +1,90 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaleCache(t *testing.T) {
+	now := time.Now()
+	var c staleCache
+	c.store("cpu", []metric{{name: "node_cpu_seconds_total", value: 1}}, now)
+
+	testCases := map[string]struct {
+		collector   string
+		at          time.Time
+		wantMetrics []metric
+		wantAge     time.Duration
+		wantFound   bool
+	}{
+		"fresh": {
+			collector:   "cpu",
+			at:          now.Add(time.Minute),
+			wantMetrics: []metric{{name: "node_cpu_seconds_total", value: 1}},
+			wantAge:     time.Minute,
+			wantFound:   true,
+		},
+		"unknown collector": {
+			collector: "meminfo",
+			at:        now,
+		},
+		"too old": {
+			collector: "cpu",
+			at:        now.Add(10 * time.Minute),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			metrics, age, found := c.lookup(tc.collector, 5*time.Minute, tc.at)
+
+			assert.Equal(t, tc.wantFound, found)
+			assert.Equal(t, tc.wantMetrics, metrics)
+			assert.Equal(t, tc.wantAge, age)
+		})
+	}
+}
+
+func TestWriteMetricsServesStaleValues(t *testing.T) {
+	var fail bool
+	e := &promExporter{
+		ExporterConfig: ExporterConfig{
+			StaleValueMaxAge: time.Hour,
+			Logger:           logging.Discard(),
+		},
+		hostname:  "nas",
+		envExpiry: time.Now().Add(time.Hour),
+		watchdog:  newWatchdog(0, nil, nil),
+	}
+	e.fns = []collector{
+		{
+			name: "flaky",
+			fn: func(context.Context) ([]metric, error) {
+				if fail {
+					return nil, errors.New("getsysinfo hiccup")
+				}
+				return []metric{{name: "node_test", value: 42}}, nil
+			},
+		},
+	}
+
+	b := new(bytes.Buffer)
+	require.NoError(t, e.WriteMetrics(context.Background(), b))
+	assert.Contains(t, b.String(), `qnapexporter_collector_stale_seconds{node="nas",collector="flaky"} 0`)
+
+	fail = true
+	b.Reset()
+	require.NoError(t, e.WriteMetrics(context.Background(), b))
+
+	output := b.String()
+	assert.Contains(t, output, `node_test{node="nas"} 42`)
+	assert.Contains(t, output, `qnap_exporter_collector_success{node="nas",collector="flaky"} 0`)
+	assert.NotContains(t, output, `qnapexporter_collector_stale_seconds{node="nas",collector="flaky"} 0`+"\n")
+}
@@ -0,0 +1,121 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// stateSaveInterval is how often the counters accumulated over every scrape (e.g. the energy) are written to the
+// state file, so that it isn't rewritten on every scrape
+const stateSaveInterval = 10 * time.Minute
+
+// exporterState holds the values which must survive exporter restarts
+type exporterState struct {
+	// SmartBaselines maps "<disk>/<attribute>" to the first value seen for that S.M.A.R.T. attribute
+	SmartBaselines map[string]stateSample `json:"smart_baselines,omitempty"`
+	// Watermarks holds the highest or lowest values seen since boot (e.g. the CPU temperature)
+	Watermarks *watermarkState `json:"watermarks,omitempty"`
+	// TemperatureHistory maps each disk to its recent temperature samples, oldest first
+	TemperatureHistory map[string][]stateSample `json:"temperature_history,omitempty"`
+	// VolumeUsageHistory maps each volume to its recent used space samples, oldest first
+	VolumeUsageHistory map[string][]stateSample `json:"volume_usage_history,omitempty"`
+	// Energy holds the cumulative energy consumed and its cost
+	Energy *energyState `json:"energy,omitempty"`
+	// Traffic holds the traffic of each interface in the current monthly accounting period
+	Traffic *trafficState `json:"traffic,omitempty"`
+}
+
+type stateSample struct {
+	Value float64   `json:"value"`
+	Time  time.Time `json:"time"`
+}
+
+// stateStore persists the exporter state as JSON in a file. If the path is empty, the state is only kept in memory.
+type stateStore struct {
+	path string
+
+	mu     sync.Mutex
+	loaded bool
+	state  exporterState
+}
+
+func newStateStore(path string) *stateStore {
+	return &stateStore{path: path}
+}
+
+// update calls fn with the current state, loading it from disk first if needed, and saves the result
+func (s *stateStore) update(fn func(state *exporterState) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	if !s.loaded {
+		// Always mark the state as loaded, so that a corrupt file is overwritten instead of failing every scrape
+		s.loaded = true
+		err = s.load()
+	}
+
+	if !fn(&s.state) || s.path == "" {
+		return err
+	}
+
+	if saveErr := s.save(); saveErr != nil {
+		return saveErr
+	}
+
+	return err
+}
+
+func (s *stateStore) load() error {
+	if s.path == "" {
+		return nil
+	}
+
+	contents, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return fmt.Errorf("read state file: %w", err)
+	}
+
+	if err := json.Unmarshal(contents, &s.state); err != nil {
+		return fmt.Errorf("parse state file %s: %w", s.path, err)
+	}
+
+	return nil
+}
+
+// save writes the state to a temporary file which then replaces the state file,
+// so that a crash never leaves a truncated file behind
+func (s *stateStore) save() error {
+	contents, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("write state file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(contents); err != nil {
+		f.Close()
+		return fmt.Errorf("write state file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write state file: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), s.path); err != nil {
+		return fmt.Errorf("write state file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package prometheus
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	baseline := stateSample{Value: 8, Time: time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	s := newStateStore(path)
+	err := s.update(func(state *exporterState) bool {
+		state.SmartBaselines = map[string]stateSample{"WD-1234/reallocated_sectors": baseline}
+		return true
+	})
+	require.NoError(t, err)
+
+	// A new store must read back the persisted state
+	s = newStateStore(path)
+	err = s.update(func(state *exporterState) bool {
+		assert.Equal(t, map[string]stateSample{"WD-1234/reallocated_sectors": baseline}, state.SmartBaselines)
+		return false
+	})
+	require.NoError(t, err)
+
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp"))
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestStateStoreCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+
+	s := newStateStore(path)
+	err := s.update(func(state *exporterState) bool { return false })
+	assert.Error(t, err)
+
+	// The corrupt file is only reported once, and is overwritten on the next change
+	err = s.update(func(state *exporterState) bool {
+		state.SmartBaselines = map[string]stateSample{"sda/pending_sectors": {Value: 1}}
+		return true
+	})
+	require.NoError(t, err)
+
+	s = newStateStore(path)
+	assert.NoError(t, s.update(func(state *exporterState) bool { return false }))
+}
+
+func TestStateStoreInMemory(t *testing.T) {
+	s := newStateStore("")
+	err := s.update(func(state *exporterState) bool {
+		state.SmartBaselines = map[string]stateSample{"sda/pending_sectors": {Value: 1}}
+		return true
+	})
+	require.NoError(t, err)
+
+	err = s.update(func(state *exporterState) bool {
+		assert.Len(t, state.SmartBaselines, 1)
+		return false
+	})
+	require.NoError(t, err)
+}
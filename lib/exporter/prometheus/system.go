@@ -1,23 +1,31 @@
 package prometheus
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/pedropombeiro/qnapexporter/lib/utils"
+	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/host"
 	"github.com/shirou/gopsutil/v3/load"
 )
 
+// loadSaturationThreshold is the 5m load average per CPU above which the CPUs are considered saturated
+const loadSaturationThreshold = 1.0
+
 var fanRpmRe = regexp.MustCompile(`(?m)fan = (\d+) rpm`)
 
-func getUptimeMetrics() ([]metric, error) {
-	u, err := host.Uptime()
+func (e *promExporter) getUptimeMetrics(ctx context.Context) ([]metric, error) {
+	u, err := host.UptimeWithContext(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if e.status != nil {
+		e.status.BootTime = time.Now().Add(-time.Duration(u) * time.Second).Truncate(time.Second)
+	}
 
 	return []metric{
 		{
@@ -26,11 +34,17 @@ func getUptimeMetrics() ([]metric, error) {
 			help:       "System uptime measured in seconds",
 			metricType: "counter",
 		},
+		{
+			name:       "qnapexporter_uptime_seconds",
+			value:      time.Since(e.startTime).Seconds(),
+			help:       "Time since the exporter process started, in seconds",
+			metricType: "counter",
+		},
 	}, err
 }
 
-func getLoadAvgMetrics() ([]metric, error) {
-	s, err := load.Avg()
+func getLoadAvgMetrics(ctx context.Context) ([]metric, error) {
+	s, err := load.AvgWithContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -40,10 +54,36 @@ func getLoadAvgMetrics() ([]metric, error) {
 		{name: "node_load5", value: s.Load5},
 		{name: "node_load15", value: s.Load15},
 	}
-	return metrics, nil
+
+	cpus, err := cpu.CountsWithContext(ctx, true)
+	if err != nil {
+		return metrics, err
+	}
+	if cpus <= 0 {
+		return metrics, nil
+	}
+
+	return append(metrics, getNormalizedLoadMetrics(s, cpus)...), nil
+}
+
+// getNormalizedLoadMetrics divides the load averages by the number of online logical CPUs,
+// so that they can be compared across models: a value above 1 means that processes are waiting for a CPU
+func getNormalizedLoadMetrics(s *load.AvgStat, cpus int) []metric {
+	load5PerCPU := s.Load5 / float64(cpus)
+	var saturated float64
+	if load5PerCPU > loadSaturationThreshold {
+		saturated = 1
+	}
+
+	return []metric{
+		{name: "node_load1_per_cpu", value: s.Load1 / float64(cpus), help: "1m load average divided by the number of online CPUs"},
+		{name: "node_load5_per_cpu", value: load5PerCPU, help: "5m load average divided by the number of online CPUs"},
+		{name: "node_load15_per_cpu", value: s.Load15 / float64(cpus), help: "15m load average divided by the number of online CPUs"},
+		{name: "node_load_saturated", value: saturated, help: "Whether the 5m load average exceeds the number of online CPUs"},
+	}
 }
 
-func (e *promExporter) getSysInfoTempMetrics() ([]metric, error) {
+func (e *promExporter) getSysInfoTempMetrics(ctx context.Context) ([]metric, error) {
 	if e.getsysinfo == "" {
 		return nil, nil
 	}
@@ -51,14 +91,23 @@ func (e *promExporter) getSysInfoTempMetrics() ([]metric, error) {
 	metrics := make([]metric, 0, 2)
 
 	for _, dev := range []string{"cputmp", "systmp"} {
-		output, err := utils.ExecCommand(e.getsysinfo, dev)
+		if e.quirk.ignores(dev) {
+			continue
+		}
+
+		output, err := e.sys.ExecCommand(ctx, e.getsysinfo, dev)
 		if err != nil {
-			return nil, err
+			return metrics, err
+		}
+
+		if strings.HasPrefix(output, "--") {
+			// The sensor is not available
+			continue
 		}
 
 		tokens := strings.SplitN(output, " ", 2)
-		value, err := strconv.ParseFloat(tokens[0], 64)
-		if err != nil {
+		value, ok := e.parseValue(ctx, tokens[0])
+		if !ok {
 			continue
 		}
 
@@ -71,28 +120,35 @@ func (e *promExporter) getSysInfoTempMetrics() ([]metric, error) {
 	return metrics, nil
 }
 
-func (e *promExporter) getSysInfoFanMetrics() ([]metric, error) {
+func (e *promExporter) getSysInfoFanMetrics(ctx context.Context) ([]metric, error) {
 	if e.getsysinfo == "" {
 		return nil, nil
 	}
 
+	if e.sysfannum <= 0 {
+		return nil, subsystemAbsentError{"no system fans reported by getsysinfo"}
+	}
+
 	metrics := make([]metric, 0, e.sysfannum)
 
 	for fannum := 1; fannum <= e.sysfannum; fannum++ {
 		fannumStr := strconv.Itoa(fannum)
+		if e.quirk.ignores("sysfan " + fannumStr) {
+			continue
+		}
 
-		fanStr, err := utils.ExecCommand(e.getsysinfo, "sysfan", fannumStr)
+		fanStr, err := e.sys.ExecCommand(ctx, e.getsysinfo, "sysfan", fannumStr)
 		if err != nil {
-			return nil, err
+			return metrics, err
 		}
 
-		fan, err := strconv.ParseFloat(strings.SplitN(fanStr, " ", 2)[0], 64)
-		if err != nil {
-			return nil, err
+		fan, ok := e.parseValue(ctx, strings.SplitN(fanStr, " ", 2)[0])
+		if !ok {
+			continue
 		}
 		metrics = append(metrics, metric{
 			name:  "node_sysfan_RPM",
-			attr:  fmt.Sprintf(`fan=%q,type="System"`, fannumStr),
+			attr:  fmt.Sprintf(`fan=%q,type="System"`, e.quirk.fanName(fannumStr)),
 			value: fan,
 		})
 	}
@@ -100,18 +156,22 @@ func (e *promExporter) getSysInfoFanMetrics() ([]metric, error) {
 	return metrics, nil
 }
 
-func (e *promExporter) getEnclosureFanMetrics() ([]metric, error) {
+func (e *promExporter) getEnclosureFanMetrics(ctx context.Context) ([]metric, error) {
 	if e.hal_app == "" {
 		return nil, nil
 	}
 
+	if len(e.enclosures) == 0 {
+		return nil, subsystemAbsentError{"no QM2 enclosures found"}
+	}
+
 	metrics := make([]metric, 0, len(e.enclosures))
 
 	for _, enc := range e.enclosures {
 		for fanNum := 0; fanNum < enc.fanCount; fanNum++ {
-			fanOutput, err := utils.ExecCommand(e.hal_app, "--se_sys_get_fan", fmt.Sprintf("enc_sys_id=%s,obj_index=%d", enc.id, fanNum))
+			fanOutput, err := e.sys.ExecCommand(ctx, e.hal_app, "--se_sys_get_fan", fmt.Sprintf("enc_sys_id=%s,obj_index=%d", enc.id, fanNum))
 			if err != nil {
-				return nil, err
+				return metrics, err
 			}
 
 			matches := fanRpmRe.FindStringSubmatch(fanOutput)
@@ -119,9 +179,9 @@ func (e *promExporter) getEnclosureFanMetrics() ([]metric, error) {
 				continue
 			}
 
-			fan, err := strconv.ParseFloat(matches[1], 64)
-			if err != nil {
-				return nil, err
+			fan, ok := e.parseValue(ctx, matches[1])
+			if !ok {
+				continue
 			}
 			metrics = append(metrics, metric{
 				name:  "node_sysfan_RPM",
@@ -0,0 +1,47 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNormalizedLoadMetrics(t *testing.T) {
+	testCases := map[string]struct {
+		load              load.AvgStat
+		cpus              int
+		expectedLoad5     float64
+		expectedSaturated float64
+	}{
+		"idle": {
+			load:          load.AvgStat{Load1: 0.5, Load5: 1, Load15: 2},
+			cpus:          4,
+			expectedLoad5: 0.25,
+		},
+		"fully busy": {
+			load:          load.AvgStat{Load1: 4, Load5: 4, Load15: 4},
+			cpus:          4,
+			expectedLoad5: 1,
+		},
+		"saturated": {
+			load:              load.AvgStat{Load1: 2, Load5: 6, Load15: 2},
+			cpus:              4,
+			expectedLoad5:     1.5,
+			expectedSaturated: 1,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			metrics := getNormalizedLoadMetrics(&tc.load, tc.cpus)
+
+			require.Len(t, metrics, 4)
+			assert.Equal(t, "node_load5_per_cpu", metrics[1].name)
+			assert.Equal(t, tc.expectedLoad5, metrics[1].value)
+			assert.Equal(t, "node_load_saturated", metrics[3].name)
+			assert.Equal(t, tc.expectedSaturated, metrics[3].value)
+		})
+	}
+}
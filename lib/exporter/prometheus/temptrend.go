@@ -0,0 +1,89 @@
+package prometheus
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// temperatureSampleInterval is the minimum time between the temperature samples kept in the state,
+	// so that the state file isn't rewritten on every scrape
+	temperatureSampleInterval = 5 * time.Minute
+
+	// temperatureTrendMinSamples is the number of samples required before a slope is reported
+	temperatureTrendMinSamples = 3
+)
+
+type temperatureSample struct {
+	disk  string
+	attr  string
+	value float64
+}
+
+// getTemperatureTrendMetrics records the disk temperatures in state and computes the slope of each disk temperature over window,
+// so that e.g. a failing fan shows up as a climbing temperature before any absolute threshold is crossed.
+// It returns whether state was changed.
+func getTemperatureTrendMetrics(state *exporterState, samples []temperatureSample, window time.Duration, now time.Time) ([]metric, bool) {
+	var changed bool
+	if state.TemperatureHistory == nil {
+		state.TemperatureHistory = make(map[string][]stateSample)
+	}
+
+	// Forget the samples which left the window, as well as disks which are no longer present
+	cutoff := now.Add(-window)
+	for disk, history := range state.TemperatureHistory {
+		idx := sort.Search(len(history), func(i int) bool { return history[i].Time.After(cutoff) })
+		switch {
+		case idx == len(history):
+			delete(state.TemperatureHistory, disk)
+			changed = true
+		case idx > 0:
+			state.TemperatureHistory[disk] = history[idx:]
+			changed = true
+		}
+	}
+
+	metrics := make([]metric, 0, len(samples))
+	for _, s := range samples {
+		history := state.TemperatureHistory[s.disk]
+		if len(history) == 0 || now.Sub(history[len(history)-1].Time) >= temperatureSampleInterval {
+			history = append(history, stateSample{Value: s.value, Time: now})
+			state.TemperatureHistory[s.disk] = history
+			changed = true
+		}
+
+		// Wait for the samples to span a good part of the window, so that the slope isn't dominated by noise
+		if len(history) < temperatureTrendMinSamples || history[len(history)-1].Time.Sub(history[0].Time) < window/4 {
+			continue
+		}
+
+		metrics = append(metrics, metric{
+			name:  "node_disk_temperature_slope_celsius_per_hour",
+			attr:  s.attr,
+			value: leastSquaresSlope(history),
+			help:  "Trend of the device temperature over the temperature trend window, as the least squares slope in degrees Celsius per hour",
+		})
+	}
+
+	return metrics, changed
+}
+
+// leastSquaresSlope returns the least squares slope of samples, in units per hour
+func leastSquaresSlope(samples []stateSample) float64 {
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := s.Time.Sub(samples[0].Time).Hours()
+		sumX += x
+		sumY += s.Value
+		sumXY += x * s.Value
+		sumXX += x * x
+	}
+
+	n := float64(len(samples))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+
+	return (n*sumXY - sumX*sumY) / denominator
+}
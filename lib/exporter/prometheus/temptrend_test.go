@@ -0,0 +1,88 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTemperatureTrendMetrics(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	attr := `device="sda",serial="WD-1234"`
+
+	tests := map[string]struct {
+		history     []stateSample
+		value       float64
+		wantSlope   *float64
+		wantHistory int
+	}{
+		"first sample": {
+			value:       40,
+			wantHistory: 1,
+		},
+		"not enough span": {
+			history: []stateSample{
+				{Value: 40, Time: now.Add(-20 * time.Minute)},
+				{Value: 41, Time: now.Add(-10 * time.Minute)},
+			},
+			value:       42,
+			wantHistory: 3,
+		},
+		"climbing": {
+			history: []stateSample{
+				{Value: 38, Time: now.Add(-3 * time.Hour)},
+				{Value: 40, Time: now.Add(-2 * time.Hour)},
+				{Value: 42, Time: now.Add(-time.Hour)},
+			},
+			value:       44,
+			wantSlope:   floatPtr(2),
+			wantHistory: 4,
+		},
+		"sample too recent is not recorded": {
+			history: []stateSample{
+				{Value: 40, Time: now.Add(-2 * time.Hour)},
+				{Value: 40, Time: now.Add(-time.Hour)},
+				{Value: 40, Time: now.Add(-time.Minute)},
+			},
+			value:       45,
+			wantSlope:   floatPtr(0),
+			wantHistory: 3,
+		},
+		"old samples are pruned": {
+			history: []stateSample{
+				{Value: 20, Time: now.Add(-10 * time.Hour)},
+				{Value: 42, Time: now.Add(-4 * time.Hour)},
+				{Value: 41, Time: now.Add(-2 * time.Hour)},
+			},
+			value:       40,
+			wantSlope:   floatPtr(-0.5),
+			wantHistory: 3,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			state := exporterState{TemperatureHistory: map[string][]stateSample{"gone": {{Value: 30, Time: now.Add(-7 * time.Hour)}}}}
+			if tc.history != nil {
+				state.TemperatureHistory["WD-1234"] = tc.history
+			}
+
+			metrics, changed := getTemperatureTrendMetrics(&state, []temperatureSample{{disk: "WD-1234", attr: attr, value: tc.value}}, 6*time.Hour, now)
+
+			assert.NotContains(t, state.TemperatureHistory, "gone")
+			assert.True(t, changed, "the history of a missing disk is removed")
+			assert.Len(t, state.TemperatureHistory["WD-1234"], tc.wantHistory)
+			if tc.wantSlope == nil {
+				assert.Empty(t, metrics)
+				return
+			}
+
+			require.Len(t, metrics, 1)
+			assert.Equal(t, "node_disk_temperature_slope_celsius_per_hour", metrics[0].name)
+			assert.Equal(t, attr, metrics[0].attr)
+			assert.InDelta(t, *tc.wantSlope, metrics[0].value, 1e-9)
+		})
+	}
+}
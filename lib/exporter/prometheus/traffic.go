@@ -0,0 +1,133 @@
+package prometheus
+
+import (
+	"fmt"
+	"time"
+)
+
+// trafficState holds the traffic of each interface since the start of the current accounting period
+type trafficState struct {
+	PeriodStart time.Time `json:"period_start"`
+	// Interfaces maps each interface to its traffic in the period
+	Interfaces map[string]*trafficCounters `json:"interfaces,omitempty"`
+}
+
+type trafficCounters struct {
+	RxBytes float64 `json:"rx_bytes"`
+	TxBytes float64 `json:"tx_bytes"`
+	// LastRx and LastTx are the byte counters of the interface when they were last read, from which the traffic
+	// since is computed, even across exporter restarts
+	LastRx float64 `json:"last_rx"`
+	LastTx float64 `json:"last_tx"`
+}
+
+type trafficSample struct {
+	iface   string
+	rxBytes float64
+	txBytes float64
+}
+
+// trafficPeriodStart returns the start of the accounting period holding now, which starts at midnight on resetDay
+// of every month
+func trafficPeriodStart(now time.Time, resetDay int) time.Time {
+	if resetDay < 1 {
+		resetDay = 1
+	}
+
+	year, month, day := now.Date()
+	if day < resetDay {
+		month--
+	}
+
+	return time.Date(year, month, resetDay, 0, 0, 0, 0, now.Location())
+}
+
+// getTrafficMetrics adds the traffic of each interface since the previous samples to the counters of the current
+// accounting period, kept in the state file so that they survive restarts
+func (e *promExporter) getTrafficMetrics(samples []trafficSample) ([]metric, error) {
+	now := time.Now()
+	var metrics []metric
+	err := e.state.update(func(state *exporterState) bool {
+		var changed bool
+		metrics, changed = updateTraffic(state, samples, e.TrafficResetDay, now)
+
+		if !changed && now.Sub(e.trafficSaved) < stateSaveInterval {
+			return false
+		}
+		e.trafficSaved = now
+		return true
+	})
+
+	return metrics, err
+}
+
+// updateTraffic adds the traffic of each interface since the previous samples to the counters of state, resetting them
+// when a new accounting period starts, and returns the counters of the current period. It returns whether a new
+// period or interface was recorded.
+func updateTraffic(state *exporterState, samples []trafficSample, resetDay int, now time.Time) ([]metric, bool) {
+	var changed bool
+	start := trafficPeriodStart(now, resetDay)
+	if state.Traffic == nil {
+		state.Traffic = &trafficState{PeriodStart: start}
+		changed = true
+	}
+	traffic := state.Traffic
+	if traffic.Interfaces == nil {
+		traffic.Interfaces = make(map[string]*trafficCounters)
+	}
+
+	if !traffic.PeriodStart.Equal(start) {
+		traffic.PeriodStart = start
+		for _, c := range traffic.Interfaces {
+			c.RxBytes, c.TxBytes = 0, 0
+		}
+		changed = true
+	}
+
+	metrics := make([]metric, 0, 2*len(samples)+1)
+	for _, s := range samples {
+		c, found := traffic.Interfaces[s.iface]
+		if !found {
+			// The traffic of the period before the interface was first seen is unknown, so only count it from now on
+			c = &trafficCounters{LastRx: s.rxBytes, LastTx: s.txBytes}
+			traffic.Interfaces[s.iface] = c
+			changed = true
+		}
+		c.RxBytes += counterDelta(c.LastRx, s.rxBytes)
+		c.TxBytes += counterDelta(c.LastTx, s.txBytes)
+		c.LastRx, c.LastTx = s.rxBytes, s.txBytes
+
+		attr := fmt.Sprintf("device=%q", s.iface)
+		metrics = append(metrics,
+			metric{
+				name:  "node_network_monthly_receive_bytes",
+				attr:  attr,
+				value: c.RxBytes,
+				help:  "Number of bytes received since the start of the monthly accounting period",
+			},
+			metric{
+				name:  "node_network_monthly_transmit_bytes",
+				attr:  attr,
+				value: c.TxBytes,
+				help:  "Number of bytes transmitted since the start of the monthly accounting period",
+			},
+		)
+	}
+	metrics = append(metrics, metric{
+		name:  "node_network_monthly_period_start_timestamp_seconds",
+		value: float64(traffic.PeriodStart.Unix()),
+		help:  "Start of the monthly traffic accounting period, in seconds since the epoch",
+	})
+
+	return metrics, changed
+}
+
+// counterDelta returns the increase of a counter from previous to current. A counter lower than before was reset
+// (e.g. by a reboot), so all of its current value is new.
+func counterDelta(previous float64, current float64) float64 {
+	if current < previous {
+		return current
+	}
+
+	return current - previous
+}
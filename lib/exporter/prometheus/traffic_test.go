@@ -0,0 +1,129 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrafficPeriodStart(t *testing.T) {
+	tests := map[string]struct {
+		now      time.Time
+		resetDay int
+		want     time.Time
+	}{
+		"first of the month": {
+			now:      time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			resetDay: 1,
+			want:     time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"after the reset day": {
+			now:      time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC),
+			resetDay: 15,
+			want:     time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC),
+		},
+		"before the reset day": {
+			now:      time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC),
+			resetDay: 15,
+			want:     time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC),
+		},
+		"before the reset day in January": {
+			now:      time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC),
+			resetDay: 15,
+			want:     time.Date(2023, 12, 15, 0, 0, 0, 0, time.UTC),
+		},
+		"unset reset day": {
+			now:  time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, trafficPeriodStart(tc.now, tc.resetDay))
+		})
+	}
+}
+
+func TestUpdateTraffic(t *testing.T) {
+	start := time.Date(2024, 3, 30, 12, 0, 0, 0, time.UTC)
+	type scrape struct {
+		now     time.Time
+		samples []trafficSample
+	}
+	tests := map[string]struct {
+		scrapes     []scrape
+		wantRx      map[string]float64
+		wantTx      map[string]float64
+		wantChanged bool
+	}{
+		"first scrape": {
+			scrapes: []scrape{
+				{now: start, samples: []trafficSample{{iface: "eth0", rxBytes: 1000, txBytes: 500}}},
+			},
+			wantRx:      map[string]float64{`device="eth0"`: 0},
+			wantTx:      map[string]float64{`device="eth0"`: 0},
+			wantChanged: true,
+		},
+		"traffic since the previous scrape": {
+			scrapes: []scrape{
+				{now: start, samples: []trafficSample{{iface: "eth0", rxBytes: 1000, txBytes: 500}}},
+				{now: start.Add(time.Minute), samples: []trafficSample{{iface: "eth0", rxBytes: 1500, txBytes: 700}}},
+				{now: start.Add(2 * time.Minute), samples: []trafficSample{{iface: "eth0", rxBytes: 1600, txBytes: 800}}},
+			},
+			wantRx: map[string]float64{`device="eth0"`: 600},
+			wantTx: map[string]float64{`device="eth0"`: 300},
+		},
+		"counters reset by a reboot": {
+			scrapes: []scrape{
+				{now: start, samples: []trafficSample{{iface: "eth0", rxBytes: 1000, txBytes: 500}}},
+				{now: start.Add(time.Minute), samples: []trafficSample{{iface: "eth0", rxBytes: 1500, txBytes: 700}}},
+				{now: start.Add(time.Hour), samples: []trafficSample{{iface: "eth0", rxBytes: 100, txBytes: 50}}},
+			},
+			wantRx: map[string]float64{`device="eth0"`: 600},
+			wantTx: map[string]float64{`device="eth0"`: 250},
+		},
+		"new period": {
+			scrapes: []scrape{
+				{now: start, samples: []trafficSample{{iface: "eth0", rxBytes: 1000, txBytes: 500}}},
+				{now: start.Add(time.Minute), samples: []trafficSample{{iface: "eth0", rxBytes: 1500, txBytes: 700}}},
+				{now: start.Add(48 * time.Hour), samples: []trafficSample{{iface: "eth0", rxBytes: 1600, txBytes: 800}}},
+			},
+			wantRx:      map[string]float64{`device="eth0"`: 100},
+			wantTx:      map[string]float64{`device="eth0"`: 100},
+			wantChanged: true,
+		},
+		"new interface": {
+			scrapes: []scrape{
+				{now: start, samples: []trafficSample{{iface: "eth0", rxBytes: 1000, txBytes: 500}}},
+				{now: start.Add(time.Minute), samples: []trafficSample{
+					{iface: "eth0", rxBytes: 1500, txBytes: 700},
+					{iface: "eth1", rxBytes: 3000, txBytes: 4000},
+				}},
+			},
+			wantRx:      map[string]float64{`device="eth0"`: 500, `device="eth1"`: 0},
+			wantTx:      map[string]float64{`device="eth0"`: 200, `device="eth1"`: 0},
+			wantChanged: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var state exporterState
+			var metrics []metric
+			var changed bool
+			for _, s := range tc.scrapes {
+				metrics, changed = updateTraffic(&state, s.samples, 1, s.now)
+			}
+
+			assert.Equal(t, tc.wantRx, metricValues("node_network_monthly_receive_bytes")(metrics))
+			assert.Equal(t, tc.wantTx, metricValues("node_network_monthly_transmit_bytes")(metrics))
+			assert.Equal(t, tc.wantChanged, changed)
+
+			last := tc.scrapes[len(tc.scrapes)-1].now
+			assert.Equal(t, map[string]float64{"": float64(trafficPeriodStart(last, 1).Unix())},
+				metricValues("node_network_monthly_period_start_timestamp_seconds")(metrics))
+		})
+	}
+}
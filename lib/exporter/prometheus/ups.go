@@ -1,17 +1,41 @@
 package prometheus
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	nut "github.com/robbiet480/go.nut"
 )
 
+const (
+	defaultUpsHost = "127.0.0.1"
+
+	// upsRefreshTimeout bounds the time a background refresh of the UPS metrics may take
+	upsRefreshTimeout = 1 * time.Minute
+)
+
+// upsInfoVariables are the NUT variables exposed as labels of node_ups_info
+var upsInfoVariables = map[string]struct{}{
+	"ups.model":     {},
+	"device.model":  {},
+	"driver.name":   {},
+	"ups.serial":    {},
+	"device.serial": {},
+	"ups.firmware":  {},
+}
+
+// upsStatusFlags are the flags which can be present in the NUT ups.status variable, exported by node_ups_status_flag
+var upsStatusFlags = []string{"OL", "OB", "LB", "HB", "RB", "CHRG", "DISCHRG", "BYPASS", "CAL", "OFF", "OVER", "TRIM", "BOOST", "FSD"}
+
 type upsState struct {
 	upsLock   sync.Mutex
 	upsClient nut.Client
@@ -20,21 +44,92 @@ type upsState struct {
 	upsConnErrTimestamp time.Time
 	upsConnAttempts     int
 	upsList             *[]nut.UPS
+	// resetRequested is set by the watchdog to force a reconnection on the next scrape
+	resetRequested atomic.Bool
+
+	// The last UPS metrics retrieved, served while a refresh happens in the background
+	cacheLock      sync.Mutex
+	cachedMetrics  []metric
+	cachedErr      error
+	cacheTimestamp time.Time
+	refreshing     bool
+
+	// The power drawn from the UPSes in the last successful retrieval, which the energy collector integrates
+	powerLock  sync.Mutex
+	powerWatts float64
+	powerTime  time.Time
+}
+
+// getCachedUpsMetrics returns the UPS metrics retrieved within the last UpsCacheTTL,
+// so that scrapes don't block on slow UPS drivers. Stale metrics are returned while
+// they are refreshed in the background; only the first scrape waits for the UPS daemon.
+func (e *promExporter) getCachedUpsMetrics(ctx context.Context) ([]metric, error) {
+	ttl := e.UpsCacheTTL
+	if ttl <= 0 {
+		return e.getUpsStatsMetricsWithRetry(ctx)
+	}
+
+	s := &e.upsState
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+
+	if s.cacheTimestamp.IsZero() {
+		s.cachedMetrics, s.cachedErr = e.getUpsStatsMetricsWithRetry(ctx)
+		s.cacheTimestamp = time.Now()
+	} else if time.Since(s.cacheTimestamp) >= ttl && !s.refreshing {
+		s.refreshing = true
+		go e.refreshUpsCache()
+	}
+
+	recordDataTime(ctx, s.cacheTimestamp)
+	metrics := make([]metric, len(s.cachedMetrics), len(s.cachedMetrics)+1)
+	copy(metrics, s.cachedMetrics)
+	metrics = append(metrics, metric{
+		name:  "qnap_exporter_ups_cache_age_seconds",
+		value: time.Since(s.cacheTimestamp).Seconds(),
+		help:  "Age of the UPS metrics served from the cache",
+	})
+
+	return metrics, s.cachedErr
 }
 
-func (e *promExporter) getUpsStatsMetricsWithRetry() ([]metric, error) {
-	metrics, err := e.getUpsStatsMetrics()
+func (e *promExporter) refreshUpsCache() {
+	ctx, cancel := context.WithTimeout(context.Background(), upsRefreshTimeout)
+	defer cancel()
+
+	metrics, err := e.getUpsStatsMetricsWithRetry(ctx)
+
+	s := &e.upsState
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+
+	s.cachedMetrics, s.cachedErr = metrics, err
+	s.cacheTimestamp = time.Now()
+	s.refreshing = false
+}
+
+func (e *promExporter) invalidateUpsCache() {
+	s := &e.upsState
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+
+	s.cachedMetrics, s.cachedErr = nil, nil
+	s.cacheTimestamp = time.Time{}
+}
+
+func (e *promExporter) getUpsStatsMetricsWithRetry(ctx context.Context) ([]metric, error) {
+	metrics, err := e.getUpsStatsMetrics(ctx)
 	var syscallErr *os.SyscallError
 	if errors.As(err, &syscallErr) {
 		switch syscallErr.Err {
 		case syscall.ECONNRESET, syscall.EPIPE:
-			metrics, err = e.getUpsStatsMetrics()
+			metrics, err = e.getUpsStatsMetrics(ctx)
 		}
 	}
 	return metrics, err
 }
 
-func (e *promExporter) getUpsStatsMetrics() (metrics []metric, err error) {
+func (e *promExporter) getUpsStatsMetrics(ctx context.Context) (metrics []metric, err error) {
 	e.upsState.upsLock.Lock()
 	defer e.upsState.upsLock.Unlock()
 
@@ -53,18 +148,28 @@ func (e *promExporter) getUpsStatsMetrics() (metrics []metric, err error) {
 		}
 	}()
 
+	if e.upsState.resetRequested.CompareAndSwap(true, false) {
+		e.Logger.Debug("Resetting UPS daemon connection")
+		e.upsState.upsClient.ProtocolVersion = ""
+		e.upsState.upsList = nil
+		e.upsState.upsConnAttempts = 0
+	}
+
 	if e.upsState.upsClient.ProtocolVersion == "" {
 		if e.upsState.upsConnAttempts >= 10 && time.Since(e.upsState.upsConnErrTimestamp) >= 1*time.Hour {
 			e.upsState.upsConnAttempts = 0
 		}
 		if e.upsState.upsConnAttempts < 10 {
-			e.Logger.Println("Connecting to UPS daemon")
+			e.Logger.Debug("Connecting to UPS daemon")
 
 			e.upsState.upsConnAttempts++
-			e.upsState.upsClient, e.upsState.upsConnErr = nut.Connect("127.0.0.1")
+			e.upsState.upsClient, e.upsState.upsConnErr = connectUps(e.UpsAddress)
 		}
 		if e.upsState.upsConnErr != nil {
 			e.upsState.upsConnErrTimestamp = time.Now()
+			if errors.Is(e.upsState.upsConnErr, syscall.ECONNREFUSED) {
+				return nil, subsystemAbsentError{fmt.Sprintf("no UPS daemon listening at %s", e.UpsAddress)}
+			}
 			return nil, fmt.Errorf("%w (attempt %d)", e.upsState.upsConnErr, e.upsState.upsConnAttempts)
 		}
 	}
@@ -78,12 +183,23 @@ func (e *promExporter) getUpsStatsMetrics() (metrics []metric, err error) {
 	}
 
 	if len(*e.upsState.upsList) == 0 {
-		return nil, nil
+		return nil, subsystemAbsentError{"no UPS configured in the UPS daemon"}
 	}
 
-	e.status.Ups = []string{}
+	names := []string{}
 	for _, ups := range *e.upsState.upsList {
-		e.status.Ups = append(e.status.Ups, ups.Name)
+		if len(e.UpsNames) == 0 || containsString(e.UpsNames, ups.Name) {
+			names = append(names, ups.Name)
+		}
+	}
+	if e.status != nil {
+		e.status.Ups = names
+	}
+
+	for _, ups := range *e.upsState.upsList {
+		if !containsString(names, ups.Name) {
+			continue
+		}
 
 		vars, err := ups.GetVariables()
 		if err != nil {
@@ -97,7 +213,13 @@ func (e *promExporter) getUpsStatsMetrics() (metrics []metric, err error) {
 		attr := fmt.Sprintf("ups=%q", ups.Name)
 
 		var status, statusHelp, firmware string
+		info := map[string]string{}
 		for _, v := range vars {
+			if _, found := upsInfoVariables[v.Name]; found {
+				// Values such as serial numbers may have been parsed as numbers
+				info[v.Name] = fmt.Sprint(v.Value)
+			}
+
 			switch v.Name {
 			case "ups.status":
 				status = v.Value.(string)
@@ -130,12 +252,133 @@ func (e *promExporter) getUpsStatsMetrics() (metrics []metric, err error) {
 			attr:  fmt.Sprintf(`status=%q,firmware=%q,%s`, status, firmware, attr),
 			value: getUpsStatus(status),
 			help:  statusHelp,
+		}, metric{
+			name: "node_ups_info",
+			attr: fmt.Sprintf(
+				`model=%q,driver=%q,serial=%q,firmware=%q,%s`,
+				firstNonEmpty(info["ups.model"], info["device.model"]),
+				info["driver.name"],
+				firstNonEmpty(info["ups.serial"], info["device.serial"]),
+				info["ups.firmware"],
+				attr,
+			),
+			value: 1,
+			help:  "Information about the UPS, as reported by the NUT driver",
 		})
+		metrics = appendUpsStatusFlagMetrics(metrics, attr, status)
+	}
+
+	for _, name := range e.UpsNames {
+		if !containsString(names, name) {
+			return metrics, fmt.Errorf("UPS %q not found in the UPS daemon at %s", name, e.UpsAddress)
+		}
+	}
+
+	if watts, ok := upsPowerWatts(metrics); ok {
+		e.upsState.powerLock.Lock()
+		e.upsState.powerWatts, e.upsState.powerTime = watts, time.Now()
+		e.upsState.powerLock.Unlock()
 	}
 
 	return metrics, nil
 }
 
+// upsPowerWatts returns the real power drawn from all the UPSes, from ups.realpower or, for the UPSes which only
+// report their load, from ups.load and ups.realpower.nominal. It returns false if no UPS reports its power.
+func upsPowerWatts(metrics []metric) (float64, bool) {
+	realPower := metricValues("ups_ups_realpower")(metrics)
+	nominal := metricValues("ups_ups_realpower_nominal")(metrics)
+	load := metricValues("ups_ups_load")(metrics)
+
+	var total float64
+	var found bool
+	for attr, n := range nominal {
+		if _, measured := realPower[attr]; measured {
+			continue
+		}
+		if l, ok := load[attr]; ok {
+			total += l / 100 * n
+			found = true
+		}
+	}
+	for _, watts := range realPower {
+		total += watts
+		found = true
+	}
+
+	return total, found
+}
+
+// lastUpsPower returns the power drawn from the UPSes, if it was retrieved within maxAge
+func (e *promExporter) lastUpsPower(maxAge time.Duration) (float64, bool) {
+	e.upsState.powerLock.Lock()
+	defer e.upsState.powerLock.Unlock()
+
+	if e.upsState.powerTime.IsZero() || time.Since(e.upsState.powerTime) > maxAge {
+		return 0, false
+	}
+
+	return e.upsState.powerWatts, true
+}
+
+// appendUpsStatusFlagMetrics appends one metric per known NUT status flag, set to 1 if the flag is present in status (e.g. "OL CHRG")
+func appendUpsStatusFlagMetrics(metrics []metric, attr string, status string) []metric {
+	flags := strings.Fields(status)
+	for _, flag := range upsStatusFlags {
+		var value float64
+		if containsString(flags, flag) {
+			value = 1
+		}
+
+		metrics = append(metrics, metric{
+			name:  "node_ups_status_flag",
+			attr:  fmt.Sprintf("flag=%q,%s", flag, attr),
+			value: value,
+			help:  "Whether the UPS status flag (e.g. OL: online, OB: on battery, LB: low battery) is set",
+		})
+	}
+
+	return metrics
+}
+
+func (e *promExporter) isUpsConnected() bool {
+	e.upsState.upsLock.Lock()
+	defer e.upsState.upsLock.Unlock()
+
+	return e.upsState.upsClient.ProtocolVersion != ""
+}
+
+func (e *promExporter) resetUpsClient() {
+	e.upsState.upsLock.Lock()
+	defer e.upsState.upsLock.Unlock()
+
+	if e.upsState.upsClient.ProtocolVersion != "" {
+		_, _ = e.upsState.upsClient.Disconnect()
+		e.upsState.upsClient.ProtocolVersion = ""
+	}
+	e.upsState.upsList = nil
+	e.upsState.upsConnAttempts = 0
+}
+
+// connectUps connects to the NUT daemon at address, which can be either `host` or `host:port`
+func connectUps(address string) (nut.Client, error) {
+	if address == "" {
+		address = defaultUpsHost
+	}
+
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nut.Connect(address)
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nut.Client{}, fmt.Errorf("parse UPS port (%s): %w", address, err)
+	}
+
+	return nut.Connect(host, port)
+}
+
 func getUpsStatus(status string) float64 {
 	switch status {
 	case "OL":
@@ -152,3 +395,13 @@ func getUpsStatus(status string) float64 {
 		return 99
 	}
 }
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,114 @@
+package prometheus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCachedUpsMetrics(t *testing.T) {
+	e := &promExporter{
+		ExporterConfig: ExporterConfig{
+			// Nothing listens on the discard port
+			UpsAddress:  "127.0.0.1:9",
+			UpsCacheTTL: time.Hour,
+			Logger:      logging.Discard(),
+		},
+	}
+
+	metrics, err := e.getCachedUpsMetrics(context.Background())
+	require.Error(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "qnap_exporter_ups_cache_age_seconds", metrics[0].name)
+	firstFetch := e.upsState.cacheTimestamp
+	require.False(t, firstFetch.IsZero())
+
+	// Fresh cache is served without contacting the UPS daemon
+	_, err = e.getCachedUpsMetrics(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, firstFetch, e.upsState.cacheTimestamp)
+
+	// Stale cache is served while being refreshed in the background
+	e.upsState.cacheLock.Lock()
+	e.upsState.cacheTimestamp = time.Now().Add(-2 * time.Hour)
+	e.upsState.cacheLock.Unlock()
+
+	metrics, err = e.getCachedUpsMetrics(context.Background())
+	require.Error(t, err)
+	assert.GreaterOrEqual(t, metrics[0].value, time.Hour.Seconds())
+
+	assert.Eventually(t, func() bool {
+		e.upsState.cacheLock.Lock()
+		defer e.upsState.cacheLock.Unlock()
+
+		return !e.upsState.refreshing && time.Since(e.upsState.cacheTimestamp) < time.Hour
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestAppendUpsStatusFlagMetrics(t *testing.T) {
+	metrics := appendUpsStatusFlagMetrics(nil, `ups="qnapups"`, "OL CHRG")
+
+	require.Len(t, metrics, len(upsStatusFlags))
+	flags := map[string]float64{}
+	for _, m := range metrics {
+		assert.Equal(t, "node_ups_status_flag", m.name)
+		flags[m.attr] = m.value
+	}
+	assert.Equal(t, 1.0, flags[`flag="OL",ups="qnapups"`])
+	assert.Equal(t, 1.0, flags[`flag="CHRG",ups="qnapups"`])
+	assert.Equal(t, 0.0, flags[`flag="OB",ups="qnapups"`])
+	assert.Equal(t, 0.0, flags[`flag="LB",ups="qnapups"`])
+}
+
+func TestUpsPowerWatts(t *testing.T) {
+	tests := map[string]struct {
+		metrics   []metric
+		wantWatts float64
+		wantFound bool
+	}{
+		"real power": {
+			metrics: []metric{
+				{name: "ups_ups_realpower", attr: `ups="qnapups"`, value: 42},
+				{name: "ups_ups_load", attr: `ups="qnapups"`, value: 10},
+				{name: "ups_ups_realpower_nominal", attr: `ups="qnapups"`, value: 900},
+			},
+			wantWatts: 42,
+			wantFound: true,
+		},
+		"load and nominal power": {
+			metrics: []metric{
+				{name: "ups_ups_load", attr: `ups="qnapups"`, value: 10},
+				{name: "ups_ups_realpower_nominal", attr: `ups="qnapups"`, value: 900},
+			},
+			wantWatts: 90,
+			wantFound: true,
+		},
+		"several UPSes": {
+			metrics: []metric{
+				{name: "ups_ups_realpower", attr: `ups="office"`, value: 42},
+				{name: "ups_ups_load", attr: `ups="rack"`, value: 20},
+				{name: "ups_ups_realpower_nominal", attr: `ups="rack"`, value: 600},
+			},
+			wantWatts: 162,
+			wantFound: true,
+		},
+		"load only": {
+			metrics: []metric{
+				{name: "ups_ups_load", attr: `ups="qnapups"`, value: 10},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			watts, found := upsPowerWatts(tc.metrics)
+
+			assert.Equal(t, tc.wantFound, found)
+			assert.InDelta(t, tc.wantWatts, watts, 1e-9)
+		})
+	}
+}
@@ -1,14 +1,55 @@
 package prometheus
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"runtime"
 
-func (e *promExporter) getVersionMetrics() (metrics []metric, err error) {
-	return []metric{
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+func (e *promExporter) getVersionMetrics(ctx context.Context) (metrics []metric, err error) {
+	var branch, revision, built, version string
+	if e.status != nil {
+		branch, revision, built, version = e.status.Branch, e.status.Revision, e.status.Built, e.status.Version
+	}
+
+	metrics = []metric{
 		{
 			name:  "go_program",
-			attr:  fmt.Sprintf("branch=%q,revision=%q,built=%q,version=%q", e.status.Branch, e.status.Revision, e.status.Built, e.status.Version),
+			attr:  fmt.Sprintf("branch=%q,revision=%q,built=%q,version=%q", branch, revision, built, version),
 			help:  "Information about qnapexporter",
 			value: 1,
 		},
-	}, nil
+		{
+			name:  "qnap_exporter_build_info",
+			attr:  fmt.Sprintf("version=%q,commit=%q,goversion=%q", utils.VERSION, utils.REVISION, runtime.Version()),
+			help:  "Version of qnapexporter and of the Go toolchain used to build it",
+			value: 1,
+		},
+	}
+
+	if e.UpdateCheckInterval <= 0 || e.updates == nil {
+		return metrics, nil
+	}
+
+	// The check runs in the background, so the result of the previous check is reported
+	e.updates.Refresh(e.UpdateCheckInterval, e.Logger)
+	latest, available := e.updates.Latest()
+	if latest == "" {
+		return metrics, nil
+	}
+	recordDataTime(ctx, e.updates.CheckedAt())
+
+	var value float64
+	if available {
+		value = 1
+	}
+
+	return append(metrics, metric{
+		name:  "qnap_exporter_update_available",
+		attr:  fmt.Sprintf("latest_version=%q", latest),
+		help:  "Whether a newer release of qnapexporter is available",
+		value: value,
+	}), nil
 }
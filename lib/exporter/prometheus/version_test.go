@@ -0,0 +1,63 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/pedropombeiro/qnapexporter/lib/update"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetVersionMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"tag_name":"v9.0.0"}`)
+	}))
+	defer srv.Close()
+
+	tests := map[string]struct {
+		interval        time.Duration
+		expectedMetrics int
+	}{
+		"update check disabled": {expectedMetrics: 2},
+		"update check enabled":  {interval: time.Hour, expectedMetrics: 3},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := &promExporter{
+				ExporterConfig: ExporterConfig{UpdateCheckInterval: tc.interval, Logger: logging.Discard()},
+				status:         &exporter.Status{Version: "v1.0.0"},
+				updates:        update.NewChecker(srv.URL, "v1.0.0"),
+			}
+
+			metrics, err := e.getVersionMetrics(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, "qnap_exporter_build_info", metrics[1].name)
+			assert.Contains(t, metrics[1].attr, `goversion="go`)
+
+			if tc.interval > 0 {
+				// The first scrape only starts the check
+				assert.Eventually(t, func() bool {
+					metrics, err = e.getVersionMetrics(context.Background())
+					return err == nil && len(metrics) == tc.expectedMetrics
+				}, time.Second, 10*time.Millisecond)
+			}
+			require.Len(t, metrics, tc.expectedMetrics)
+			if tc.interval > 0 {
+				assert.Equal(t, metric{
+					name:  "qnap_exporter_update_available",
+					attr:  `latest_version="v9.0.0"`,
+					help:  "Whether a newer release of qnapexporter is available",
+					value: 1,
+				}, metrics[2])
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package prometheus
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -17,16 +18,13 @@ type volumeInfo struct {
 	freeSizeBytes, totalSizeBytes float64
 }
 
-func (e *promExporter) readSysVolInfo() {
-	volCount := 0
-	sysvolnumOutput, err := utils.ExecCommand(e.getsysinfo, "sysvolnum")
-	if err == nil {
-		volCount, err = strconv.Atoi(sysvolnumOutput)
-		if err != nil {
-			volCount = 0
-		}
+// readSysVolInfo discovers the volumes reported by getsysinfo, returning an error if their number can't be read
+func (e *promExporter) readSysVolInfo(ctx context.Context) error {
+	volCount, countErr := e.readSysInfoCount(ctx, "sysvolnum")
+	if countErr != nil {
+		volCount = 0
 	}
-	e.Logger.Printf("Retrieved volCount: %d", volCount)
+	e.Logger.Debug("Retrieved volume count", "sysvolnum", volCount)
 
 	e.volumes = make([]volumeInfo, 0, volCount)
 
@@ -34,48 +32,48 @@ func (e *promExporter) readSysVolInfo() {
 	for parsedVolCount := 0; parsedVolCount < volCount; idx++ {
 		volIdx := strconv.FormatUint(idx, 10)
 
-		desc, err := utils.ExecCommand(e.getsysinfo, "vol_desc", volIdx)
+		desc, err := e.sys.ExecCommand(ctx, e.getsysinfo, "vol_desc", volIdx)
 		if err != nil {
-			e.Logger.Printf("Error fetching volume %d description: %v", idx, err)
+			e.Logger.Error("Error fetching volume description", "index", idx, "err", err)
 			continue
 		}
 		description := parseVolDesc(desc)
-		e.Logger.Printf("Retrieved vol_desc %q, parsed to %q", desc, description)
+		e.Logger.Debug("Retrieved volume description", "vol_desc", desc, "volume", description)
 
 		parsedVolCount++
 		if description == "" {
 			continue
 		}
 
-		fileSystem, err := utils.ExecCommand(e.getsysinfo, "vol_fs", volIdx)
+		fileSystem, err := e.sys.ExecCommand(ctx, e.getsysinfo, "vol_fs", volIdx)
 		if err != nil {
-			e.Logger.Printf("Error fetching volume %q file system: %v", description, err)
+			e.Logger.Error("Error fetching volume file system", "volume", description, "err", err)
 			continue
 		}
-		e.Logger.Printf("Retrieved volume %q vol_fs %q", description, fileSystem)
+		e.Logger.Debug("Retrieved volume file system", "volume", description, "vol_fs", fileSystem)
 		if fileSystem == "Unknown" {
-			e.Logger.Printf("Ignoring %q volume with %s file system", description, fileSystem)
+			e.Logger.Info("Ignoring volume with unsupported file system", "volume", description, "vol_fs", fileSystem)
 			continue
 		}
 
-		volsizeStr, err := utils.ExecCommand(e.getsysinfo, "vol_totalsize", volIdx)
+		volsizeStr, err := e.sys.ExecCommand(ctx, e.getsysinfo, "vol_totalsize", volIdx)
 		if err != nil {
-			e.Logger.Printf("Error fetching volume %q size: %v", description, err)
+			e.Logger.Error("Error fetching volume size", "volume", description, "err", err)
 			continue
 		}
-		e.Logger.Printf("Retrieved volume %q vol_totalsize %q", description, volsizeStr)
+		e.Logger.Debug("Retrieved volume size", "volume", description, "vol_totalsize", volsizeStr)
 
 		volsizeBytes, err := parseVolSize(volsizeStr)
 		if err != nil {
 			continue
 		}
 
-		status, err := utils.ExecCommand(e.getsysinfo, "vol_status", volIdx)
+		status, err := e.sys.ExecCommand(ctx, e.getsysinfo, "vol_status", volIdx)
 		if err != nil {
-			e.Logger.Printf("Error fetching volume %q status: %v", description, err)
+			e.Logger.Error("Error fetching volume status", "volume", description, "err", err)
 			continue
 		}
-		e.Logger.Printf("Retrieved volume %q vol_status %q", description, status)
+		e.Logger.Debug("Retrieved volume status", "volume", description, "vol_status", status)
 
 		e.volumes = append(
 			e.volumes,
@@ -89,27 +87,36 @@ func (e *promExporter) readSysVolInfo() {
 		)
 	}
 
-	e.Logger.Printf("Found volumes %v", e.volumes)
+	e.Logger.Debug("Found volumes", "volumes", e.volumes)
+
+	return countErr
 }
 
-func (e *promExporter) getSysInfoVolMetrics() ([]metric, error) {
+func (e *promExporter) getSysInfoVolMetrics(ctx context.Context) ([]metric, error) {
 	if e.getsysinfo == "" {
 		return nil, nil
 	}
 
 	metrics := make([]metric, 0, 2*len(e.volumes))
-	e.status.Volumes = []string{}
+	samples := make([]volumeUsageSample, 0, len(e.volumes))
+	if e.status != nil {
+		e.status.Volumes = []string{}
+	}
 
 	expired := e.volumeLastFetch.IsZero() || time.Now().After(e.volumeLastFetch.Add(volumeValidity))
 	if expired {
 		e.volumeLastFetch = time.Now()
 	}
+	// The free space of the volumes is only read again once expired
+	recordDataTime(ctx, e.volumeLastFetch)
 
 	for idx, v := range e.volumes {
-		e.status.Volumes = append(e.status.Volumes, v.description)
+		if e.status != nil {
+			e.status.Volumes = append(e.status.Volumes, v.description)
+		}
 
 		if expired || v.freeSizeBytes == 0 {
-			freesizeStr, err := utils.ExecCommand(e.getsysinfo, "vol_freesize", v.index)
+			freesizeStr, err := e.sys.ExecCommand(ctx, e.getsysinfo, "vol_freesize", v.index)
 			if err != nil {
 				return nil, err
 			}
@@ -119,7 +126,9 @@ func (e *promExporter) getSysInfoVolMetrics() ([]metric, error) {
 			}
 
 			v.freeSizeBytes = freeSizeBytes
+			e.volumesMu.Lock()
 			e.volumes[idx] = v
+			e.volumesMu.Unlock()
 		}
 
 		attr := fmt.Sprintf("volume=%q,filesystem=%q,status=%q", v.description, v.fileSystem, v.status)
@@ -136,9 +145,26 @@ func (e *promExporter) getSysInfoVolMetrics() ([]metric, error) {
 			},
 		}
 		metrics = append(metrics, newMetrics...)
+		samples = append(samples, volumeUsageSample{
+			volume:     v.description,
+			attr:       attr,
+			usedBytes:  v.totalSizeBytes - v.freeSizeBytes,
+			availBytes: v.freeSizeBytes,
+		})
+	}
+
+	if e.VolumeForecastWindow <= 0 || len(samples) == 0 {
+		return metrics, nil
 	}
 
-	return metrics, nil
+	var forecastMetrics []metric
+	err := e.state.update(func(state *exporterState) bool {
+		var changed bool
+		forecastMetrics, changed = getVolumeForecastMetrics(state, samples, e.VolumeForecastWindow, time.Now())
+		return changed
+	})
+
+	return append(metrics, forecastMetrics...), err
 }
 
 func parseVolDesc(desc string) string {
@@ -157,7 +183,7 @@ func parseVolDesc(desc string) string {
 
 func parseVolSize(s string) (float64, error) {
 	fields := strings.Fields(s)
-	size, err := strconv.ParseFloat(fields[0], 64)
+	size, err := utils.ParseFloat(fields[0])
 	if err != nil {
 		return 0, fmt.Errorf("parse volume size (%s): %w", s, err)
 	}
@@ -0,0 +1,77 @@
+package prometheus
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// volumeUsageSampleInterval is the minimum time between the volume usage samples kept in the state
+	volumeUsageSampleInterval = time.Hour
+
+	// volumeForecastMinSamples is the number of samples required before a forecast is reported
+	volumeForecastMinSamples = 3
+)
+
+type volumeUsageSample struct {
+	volume     string
+	attr       string
+	usedBytes  float64
+	availBytes float64
+}
+
+// getVolumeForecastMetrics records the used space of each volume in state and extrapolates the trend over window
+// to estimate when each volume will be full. It returns whether state was changed.
+func getVolumeForecastMetrics(state *exporterState, samples []volumeUsageSample, window time.Duration, now time.Time) ([]metric, bool) {
+	var changed bool
+	if state.VolumeUsageHistory == nil {
+		state.VolumeUsageHistory = make(map[string][]stateSample)
+	}
+
+	// Forget the samples which left the window, as well as volumes which are no longer present
+	cutoff := now.Add(-window)
+	for volume, history := range state.VolumeUsageHistory {
+		idx := sort.Search(len(history), func(i int) bool { return history[i].Time.After(cutoff) })
+		switch {
+		case idx == len(history):
+			delete(state.VolumeUsageHistory, volume)
+			changed = true
+		case idx > 0:
+			state.VolumeUsageHistory[volume] = history[idx:]
+			changed = true
+		}
+	}
+
+	metrics := make([]metric, 0, 2*len(samples))
+	for _, s := range samples {
+		history := state.VolumeUsageHistory[s.volume]
+		if len(history) == 0 || now.Sub(history[len(history)-1].Time) >= volumeUsageSampleInterval {
+			history = append(history, stateSample{Value: s.usedBytes, Time: now})
+			state.VolumeUsageHistory[s.volume] = history
+			changed = true
+		}
+
+		if len(history) < volumeForecastMinSamples || history[len(history)-1].Time.Sub(history[0].Time) < window/4 {
+			continue
+		}
+
+		growth := leastSquaresSlope(history) * 24
+		metrics = append(metrics, metric{
+			name:  "node_volume_usage_growth_bytes_per_day",
+			attr:  s.attr,
+			value: growth,
+			help:  "Trend of the used space in the volume over the volume forecast window, as the least squares slope in bytes per day",
+		})
+		// A volume whose usage is steady or shrinking never fills up
+		if growth > 0 {
+			metrics = append(metrics, metric{
+				name:  "node_volume_days_until_full",
+				attr:  s.attr,
+				value: s.availBytes / growth,
+				help:  "Estimated number of days until the volume is full, extrapolating the usage growth over the volume forecast window",
+			})
+		}
+	}
+
+	return metrics, changed
+}
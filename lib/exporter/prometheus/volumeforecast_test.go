@@ -0,0 +1,107 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetVolumeForecastMetrics(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	window := 7 * 24 * time.Hour
+	attr := `volume="DataVol1",filesystem="ext4",status="Ready"`
+	const gb = 1024 * 1024 * 1024
+
+	tests := map[string]struct {
+		history     []stateSample
+		used, avail float64
+		wantGrowth  *float64
+		wantDays    *float64
+		wantHistory int
+	}{
+		"first sample": {
+			used:        100 * gb,
+			avail:       900 * gb,
+			wantHistory: 1,
+		},
+		"growing": {
+			history: []stateSample{
+				{Value: 70 * gb, Time: now.Add(-3 * 24 * time.Hour)},
+				{Value: 80 * gb, Time: now.Add(-2 * 24 * time.Hour)},
+				{Value: 90 * gb, Time: now.Add(-24 * time.Hour)},
+			},
+			used:        100 * gb,
+			avail:       900 * gb,
+			wantGrowth:  floatPtr(10 * gb),
+			wantDays:    floatPtr(90),
+			wantHistory: 4,
+		},
+		"shrinking": {
+			history: []stateSample{
+				{Value: 130 * gb, Time: now.Add(-3 * 24 * time.Hour)},
+				{Value: 120 * gb, Time: now.Add(-2 * 24 * time.Hour)},
+				{Value: 110 * gb, Time: now.Add(-24 * time.Hour)},
+			},
+			used:        100 * gb,
+			avail:       900 * gb,
+			wantGrowth:  floatPtr(-10 * gb),
+			wantHistory: 4,
+		},
+		"not enough span": {
+			history: []stateSample{
+				{Value: 98 * gb, Time: now.Add(-3 * time.Hour)},
+				{Value: 99 * gb, Time: now.Add(-2 * time.Hour)},
+			},
+			used:        100 * gb,
+			avail:       900 * gb,
+			wantHistory: 3,
+		},
+		"old samples are pruned": {
+			history: []stateSample{
+				{Value: 10 * gb, Time: now.Add(-10 * 24 * time.Hour)},
+				{Value: 80 * gb, Time: now.Add(-2 * 24 * time.Hour)},
+				{Value: 90 * gb, Time: now.Add(-24 * time.Hour)},
+			},
+			used:        100 * gb,
+			avail:       100 * gb,
+			wantGrowth:  floatPtr(10 * gb),
+			wantDays:    floatPtr(10),
+			wantHistory: 3,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			state := exporterState{VolumeUsageHistory: map[string][]stateSample{"gone": {{Value: 1, Time: now.Add(-8 * 24 * time.Hour)}}}}
+			if tc.history != nil {
+				state.VolumeUsageHistory["DataVol1"] = tc.history
+			}
+
+			samples := []volumeUsageSample{{volume: "DataVol1", attr: attr, usedBytes: tc.used, availBytes: tc.avail}}
+			metrics, changed := getVolumeForecastMetrics(&state, samples, window, now)
+
+			assert.True(t, changed)
+			assert.NotContains(t, state.VolumeUsageHistory, "gone")
+			assert.Len(t, state.VolumeUsageHistory["DataVol1"], tc.wantHistory)
+
+			values := map[string]float64{}
+			for _, m := range metrics {
+				assert.Equal(t, attr, m.attr)
+				values[m.name] = m.value
+			}
+			if tc.wantGrowth == nil {
+				assert.Empty(t, metrics)
+				return
+			}
+			require.Contains(t, values, "node_volume_usage_growth_bytes_per_day")
+			assert.InDelta(t, *tc.wantGrowth, values["node_volume_usage_growth_bytes_per_day"], 1)
+			if tc.wantDays == nil {
+				assert.NotContains(t, values, "node_volume_days_until_full")
+			} else {
+				assert.InDelta(t, *tc.wantDays, values["node_volume_days_until_full"], 1e-6)
+			}
+		})
+	}
+}
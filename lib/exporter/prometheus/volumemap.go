@@ -0,0 +1,141 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// getVolumeDeviceMetrics maps each mounted volume to the stack of block devices backing it
+// (e.g. dm-0 -> md1 -> sda3 -> sda), so that per-volume I/O can be computed by joining with the disk metrics
+func (e *promExporter) getVolumeDeviceMetrics(ctx context.Context) ([]metric, error) {
+	mounts, err := mountedDeviceStacks(e.sys, ctx, e.blockDir)
+	if err != nil {
+		return nil, err
+	}
+
+	volumeNames := e.volumeMountpoints()
+	var metrics []metric
+	for _, m := range mounts {
+		for _, dev := range m.devices {
+			metrics = append(metrics, metric{
+				name:  "node_volume_device_info",
+				attr:  fmt.Sprintf("volume=%q,mountpoint=%q,device=%q", volumeNames[m.mountpoint], m.mountpoint, dev),
+				value: 1,
+				help:  "Block devices backing each mounted volume, from the mapped device down to the physical disks",
+			})
+		}
+	}
+
+	return metrics, nil
+}
+
+// volumeDeviceStacks maps the name of each mounted volume to the stack of block devices backing it
+func (e *promExporter) volumeDeviceStacks(ctx context.Context) map[string][]string {
+	mounts, _ := mountedDeviceStacks(e.sys, ctx, e.blockDir)
+	volumeNames := e.volumeMountpoints()
+
+	stacks := make(map[string][]string, len(mounts))
+	for _, m := range mounts {
+		if volume := volumeNames[m.mountpoint]; volume != "" {
+			stacks[volume] = m.devices
+		}
+	}
+
+	return stacks
+}
+
+type mountedDeviceStack struct {
+	mountpoint string
+	devices    []string
+}
+
+// mountedDeviceStacks returns the stack of block devices backing each mounted block device
+func mountedDeviceStacks(sys utils.System, ctx context.Context, root string) ([]mountedDeviceStack, error) {
+	partitions, err := disk.PartitionsWithContext(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(partitions))
+	var mounts []mountedDeviceStack
+	for _, p := range partitions {
+		if !strings.HasPrefix(p.Device, devDir+"/") || seen[p.Mountpoint] {
+			continue
+		}
+		seen[p.Mountpoint] = true
+
+		// Resolve e.g. /dev/mapper/cachedev1 to dm-0
+		device, err := sys.EvalSymlinks(p.Device)
+		if err != nil {
+			continue
+		}
+
+		mounts = append(mounts, mountedDeviceStack{
+			mountpoint: p.Mountpoint,
+			devices:    resolveBlockDeviceStack(sys, root, filepath.Base(device)),
+		})
+	}
+
+	return mounts, nil
+}
+
+// volumeMountpoints maps the mount point of each volume reported by getsysinfo to its name,
+// following the /share/<volume name> links created by QTS
+func (e *promExporter) volumeMountpoints() map[string]string {
+	e.volumesMu.Lock()
+	volumes := append([]volumeInfo(nil), e.volumes...)
+	e.volumesMu.Unlock()
+
+	names := make(map[string]string, len(volumes))
+	for _, v := range volumes {
+		mountpoint, err := e.sys.EvalSymlinks(filepath.Join(shareDir, v.description))
+		if err == nil {
+			names[mountpoint] = v.description
+		}
+	}
+
+	return names
+}
+
+// resolveBlockDeviceStack returns name followed by every block device it is built on, as found in the
+// slaves directories of root (e.g. /sys/class/block or /sys/block), along with the disks holding the partitions found
+func resolveBlockDeviceStack(sys utils.System, root string, name string) []string {
+	if blockDevicePath(sys, root, name) == "" {
+		return nil
+	}
+
+	stack := []string{name}
+	seen := map[string]bool{name: true}
+	for idx := 0; idx < len(stack); idx++ {
+		dir := blockDevicePath(sys, root, stack[idx])
+		if dir == "" {
+			continue
+		}
+		var lower []string
+
+		slaves, _ := sys.ReadDir(filepath.Join(dir, "slaves"))
+		for _, s := range slaves {
+			lower = append(lower, s.Name())
+		}
+		if _, err := sys.Stat(filepath.Join(dir, "partition")); err == nil {
+			// The device directory of a partition is nested in the one of its disk
+			if path, err := sys.EvalSymlinks(dir); err == nil {
+				lower = append(lower, filepath.Base(filepath.Dir(path)))
+			}
+		}
+
+		for _, l := range lower {
+			if !seen[l] {
+				seen[l] = true
+				stack = append(stack, l)
+			}
+		}
+	}
+
+	return stack
+}
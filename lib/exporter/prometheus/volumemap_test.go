@@ -0,0 +1,84 @@
+package prometheus
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveBlockDeviceStack(t *testing.T) {
+	root := t.TempDir()
+	devices := filepath.Join(root, "devices")
+	for _, dir := range []string{
+		"devices/dm-0/slaves", "devices/dm-1/slaves", "devices/md1/slaves",
+		"devices/sda/sda3", "devices/sdb/sdb3",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0o755))
+	}
+	for _, p := range []string{"sda/sda3", "sdb/sdb3"} {
+		require.NoError(t, os.WriteFile(filepath.Join(devices, p, "partition"), []byte("3\n"), 0o644))
+	}
+	links := map[string]string{
+		"dm-0":                     "devices/dm-0",
+		"dm-1":                     "devices/dm-1",
+		"md1":                      "devices/md1",
+		"sda":                      "devices/sda",
+		"sda3":                     "devices/sda/sda3",
+		"sdb":                      "devices/sdb",
+		"sdb3":                     "devices/sdb/sdb3",
+		"devices/dm-0/slaves/dm-1": "devices/dm-1",
+		"devices/dm-1/slaves/md1":  "devices/md1",
+		"devices/md1/slaves/sda3":  "devices/sda/sda3",
+		"devices/md1/slaves/sdb3":  "devices/sdb/sdb3",
+	}
+	for name, target := range links {
+		require.NoError(t, os.Symlink(filepath.Join(root, target), filepath.Join(root, name)))
+	}
+
+	testCases := map[string]struct {
+		device string
+		want   []string
+	}{
+		"thin volume on cache and raid": {
+			device: "dm-0",
+			want:   []string{"dm-0", "dm-1", "md1", "sda3", "sdb3", "sda", "sdb"},
+		},
+		"partition": {
+			device: "sda3",
+			want:   []string{"sda3", "sda"},
+		},
+		"whole disk": {
+			device: "sdb",
+			want:   []string{"sdb"},
+		},
+		"unknown device": {
+			device: "nvme0n1",
+			want:   nil,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, resolveBlockDeviceStack(utils.System{}, root, tc.device))
+		})
+	}
+}
+
+func TestResolveBlockDeviceStackLegacyLayout(t *testing.T) {
+	// In the deprecated sysfs layout, the devices are directories and the partitions are nested in their disk
+	root := t.TempDir()
+	for _, dir := range []string{"md0/slaves", "sda/sda3", "sdb/sdb3"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0o755))
+	}
+	for _, p := range []string{"sda/sda3", "sdb/sdb3"} {
+		require.NoError(t, os.WriteFile(filepath.Join(root, p, "partition"), []byte("3\n"), 0o644))
+		require.NoError(t, os.Symlink(filepath.Join(root, p), filepath.Join(root, "md0", "slaves", filepath.Base(p))))
+	}
+
+	assert.Equal(t, []string{"md0", "sda3", "sdb3", "sda", "sdb"}, resolveBlockDeviceStack(utils.System{}, root, "md0"))
+	assert.Equal(t, []string{"sdb3", "sdb"}, resolveBlockDeviceStack(utils.System{}, root, "sdb3"))
+}
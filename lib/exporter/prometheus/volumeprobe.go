@@ -0,0 +1,153 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// volumeProbeFile is the file written at the root of each volume by the volume probe, when VolumeProbeWrite is set
+	volumeProbeFile = ".qnapexporter-probe"
+	volumeProbeSize = 4096
+)
+
+// getVolumeProbeMetrics times the read of the root directory of each volume, or the synchronous write and stat of a
+// small file at its root when VolumeProbeWrite is set, through the local file system. This catches the slowness of a
+// whole storage pool (e.g. a rebuilding RAID), which is diluted in the averages of the disk statistics.
+func (e *promExporter) getVolumeProbeMetrics(ctx context.Context) ([]metric, error) {
+	if !e.VolumeProbe {
+		return nil, nil
+	}
+
+	mountpoints := e.volumeMountpoints()
+	if len(mountpoints) == 0 {
+		return nil, subsystemAbsentError{"no mounted volume found"}
+	}
+
+	volumes := make([]string, 0, len(mountpoints))
+	byVolume := make(map[string]string, len(mountpoints))
+	for mountpoint, volume := range mountpoints {
+		volumes = append(volumes, volume)
+		byVolume[volume] = mountpoint
+	}
+	sort.Strings(volumes)
+
+	// Probe the volumes concurrently, so that a storage pool which stopped responding doesn't delay the others
+	durations := make([]time.Duration, len(volumes))
+	errs := make([]error, len(volumes))
+	var wg sync.WaitGroup
+	for idx, volume := range volumes {
+		wg.Add(1)
+		go func(idx int, volume string) {
+			defer wg.Done()
+
+			start := time.Now()
+			errs[idx] = probeVolume(ctx, byVolume[volume], e.VolumeProbeWrite)
+			durations[idx] = time.Since(start)
+		}(idx, volume)
+	}
+	wg.Wait()
+
+	var err error
+	metrics := make([]metric, 0, 2*len(volumes))
+	for idx, volume := range volumes {
+		var success float64 = 1
+		if errs[idx] != nil {
+			success = 0
+			err = fmt.Errorf("probe volume %s: %w", volume, errs[idx])
+		}
+
+		attr := fmt.Sprintf("volume=%q", volume)
+		metrics = append(metrics,
+			metric{
+				name:  "node_volume_probe_success",
+				attr:  attr,
+				value: success,
+				help:  "Whether the root of the volume could be probed",
+			},
+			metric{
+				name:  "node_volume_probe_duration_seconds",
+				attr:  attr,
+				value: durations[idx].Seconds(),
+				help:  "Time taken to probe the root of the volume",
+			},
+		)
+	}
+
+	return metrics, err
+}
+
+// probeVolume reads the root directory of the volume mounted at root or, if write is set, overwrites the probe file
+// at its root, waits for it to reach the storage and reads its attributes.
+// A storage pool which stopped responding blocks the file system calls, so that the probe gives up when ctx is done.
+func probeVolume(ctx context.Context, root string, write bool) error {
+	done := make(chan error, 1)
+	go func() {
+		if write {
+			done <- writeProbeFile(filepath.Join(root, volumeProbeFile))
+			return
+		}
+		done <- readProbeDir(root)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// readProbeDir opens the directory at path, and reads its attributes and its first entry, without writing anything
+func readProbeDir(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Stat(); err != nil {
+		return err
+	}
+	if _, err := f.Readdirnames(1); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
+
+func writeProbeFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(make([]byte, volumeProbeSize))
+	if err == nil {
+		// Without the sync, the write would only reach the page cache
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() != volumeProbeSize {
+		return fmt.Errorf("probe file holds %d bytes instead of %d", info.Size(), volumeProbeSize)
+	}
+
+	return nil
+}
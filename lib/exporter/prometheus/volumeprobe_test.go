@@ -0,0 +1,80 @@
+package prometheus
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeVolume(t *testing.T) {
+	tests := map[string]struct {
+		setup   func(t *testing.T, dir string) string
+		write   bool
+		wantErr bool
+	}{
+		"read": {
+			setup: func(t *testing.T, dir string) string {
+				return dir
+			},
+		},
+		"read of an empty volume": {
+			setup: func(t *testing.T, dir string) string {
+				path := filepath.Join(dir, "empty")
+				require.NoError(t, os.Mkdir(path, 0o700))
+				return path
+			},
+		},
+		"read of a missing volume": {
+			setup: func(t *testing.T, dir string) string {
+				return filepath.Join(dir, "missing")
+			},
+			wantErr: true,
+		},
+		"write of a new file": {
+			setup: func(t *testing.T, dir string) string {
+				return dir
+			},
+			write: true,
+		},
+		"write of an existing file": {
+			setup: func(t *testing.T, dir string) string {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, volumeProbeFile), make([]byte, 2*volumeProbeSize), 0o600))
+				return dir
+			},
+			write: true,
+		},
+		"write to a missing volume": {
+			setup: func(t *testing.T, dir string) string {
+				return filepath.Join(dir, "missing")
+			},
+			write:   true,
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			root := tc.setup(t, dir)
+
+			err := probeVolume(context.Background(), root, tc.write)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			info, err := os.Stat(filepath.Join(root, volumeProbeFile))
+			if !tc.write {
+				assert.True(t, os.IsNotExist(err), "the read probe wrote a file")
+				return
+			}
+			require.NoError(t, err)
+			assert.EqualValues(t, volumeProbeSize, info.Size())
+		})
+	}
+}
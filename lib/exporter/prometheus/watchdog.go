@@ -0,0 +1,145 @@
+package prometheus
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+)
+
+const watchdogInterval = 1 * time.Second
+
+// watchdog keeps track of the collectors currently running, and reports the ones
+// which have been running for longer than the configured timeout
+type watchdog struct {
+	mu         sync.Mutex
+	timeout    time.Duration
+	onHung     func(collector string)
+	running    map[string]time.Time
+	reported   map[string]bool
+	detections map[string]int
+	done       chan struct{}
+	doneOnce   sync.Once
+	logger     *logging.Logger
+}
+
+func newWatchdog(timeout time.Duration, onHung func(collector string), logger *logging.Logger) *watchdog {
+	return &watchdog{
+		timeout:    timeout,
+		onHung:     onHung,
+		running:    map[string]time.Time{},
+		reported:   map[string]bool{},
+		detections: map[string]int{},
+		done:       make(chan struct{}),
+		logger:     logger,
+	}
+}
+
+func (w *watchdog) configure(timeout time.Duration, onHung func(collector string)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.timeout = timeout
+	w.onHung = onHung
+}
+
+// shutdown stops runWatchdog, and can safely be called more than once
+func (w *watchdog) shutdown() {
+	w.doneOnce.Do(func() { close(w.done) })
+}
+
+func (w *watchdog) start(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.running[name] = time.Now()
+	delete(w.reported, name)
+}
+
+func (w *watchdog) isRunning(name string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, running := w.running[name]
+	return running
+}
+
+func (w *watchdog) stop(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.running, name)
+}
+
+// hungCollectors returns the collectors which exceeded the timeout since the last check,
+// incrementing their detection count, along with the callback to notify
+func (w *watchdog) hungCollectors() ([]string, func(collector string)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timeout <= 0 {
+		return nil, nil
+	}
+
+	var hung []string
+	for name, start := range w.running {
+		if w.reported[name] || time.Since(start) < w.timeout {
+			continue
+		}
+
+		w.reported[name] = true
+		w.detections[name]++
+		hung = append(hung, name)
+	}
+
+	return hung, w.onHung
+}
+
+func (w *watchdog) metrics(collectors []collector) []metric {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timeout <= 0 {
+		return nil
+	}
+
+	metrics := make([]metric, 0, len(collectors))
+	for _, c := range collectors {
+		metrics = append(metrics, metric{
+			name:       "qnap_exporter_watchdog_detections_total",
+			attr:       fmt.Sprintf("collector=%q", c.name),
+			value:      float64(w.detections[c.name]),
+			help:       "Number of times the watchdog detected a hung collector",
+			metricType: "counter",
+		})
+	}
+
+	return metrics
+}
+
+func (e *promExporter) runWatchdog() {
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			hung, onHung := e.watchdog.hungCollectors()
+			for _, name := range hung {
+				e.watchdog.logger.Warn("Collector has been running for longer than the watchdog timeout", "collector", name)
+
+				if name == "ups" {
+					// The hung call keeps holding the UPS lock, but make sure the next
+					// UPS scrape starts with a fresh connection once it returns
+					e.upsState.resetRequested.Store(true)
+				}
+				if onHung != nil {
+					onHung(name)
+				}
+			}
+		case <-e.watchdog.done:
+			return
+		}
+	}
+}
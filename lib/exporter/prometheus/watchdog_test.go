@@ -0,0 +1,51 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchdogHungCollectors(t *testing.T) {
+	w := newWatchdog(10*time.Millisecond, nil, nil)
+
+	w.start("ups")
+	w.start("cpu")
+	w.stop("cpu")
+
+	hung, _ := w.hungCollectors()
+	assert.Empty(t, hung)
+
+	time.Sleep(20 * time.Millisecond)
+	hung, _ = w.hungCollectors()
+	assert.Equal(t, []string{"ups"}, hung)
+
+	// Only reported once per run
+	hung, _ = w.hungCollectors()
+	assert.Empty(t, hung)
+
+	metrics := w.metrics([]collector{{name: "ups"}, {name: "cpu"}})
+	if assert.Len(t, metrics, 2) {
+		assert.Equal(t, 1.0, metrics[0].value)
+		assert.Equal(t, 0.0, metrics[1].value)
+	}
+}
+
+func TestWatchdogDisabled(t *testing.T) {
+	w := newWatchdog(0, nil, nil)
+
+	w.start("ups")
+	hung, _ := w.hungCollectors()
+	assert.Empty(t, hung)
+	assert.Empty(t, w.metrics([]collector{{name: "ups"}}))
+}
+
+func TestWatchdogShutdownTwice(t *testing.T) {
+	w := newWatchdog(0, nil, nil)
+
+	assert.NotPanics(t, func() {
+		w.shutdown()
+		w.shutdown()
+	})
+}
@@ -0,0 +1,152 @@
+package prometheus
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// watermarkSpec describes a value tracked since boot, derived from the metrics of a collector
+type watermarkSpec struct {
+	collector string
+	name      string
+	help      string
+	// max selects whether the highest (true) or lowest (false) value is tracked
+	max bool
+	// values extracts the tracked values from the collector metrics, keyed by attributes
+	values func(metrics []metric) map[string]float64
+}
+
+var watermarkSpecs = []watermarkSpec{
+	{
+		collector: "systemp",
+		name:      "node_cputmp_C_max",
+		help:      "Highest CPU temperature since boot",
+		max:       true,
+		values:    metricValues("node_cputmp_C"),
+	},
+	{
+		collector: "hwmon",
+		name:      "node_cputmp_C_max",
+		help:      "Highest CPU temperature since boot",
+		max:       true,
+		values:    metricValues("node_cputmp_C"),
+	},
+	{
+		collector: "volume",
+		name:      "node_volume_usage_ratio_max",
+		help:      "Highest ratio of used space in the volume since boot",
+		max:       true,
+		values:    volumeUsageValues,
+	},
+	{
+		collector: "meminfo",
+		name:      "node_memory_MemAvailable_bytes_min",
+		help:      "Lowest memory available for starting new applications since boot",
+		values:    metricValues("node_memory_MemAvailable_bytes"),
+	},
+}
+
+// watermarkState holds the watermarks, which are reset when the system boots
+type watermarkState struct {
+	BootTime time.Time `json:"boot_time"`
+	// Values maps "<metric name>{<attributes>}" to the watermark and the time it was reached
+	Values map[string]stateSample `json:"values,omitempty"`
+}
+
+func metricValues(name string) func(metrics []metric) map[string]float64 {
+	return func(metrics []metric) map[string]float64 {
+		values := map[string]float64{}
+		for _, m := range metrics {
+			if m.name == name {
+				values[m.attr] = m.value
+			}
+		}
+
+		return values
+	}
+}
+
+func volumeUsageValues(metrics []metric) map[string]float64 {
+	sizes := metricValues("node_volume_size_bytes")(metrics)
+	values := map[string]float64{}
+	for attr, avail := range metricValues("node_volume_avail_bytes")(metrics) {
+		if size := sizes[attr]; size > 0 {
+			values[attr] = 1 - avail/size
+		}
+	}
+
+	return values
+}
+
+// getWatermarkMetrics updates the watermarks derived from the metrics of collector, persisting them in the state file
+func (e *promExporter) getWatermarkMetrics(ctx context.Context, collector string, metrics []metric) ([]metric, error) {
+	var specs []watermarkSpec
+	for _, spec := range watermarkSpecs {
+		if spec.collector == collector {
+			specs = append(specs, spec)
+		}
+	}
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	bootTime, err := host.BootTimeWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var watermarks []metric
+	err = e.state.update(func(state *exporterState) bool {
+		var changed bool
+		watermarks, changed = updateWatermarks(state, specs, metrics, time.Unix(int64(bootTime), 0), time.Now())
+		return changed
+	})
+
+	return watermarks, err
+}
+
+// updateWatermarks records the values of metrics exceeding the watermarks of specs, which are reset
+// if the system booted since they were recorded, and returns the watermark metrics
+func updateWatermarks(state *exporterState, specs []watermarkSpec, metrics []metric, bootTime time.Time, now time.Time) ([]metric, bool) {
+	var changed bool
+	if state.Watermarks == nil || !state.Watermarks.BootTime.Equal(bootTime) {
+		state.Watermarks = &watermarkState{BootTime: bootTime}
+		changed = true
+	}
+	if state.Watermarks.Values == nil {
+		state.Watermarks.Values = make(map[string]stateSample)
+	}
+
+	var watermarks []metric
+	for _, spec := range specs {
+		values := spec.values(metrics)
+		attrs := make([]string, 0, len(values))
+		for attr := range values {
+			attrs = append(attrs, attr)
+		}
+		sort.Strings(attrs)
+
+		for _, attr := range attrs {
+			value := values[attr]
+			key := getMetricFullName("", metric{name: spec.name, attr: attr})
+			watermark, found := state.Watermarks.Values[key]
+			if !found || spec.max && value > watermark.Value || !spec.max && value < watermark.Value {
+				watermark = stateSample{Value: value, Time: now}
+				state.Watermarks.Values[key] = watermark
+				changed = true
+			}
+
+			watermarks = append(watermarks, metric{
+				name:  spec.name,
+				attr:  attr,
+				value: watermark.Value,
+				help:  spec.help,
+			})
+		}
+	}
+
+	return watermarks, changed
+}
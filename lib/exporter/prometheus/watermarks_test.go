@@ -0,0 +1,103 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateWatermarks(t *testing.T) {
+	boot := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	now := boot.Add(24 * time.Hour)
+	specs := watermarkSpecs
+
+	testCases := map[string]struct {
+		state       exporterState
+		metrics     []metric
+		bootTime    time.Time
+		wantMetrics []metric
+		wantChanged bool
+	}{
+		"first values": {
+			metrics: []metric{
+				{name: "node_cputmp_C", value: 55},
+				{name: "node_memory_MemAvailable_bytes", value: 1000},
+			},
+			bootTime: boot,
+			wantMetrics: []metric{
+				{name: "node_cputmp_C_max", value: 55, help: "Highest CPU temperature since boot"},
+				{name: "node_memory_MemAvailable_bytes_min", value: 1000, help: "Lowest memory available for starting new applications since boot"},
+			},
+			wantChanged: true,
+		},
+		"watermarks kept": {
+			state: exporterState{Watermarks: &watermarkState{
+				BootTime: boot,
+				Values: map[string]stateSample{
+					"node_cputmp_C_max":                  {Value: 70, Time: boot},
+					"node_memory_MemAvailable_bytes_min": {Value: 500, Time: boot},
+				},
+			}},
+			metrics: []metric{
+				{name: "node_cputmp_C", value: 55},
+				{name: "node_memory_MemAvailable_bytes", value: 1000},
+			},
+			bootTime: boot,
+			wantMetrics: []metric{
+				{name: "node_cputmp_C_max", value: 70, help: "Highest CPU temperature since boot"},
+				{name: "node_memory_MemAvailable_bytes_min", value: 500, help: "Lowest memory available for starting new applications since boot"},
+			},
+		},
+		"reset on boot": {
+			state: exporterState{Watermarks: &watermarkState{
+				BootTime: boot,
+				Values:   map[string]stateSample{"node_cputmp_C_max": {Value: 70, Time: boot}},
+			}},
+			metrics:  []metric{{name: "node_cputmp_C", value: 55}},
+			bootTime: boot.Add(time.Hour),
+			wantMetrics: []metric{
+				{name: "node_cputmp_C_max", value: 55, help: "Highest CPU temperature since boot"},
+			},
+			wantChanged: true,
+		},
+		"volume usage": {
+			state: exporterState{Watermarks: &watermarkState{
+				BootTime: boot,
+				Values:   map[string]stateSample{`node_volume_usage_ratio_max{volume="DataVol1"}`: {Value: 0.5, Time: boot}},
+			}},
+			metrics: []metric{
+				{name: "node_volume_avail_bytes", attr: `volume="DataVol1"`, value: 25},
+				{name: "node_volume_size_bytes", attr: `volume="DataVol1"`, value: 100},
+				{name: "node_volume_avail_bytes", attr: `volume="DataVol2"`, value: 50},
+				{name: "node_volume_size_bytes", attr: `volume="DataVol2"`, value: 100},
+			},
+			bootTime: boot,
+			wantMetrics: []metric{
+				{name: "node_volume_usage_ratio_max", attr: `volume="DataVol1"`, value: 0.75, help: "Highest ratio of used space in the volume since boot"},
+				{name: "node_volume_usage_ratio_max", attr: `volume="DataVol2"`, value: 0.5, help: "Highest ratio of used space in the volume since boot"},
+			},
+			wantChanged: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var metrics []metric
+			var changed bool
+			for _, spec := range specs {
+				if spec.collector == "hwmon" {
+					// Same watermark as the systemp collector
+					continue
+				}
+
+				m, c := updateWatermarks(&tc.state, []watermarkSpec{spec}, tc.metrics, tc.bootTime, now)
+				metrics = append(metrics, m...)
+				changed = changed || c
+			}
+
+			assert.Equal(t, tc.wantMetrics, metrics)
+			assert.Equal(t, tc.wantChanged, changed)
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+// zpoolListProperties are the pool properties read by `zpool list`, in the order of zpoolPool's fields
+const zpoolListProperties = "name,size,allocated,free,fragmentation,capacity,dedupratio,health"
+
+var (
+	zfsScrubProgressRe = regexp.MustCompile(`([\d.]+)% done`)
+	zfsScrubDoneRe     = regexp.MustCompile(`^scrub repaired \S+ in \S+ with (\d+) errors on (.+)$`)
+	zfsDataErrorsRe    = regexp.MustCompile(`^(\d+) data errors`)
+
+	// zfsPoolStates are the states reported by zpool for a pool
+	zfsPoolStates = []string{"ONLINE", "DEGRADED", "FAULTED", "OFFLINE", "REMOVED", "UNAVAIL", "SUSPENDED"}
+
+	// zfsArcStats maps the ARC statistics read from arcstats to the metrics they are exported as
+	zfsArcStats = []struct {
+		stat       string
+		name       string
+		help       string
+		metricType string
+	}{
+		{stat: "size", name: "node_zfs_arc_size_bytes", help: "Current size of the ZFS ARC"},
+		{stat: "c", name: "node_zfs_arc_target_size_bytes", help: "Target size of the ZFS ARC"},
+		{stat: "c_max", name: "node_zfs_arc_max_size_bytes", help: "Maximum size of the ZFS ARC"},
+		{stat: "hits", name: "node_zfs_arc_hits_total", help: "Number of ZFS ARC hits", metricType: "counter"},
+		{stat: "misses", name: "node_zfs_arc_misses_total", help: "Number of ZFS ARC misses", metricType: "counter"},
+		{stat: "l2_size", name: "node_zfs_l2arc_size_bytes", help: "Current size of the ZFS L2ARC (SSD cache)"},
+		{stat: "l2_hits", name: "node_zfs_l2arc_hits_total", help: "Number of ZFS L2ARC (SSD cache) hits", metricType: "counter"},
+		{stat: "l2_misses", name: "node_zfs_l2arc_misses_total", help: "Number of ZFS L2ARC (SSD cache) misses", metricType: "counter"},
+	}
+)
+
+type zpoolPool struct {
+	name          string
+	size          float64
+	allocated     float64
+	free          float64
+	fragmentation *float64
+	capacity      float64
+	dedupRatio    float64
+	health        string
+}
+
+type zpoolStatus struct {
+	scrubInProgress    bool
+	scrubProgress      float64
+	resilverInProgress bool
+	lastScrub          time.Time
+	lastScrubErrors    float64
+	dataErrors         *float64
+}
+
+type zfsDataset struct {
+	name     string
+	property string
+	value    float64
+}
+
+// getZfsMetrics reports the health, capacity and scrub status of the ZFS pools used by QuTS hero,
+// along with the space used by their datasets and the ARC statistics
+func (e *promExporter) getZfsMetrics(ctx context.Context) ([]metric, error) {
+	if e.zpool == "" {
+		return nil, subsystemAbsentError{"zpool not found, ZFS is only used by QuTS hero"}
+	}
+
+	lines, err := e.sys.ExecCommandGetLines(ctx, e.zpool, "list", "-Hp", "-o", zpoolListProperties)
+	if err != nil {
+		return nil, fmt.Errorf("list ZFS pools: %w", err)
+	}
+	pools := parseZpoolList(lines)
+	if len(pools) == 0 {
+		return nil, subsystemAbsentError{"no ZFS pool found"}
+	}
+
+	output, err := e.sys.ExecCommand(ctx, e.zpool, "status")
+	if err != nil {
+		return nil, fmt.Errorf("get ZFS pool status: %w", err)
+	}
+	statuses := parseZpoolStatus(output)
+
+	metrics := make([]metric, 0, len(pools)*(15+len(zfsPoolStates))+len(zfsArcStats))
+	for _, p := range pools {
+		metrics = append(metrics, p.metrics(statuses[p.name])...)
+	}
+
+	if e.zfs != "" {
+		lines, err := e.sys.ExecCommandGetLines(ctx, e.zfs, "get", "-Hp", "-t", "filesystem,volume", "-o", "name,property,value", "used,available,compressratio")
+		if err != nil {
+			return metrics, fmt.Errorf("get ZFS dataset properties: %w", err)
+		}
+		metrics = append(metrics, zfsDatasetMetrics(parseZfsGet(lines))...)
+	}
+
+	arcMetrics, err := getZfsArcMetrics(e.sys, zfsArcStatsPath)
+	if err != nil {
+		return metrics, err
+	}
+
+	return append(metrics, arcMetrics...), nil
+}
+
+// parseZpoolList parses the output of `zpool list -Hp -o <zpoolListProperties>`
+func parseZpoolList(lines []string) []zpoolPool {
+	pools := make([]zpoolPool, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 8 {
+			continue
+		}
+
+		p := zpoolPool{name: fields[0], health: fields[7]}
+		p.size, _ = utils.ParseFloat(fields[1])
+		p.allocated, _ = utils.ParseFloat(fields[2])
+		p.free, _ = utils.ParseFloat(fields[3])
+		// The fragmentation is reported as "-" for pools without the spacemap_histogram feature
+		if fragmentation, err := utils.ParseFloat(strings.TrimSuffix(fields[4], "%")); err == nil {
+			fragmentation /= 100
+			p.fragmentation = &fragmentation
+		}
+		p.capacity, _ = utils.ParseFloat(strings.TrimSuffix(fields[5], "%"))
+		p.dedupRatio, _ = utils.ParseFloat(strings.TrimSuffix(fields[6], "x"))
+		pools = append(pools, p)
+	}
+
+	return pools
+}
+
+// parseZpoolStatus parses the scan and errors lines of `zpool status`, by pool name
+func parseZpoolStatus(output string) map[string]zpoolStatus {
+	statuses := map[string]zpoolStatus{}
+	var pool, section string
+	for _, line := range strings.Split(output, "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		// Sections such as "scan:" span several lines, with the continuation lines indented by a tab
+		if !found || strings.HasPrefix(line, "\t") {
+			key, value = section, line
+		} else {
+			section = key
+		}
+		value = strings.TrimSpace(value)
+
+		s := statuses[pool]
+		switch key {
+		case "pool":
+			pool = value
+			statuses[pool] = zpoolStatus{}
+			continue
+		case "scan":
+			switch {
+			case strings.HasPrefix(value, "scrub in progress"):
+				s.scrubInProgress = true
+			case strings.HasPrefix(value, "resilver in progress"):
+				s.resilverInProgress = true
+			}
+			if m := zfsScrubProgressRe.FindStringSubmatch(value); m != nil && s.scrubInProgress {
+				s.scrubProgress, _ = utils.ParseFloat(m[1])
+			}
+			if m := zfsScrubDoneRe.FindStringSubmatch(value); m != nil {
+				if t, err := time.ParseInLocation(time.ANSIC, m[2], time.Local); err == nil {
+					s.lastScrub = t
+					s.lastScrubErrors, _ = utils.ParseFloat(m[1])
+				}
+			}
+		case "errors":
+			var count float64
+			if m := zfsDataErrorsRe.FindStringSubmatch(value); m != nil {
+				count, _ = utils.ParseFloat(m[1])
+			} else if value != "No known data errors" {
+				continue
+			}
+			s.dataErrors = &count
+		default:
+			continue
+		}
+		if pool != "" {
+			statuses[pool] = s
+		}
+	}
+
+	return statuses
+}
+
+func (p zpoolPool) metrics(s zpoolStatus) []metric {
+	attr := fmt.Sprintf("pool=%q", p.name)
+
+	metrics := []metric{
+		{name: "node_zfs_pool_size_bytes", attr: attr, value: p.size, help: "Total size of the ZFS pool"},
+		{name: "node_zfs_pool_allocated_bytes", attr: attr, value: p.allocated, help: "Space allocated in the ZFS pool"},
+		{name: "node_zfs_pool_free_bytes", attr: attr, value: p.free, help: "Free space in the ZFS pool"},
+		{name: "node_zfs_pool_capacity_ratio", attr: attr, value: p.capacity / 100, help: "Ratio of the ZFS pool space which is allocated"},
+		{name: "node_zfs_pool_dedup_ratio", attr: attr, value: p.dedupRatio, help: "Deduplication ratio of the ZFS pool"},
+	}
+	if p.fragmentation != nil {
+		metrics = append(metrics, metric{name: "node_zfs_pool_fragmentation_ratio", attr: attr, value: *p.fragmentation, help: "Fragmentation of the free space of the ZFS pool"})
+	}
+
+	var healthy float64
+	if p.health == "ONLINE" {
+		healthy = 1
+	}
+	metrics = append(metrics, metric{name: "node_zfs_pool_healthy", attr: attr, value: healthy, help: "Whether the ZFS pool is ONLINE"})
+	for _, state := range zfsPoolStates {
+		var value float64
+		if p.health == state {
+			value = 1
+		}
+		metrics = append(metrics, metric{
+			name:  "node_zfs_pool_state",
+			attr:  fmt.Sprintf("%s,state=%q", attr, state),
+			value: value,
+			help:  "Whether the ZFS pool is in the given state",
+		})
+	}
+
+	var scrubbing, resilvering float64
+	if s.scrubInProgress {
+		scrubbing = 1
+	}
+	if s.resilverInProgress {
+		resilvering = 1
+	}
+	metrics = append(
+		metrics,
+		metric{name: "node_zfs_pool_scrub_in_progress", attr: attr, value: scrubbing, help: "Whether a scrub of the ZFS pool is in progress"},
+		metric{name: "node_zfs_pool_resilver_in_progress", attr: attr, value: resilvering, help: "Whether a resilver of the ZFS pool is in progress"},
+	)
+	if s.scrubInProgress {
+		metrics = append(metrics, metric{name: "node_zfs_pool_scrub_progress_ratio", attr: attr, value: s.scrubProgress / 100, help: "Progress of the scrub of the ZFS pool in progress"})
+	}
+	if !s.lastScrub.IsZero() {
+		metrics = append(
+			metrics,
+			metric{name: "node_zfs_pool_last_scrub_timestamp_seconds", attr: attr, value: float64(s.lastScrub.Unix()), help: "Time at which the last scrub of the ZFS pool completed"},
+			metric{name: "node_zfs_pool_last_scrub_errors", attr: attr, value: s.lastScrubErrors, help: "Number of errors found by the last scrub of the ZFS pool"},
+		)
+	}
+	if s.dataErrors != nil {
+		metrics = append(metrics, metric{name: "node_zfs_pool_data_errors", attr: attr, value: *s.dataErrors, help: "Number of data errors reported by the ZFS pool"})
+	}
+
+	return metrics
+}
+
+// parseZfsGet parses the output of `zfs get -Hp -o name,property,value`
+func parseZfsGet(lines []string) []zfsDataset {
+	datasets := make([]zfsDataset, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			continue
+		}
+
+		value, err := utils.ParseFloat(strings.TrimSuffix(fields[2], "x"))
+		if err != nil {
+			continue
+		}
+		datasets = append(datasets, zfsDataset{name: fields[0], property: fields[1], value: value})
+	}
+
+	return datasets
+}
+
+func zfsDatasetMetrics(datasets []zfsDataset) []metric {
+	metrics := make([]metric, 0, len(datasets))
+	for _, d := range datasets {
+		pool, _, _ := strings.Cut(d.name, "/")
+		m := metric{attr: fmt.Sprintf("pool=%q,dataset=%q", pool, d.name), value: d.value}
+		switch d.property {
+		case "used":
+			m.name, m.help = "node_zfs_dataset_used_bytes", "Space used by the ZFS dataset and its descendants"
+		case "available":
+			m.name, m.help = "node_zfs_dataset_available_bytes", "Space available to the ZFS dataset"
+		case "compressratio":
+			m.name, m.help = "node_zfs_dataset_compression_ratio", "Compression ratio achieved for the ZFS dataset"
+		default:
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+
+	return metrics
+}
+
+// getZfsArcMetrics reads the ARC statistics from the arcstats kstat file
+func getZfsArcMetrics(sys utils.System, path string) ([]metric, error) {
+	lines, err := sys.ReadFileLines(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	// Each line holds the name, type and value of a statistic, after a header line
+	stats := map[string]float64{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		if value, err := utils.ParseFloat(fields[2]); err == nil {
+			stats[fields[0]] = value
+		}
+	}
+
+	metrics := make([]metric, 0, len(zfsArcStats))
+	for _, s := range zfsArcStats {
+		if value, found := stats[s.stat]; found {
+			metrics = append(metrics, metric{name: s.name, value: value, help: s.help, metricType: s.metricType})
+		}
+	}
+
+	return metrics, nil
+}
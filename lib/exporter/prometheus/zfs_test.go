@@ -0,0 +1,177 @@
+package prometheus
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseZpoolList(t *testing.T) {
+	lines := []string{
+		"zpool1\t3985729650688\t1195718895206\t2790010755482\t12\t30\t1.00\tONLINE",
+		"zpool2\t1992864825344\t996432412672\t996432412672\t-\t50\t1.25x\tDEGRADED",
+		"garbage",
+	}
+
+	pools := parseZpoolList(lines)
+
+	require.Len(t, pools, 2)
+	assert.Equal(t, zpoolPool{
+		name:          "zpool1",
+		size:          3985729650688,
+		allocated:     1195718895206,
+		free:          2790010755482,
+		fragmentation: floatPtr(0.12),
+		capacity:      30,
+		dedupRatio:    1,
+		health:        "ONLINE",
+	}, pools[0])
+	assert.Nil(t, pools[1].fragmentation)
+	assert.Equal(t, 1.25, pools[1].dedupRatio)
+	assert.Equal(t, "DEGRADED", pools[1].health)
+}
+
+func TestParseZpoolStatus(t *testing.T) {
+	output := `  pool: zpool1
+ state: ONLINE
+  scan: scrub repaired 0B in 01:23:45 with 2 errors on Sun Jun 12 01:47:46 2022
+config:
+
+	NAME                                      STATE     READ WRITE CKSUM
+	zpool1                                    ONLINE       0     0     0
+	  raidz1-0                                ONLINE       0     0     0
+	    qzfs/enc_0/disk_0x1_5000C500B4A6D7E8  ONLINE       0     0     0
+
+errors: No known data errors
+
+  pool: zpool2
+ state: DEGRADED
+status: One or more devices could not be used because the label is missing or
+	invalid.
+  scan: scrub in progress since Mon Jun 13 02:00:00 2022
+	1.23T scanned at 512M/s, 500G issued at 200M/s, 2T total
+	0B repaired, 25.00% done, 02:11:03 to go
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	zpool2      DEGRADED     0     0     0
+
+errors: 3 data errors, use '-v' for a list
+
+  pool: zpool3
+ state: ONLINE
+  scan: resilver in progress since Mon Jun 13 03:00:00 2022
+config:
+
+errors: No known data errors`
+
+	statuses := parseZpoolStatus(output)
+
+	require.Len(t, statuses, 3)
+	assert.Equal(t, zpoolStatus{
+		lastScrub:       time.Date(2022, 6, 12, 1, 47, 46, 0, time.Local),
+		lastScrubErrors: 2,
+		dataErrors:      floatPtr(0),
+	}, statuses["zpool1"])
+	assert.Equal(t, zpoolStatus{
+		scrubInProgress: true,
+		scrubProgress:   25,
+		dataErrors:      floatPtr(3),
+	}, statuses["zpool2"])
+	assert.Equal(t, zpoolStatus{
+		resilverInProgress: true,
+		dataErrors:         floatPtr(0),
+	}, statuses["zpool3"])
+}
+
+func TestZpoolPoolMetrics(t *testing.T) {
+	p := zpoolPool{name: "zpool1", size: 100, allocated: 30, free: 70, capacity: 30, dedupRatio: 1, health: "DEGRADED"}
+
+	metrics := p.metrics(zpoolStatus{scrubInProgress: true, scrubProgress: 25})
+
+	values := map[string]float64{}
+	for _, m := range metrics {
+		values[getMetricFullName("", m)] = m.value
+	}
+	assert.Equal(t, 0.3, values[`node_zfs_pool_capacity_ratio{pool="zpool1"}`])
+	assert.Equal(t, 0.0, values[`node_zfs_pool_healthy{pool="zpool1"}`])
+	assert.Equal(t, 1.0, values[`node_zfs_pool_state{pool="zpool1",state="DEGRADED"}`])
+	assert.Equal(t, 0.0, values[`node_zfs_pool_state{pool="zpool1",state="ONLINE"}`])
+	assert.Equal(t, 1.0, values[`node_zfs_pool_scrub_in_progress{pool="zpool1"}`])
+	assert.Equal(t, 0.25, values[`node_zfs_pool_scrub_progress_ratio{pool="zpool1"}`])
+	assert.NotContains(t, values, `node_zfs_pool_fragmentation_ratio{pool="zpool1"}`)
+	assert.NotContains(t, values, `node_zfs_pool_last_scrub_timestamp_seconds{pool="zpool1"}`)
+}
+
+func TestZfsDatasetMetrics(t *testing.T) {
+	lines := []string{
+		"zpool1\tused\t1195718895206",
+		"zpool1/zfs1\tavailable\t2790010755482",
+		"zpool1/zfs1\tcompressratio\t1.50x",
+		"zpool1/zfs1\tused\t-",
+	}
+
+	metrics := zfsDatasetMetrics(parseZfsGet(lines))
+
+	require.Len(t, metrics, 3)
+	assert.Equal(t, "node_zfs_dataset_used_bytes", metrics[0].name)
+	assert.Equal(t, `pool="zpool1",dataset="zpool1"`, metrics[0].attr)
+	assert.Equal(t, "node_zfs_dataset_available_bytes", metrics[1].name)
+	assert.Equal(t, `pool="zpool1",dataset="zpool1/zfs1"`, metrics[1].attr)
+	assert.Equal(t, "node_zfs_dataset_compression_ratio", metrics[2].name)
+	assert.Equal(t, 1.5, metrics[2].value)
+}
+
+func TestGetZfsArcMetrics(t *testing.T) {
+	tests := map[string]struct {
+		contents string
+		want     map[string]float64
+	}{
+		"no ZFS": {},
+		"arcstats": {
+			contents: strings.Join([]string{
+				"13 1 0x01 96 26112 5370455372 1436293844231",
+				"name                            type data",
+				"hits                            4    1000",
+				"misses                          4    50",
+				"c                               4    4294967296",
+				"c_max                           4    8589934592",
+				"size                            4    4194304000",
+			}, "\n"),
+			want: map[string]float64{
+				"node_zfs_arc_size_bytes":        4194304000,
+				"node_zfs_arc_target_size_bytes": 4294967296,
+				"node_zfs_arc_max_size_bytes":    8589934592,
+				"node_zfs_arc_hits_total":        1000,
+				"node_zfs_arc_misses_total":      50,
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "arcstats")
+			if tc.contents != "" {
+				require.NoError(t, os.WriteFile(path, []byte(tc.contents), 0o644))
+			}
+
+			metrics, err := getZfsArcMetrics(utils.System{}, path)
+			require.NoError(t, err)
+
+			values := map[string]float64{}
+			for _, m := range metrics {
+				values[m.name] = m.value
+			}
+			assert.Equal(t, len(tc.want), len(values))
+			for name, value := range tc.want {
+				assert.Equal(t, value, values[name], name)
+			}
+		})
+	}
+}
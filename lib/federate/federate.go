@@ -0,0 +1,217 @@
+package federate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// TargetLabel is the label added to the metrics of each target, holding the name of the target
+	TargetLabel = "federate_target"
+
+	scrapeTimeout = 10 * time.Second
+)
+
+// Target is another exporter whose metrics are federated
+type Target struct {
+	// Name is the value of TargetLabel on the metrics of the target
+	Name string
+	// URL is the metrics endpoint of the target, e.g. http://192.168.1.11:9094/metrics
+	URL string
+	// Username and Password are the basic authentication credentials of the target, if any
+	Username string
+	Password string
+}
+
+// ValidateTargets checks that the targets have a unique name and an http or https URL
+func ValidateTargets(targets []Target) error {
+	names := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		if t.Name == "" {
+			return fmt.Errorf("federate target %q has no name", t.URL)
+		}
+		if names[t.Name] {
+			return fmt.Errorf("duplicate federate target %q", t.Name)
+		}
+		names[t.Name] = true
+
+		u, err := url.Parse(t.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid URL %q of federate target %q: must be an http or https URL", t.URL, t.Name)
+		}
+	}
+
+	return nil
+}
+
+// Federator scrapes the targets and merges their metrics, each labeled with the name of its target
+type Federator struct {
+	targets []Target
+	client  *http.Client
+	logger  *logging.Logger
+}
+
+func New(targets []Target, logger *logging.Logger) *Federator {
+	return &Federator{
+		targets: targets,
+		client:  &http.Client{Timeout: scrapeTimeout},
+		logger:  logger,
+	}
+}
+
+// ServeHTTP serves the merged metrics of the targets in the Prometheus text format
+func (f *Federator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", string(expfmt.FmtText))
+	w.Header().Set("Cache-Control", "no-cache")
+
+	for _, family := range f.Gather(r.Context()) {
+		if _, err := expfmt.MetricFamilyToText(w, family); err != nil {
+			f.logger.Error("Error writing response", "path", r.URL.Path, "err", err)
+			return
+		}
+	}
+}
+
+// Gather scrapes the targets concurrently and returns their metrics merged by name and sorted, along with whether
+// each target could be scraped and how long it took
+func (f *Federator) Gather(ctx context.Context) []*dto.MetricFamily {
+	results := make([]map[string]*dto.MetricFamily, len(f.targets))
+	durations := make([]time.Duration, len(f.targets))
+	var wg sync.WaitGroup
+	for idx, t := range f.targets {
+		wg.Add(1)
+		go func(idx int, t Target) {
+			defer wg.Done()
+
+			start := time.Now()
+			families, err := f.scrape(ctx, t)
+			durations[idx] = time.Since(start)
+			if err != nil {
+				f.logger.Warn("Error scraping the federate target", "target", t.Name, "err", err)
+				return
+			}
+			results[idx] = families
+		}(idx, t)
+	}
+	wg.Wait()
+
+	up := &dto.MetricFamily{
+		Name: proto.String("qnapexporter_federate_target_up"),
+		Help: proto.String("Whether the metrics of the federate target could be scraped"),
+		Type: dto.MetricType_GAUGE.Enum(),
+	}
+	duration := &dto.MetricFamily{
+		Name: proto.String("qnapexporter_federate_target_scrape_duration_seconds"),
+		Help: proto.String("Time taken to scrape the federate target"),
+		Type: dto.MetricType_GAUGE.Enum(),
+	}
+	merged := map[string]*dto.MetricFamily{up.GetName(): up, duration.GetName(): duration}
+	for idx, t := range f.targets {
+		var value float64
+		if results[idx] != nil {
+			value = 1
+		}
+		up.Metric = append(up.Metric, gaugeMetric(t.Name, value))
+		duration.Metric = append(duration.Metric, gaugeMetric(t.Name, durations[idx].Seconds()))
+
+		mergeFamilies(merged, results[idx], t.Name)
+	}
+
+	names := make([]string, 0, len(merged))
+	for name := range merged {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	families := make([]*dto.MetricFamily, 0, len(names))
+	for _, name := range names {
+		families = append(families, merged[name])
+	}
+
+	return families
+}
+
+func (f *Federator) scrape(ctx context.Context, t Target) (map[string]*dto.MetricFamily, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Ask for the text format, which is the only one parsed
+	req.Header.Set("Accept", string(expfmt.FmtText))
+	if t.Username != "" {
+		req.SetBasicAuth(t.Username, t.Password)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("parse metrics: %w", err)
+	}
+	if len(families) == 0 {
+		return nil, errors.New("no metrics")
+	}
+
+	return families, nil
+}
+
+// mergeFamilies adds the metrics of families to merged, labeled with the target. The metrics of a family whose type
+// differs from the one already merged under the same name are dropped, since they can't be exposed together.
+func mergeFamilies(merged map[string]*dto.MetricFamily, families map[string]*dto.MetricFamily, target string) {
+	for name, family := range families {
+		for _, m := range family.GetMetric() {
+			addTargetLabel(m, target)
+		}
+
+		existing, found := merged[name]
+		if !found {
+			merged[name] = family
+			continue
+		}
+		if existing.GetType() != family.GetType() {
+			continue
+		}
+		existing.Metric = append(existing.Metric, family.GetMetric()...)
+	}
+}
+
+// addTargetLabel labels m with the target, unless it already has a target label, e.g. when federating an exporter
+// which itself federates other exporters
+func addTargetLabel(m *dto.Metric, target string) {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == TargetLabel {
+			return
+		}
+	}
+
+	m.Label = append(m.Label, &dto.LabelPair{Name: proto.String(TargetLabel), Value: proto.String(target)})
+}
+
+func gaugeMetric(target string, value float64) *dto.Metric {
+	return &dto.Metric{
+		Label: []*dto.LabelPair{{Name: proto.String(TargetLabel), Value: proto.String(target)}},
+		Gauge: &dto.Gauge{Value: proto.Float64(value)},
+	}
+}
@@ -0,0 +1,165 @@
+package federate
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTargets(t *testing.T) {
+	tests := map[string]struct {
+		targets []Target
+		wantErr string
+	}{
+		"valid": {
+			targets: []Target{{Name: "nas1", URL: "http://192.168.1.10:9094/metrics"}, {Name: "nas2", URL: "https://nas2:9094/metrics"}},
+		},
+		"no name": {
+			targets: []Target{{URL: "http://192.168.1.10:9094/metrics"}},
+			wantErr: `federate target "http://192.168.1.10:9094/metrics" has no name`,
+		},
+		"duplicate name": {
+			targets: []Target{{Name: "nas1", URL: "http://192.168.1.10:9094/metrics"}, {Name: "nas1", URL: "http://192.168.1.11:9094/metrics"}},
+			wantErr: `duplicate federate target "nas1"`,
+		},
+		"invalid URL": {
+			targets: []Target{{Name: "nas1", URL: "192.168.1.10:9094"}},
+			wantErr: `invalid URL "192.168.1.10:9094" of federate target "nas1": must be an http or https URL`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateTargets(tc.targets)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestFederatorServeHTTP(t *testing.T) {
+	nas1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "prometheus" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, _ = w.Write([]byte(`# HELP node_load1 1m load average
+# TYPE node_load1 gauge
+node_load1{node="nas1"} 0.5
+`))
+	}))
+	defer nas1.Close()
+	nas2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`# HELP node_load1 1m load average
+# TYPE node_load1 gauge
+node_load1{node="nas2"} 1.5
+# HELP node_boot_time_seconds Node boot time
+# TYPE node_boot_time_seconds gauge
+node_boot_time_seconds{node="nas2",federate_target="nas3"} 1.7e+09
+`))
+	}))
+	defer nas2.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer down.Close()
+
+	f := New([]Target{
+		{Name: "nas1", URL: nas1.URL, Username: "prometheus", Password: "secret"},
+		{Name: "nas2", URL: nas2.URL},
+		{Name: "down", URL: down.URL},
+	}, logging.Discard())
+
+	w := httptest.NewRecorder()
+	f.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/federate", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]float64{
+		`federate_target="nas1",node="nas1"`: 0.5,
+		`federate_target="nas2",node="nas2"`: 1.5,
+	}, values(families["node_load1"]))
+	assert.Equal(t, map[string]float64{
+		`federate_target="nas3",node="nas2"`: 1.7e+09,
+	}, values(families["node_boot_time_seconds"]))
+	assert.Equal(t, map[string]float64{
+		`federate_target="nas1"`: 1,
+		`federate_target="nas2"`: 1,
+		`federate_target="down"`: 0,
+	}, values(families["qnapexporter_federate_target_up"]))
+	assert.Len(t, families["qnapexporter_federate_target_scrape_duration_seconds"].GetMetric(), 3)
+}
+
+func TestGatherTypeConflict(t *testing.T) {
+	counter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("# TYPE requests counter\nrequests 3\n"))
+	}))
+	defer counter.Close()
+	gauge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("# TYPE requests gauge\nrequests 5\n"))
+	}))
+	defer gauge.Close()
+
+	f := New([]Target{{Name: "counter", URL: counter.URL}, {Name: "gauge", URL: gauge.URL}}, logging.Discard())
+
+	var requests *dto.MetricFamily
+	for _, family := range f.Gather(context.Background()) {
+		if family.GetName() == "requests" {
+			requests = family
+		}
+	}
+
+	require.NotNil(t, requests)
+	assert.Equal(t, dto.MetricType_COUNTER, requests.GetType())
+	assert.Equal(t, map[string]float64{`federate_target="counter"`: 3}, values(requests))
+}
+
+// values maps the labels of each metric of family, sorted by name, to its value
+func values(family *dto.MetricFamily) map[string]float64 {
+	result := map[string]float64{}
+	for _, m := range family.GetMetric() {
+		var key string
+		for idx, l := range sortedLabels(m) {
+			if idx > 0 {
+				key += ","
+			}
+			key += l.GetName() + `="` + l.GetValue() + `"`
+		}
+
+		switch {
+		case m.Gauge != nil:
+			result[key] = m.GetGauge().GetValue()
+		case m.Counter != nil:
+			result[key] = m.GetCounter().GetValue()
+		default:
+			result[key] = m.GetUntyped().GetValue()
+		}
+	}
+
+	return result
+}
+
+func sortedLabels(m *dto.Metric) []*dto.LabelPair {
+	labels := append([]*dto.LabelPair(nil), m.GetLabel()...)
+	sort.Slice(labels, func(i, j int) bool { return labels[i].GetName() < labels[j].GetName() })
+
+	return labels
+}
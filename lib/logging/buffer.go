@@ -0,0 +1,43 @@
+package logging
+
+import "sync"
+
+// Buffer keeps the most recent log entries in memory, e.g. to show them on the status page
+type Buffer struct {
+	size int
+
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// NewBuffer returns a buffer keeping the last size entries, or nil if size is not positive
+func NewBuffer(size int) *Buffer {
+	if size <= 0 {
+		return nil
+	}
+
+	return &Buffer{size: size, entries: make([]Entry, 0, size)}
+}
+
+func (b *Buffer) add(entry Entry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.entries) == b.size {
+		copy(b.entries, b.entries[1:])
+		b.entries = b.entries[:b.size-1]
+	}
+	b.entries = append(b.entries, entry)
+}
+
+// Entries returns the entries kept, oldest first
+func (b *Buffer) Entries() []Entry {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]Entry(nil), b.entries...)
+}
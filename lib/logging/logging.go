@@ -0,0 +1,250 @@
+// Package logging implements a leveled, structured logger writing either text or JSON lines
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log entry
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+
+	return levelNames[l]
+}
+
+func (l Level) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.String())
+}
+
+// ParseLevel parses a level name: debug, info, warn or error
+func ParseLevel(s string) (Level, error) {
+	for idx, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(idx), nil
+		}
+	}
+
+	return LevelInfo, fmt.Errorf("unknown log level %q", s)
+}
+
+// Formats of the log lines
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// ValidateFormat checks that format is a known log format
+func ValidateFormat(format string) error {
+	switch format {
+	case FormatText, FormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+// Field is a key/value pair giving the context of a log entry
+type Field struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Entry is a log entry, as kept in a Buffer
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Level   Level     `json:"level"`
+	Message string    `json:"msg"`
+	Fields  []Field   `json:"fields,omitempty"`
+}
+
+// handler holds the destination of the log entries, which is shared by a logger and the loggers derived from it
+type handler struct {
+	mu     sync.Mutex
+	w      io.Writer
+	level  Level
+	format string
+	buffer *Buffer
+}
+
+// Logger writes the entries at or above its level, along with its fields and those given with each entry
+type Logger struct {
+	h      *handler
+	fields []Field
+}
+
+// New returns a logger writing the entries at or above level to w, in the given format (text or json).
+// The entries are also kept in buffer, if not nil.
+func New(w io.Writer, level Level, format string, buffer *Buffer) *Logger {
+	return &Logger{h: &handler{w: w, level: level, format: format, buffer: buffer}}
+}
+
+// Discard returns a logger which drops every entry
+func Discard() *Logger {
+	return New(io.Discard, LevelError+1, FormatText, nil)
+}
+
+// With returns a logger adding the key/value pairs in keyvals to every entry
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	fields := make([]Field, 0, len(l.fields)+len(keyvals)/2)
+	fields = append(fields, l.fields...)
+
+	return &Logger{h: l.h, fields: appendFields(fields, keyvals)}
+}
+
+// Enabled reports whether entries of the given level are written
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.h.level
+}
+
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.log(LevelDebug, msg, keyvals)
+}
+
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.log(LevelInfo, msg, keyvals)
+}
+
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.log(LevelWarn, msg, keyvals)
+}
+
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.log(LevelError, msg, keyvals)
+}
+
+func (l *Logger) log(level Level, msg string, keyvals []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	fields := make([]Field, 0, len(l.fields)+len(keyvals)/2)
+	fields = append(fields, l.fields...)
+	entry := Entry{Time: time.Now(), Level: level, Message: msg, Fields: appendFields(fields, keyvals)}
+
+	var line []byte
+	if l.h.format == FormatJSON {
+		line = formatJSON(entry)
+	} else {
+		line = formatText(entry)
+	}
+
+	l.h.mu.Lock()
+	defer l.h.mu.Unlock()
+
+	_, _ = l.h.w.Write(line)
+	if l.h.buffer != nil {
+		l.h.buffer.add(entry)
+	}
+}
+
+// appendFields appends the key/value pairs in keyvals to fields. A key without a value is given the value "MISSING".
+func appendFields(fields []Field, keyvals []interface{}) []Field {
+	for idx := 0; idx < len(keyvals); idx += 2 {
+		value := "MISSING"
+		if idx+1 < len(keyvals) {
+			value = formatValue(keyvals[idx+1])
+		}
+
+		fields = append(fields, Field{Key: fmt.Sprint(keyvals[idx]), Value: value})
+	}
+
+	return fields
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "<nil>"
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// formatText formats entry as e.g. `2023/01/02 15:04:05 ERROR Collector failed collector=smart err="exit status 2"`
+func formatText(entry Entry) []byte {
+	var sb strings.Builder
+	sb.WriteString(entry.Time.Format("2006/01/02 15:04:05"))
+	sb.WriteByte(' ')
+	sb.WriteString(strings.ToUpper(entry.Level.String()))
+	sb.WriteByte(' ')
+	sb.WriteString(entry.Message)
+	for _, f := range entry.Fields {
+		sb.WriteByte(' ')
+		sb.WriteString(f.Key)
+		sb.WriteByte('=')
+		if f.Value == "" || strings.ContainsAny(f.Value, " \"=\t\n") {
+			sb.WriteString(fmt.Sprintf("%q", f.Value))
+		} else {
+			sb.WriteString(f.Value)
+		}
+	}
+	sb.WriteByte('\n')
+
+	return []byte(sb.String())
+}
+
+// formatJSON formats entry as a JSON object holding the time, level and msg keys followed by the fields
+func formatJSON(entry Entry) []byte {
+	var sb strings.Builder
+	writePair := func(key, value string) {
+		k, _ := json.Marshal(key)
+		v, _ := json.Marshal(value)
+		sb.Write(k)
+		sb.WriteByte(':')
+		sb.Write(v)
+	}
+
+	sb.WriteByte('{')
+	writePair("time", entry.Time.Format(time.RFC3339Nano))
+	sb.WriteByte(',')
+	writePair("level", entry.Level.String())
+	sb.WriteByte(',')
+	writePair("msg", entry.Message)
+	for _, f := range entry.Fields {
+		sb.WriteByte(',')
+		writePair(f.Key, f.Value)
+	}
+	sb.WriteString("}\n")
+
+	return []byte(sb.String())
+}
+
+// StdLogger returns a standard library logger writing each of its lines as an entry of the given level,
+// for the packages which require one (e.g. net/http)
+func (l *Logger) StdLogger(level Level) *log.Logger {
+	return log.New(levelWriter{logger: l, level: level}, "", 0)
+}
+
+type levelWriter struct {
+	logger *Logger
+	level  Level
+}
+
+func (w levelWriter) Write(p []byte) (int, error) {
+	w.logger.log(w.level, strings.TrimSpace(string(p)), nil)
+
+	return len(p), nil
+}
@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		want    Level
+		wantErr bool
+	}{
+		"debug":      {input: "debug", want: LevelDebug},
+		"info":       {input: "info", want: LevelInfo},
+		"warn":       {input: "WARN", want: LevelWarn},
+		"error":      {input: "Error", want: LevelError},
+		"unknown":    {input: "verbose", want: LevelInfo, wantErr: true},
+		"empty name": {input: "", want: LevelInfo, wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			level, err := ParseLevel(tc.input)
+
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.want, level)
+		})
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	assert.NoError(t, ValidateFormat(FormatText))
+	assert.NoError(t, ValidateFormat(FormatJSON))
+	assert.Error(t, ValidateFormat("logfmt"))
+}
+
+func TestLoggerText(t *testing.T) {
+	b := new(bytes.Buffer)
+	logger := New(b, LevelInfo, FormatText, nil).With("collector", "smart")
+
+	logger.Debug("Dropped")
+	logger.Error("Collector failed", "err", errors.New("exit status 2"), "duration", 1500*time.Millisecond, "dangling")
+
+	assert.Regexp(t,
+		regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} ERROR Collector failed collector=smart err="exit status 2" duration=1.5s dangling=MISSING\n$`),
+		b.String())
+}
+
+func TestLoggerJSON(t *testing.T) {
+	b := new(bytes.Buffer)
+	logger := New(b, LevelDebug, FormatJSON, nil)
+
+	logger.Warn("Tool not found", "tool", "smartctl", "path", "")
+
+	var decoded map[string]string
+	require.NoError(t, json.Unmarshal(b.Bytes(), &decoded))
+	assert.Equal(t, "warn", decoded["level"])
+	assert.Equal(t, "Tool not found", decoded["msg"])
+	assert.Equal(t, "smartctl", decoded["tool"])
+	assert.Equal(t, "", decoded["path"])
+	assert.NotEmpty(t, decoded["time"])
+}
+
+func TestLoggerWith(t *testing.T) {
+	b := new(bytes.Buffer)
+	parent := New(b, LevelInfo, FormatText, nil)
+	child := parent.With("sink", "mqtt")
+
+	parent.Info("parent")
+	child.Info("child")
+
+	assert.NotContains(t, b.String(), "parent sink=mqtt")
+	assert.Contains(t, b.String(), "child sink=mqtt")
+}
+
+func TestStdLogger(t *testing.T) {
+	buffer := NewBuffer(1)
+	logger := New(new(bytes.Buffer), LevelInfo, FormatText, buffer)
+
+	logger.StdLogger(LevelError).Println("http: TLS handshake error")
+
+	entries := buffer.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, LevelError, entries[0].Level)
+	assert.Equal(t, "http: TLS handshake error", entries[0].Message)
+}
+
+func TestBuffer(t *testing.T) {
+	assert.Nil(t, NewBuffer(0))
+	assert.Nil(t, (*Buffer)(nil).Entries())
+
+	buffer := NewBuffer(2)
+	logger := New(new(bytes.Buffer), LevelWarn, FormatText, buffer)
+	logger.Info("filtered")
+	logger.Warn("first")
+	logger.Warn("second")
+	logger.Error("third", "key", "value")
+
+	entries := buffer.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "second", entries[0].Message)
+	assert.Equal(t, "third", entries[1].Message)
+	assert.Equal(t, []Field{{Key: "key", Value: "value"}}, entries[1].Fields)
+
+	encoded, err := json.Marshal(entries[1])
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), `"level":"error"`)
+}
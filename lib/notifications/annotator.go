@@ -5,10 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"time"
 
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
 	"github.com/pedropombeiro/qnapexporter/lib/notifications/tagextractor"
 )
 
@@ -32,7 +32,7 @@ func NewSimpleAnnotator(
 	grafanaURL, grafanaAuthToken string,
 	tags []string,
 	c httpClient,
-	logger *log.Logger,
+	logger *logging.Logger,
 ) Annotator {
 	if len(tags) == 1 && tags[0] == "" {
 		tags = nil
@@ -56,7 +56,7 @@ type regionMatchingAnnotator struct {
 	tagExtractor     tagextractor.TagExtractor
 	cache            RegionMatcher
 	client           httpClient
-	logger           *log.Logger
+	logger           *logging.Logger
 }
 
 func NewRegionMatchingAnnotator(
@@ -65,7 +65,7 @@ func NewRegionMatchingAnnotator(
 	tagExtractor tagextractor.TagExtractor,
 	cache RegionMatcher,
 	c httpClient,
-	logger *log.Logger,
+	logger *logging.Logger,
 ) Annotator {
 	if len(tags) == 1 && tags[0] == "" {
 		tags = nil
@@ -102,13 +102,13 @@ func (a *regionMatchingAnnotator) Post(annotation string, time time.Time) (int,
 
 	jsonBytes, err := json.Marshal(ga)
 	if err != nil {
-		a.logger.Printf("Error marshalling Grafana annotation: %v\n", err)
+		a.logger.Error("Error marshalling Grafana annotation", "err", err)
 		return -1, err
 	}
 	bodyReader := bytes.NewReader(jsonBytes)
 	req, err := http.NewRequest(reqType, url, bodyReader)
 	if err != nil {
-		a.logger.Printf("Error creating Grafana annotation request: %v\n", err)
+		a.logger.Error("Error creating Grafana annotation request", "err", err)
 		return -1, err
 	}
 
@@ -138,14 +138,14 @@ func (a *regionMatchingAnnotator) Post(annotation string, time time.Time) (int,
 				a.cache.Add(response.Id, annotation)
 			}
 
-			a.logger.Printf("%s (status: %q), ID: %d\n", response.Message, resp.Status, response.Id)
+			a.logger.Info(response.Message, "status", resp.Status, "id", response.Id)
 			return response.Id, nil
 		}
 
-		a.logger.Printf("Error creating Grafana annotation at %s: HTTP %d %q\n", url, resp.StatusCode, resp.Status)
+		a.logger.Error("Error creating Grafana annotation", "url", url, "status", resp.Status)
 		err = fmt.Errorf("call to %s failed with HTTP %d %q", url, resp.StatusCode, resp.Status)
 	} else {
-		a.logger.Printf("Error creating Grafana annotation at %s: %v\n", url, err)
+		a.logger.Error("Error creating Grafana annotation", "url", url, "err", err)
 	}
 
 	return -1, err
@@ -3,12 +3,12 @@ package notifications
 import (
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
 	"github.com/pedropombeiro/qnapexporter/lib/notifications/tagextractor"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -23,7 +23,7 @@ func TestNewAnnotator(t *testing.T) {
 		new(tagextractor.MockTagExtractor),
 		new(MockRegionMatcher),
 		new(mockHttpClient),
-		log.New(io.Discard, "", 0),
+		logging.Discard(),
 	)
 
 	require.NotNil(t, a)
@@ -239,7 +239,7 @@ func TestPostAnnotation(t *testing.T) {
 				tagExtractorMock,
 				cacheMock,
 				clientMock,
-				log.New(io.Discard, "", 0),
+				logging.Discard(),
 			)
 
 			id, err := a.Post(tc.notification, time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC))
@@ -0,0 +1,152 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/golang/snappy"
+	promclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	ModePushgateway = "pushgateway"
+	ModeRemoteWrite = "remote_write"
+)
+
+// Config describes where and how often to push the metrics
+type Config struct {
+	// Mode is either ModePushgateway or ModeRemoteWrite
+	Mode string
+	URL  string
+	// Job is the job name used for the Pushgateway grouping key
+	Job string
+}
+
+// Sink pushes each collection it receives, according to its config
+type Sink struct {
+	config Config
+}
+
+func NewSink(config Config) *Sink {
+	return &Sink{config: config}
+}
+
+func (s *Sink) Name() string {
+	return s.config.Mode
+}
+
+func (s *Sink) Write(ctx context.Context, families []*dto.MetricFamily) error {
+	return write(ctx, s.config, families)
+}
+
+func write(ctx context.Context, config Config, families []*dto.MetricFamily) error {
+	switch config.Mode {
+	case ModePushgateway:
+		gatherer := promclient.GathererFunc(func() ([]*dto.MetricFamily, error) { return families, nil })
+		return push.New(config.URL, config.Job).Gatherer(gatherer).PushContext(ctx)
+	case ModeRemoteWrite:
+		return remoteWrite(ctx, config.URL, families)
+	default:
+		return fmt.Errorf("unknown push mode %q", config.Mode)
+	}
+}
+
+func remoteWrite(ctx context.Context, url string, families []*dto.MetricFamily) error {
+	body := snappy.Encode(nil, encodeWriteRequest(families, time.Now()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("remote write returned HTTP status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
+
+// encodeWriteRequest encodes the gauges, counters and untyped metrics in families as a
+// remote write protobuf WriteRequest message (see prometheus/prompb/remote.proto)
+func encodeWriteRequest(families []*dto.MetricFamily, now time.Time) []byte {
+	var req []byte
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			var value float64
+			switch f.GetType() {
+			case dto.MetricType_GAUGE:
+				value = m.GetGauge().GetValue()
+			case dto.MetricType_COUNTER:
+				value = m.GetCounter().GetValue()
+			case dto.MetricType_UNTYPED:
+				value = m.GetUntyped().GetValue()
+			default:
+				// The exporter doesn't produce summaries or histograms
+				continue
+			}
+
+			timestamp := now.UnixMilli()
+			if m.TimestampMs != nil {
+				timestamp = m.GetTimestampMs()
+			}
+
+			labels := map[string]string{"__name__": f.GetName()}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+
+			req = protowire.AppendTag(req, 1, protowire.BytesType)
+			req = protowire.AppendBytes(req, encodeTimeSeries(labels, value, timestamp))
+		}
+	}
+
+	return req
+}
+
+func encodeTimeSeries(labels map[string]string, value float64, timestamp int64) []byte {
+	// Remote write requires the labels to be sorted by name
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var ts []byte
+	for _, name := range names {
+		var label []byte
+		label = protowire.AppendTag(label, 1, protowire.BytesType)
+		label = protowire.AppendString(label, name)
+		label = protowire.AppendTag(label, 2, protowire.BytesType)
+		label = protowire.AppendString(label, labels[name])
+
+		ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, label)
+	}
+
+	var sample []byte
+	sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(value))
+	sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, uint64(timestamp))
+
+	ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+	return protowire.AppendBytes(ts, sample)
+}
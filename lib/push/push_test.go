@@ -0,0 +1,109 @@
+package push
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/snappy"
+	promclient "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func newTestFamilies(t *testing.T) []*dto.MetricFamily {
+	registry := promclient.NewRegistry()
+	gauge := promclient.NewGauge(promclient.GaugeOpts{
+		Name:        "node_test",
+		Help:        "Test metric",
+		ConstLabels: promclient.Labels{"node": "nas"},
+	})
+	gauge.Set(42)
+	require.NoError(t, registry.Register(gauge))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	return families
+}
+
+func TestPushPushgateway(t *testing.T) {
+	var method, path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := Config{Mode: ModePushgateway, URL: server.URL, Job: "qnapexporter"}
+	err := NewSink(config).Write(context.Background(), newTestFamilies(t))
+	require.NoError(t, err)
+
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/metrics/job/qnapexporter", path)
+	assert.Contains(t, body, "node_test")
+}
+
+func TestPushRemoteWrite(t *testing.T) {
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		b, _ := io.ReadAll(r.Body)
+		body, _ = snappy.Decode(nil, b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	config := Config{Mode: ModeRemoteWrite, URL: server.URL}
+	err := NewSink(config).Write(context.Background(), newTestFamilies(t))
+	require.NoError(t, err)
+
+	assert.Equal(t, "snappy", header.Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", header.Get("Content-Type"))
+
+	// WriteRequest with a single TimeSeries
+	num, typ, n := protowire.ConsumeTag(body)
+	require.Positive(t, n)
+	assert.Equal(t, protowire.Number(1), num)
+	assert.Equal(t, protowire.BytesType, typ)
+	ts, m := protowire.ConsumeBytes(body[n:])
+	require.Positive(t, m)
+	assert.Len(t, body, n+m)
+
+	// Labels are sorted by name: __name__ first
+	var labels []string
+	for len(ts) > 0 {
+		num, _, n := protowire.ConsumeTag(ts)
+		v, m := protowire.ConsumeBytes(ts[n:])
+		ts = ts[n+m:]
+		if num != 1 {
+			continue
+		}
+
+		_, _, n = protowire.ConsumeTag(v)
+		name, m := protowire.ConsumeString(v[n:])
+		_, _, o := protowire.ConsumeTag(v[n+m:])
+		value, _ := protowire.ConsumeString(v[n+m+o:])
+		labels = append(labels, name+"="+value)
+	}
+	assert.Equal(t, []string{"__name__=node_test", "node=nas"}, labels)
+}
+
+func TestPushRemoteWriteError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	config := Config{Mode: ModeRemoteWrite, URL: server.URL}
+	err := NewSink(config).Write(context.Background(), newTestFamilies(t))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of order sample")
+}
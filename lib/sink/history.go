@@ -0,0 +1,51 @@
+package sink
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// History is a sink keeping the last collections in memory, e.g. to look at recent values without a Prometheus server
+type History struct {
+	size int
+
+	mu          sync.Mutex
+	collections []Collection
+}
+
+func NewHistory(size int) *History {
+	return &History{size: size, collections: make([]Collection, 0, size)}
+}
+
+func (h *History) Name() string {
+	return "history"
+}
+
+func (h *History) Write(ctx context.Context, families []*dto.MetricFamily) error {
+	if h.size <= 0 {
+		return nil
+	}
+	c := newCollection(families, time.Now())
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.collections) == h.size {
+		copy(h.collections, h.collections[1:])
+		h.collections = h.collections[:h.size-1]
+	}
+	h.collections = append(h.collections, c)
+
+	return nil
+}
+
+// Collections returns the collections kept, oldest first
+func (h *History) Collections() []Collection {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]Collection(nil), h.collections...)
+}
@@ -0,0 +1,40 @@
+package sink
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	tests := map[string]struct {
+		size   int
+		writes int
+		want   int
+	}{
+		"disabled": {size: 0, writes: 2, want: 0},
+		"not full": {size: 3, writes: 2, want: 2},
+		"wraps":    {size: 3, writes: 5, want: 3},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := NewHistory(tc.size)
+			families := newTestFamilies(t)
+			for i := 0; i < tc.writes; i++ {
+				require.NoError(t, h.Write(context.Background(), families))
+			}
+
+			collections := h.Collections()
+			require.Len(t, collections, tc.want)
+			for _, c := range collections {
+				assert.Equal(t, []Sample{{Name: "node_test", Labels: map[string]string{"node": "nas"}, Value: 42}}, c.Samples)
+			}
+			for i := 1; i < len(collections); i++ {
+				assert.False(t, collections[i].Time.Before(collections[i-1].Time))
+			}
+		})
+	}
+}
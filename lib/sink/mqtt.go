@@ -0,0 +1,176 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	mqttConnect    = 0x10
+	mqttConnAck    = 0x20
+	mqttPublish    = 0x30
+	mqttDisconnect = 0xe0
+
+	mqttProtocolLevel = 4 // MQTT 3.1.1
+	mqttKeepAlive     = 60
+	mqttDefaultPort   = "1883"
+)
+
+// MQTTConfig describes the MQTT broker and topic to publish the metrics to
+type MQTTConfig struct {
+	// Broker is the address of the broker, e.g. tcp://192.168.1.10:1883
+	Broker   string
+	Topic    string
+	User     string
+	Password string
+}
+
+// MQTT is a sink publishing each collection as a JSON document to an MQTT topic (e.g. for Home Assistant or Node-RED).
+// It only needs QoS 0, so it implements the few packets required instead of depending on a full MQTT client.
+type MQTT struct {
+	config MQTTConfig
+	addr   string
+}
+
+// NewMQTT validates config and returns the corresponding sink
+func NewMQTT(config MQTTConfig) (*MQTT, error) {
+	addr, err := parseBrokerAddress(config.Broker)
+	if err != nil {
+		return nil, err
+	}
+	if config.Topic == "" {
+		return nil, errors.New("MQTT topic is required")
+	}
+
+	return &MQTT{config: config, addr: addr}, nil
+}
+
+func parseBrokerAddress(broker string) (string, error) {
+	u, err := url.Parse(broker)
+	if err != nil || u.Scheme != "tcp" && u.Scheme != "mqtt" || u.Host == "" {
+		return "", fmt.Errorf("invalid MQTT broker %q, expected tcp://host:port", broker)
+	}
+
+	if u.Port() == "" {
+		return net.JoinHostPort(u.Hostname(), mqttDefaultPort), nil
+	}
+
+	return u.Host, nil
+}
+
+func (m *MQTT) Name() string {
+	return "mqtt"
+}
+
+// Write connects to the broker, publishes the collection and disconnects, so that no connection needs
+// to be kept alive between collections
+func (m *MQTT) Write(ctx context.Context, families []*dto.MetricFamily) error {
+	payload, err := json.Marshal(newCollection(families, time.Now()))
+	if err != nil {
+		return err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return fmt.Errorf("connect to MQTT broker: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(m.connectPacket()); err != nil {
+		return fmt.Errorf("connect to MQTT broker: %w", err)
+	}
+	if err := readConnAck(bufio.NewReader(conn)); err != nil {
+		return fmt.Errorf("connect to MQTT broker: %w", err)
+	}
+
+	if _, err := conn.Write(publishPacket(m.config.Topic, payload)); err != nil {
+		return fmt.Errorf("publish to MQTT broker: %w", err)
+	}
+	_, _ = conn.Write([]byte{mqttDisconnect, 0})
+
+	return nil
+}
+
+func (m *MQTT) connectPacket() []byte {
+	hostname, _ := os.Hostname()
+
+	var flags byte = 0x02 // Clean session
+	var payload []byte
+	payload = appendMQTTString(payload, "qnapexporter-"+hostname)
+	if m.config.User != "" {
+		flags |= 0x80
+		payload = appendMQTTString(payload, m.config.User)
+		if m.config.Password != "" {
+			flags |= 0x40
+			payload = appendMQTTString(payload, m.config.Password)
+		}
+	}
+
+	body := appendMQTTString(nil, "MQTT")
+	body = append(body, mqttProtocolLevel, flags)
+	body = binary.BigEndian.AppendUint16(body, mqttKeepAlive)
+	body = append(body, payload...)
+
+	return appendMQTTPacket(nil, mqttConnect, body)
+}
+
+func publishPacket(topic string, payload []byte) []byte {
+	body := appendMQTTString(nil, topic)
+	body = append(body, payload...)
+
+	return appendMQTTPacket(nil, mqttPublish, body)
+}
+
+func readConnAck(r *bufio.Reader) error {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return err
+	}
+	if header[0] != mqttConnAck || header[1] != 2 {
+		return fmt.Errorf("unexpected MQTT packet 0x%02x", header[0])
+	}
+	if header[3] != 0 {
+		return fmt.Errorf("connection refused by MQTT broker (return code %d)", header[3])
+	}
+
+	return nil
+}
+
+func appendMQTTPacket(b []byte, packetType byte, body []byte) []byte {
+	b = append(b, packetType)
+	// The remaining length is encoded in 7-bit groups, least significant first
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if length == 0 {
+			break
+		}
+	}
+
+	return append(b, body...)
+}
+
+func appendMQTTString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
@@ -0,0 +1,165 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mqttPacket struct {
+	packetType byte
+	body       []byte
+}
+
+// runFakeBroker accepts a single connection, answers CONNECT with returnCode and sends the packets it receives to the returned channel
+func runFakeBroker(t *testing.T, returnCode byte) (string, <-chan mqttPacket) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	packets := make(chan mqttPacket, 4)
+	go func() {
+		defer close(packets)
+
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		r := bufio.NewReader(conn)
+		for {
+			p, err := readMQTTPacket(r)
+			if err != nil {
+				return
+			}
+			packets <- p
+			if p.packetType == mqttConnect {
+				_, _ = conn.Write([]byte{mqttConnAck, 2, 0, returnCode})
+			}
+		}
+	}()
+
+	return "tcp://" + listener.Addr().String(), packets
+}
+
+func readMQTTPacket(r *bufio.Reader) (mqttPacket, error) {
+	packetType, err := r.ReadByte()
+	if err != nil {
+		return mqttPacket{}, err
+	}
+
+	length, multiplier := 0, 1
+	for {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return mqttPacket{}, err
+		}
+		length += int(digit&0x7f) * multiplier
+		multiplier *= 128
+		if digit&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+	_, err = io.ReadFull(r, body)
+
+	return mqttPacket{packetType: packetType, body: body}, err
+}
+
+func readMQTTString(b []byte) (string, []byte) {
+	n := binary.BigEndian.Uint16(b)
+	return string(b[2 : 2+n]), b[2+n:]
+}
+
+func TestMQTTWrite(t *testing.T) {
+	broker, packets := runFakeBroker(t, 0)
+	m, err := NewMQTT(MQTTConfig{Broker: broker, Topic: "nas/metrics", User: "user", Password: "secret"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, m.Write(ctx, newTestFamilies(t)))
+
+	connect := <-packets
+	require.Equal(t, byte(mqttConnect), connect.packetType)
+	protocol, rest := readMQTTString(connect.body)
+	assert.Equal(t, "MQTT", protocol)
+	assert.Equal(t, byte(mqttProtocolLevel), rest[0])
+	assert.Equal(t, byte(0xc2), rest[1], "clean session, user name and password flags")
+	_, rest = readMQTTString(rest[4:])
+	user, rest := readMQTTString(rest)
+	password, _ := readMQTTString(rest)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "secret", password)
+
+	publish := <-packets
+	require.Equal(t, byte(mqttPublish), publish.packetType)
+	topic, payload := readMQTTString(publish.body)
+	assert.Equal(t, "nas/metrics", topic)
+	var c Collection
+	require.NoError(t, json.Unmarshal(payload, &c))
+	assert.Equal(t, []Sample{{Name: "node_test", Labels: map[string]string{"node": "nas"}, Value: 42}}, c.Samples)
+
+	disconnect := <-packets
+	assert.Equal(t, byte(mqttDisconnect), disconnect.packetType)
+}
+
+func TestMQTTWriteRefused(t *testing.T) {
+	broker, _ := runFakeBroker(t, 5)
+	m, err := NewMQTT(MQTTConfig{Broker: broker, Topic: "nas/metrics"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = m.Write(ctx, newTestFamilies(t))
+	assert.ErrorContains(t, err, "return code 5")
+}
+
+func TestNewMQTT(t *testing.T) {
+	tests := map[string]struct {
+		config   MQTTConfig
+		wantAddr string
+		wantErr  string
+	}{
+		"default port": {
+			config:   MQTTConfig{Broker: "tcp://192.168.1.10", Topic: "nas"},
+			wantAddr: "192.168.1.10:1883",
+		},
+		"explicit port": {
+			config:   MQTTConfig{Broker: "mqtt://broker:8883", Topic: "nas"},
+			wantAddr: "broker:8883",
+		},
+		"unsupported scheme": {
+			config:  MQTTConfig{Broker: "ws://broker", Topic: "nas"},
+			wantErr: "invalid MQTT broker",
+		},
+		"missing topic": {
+			config:  MQTTConfig{Broker: "tcp://broker"},
+			wantErr: "MQTT topic is required",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			m, err := NewMQTT(tc.config)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantAddr, m.addr)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package sink
+
+import (
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Sample is a single metric value, in the JSON layout used by the MQTT and history sinks
+type Sample struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+// Collection holds the samples of a collection, in the JSON layout used by the MQTT and history sinks
+type Collection struct {
+	Time    time.Time `json:"time"`
+	Samples []Sample  `json:"samples"`
+}
+
+// newCollection flattens the gauges, counters and untyped metrics in families into samples
+func newCollection(families []*dto.MetricFamily, now time.Time) Collection {
+	c := Collection{Time: now}
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			var value float64
+			switch f.GetType() {
+			case dto.MetricType_GAUGE:
+				value = m.GetGauge().GetValue()
+			case dto.MetricType_COUNTER:
+				value = m.GetCounter().GetValue()
+			case dto.MetricType_UNTYPED:
+				value = m.GetUntyped().GetValue()
+			default:
+				// The exporter doesn't produce summaries or histograms
+				continue
+			}
+
+			s := Sample{Name: f.GetName(), Value: value}
+			if len(m.GetLabel()) > 0 {
+				s.Labels = make(map[string]string, len(m.GetLabel()))
+				for _, l := range m.GetLabel() {
+					s.Labels[l.GetName()] = l.GetValue()
+				}
+			}
+			c.Samples = append(c.Samples, s)
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,105 @@
+package sink
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	promclient "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Sink receives the metric families of each collection (e.g. to push them to a remote server)
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, families []*dto.MetricFamily) error
+}
+
+// FanOut hands each collection to several sinks. Sinks run concurrently with a timeout, so that
+// a failing or slow sink does not delay or break the others.
+type FanOut struct {
+	sinks   []Sink
+	timeout time.Duration
+	logger  *logging.Logger
+
+	mu   sync.Mutex
+	busy map[string]bool
+	wg   sync.WaitGroup
+}
+
+func NewFanOut(sinks []Sink, timeout time.Duration, logger *logging.Logger) *FanOut {
+	return &FanOut{
+		sinks:   sinks,
+		timeout: timeout,
+		logger:  logger,
+		busy:    make(map[string]bool, len(sinks)),
+	}
+}
+
+// Publish hands families to every sink without waiting for them. A sink which is still
+// writing the previous collection skips this one.
+func (f *FanOut) Publish(ctx context.Context, families []*dto.MetricFamily) {
+	for _, s := range f.sinks {
+		if !f.acquire(s.Name()) {
+			f.logger.Warn("Skipping sink, which is still writing the previous collection", "sink", s.Name())
+			continue
+		}
+
+		f.wg.Add(1)
+		go func(s Sink) {
+			defer f.wg.Done()
+			defer f.release(s.Name())
+
+			ctx, cancel := context.WithTimeout(ctx, f.timeout)
+			defer cancel()
+
+			if err := s.Write(ctx, families); err != nil {
+				f.logger.Error("Error writing metrics to sink", "sink", s.Name(), "err", err)
+			}
+		}(s)
+	}
+}
+
+// Wait waits for the sinks to complete the collections published so far
+func (f *FanOut) Wait() {
+	f.wg.Wait()
+}
+
+func (f *FanOut) acquire(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.busy[name] {
+		return false
+	}
+	f.busy[name] = true
+
+	return true
+}
+
+func (f *FanOut) release(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.busy, name)
+}
+
+// Run gathers the metrics from gatherer every interval and publishes them to the sinks of f, until ctx is done
+func Run(ctx context.Context, gatherer promclient.Gatherer, interval time.Duration, f *FanOut) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Gather errors are reported by the exporter itself, so publish whatever was gathered
+		families, _ := gatherer.Gather()
+		f.Publish(ctx, families)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			f.Wait()
+			return
+		}
+	}
+}
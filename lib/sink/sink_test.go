@@ -0,0 +1,98 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	promclient "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSink struct {
+	name  string
+	err   error
+	delay time.Duration
+
+	mu     sync.Mutex
+	writes int
+}
+
+func (s *fakeSink) Name() string {
+	return s.name
+}
+
+func (s *fakeSink) Write(ctx context.Context, families []*dto.MetricFamily) error {
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writes++
+
+	return s.err
+}
+
+func (s *fakeSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.writes
+}
+
+func newTestFamilies(t *testing.T) []*dto.MetricFamily {
+	registry := promclient.NewRegistry()
+	gauge := promclient.NewGauge(promclient.GaugeOpts{
+		Name:        "node_test",
+		Help:        "Test metric",
+		ConstLabels: promclient.Labels{"node": "nas"},
+	})
+	gauge.Set(42)
+	require.NoError(t, registry.Register(gauge))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	return families
+}
+
+func TestFanOutPublish(t *testing.T) {
+	healthy := &fakeSink{name: "healthy"}
+	failing := &fakeSink{name: "failing", err: errors.New("broker unreachable")}
+	slow := &fakeSink{name: "slow", delay: time.Hour}
+	f := NewFanOut([]Sink{healthy, failing, slow}, 100*time.Millisecond, logging.Discard())
+
+	families := newTestFamilies(t)
+	f.Publish(context.Background(), families)
+	f.Wait()
+	f.Publish(context.Background(), families)
+	f.Wait()
+
+	assert.Equal(t, 2, healthy.count())
+	assert.Equal(t, 2, failing.count())
+	// The slow sink times out without affecting the others
+	assert.Zero(t, slow.count())
+}
+
+func TestFanOutSkipsBusySink(t *testing.T) {
+	slow := &fakeSink{name: "slow", delay: 200 * time.Millisecond}
+	fast := &fakeSink{name: "fast"}
+	f := NewFanOut([]Sink{slow, fast}, time.Second, logging.Discard())
+
+	families := newTestFamilies(t)
+	f.Publish(context.Background(), families)
+	time.Sleep(50 * time.Millisecond)
+	f.Publish(context.Background(), families)
+	f.Wait()
+
+	assert.Equal(t, 1, slow.count())
+	assert.Equal(t, 2, fast.count())
+}
@@ -1,6 +1,9 @@
 package status
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
 	"io"
 	"time"
@@ -8,6 +11,7 @@ import (
 	"github.com/dustin/go-humanize"
 	"github.com/dustin/go-humanize/english"
 	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
 )
 
 const (
@@ -33,7 +37,7 @@ const (
 	<h1>Active endpoints</h1>
 	<table>
 		<tbody>
-			{{ range . }}
+			{{ range .Endpoints }}
 			{{ if .Path }}
 			<tr>
 				<td>
@@ -63,6 +67,80 @@ const (
 			{{ end }}
 		</tbody>
 	</table>
+
+	<h1>Collectors</h1>
+	<table>
+		<thead>
+			<tr>
+				<th>Collector</th>
+				<th>Duration</th>
+				<th>Status</th>
+			</tr>
+		</thead>
+		<tbody>
+			{{ range .Collectors }}
+			<tr>
+				<td>{{ .Name }}</td>
+				<td>{{ if not .Absent }}{{ .Duration }}{{ end }}</td>
+				<td>{{ if .Absent }}Hardware not present{{ else if .Error }}{{ .Error }}{{ else }}OK{{ end }}</td>
+			</tr>
+			{{ else }}
+			<tr>
+				<td colspan="3">No metrics fetched yet</td>
+			</tr>
+			{{ end }}
+		</tbody>
+	</table>
+
+	<h1>Discovery</h1>
+	<table>
+		<thead>
+			<tr>
+				<th>Item</th>
+				<th>Found</th>
+				<th>Status</th>
+			</tr>
+		</thead>
+		<tbody>
+			{{ range .Discovery }}
+			<tr>
+				<td>{{ .Item }}</td>
+				<td>{{ .Count }}</td>
+				<td>{{ if .Error }}{{ .Error }}{{ else }}OK{{ end }}</td>
+			</tr>
+			{{ else }}
+			<tr>
+				<td colspan="3">Environment not read yet</td>
+			</tr>
+			{{ end }}
+		</tbody>
+	</table>
+
+	<h1>Recent log</h1>
+	<table>
+		<thead>
+			<tr>
+				<th>Time</th>
+				<th>Level</th>
+				<th>Message</th>
+				<th>Fields</th>
+			</tr>
+		</thead>
+		<tbody>
+			{{ range .Log }}
+			<tr>
+				<td>{{ .Time.Format "2006-01-02 15:04:05" }}</td>
+				<td>{{ .Level }}</td>
+				<td>{{ .Message }}</td>
+				<td>{{ range $i, $f := .Fields }}{{ if $i }} {{ end }}{{ $f.Key }}={{ $f.Value }}{{ end }}</td>
+			</tr>
+			{{ else }}
+			<tr>
+				<td colspan="4">No log entries</td>
+			</tr>
+			{{ end }}
+		</tbody>
+	</table>
 </body>
 `
 )
@@ -72,11 +150,55 @@ type endpointStatus struct {
 	Properties map[string]string
 }
 
+type jsonStatus struct {
+	Version                  string                `json:"version"`
+	Revision                 string                `json:"revision"`
+	Branch                   string                `json:"branch"`
+	Built                    string                `json:"built"`
+	Model                    string                `json:"model,omitempty"`
+	Firmware                 string                `json:"firmware,omitempty"`
+	StartTime                *time.Time            `json:"start_time"`
+	BootTime                 *time.Time            `json:"boot_time"`
+	LastFetch                *time.Time            `json:"last_fetch"`
+	LastFetchDurationSeconds float64               `json:"last_fetch_duration_seconds"`
+	MetricCount              int                   `json:"metric_count"`
+	Collectors               []jsonCollectorStatus `json:"collectors"`
+	Ups                      []string              `json:"ups"`
+	Devices                  []string              `json:"devices"`
+	Volumes                  []string              `json:"volumes"`
+	Interfaces               []string              `json:"interfaces"`
+	Enclosures               []string              `json:"enclosures"`
+	DmCaches                 []string              `json:"dm_caches"`
+	DmCacheDevice            string                `json:"dm_cache_device,omitempty"`
+	Docker                   string                `json:"docker,omitempty"`
+	AbsentSubsystems         []string              `json:"absent_subsystems"`
+	Discovery                []jsonDiscoveryStatus `json:"discovery"`
+	LastNotification         *time.Time            `json:"last_notification,omitempty"`
+	ListenURLs               []string              `json:"listen_urls,omitempty"`
+}
+
+type jsonCollectorStatus struct {
+	Name            string  `json:"name"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Error           string  `json:"error,omitempty"`
+	Absent          bool    `json:"absent,omitempty"`
+}
+
+type jsonDiscoveryStatus struct {
+	Item  string `json:"item"`
+	Count int    `json:"count"`
+	Error string `json:"error,omitempty"`
+}
+
 type Status struct {
 	MetricsEndpoint      string
 	NotificationEndpoint string
 	ExporterStatus       exporter.Status
 	LastNotification     time.Time
+	// ListenURLs are the base URLs of the endpoints, one per listen address
+	ListenURLs []string
+	// Log holds the recent log entries shown on the status page, if not nil
+	Log *logging.Buffer
 }
 
 func (s *Status) WriteHTML(w io.Writer) error {
@@ -84,7 +206,11 @@ func (s *Status) WriteHTML(w io.Writer) error {
 	ms := endpointStatus{
 		Path: s.MetricsEndpoint,
 		Properties: map[string]string{
-			"Uptime":        humanizeTime(e.Uptime),
+			"Listening on":  humanizeList(s.ListenURLs),
+			"Model":         e.Model,
+			"Firmware":      e.Firmware,
+			"Started":       humanizeTime(e.StartTime),
+			"Booted":        humanizeTime(e.BootTime),
 			"Last fetch":    humanizeTime(e.LastFetch),
 			"Last duration": e.LastFetchDuration.String(),
 			"Metrics":       humanize.Comma(int64(e.MetricCount)),
@@ -96,6 +222,7 @@ func (s *Status) WriteHTML(w io.Writer) error {
 			"dm-caches":     humanizeList(e.DmCaches),
 			"dm-volume":     e.DmCacheDevice,
 			"Docker":        e.Docker,
+			"Absent":        humanizeList(e.AbsentSubsystems),
 		},
 	}
 	endpoints := []endpointStatus{ms}
@@ -106,9 +233,21 @@ func (s *Status) WriteHTML(w io.Writer) error {
 		},
 	})
 
+	data := struct {
+		Endpoints  []endpointStatus
+		Collectors []exporter.CollectorStatus
+		Discovery  []exporter.DiscoveryStatus
+		Log        []logging.Entry
+	}{
+		Endpoints:  endpoints,
+		Collectors: e.Collectors,
+		Discovery:  e.Discovery,
+		Log:        newestFirst(s.Log.Entries()),
+	}
+
 	tmpl, err := template.New("html").Parse(statusHtmlTemplate)
 	if err == nil {
-		err = tmpl.Execute(w, endpoints)
+		err = tmpl.Execute(w, data)
 		if err == nil {
 			return nil
 		}
@@ -117,6 +256,95 @@ func (s *Status) WriteHTML(w io.Writer) error {
 	return err
 }
 
+// WriteJSON writes the status in a machine-readable format, as served by the /api/status endpoint
+func (s *Status) WriteJSON(w io.Writer) error {
+	e := s.ExporterStatus
+	status := jsonStatus{
+		Version:                  e.Version,
+		Revision:                 e.Revision,
+		Branch:                   e.Branch,
+		Built:                    e.Built,
+		Model:                    e.Model,
+		Firmware:                 e.Firmware,
+		StartTime:                timePtr(e.StartTime),
+		BootTime:                 timePtr(e.BootTime),
+		LastFetch:                timePtr(e.LastFetch),
+		LastFetchDurationSeconds: e.LastFetchDuration.Seconds(),
+		MetricCount:              e.MetricCount,
+		Collectors:               make([]jsonCollectorStatus, 0, len(e.Collectors)),
+		Ups:                      e.Ups,
+		Devices:                  e.Devices,
+		Volumes:                  e.Volumes,
+		Interfaces:               e.Interfaces,
+		Enclosures:               e.Enclosures,
+		DmCaches:                 e.DmCaches,
+		DmCacheDevice:            e.DmCacheDevice,
+		Docker:                   e.Docker,
+		AbsentSubsystems:         e.AbsentSubsystems,
+		Discovery:                make([]jsonDiscoveryStatus, 0, len(e.Discovery)),
+		LastNotification:         timePtr(s.LastNotification),
+		ListenURLs:               s.ListenURLs,
+	}
+	for _, c := range e.Collectors {
+		status.Collectors = append(status.Collectors, jsonCollectorStatus{
+			Name:            c.Name,
+			DurationSeconds: c.Duration.Seconds(),
+			Error:           c.Error,
+			Absent:          c.Absent,
+		})
+	}
+
+	for _, d := range e.Discovery {
+		status.Discovery = append(status.Discovery, jsonDiscoveryStatus{Item: d.Item, Count: d.Count, Error: d.Error})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(status)
+}
+
+// Ready returns an error if the exporter is not ready to serve metrics, i.e. if the environment was not read yet
+// or if more than maxFailingRatio of the collectors which ran in the last fetch failed
+func (s *Status) Ready(maxFailingRatio float64) error {
+	e := s.ExporterStatus
+	if e.EnvironmentRead.IsZero() {
+		return errors.New("the environment was not read yet")
+	}
+
+	var ran, failing int
+	for _, c := range e.Collectors {
+		if c.Absent {
+			continue
+		}
+		ran++
+		if c.Error != "" {
+			failing++
+		}
+	}
+	if ran > 0 && float64(failing)/float64(ran) > maxFailingRatio {
+		return fmt.Errorf("%d of %d collectors failed", failing, ran)
+	}
+
+	return nil
+}
+
+// newestFirst reverses entries in place, so that the most recent log entries are shown first
+func newestFirst(entries []logging.Entry) []logging.Entry {
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+
+	return entries
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
 func humanizeList(a []string) string {
 	if len(a) == 0 {
 		return "N/A"
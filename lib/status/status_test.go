@@ -1,10 +1,16 @@
 package status
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -13,3 +19,132 @@ func TestWriteHTML(t *testing.T) {
 	err := s.WriteHTML(os.Stderr)
 	require.NoError(t, err)
 }
+
+func TestWriteHTMLCollectors(t *testing.T) {
+	s := Status{
+		MetricsEndpoint: "/metrics",
+		ExporterStatus: exporter.Status{
+			Collectors: []exporter.CollectorStatus{
+				{Name: "cpu", Duration: 2 * time.Millisecond},
+				{Name: "ups", Absent: true},
+				{Name: "smart", Duration: time.Second, Error: "exit status 2"},
+			},
+		},
+	}
+
+	b := new(bytes.Buffer)
+	require.NoError(t, s.WriteHTML(b))
+
+	assert.Contains(t, b.String(), "<td>cpu</td>")
+	assert.Contains(t, b.String(), "Hardware not present")
+	assert.Contains(t, b.String(), "<td>exit status 2</td>")
+}
+
+func TestWriteHTMLLog(t *testing.T) {
+	buffer := logging.NewBuffer(10)
+	logger := logging.New(new(bytes.Buffer), logging.LevelInfo, logging.FormatText, buffer)
+	logger.Info("Environment read")
+	logger.Error("Collector failed", "collector", "smart")
+
+	s := Status{MetricsEndpoint: "/metrics", Log: buffer}
+
+	b := new(bytes.Buffer)
+	require.NoError(t, s.WriteHTML(b))
+
+	html := b.String()
+	assert.Contains(t, html, "<td>Collector failed</td>")
+	assert.Contains(t, html, "<td>collector=smart</td>")
+	assert.Less(t, strings.Index(html, "Collector failed"), strings.Index(html, "Environment read"), "newest entries come first")
+
+	b.Reset()
+	require.NoError(t, (&Status{}).WriteHTML(b))
+	assert.Contains(t, b.String(), "No log entries")
+}
+
+func TestWriteJSON(t *testing.T) {
+	s := Status{
+		MetricsEndpoint: "/metrics",
+		ListenURLs:      []string{"http://0.0.0.0:9094", "http://[::]:9094"},
+		ExporterStatus: exporter.Status{
+			Version:           "1.2.3",
+			LastFetch:         time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+			LastFetchDuration: 1500 * time.Millisecond,
+			MetricCount:       42,
+			Devices:           []string{"sda", "sdb"},
+			Collectors: []exporter.CollectorStatus{
+				{Name: "smart", Duration: time.Second, Error: "exit status 2"},
+			},
+			Discovery: []exporter.DiscoveryStatus{
+				{Item: "devices", Error: "permission denied"},
+				{Item: "interfaces", Count: 2},
+			},
+		},
+	}
+
+	b := new(bytes.Buffer)
+	require.NoError(t, s.WriteJSON(b))
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(b.Bytes(), &decoded))
+	assert.Equal(t, "1.2.3", decoded["version"])
+	assert.Equal(t, "2022-01-02T03:04:05Z", decoded["last_fetch"])
+	assert.Nil(t, decoded["start_time"])
+	assert.Equal(t, 1.5, decoded["last_fetch_duration_seconds"])
+	assert.Equal(t, 42.0, decoded["metric_count"])
+	assert.Equal(t, []interface{}{"sda", "sdb"}, decoded["devices"])
+	assert.Equal(t, []interface{}{"http://0.0.0.0:9094", "http://[::]:9094"}, decoded["listen_urls"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "smart", "duration_seconds": 1.0, "error": "exit status 2"},
+	}, decoded["collectors"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"item": "devices", "count": 0.0, "error": "permission denied"},
+		map[string]interface{}{"item": "interfaces", "count": 2.0},
+	}, decoded["discovery"])
+}
+
+func TestReady(t *testing.T) {
+	collectors := []exporter.CollectorStatus{
+		{Name: "cpu"},
+		{Name: "ups", Absent: true},
+		{Name: "smart", Error: "exit status 2"},
+		{Name: "mdstat"},
+	}
+
+	tests := map[string]struct {
+		status          exporter.Status
+		maxFailingRatio float64
+		expectedErr     string
+	}{
+		"environment not read": {
+			maxFailingRatio: 0.5,
+			expectedErr:     "the environment was not read yet",
+		},
+		"no fetch yet": {
+			status:          exporter.Status{EnvironmentRead: time.Now()},
+			maxFailingRatio: 0.5,
+		},
+		"below threshold": {
+			status:          exporter.Status{EnvironmentRead: time.Now(), Collectors: collectors},
+			maxFailingRatio: 0.5,
+		},
+		"above threshold": {
+			status:          exporter.Status{EnvironmentRead: time.Now(), Collectors: collectors},
+			maxFailingRatio: 0.25,
+			expectedErr:     "1 of 3 collectors failed",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := Status{ExporterStatus: tc.status}
+
+			err := s.Ready(tc.maxFailingRatio)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
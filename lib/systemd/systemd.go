@@ -0,0 +1,95 @@
+// Package systemd implements the sd_notify protocol used by services of Type=notify,
+// without depending on libsystemd
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+)
+
+const (
+	// StateReady tells the service manager that the service finished starting up
+	StateReady = "READY=1"
+	// StateStopping tells the service manager that the service is shutting down
+	StateStopping = "STOPPING=1"
+	// StateWatchdog is the keepalive sent when WatchdogSec is set
+	StateWatchdog = "WATCHDOG=1"
+
+	notifySocketEnv = "NOTIFY_SOCKET"
+	watchdogUsecEnv = "WATCHDOG_USEC"
+	watchdogPidEnv  = "WATCHDOG_PID"
+)
+
+// Notify sends state to the service manager. It returns false if the process was not started
+// by systemd with notification support (i.e. NOTIFY_SOCKET is not set).
+func Notify(state string) (bool, error) {
+	socket := os.Getenv(notifySocketEnv)
+	if socket == "" {
+		return false, nil
+	}
+	if socket[0] == '@' {
+		// Abstract namespace socket
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("connect to systemd notification socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("notify systemd: %w", err)
+	}
+
+	return true, nil
+}
+
+// WatchdogInterval returns the interval within which systemd expects a keepalive,
+// or 0 if the watchdog is not enabled for this process
+func WatchdogInterval() (time.Duration, error) {
+	usec := os.Getenv(watchdogUsecEnv)
+	if usec == "" {
+		return 0, nil
+	}
+
+	if pid := os.Getenv(watchdogPidEnv); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		// The watchdog is meant for another process (e.g. a parent shell script)
+		return 0, nil
+	}
+
+	n, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid %s value %q", watchdogUsecEnv, usec)
+	}
+
+	return time.Duration(n) * time.Microsecond, nil
+}
+
+// RunWatchdog sends a keepalive to systemd twice per interval while healthy returns true, until ctx is done.
+// Once healthy returns false, the keepalives stop so that systemd restarts the service.
+func RunWatchdog(ctx context.Context, interval time.Duration, healthy func() bool, logger *logging.Logger) {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !healthy() {
+				logger.Warn("Exporter is unhealthy, stopping systemd watchdog keepalives")
+				return
+			}
+			if _, err := Notify(StateWatchdog); err != nil {
+				logger.Error("Error sending systemd watchdog keepalive", "err", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package systemd
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listenNotifySocket(t *testing.T) *net.UnixConn {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv(notifySocketEnv, path)
+
+	return conn
+}
+
+func readState(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+
+	buf := make([]byte, 64)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+
+	return string(buf[:n])
+}
+
+func TestNotify(t *testing.T) {
+	t.Run("without socket", func(t *testing.T) {
+		t.Setenv(notifySocketEnv, "")
+
+		sent, err := Notify(StateReady)
+		require.NoError(t, err)
+		assert.False(t, sent)
+	})
+
+	t.Run("with socket", func(t *testing.T) {
+		conn := listenNotifySocket(t)
+
+		sent, err := Notify(StateReady)
+		require.NoError(t, err)
+		assert.True(t, sent)
+		assert.Equal(t, StateReady, readState(t, conn))
+	})
+
+	t.Run("with missing socket", func(t *testing.T) {
+		t.Setenv(notifySocketEnv, filepath.Join(t.TempDir(), "missing.sock"))
+
+		sent, err := Notify(StateReady)
+		assert.Error(t, err)
+		assert.False(t, sent)
+	})
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	testCases := map[string]struct {
+		usec    string
+		pid     string
+		want    time.Duration
+		wantErr bool
+	}{
+		"disabled": {},
+		"enabled": {
+			usec: "30000000",
+			want: 30 * time.Second,
+		},
+		"enabled for this process": {
+			usec: "30000000",
+			pid:  strconv.Itoa(os.Getpid()),
+			want: 30 * time.Second,
+		},
+		"enabled for another process": {
+			usec: "30000000",
+			pid:  "1",
+		},
+		"invalid": {
+			usec:    "30s",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(watchdogUsecEnv, tc.usec)
+			t.Setenv(watchdogPidEnv, tc.pid)
+
+			got, err := WatchdogInterval()
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestRunWatchdog(t *testing.T) {
+	conn := listenNotifySocket(t)
+	logger := logging.Discard()
+
+	keepalives := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		RunWatchdog(context.Background(), 20*time.Millisecond, func() bool {
+			keepalives++
+			return keepalives <= 2
+		}, logger)
+	}()
+
+	assert.Equal(t, StateWatchdog, readState(t, conn))
+	assert.Equal(t, StateWatchdog, readState(t, conn))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watchdog kept running after becoming unhealthy")
+	}
+}
@@ -0,0 +1,129 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+)
+
+const (
+	// Interval is the time between two reports
+	Interval = 24 * time.Hour
+
+	// readyPollInterval is how often the status is checked for a completed scrape before the first report
+	readyPollInterval = time.Minute
+	sendTimeout       = 30 * time.Second
+)
+
+// Outcomes of a collector in the last scrape, as reported in Report.Collectors. The other values are the error
+// classes reported by qnapexporter_collector_error_info (e.g. timeout).
+const (
+	OutcomeSuccess = "success"
+	OutcomeAbsent  = "absent"
+	OutcomeError   = "error"
+)
+
+// Report holds the anonymous statistics sent to the telemetry endpoint. It deliberately leaves out anything which
+// could identify the NAS or its owner: no hostname, address, serial number, volume, device or share name, and no
+// error message, only the class of the errors.
+type Report struct {
+	// Version is the version of qnapexporter
+	Version string `json:"version"`
+	// Platform is the operating system and architecture of the binary, e.g. linux/amd64
+	Platform string `json:"platform"`
+	// Model is the NAS model reported by getsysinfo, e.g. TS-453D
+	Model string `json:"model,omitempty"`
+	// Firmware is the QTS firmware version, e.g. 5.1.0
+	Firmware string `json:"firmware,omitempty"`
+	// Collectors maps each collector run in the last scrape to its outcome
+	Collectors map[string]string `json:"collectors"`
+}
+
+// NewReport builds the report from the status of the exporter
+func NewReport(s exporter.Status) Report {
+	r := Report{
+		Version:    s.Version,
+		Platform:   runtime.GOOS + "/" + runtime.GOARCH,
+		Model:      s.Model,
+		Firmware:   s.Firmware,
+		Collectors: make(map[string]string, len(s.Collectors)),
+	}
+	for _, c := range s.Collectors {
+		switch {
+		case c.Absent:
+			r.Collectors[c.Name] = OutcomeAbsent
+		case c.Error == "":
+			r.Collectors[c.Name] = OutcomeSuccess
+		case c.ErrorClass != "":
+			r.Collectors[c.Name] = c.ErrorClass
+		default:
+			r.Collectors[c.Name] = OutcomeError
+		}
+	}
+
+	return r
+}
+
+// Send posts the report to url as JSON
+func Send(ctx context.Context, client *http.Client, url string, r Report) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("send telemetry report: unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Run sends a report to url every Interval, starting once the first scrape completed, until ctx is done.
+// status returns the current status of the exporter.
+func Run(ctx context.Context, url string, status func() exporter.Status, logger *logging.Logger) {
+	client := &http.Client{Timeout: sendTimeout}
+
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+
+	var lastSent time.Time
+	for {
+		if s := status(); !s.LastFetch.IsZero() && time.Since(lastSent) >= Interval {
+			r := NewReport(s)
+			if err := Send(ctx, client, url, r); err != nil {
+				logger.Warn("Error sending the telemetry report", "err", err)
+			} else {
+				logger.Debug("Sent the telemetry report", "model", r.Model, "firmware", r.Firmware)
+			}
+			// A failed report is not retried before the next interval, so that an unreachable endpoint is not hammered
+			lastSent = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReport(t *testing.T) {
+	s := exporter.Status{
+		Version:    "v1.2.3",
+		Model:      "TS-453D",
+		Firmware:   "5.1.0",
+		LastFetch:  time.Now(),
+		Interfaces: []string{"eth0"},
+		Volumes:    []string{"DataVol1"},
+		Collectors: []exporter.CollectorStatus{
+			{Name: "cpu"},
+			{Name: "ups", Absent: true},
+			{Name: "smart", Error: "exec: smartctl -A /dev/sda: timeout", ErrorClass: "timeout"},
+			{Name: "docker", Error: "unclassified"},
+		},
+	}
+
+	assert.Equal(t, Report{
+		Version:  "v1.2.3",
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		Model:    "TS-453D",
+		Firmware: "5.1.0",
+		Collectors: map[string]string{
+			"cpu":    OutcomeSuccess,
+			"ups":    OutcomeAbsent,
+			"smart":  "timeout",
+			"docker": OutcomeError,
+		},
+	}, NewReport(s))
+}
+
+func TestSend(t *testing.T) {
+	tests := map[string]struct {
+		status  int
+		wantErr string
+	}{
+		"accepted": {status: http.StatusAccepted},
+		"rejected": {status: http.StatusBadRequest, wantErr: "send telemetry report: unexpected status 400 Bad Request"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var received Report
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			report := Report{Version: "v1.2.3", Platform: "linux/amd64", Collectors: map[string]string{"cpu": OutcomeSuccess}}
+			err := Send(context.Background(), server.Client(), server.URL, report)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, report, received)
+		})
+	}
+}
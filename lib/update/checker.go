@@ -0,0 +1,73 @@
+package update
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// backgroundCheck runs fetch in the background, at most once per interval for the same key, and keeps the result
+// of the last successful run, so that callers never wait for the remote feed
+type backgroundCheck[T any] struct {
+	fetch func(ctx context.Context, key string) (T, error)
+
+	mu        sync.Mutex
+	key       string
+	latest    T
+	checked   bool
+	checking  bool
+	lastCheck time.Time
+	// checkedAt is when the last successful check completed
+	checkedAt time.Time
+}
+
+// refresh starts a check for key in the background if the last one started more than interval ago or was for
+// another key. onDone is called with the outcome of the check, unless the key changed in the meantime.
+func (c *backgroundCheck[T]) refresh(key string, interval time.Duration, onDone func(T, error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.checking || (c.key == key && !c.lastCheck.IsZero() && time.Since(c.lastCheck) < interval) {
+		return
+	}
+	if c.key != key {
+		var zero T
+		c.key, c.latest, c.checked = key, zero, false
+	}
+	c.checking = true
+	c.lastCheck = time.Now()
+
+	go func() {
+		latest, err := c.fetch(context.Background(), key)
+
+		c.mu.Lock()
+		c.checking = false
+		if c.key != key {
+			// The key changed while checking, so the result is stale
+			c.mu.Unlock()
+			return
+		}
+		if err == nil {
+			c.latest, c.checked, c.checkedAt = latest, true, time.Now()
+		}
+		c.mu.Unlock()
+
+		onDone(latest, err)
+	}()
+}
+
+// result returns the result of the last successful check, and whether there was one
+func (c *backgroundCheck[T]) result() (T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.latest, c.checked
+}
+
+// CheckedAt returns when the last successful check completed, or the zero time if none did
+func (c *backgroundCheck[T]) CheckedAt() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.checkedAt
+}
@@ -0,0 +1,44 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackgroundCheck(t *testing.T) {
+	c := &backgroundCheck[string]{
+		fetch: func(_ context.Context, key string) (string, error) {
+			if key == "broken" {
+				return "", errors.New("feed unavailable")
+			}
+			return "latest for " + key, nil
+		},
+	}
+	refresh := func(key string) error {
+		done := make(chan error, 1)
+		c.refresh(key, time.Hour, func(_ string, err error) { done <- err })
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(time.Second):
+			t.Fatal("check didn't complete")
+			return nil
+		}
+	}
+
+	assert.NoError(t, refresh("TS-453D"))
+	latest, checked := c.result()
+	assert.True(t, checked)
+	assert.Equal(t, "latest for TS-453D", latest)
+	checkedAt := c.CheckedAt()
+
+	// A failed check for another key drops the result of the previous key
+	assert.Error(t, refresh("broken"))
+	_, checked = c.result()
+	assert.False(t, checked)
+	assert.Equal(t, checkedAt, c.CheckedAt())
+}
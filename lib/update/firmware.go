@@ -0,0 +1,153 @@
+package update
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+)
+
+// DefaultFirmwareReleaseURL is the feed listing the latest firmware release of each QNAP model, as used by the
+// QTS Live Update
+const DefaultFirmwareReleaseURL = "https://update.qnap.com/FirmwareRelease.xml"
+
+// Firmware describes a firmware version, e.g. QTS 5.1.0 build 20230822
+type Firmware struct {
+	Version string `xml:"version"`
+	Build   string `xml:"build"`
+}
+
+func (f Firmware) String() string {
+	if f.Build == "" {
+		return f.Version
+	}
+
+	return f.Version + " build " + f.Build
+}
+
+type firmwareReleaseItem struct {
+	Firmware
+	Model string `xml:"modelName"`
+}
+
+// LatestFirmware retrieves the latest firmware release for model from the feed at url. The releases are looked up
+// in every releaseitem element of the feed, wherever it is nested, since the layout of the feed is undocumented.
+func LatestFirmware(ctx context.Context, client *http.Client, url, model string) (Firmware, error) {
+	body, err := get(ctx, client, url)
+	if err != nil {
+		return Firmware{}, fmt.Errorf("retrieve firmware releases: %w", err)
+	}
+	defer body.Close()
+
+	return parseLatestFirmware(body, model)
+}
+
+func parseLatestFirmware(r io.Reader, model string) (Firmware, error) {
+	var (
+		latest Firmware
+		found  bool
+	)
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Firmware{}, fmt.Errorf("decode firmware releases: %w", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || !strings.EqualFold(start.Name.Local, "releaseitem") {
+			continue
+		}
+
+		var item firmwareReleaseItem
+		if err := decoder.DecodeElement(&item, &start); err != nil {
+			return Firmware{}, fmt.Errorf("decode firmware releases: %w", err)
+		}
+		if !strings.EqualFold(strings.TrimSpace(item.Model), model) {
+			continue
+		}
+
+		item.Version, item.Build = strings.TrimSpace(item.Version), strings.TrimSpace(item.Build)
+		if !found || IsNewerFirmware(latest, item.Firmware) {
+			latest, found = item.Firmware, true
+		}
+	}
+
+	if !found {
+		return Firmware{}, fmt.Errorf("no firmware release found for model %s", model)
+	}
+
+	return latest, nil
+}
+
+// IsNewerFirmware returns whether the latest firmware is newer than the current one, comparing the versions
+// component by component (e.g. 5.1.0 < 5.1.10), and then the build numbers
+func IsNewerFirmware(current, latest Firmware) bool {
+	if c := compareNumbers(strings.Split(current.Version, "."), strings.Split(latest.Version, ".")); c != 0 {
+		return c < 0
+	}
+
+	return compareNumbers([]string{current.Build}, []string{latest.Build}) < 0
+}
+
+// compareNumbers compares two lists of numbers, a missing or invalid number being considered 0
+func compareNumbers(a, b []string) int {
+	for idx := 0; idx < len(a) || idx < len(b); idx++ {
+		var x, y int
+		if idx < len(a) {
+			x, _ = strconv.Atoi(a[idx])
+		}
+		if idx < len(b) {
+			y, _ = strconv.Atoi(b[idx])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// FirmwareChecker checks whether a newer firmware is available for the NAS model
+type FirmwareChecker struct {
+	backgroundCheck[Firmware]
+}
+
+func NewFirmwareChecker(url string) *FirmwareChecker {
+	client := &http.Client{Timeout: 30 * time.Second}
+	return &FirmwareChecker{
+		backgroundCheck: backgroundCheck[Firmware]{
+			fetch: func(ctx context.Context, model string) (Firmware, error) {
+				return LatestFirmware(ctx, client, url, model)
+			},
+		},
+	}
+}
+
+// Refresh starts a check for model in the background if the last one started more than interval ago
+// or was for another model
+func (c *FirmwareChecker) Refresh(model string, interval time.Duration, logger *logging.Logger) {
+	c.refresh(model, interval, func(_ Firmware, err error) {
+		if err != nil {
+			logger.Warn("Error checking for firmware updates", "model", model, "err", err)
+		}
+	})
+}
+
+// Latest returns the latest firmware found by the last successful check, and whether there was one
+func (c *FirmwareChecker) Latest() (Firmware, bool) {
+	return c.result()
+}
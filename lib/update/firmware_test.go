@@ -0,0 +1,112 @@
+package update
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const firmwareReleaseFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<docRoot>
+  <func>
+    <ownContent>
+      <releaseitem>
+        <modelName>TS-453D</modelName>
+        <version>5.0.1</version>
+        <build>20230515</build>
+      </releaseitem>
+      <releaseitem>
+        <modelName>TS-453D</modelName>
+        <version>5.1.0</version>
+        <build>20230822</build>
+      </releaseitem>
+      <releaseitem>
+        <modelName>TS-251</modelName>
+        <version>4.3.6</version>
+        <build>20220526</build>
+      </releaseitem>
+    </ownContent>
+  </func>
+</docRoot>`
+
+func TestParseLatestFirmware(t *testing.T) {
+	tests := map[string]struct {
+		model   string
+		want    Firmware
+		wantErr bool
+	}{
+		"latest of several releases": {model: "TS-453D", want: Firmware{Version: "5.1.0", Build: "20230822"}},
+		"case insensitive model":     {model: "ts-251", want: Firmware{Version: "4.3.6", Build: "20220526"}},
+		"unknown model":              {model: "TS-h973AX", wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, err := parseLatestFirmware(strings.NewReader(firmwareReleaseFeed), tc.model)
+
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, f)
+		})
+	}
+
+	_, err := parseLatestFirmware(strings.NewReader("<docRoot><releaseitem>"), "TS-453D")
+	assert.Error(t, err)
+}
+
+func TestIsNewerFirmware(t *testing.T) {
+	tests := map[string]struct {
+		current, latest Firmware
+		expected        bool
+	}{
+		"newer version":      {current: Firmware{"5.0.1", "20230515"}, latest: Firmware{"5.1.0", "20230822"}, expected: true},
+		"numeric components": {current: Firmware{"5.1.9", "20230515"}, latest: Firmware{"5.1.10", "20230101"}, expected: true},
+		"newer build":        {current: Firmware{"5.1.0", "20230822"}, latest: Firmware{"5.1.0", "20231010"}, expected: true},
+		"same firmware":      {current: Firmware{"5.1.0", "20230822"}, latest: Firmware{"5.1.0", "20230822"}},
+		"older firmware":     {current: Firmware{"5.1.0", "20230822"}, latest: Firmware{"5.0.1", "20231010"}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, IsNewerFirmware(tc.current, tc.latest))
+		})
+	}
+}
+
+func TestFirmwareChecker(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprint(w, firmwareReleaseFeed)
+	}))
+	defer srv.Close()
+
+	c := NewFirmwareChecker(srv.URL)
+	_, checked := c.Latest()
+	assert.False(t, checked)
+	assert.True(t, c.CheckedAt().IsZero())
+
+	c.Refresh("TS-453D", time.Hour, logging.Discard())
+	assert.Eventually(t, func() bool {
+		_, checked := c.Latest()
+		return checked
+	}, time.Second, 10*time.Millisecond)
+	latest, _ := c.Latest()
+	assert.Equal(t, "5.1.0 build 20230822", latest.String())
+	assert.WithinDuration(t, time.Now(), c.CheckedAt(), time.Second)
+
+	// The last check is recent enough
+	c.Refresh("TS-453D", time.Hour, logging.Discard())
+	assert.Equal(t, int32(1), requests.Load())
+}
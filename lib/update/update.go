@@ -0,0 +1,330 @@
+package update
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+)
+
+const (
+	// DefaultReleaseURL is the GitHub API endpoint describing the latest release
+	DefaultReleaseURL = "https://api.github.com/repos/pedropombeiro/qnapexporter/releases/latest"
+
+	// assetName is the release asset holding the binary, as published by the release workflow
+	assetName  = "qnapexporter.tar.gz"
+	binaryName = "qnapexporter"
+	// checksumAssetName is the release asset holding the SHA-256 sum of assetName, without which no update is installed
+	checksumAssetName = assetName + ".sha256"
+
+	// releasePlatform is the only platform for which a binary is published
+	releasePlatform = "linux/amd64"
+
+	requestTimeout = 5 * time.Minute
+)
+
+var errNoVersion = errors.New("not a release version")
+
+// Release describes a published release
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Asset describes a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+func (r *Release) asset(name string) *Asset {
+	for idx := range r.Assets {
+		if r.Assets[idx].Name == name {
+			return &r.Assets[idx]
+		}
+	}
+
+	return nil
+}
+
+// LatestRelease retrieves the description of the latest release from url
+func LatestRelease(ctx context.Context, client *http.Client, url string) (*Release, error) {
+	body, err := get(ctx, client, url)
+	if err != nil {
+		return nil, fmt.Errorf("retrieve latest release: %w", err)
+	}
+	defer body.Close()
+
+	var r Release
+	if err := json.NewDecoder(body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("decode latest release: %w", err)
+	}
+	if r.TagName == "" {
+		return nil, errors.New("decode latest release: missing tag name")
+	}
+
+	return &r, nil
+}
+
+// IsNewer returns whether the latest version is newer than the current one. Versions which are not
+// release versions (e.g. "dev" for local builds) are never considered outdated.
+func IsNewer(current, latest string) bool {
+	c, err := parseVersion(current)
+	if err != nil {
+		return false
+	}
+	l, err := parseVersion(latest)
+	if err != nil {
+		return false
+	}
+
+	for idx := range c {
+		if l[idx] != c[idx] {
+			return l[idx] > c[idx]
+		}
+	}
+
+	return false
+}
+
+// parseVersion parses versions such as "v1.2.3" or "1.2", ignoring any pre-release suffix
+func parseVersion(s string) ([3]int, error) {
+	var v [3]int
+
+	s = strings.TrimPrefix(s, "v")
+	s, _, _ = strings.Cut(s, "-")
+	parts := strings.Split(s, ".")
+	if len(parts) > len(v) {
+		return v, fmt.Errorf("parse version %q: %w", s, errNoVersion)
+	}
+	for idx, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("parse version %q: %w", s, errNoVersion)
+		}
+		v[idx] = n
+	}
+
+	return v, nil
+}
+
+// Checker checks whether a newer release is available
+type Checker struct {
+	backgroundCheck[string]
+
+	current string
+}
+
+func NewChecker(url, current string) *Checker {
+	client := &http.Client{Timeout: 30 * time.Second}
+	return &Checker{
+		backgroundCheck: backgroundCheck[string]{
+			fetch: func(ctx context.Context, _ string) (string, error) {
+				r, err := LatestRelease(ctx, client, url)
+				if err != nil {
+					return "", err
+				}
+
+				return r.TagName, nil
+			},
+		},
+		current: current,
+	}
+}
+
+// Refresh starts a check in the background if the last one started more than interval ago
+func (c *Checker) Refresh(interval time.Duration, logger *logging.Logger) {
+	c.refresh("", interval, func(latest string, err error) {
+		if err != nil {
+			logger.Warn("Error checking for updates", "err", err)
+		} else if IsNewer(c.current, latest) {
+			logger.Info("A newer qnapexporter version is available", "latest", latest, "running", c.current)
+		}
+	})
+}
+
+// Latest returns the latest release version and whether it is newer than the running one.
+// The version is empty until a check succeeds.
+func (c *Checker) Latest() (version string, available bool) {
+	latest, checked := c.result()
+	if !checked {
+		return "", false
+	}
+
+	return latest, IsNewer(c.current, latest)
+}
+
+// SelfUpdate replaces the binary at exePath with the latest release, if it is newer than current.
+// It returns the version installed, or an empty string if current is already the latest version.
+func SelfUpdate(ctx context.Context, url, current, exePath string) (string, error) {
+	if platform := runtime.GOOS + "/" + runtime.GOARCH; platform != releasePlatform {
+		return "", fmt.Errorf("no release binary is published for %s", platform)
+	}
+	if _, err := parseVersion(current); err != nil {
+		return "", fmt.Errorf("cannot update a development build: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	return selfUpdate(ctx, &http.Client{}, url, current, exePath)
+}
+
+func selfUpdate(ctx context.Context, client *http.Client, url, current, exePath string) (string, error) {
+	r, err := LatestRelease(ctx, client, url)
+	if err != nil {
+		return "", err
+	}
+	if !IsNewer(current, r.TagName) {
+		return "", nil
+	}
+
+	asset := r.asset(assetName)
+	if asset == nil {
+		return "", fmt.Errorf("release %s has no %s asset", r.TagName, assetName)
+	}
+	checksum := r.asset(checksumAssetName)
+	if checksum == nil {
+		return "", fmt.Errorf("release %s has no %s asset, refusing to install an unverified binary", r.TagName, checksumAssetName)
+	}
+	archive, err := download(ctx, client, asset.URL)
+	if err != nil {
+		return "", err
+	}
+	if err := verifyChecksum(ctx, client, checksum.URL, archive); err != nil {
+		return "", err
+	}
+
+	binary, err := extractBinary(archive)
+	if err != nil {
+		return "", fmt.Errorf("extract %s: %w", assetName, err)
+	}
+	if err := replaceFile(exePath, binary); err != nil {
+		return "", err
+	}
+
+	return r.TagName, nil
+}
+
+func get(ctx context.Context, client *http.Client, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: unexpected status %s", url, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+func download(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	body, err := get(ctx, client, url)
+	if err != nil {
+		return nil, fmt.Errorf("download release: %w", err)
+	}
+	defer body.Close()
+
+	contents, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("download release: %w", err)
+	}
+
+	return contents, nil
+}
+
+// verifyChecksum compares the SHA-256 sum of archive with the one published alongside it (in sha256sum format)
+func verifyChecksum(ctx context.Context, client *http.Client, url string, archive []byte) error {
+	contents, err := download(ctx, client, url)
+	if err != nil {
+		return err
+	}
+
+	fields := strings.Fields(string(contents))
+	if len(fields) == 0 {
+		return errors.New("verify release checksum: empty checksum file")
+	}
+	sum := sha256.Sum256(archive)
+	if !strings.EqualFold(fields[0], hex.EncodeToString(sum[:])) {
+		return fmt.Errorf("verify release checksum: expected %s, got %x", fields[0], sum)
+	}
+
+	return nil
+}
+
+// extractBinary returns the contents of the qnapexporter binary from a .tar.gz archive
+func extractBinary(archive []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in archive", binaryName)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if hdr.Typeflag == tar.TypeReg && filepath.Base(hdr.Name) == binaryName {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// replaceFile atomically replaces path with contents, keeping its permissions
+func replaceFile(path string, contents []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("replace binary: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.new")
+	if err != nil {
+		return fmt.Errorf("replace binary: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(contents); err != nil {
+		f.Close()
+		return fmt.Errorf("replace binary: %w", err)
+	}
+	if err := f.Chmod(info.Mode().Perm()); err != nil {
+		f.Close()
+		return fmt.Errorf("replace binary: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("replace binary: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("replace binary: %w", err)
+	}
+
+	return nil
+}
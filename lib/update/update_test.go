@@ -0,0 +1,166 @@
+package update
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsNewer(t *testing.T) {
+	tests := map[string]struct {
+		current, latest string
+		expected        bool
+	}{
+		"newer patch":       {current: "v1.2.3", latest: "v1.2.4", expected: true},
+		"newer minor":       {current: "v1.2.3", latest: "v1.10.0", expected: true},
+		"same":              {current: "v1.2.3", latest: "v1.2.3", expected: false},
+		"older":             {current: "v2.0.0", latest: "v1.9.9", expected: false},
+		"without prefix":    {current: "1.2", latest: "v1.2.1", expected: true},
+		"pre-release":       {current: "v1.2.3-rc1", latest: "v1.2.3", expected: false},
+		"development build": {current: "dev", latest: "v1.2.3", expected: false},
+		"invalid latest":    {current: "v1.2.3", latest: "nightly", expected: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, IsNewer(tc.current, tc.latest))
+		})
+	}
+}
+
+func TestChecker(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprint(w, `{"tag_name":"v1.3.0","assets":[]}`)
+	}))
+	defer srv.Close()
+
+	c := NewChecker(srv.URL, "v1.2.0")
+	latest, available := c.Latest()
+	assert.Empty(t, latest)
+	assert.False(t, available)
+	assert.True(t, c.CheckedAt().IsZero())
+
+	c.Refresh(time.Hour, logging.Discard())
+	assert.Eventually(t, func() bool {
+		_, available := c.Latest()
+		return available
+	}, time.Second, 10*time.Millisecond)
+	latest, _ = c.Latest()
+	assert.Equal(t, "v1.3.0", latest)
+	assert.WithinDuration(t, time.Now(), c.CheckedAt(), time.Second)
+
+	// The last check is recent enough
+	c.Refresh(time.Hour, logging.Discard())
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestSelfUpdate(t *testing.T) {
+	archive := newArchive(t, map[string]string{"README.md": "readme", "bin/qnapexporter": "new binary"})
+	sum := sha256.Sum256(archive)
+
+	tests := map[string]struct {
+		current         string
+		checksum        string
+		noChecksum      bool
+		expectedVersion string
+		expectedBinary  string
+		expectedErr     string
+	}{
+		"update": {
+			current:         "v1.0.0",
+			checksum:        fmt.Sprintf("%x  qnapexporter.tar.gz\n", sum),
+			expectedVersion: "v1.1.0",
+			expectedBinary:  "new binary",
+		},
+		"up to date": {
+			current:        "v1.1.0",
+			expectedBinary: "old binary",
+		},
+		"checksum mismatch": {
+			current:        "v1.0.0",
+			checksum:       "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef  qnapexporter.tar.gz\n",
+			expectedBinary: "old binary",
+			expectedErr:    "verify release checksum",
+		},
+		"no checksum": {
+			current:        "v1.0.0",
+			noChecksum:     true,
+			expectedBinary: "old binary",
+			expectedErr:    "refusing to install an unverified binary",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+				assets := `{"name":"qnapexporter.tar.gz","browser_download_url":"%[1]s/archive"}`
+				if !tc.noChecksum {
+					assets += `,{"name":"qnapexporter.tar.gz.sha256","browser_download_url":"%[1]s/sha256"}`
+				}
+				fmt.Fprintf(w, `{"tag_name":"v1.1.0","assets":[`+assets+`]}`, srv.URL)
+			})
+			mux.HandleFunc("/archive", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write(archive) })
+			mux.HandleFunc("/sha256", func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, tc.checksum) })
+
+			exePath := filepath.Join(t.TempDir(), "qnapexporter")
+			require.NoError(t, os.WriteFile(exePath, []byte("old binary"), 0755))
+
+			version, err := selfUpdate(context.Background(), srv.Client(), srv.URL+"/latest", tc.current, exePath)
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedVersion, version)
+
+			contents, err := os.ReadFile(exePath)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedBinary, string(contents))
+
+			info, err := os.Stat(exePath)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+			entries, err := os.ReadDir(filepath.Dir(exePath))
+			require.NoError(t, err)
+			assert.Len(t, entries, 1, "temporary files are cleaned up")
+		})
+	}
+}
+
+func newArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	b := new(bytes.Buffer)
+	gz := gzip.NewWriter(b)
+	tw := tar.NewWriter(gz)
+	for name, contents := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(contents)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	return b.Bytes()
+}
@@ -0,0 +1,247 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Backend runs the commands and reads the procfs and sysfs files the collectors get their data from,
+// so that they can be served from captured data (see MockBackend) instead of a NAS
+type Backend interface {
+	// Command runs name with args, adding env to its environment, and returns its standard output.
+	// An error reporting a non-zero exit status has an ExitCode method.
+	Command(ctx context.Context, env []string, name string, args ...string) ([]byte, error)
+	LookPath(file string) (string, error)
+
+	ReadFile(path string) ([]byte, error)
+	ReadDir(path string) ([]fs.DirEntry, error)
+	Stat(path string) (fs.FileInfo, error)
+	Readlink(path string) (string, error)
+	EvalSymlinks(path string) (string, error)
+	Glob(pattern string) ([]string, error)
+}
+
+// System runs the commands and reads the files through a Backend. The zero value uses the local system.
+type System struct {
+	backend Backend
+}
+
+// NewSystem returns a System using b, or the local system if b is nil
+func NewSystem(b Backend) System {
+	return System{backend: b}
+}
+
+func (s System) b() Backend {
+	if s.backend == nil {
+		return osBackend{}
+	}
+
+	return s.backend
+}
+
+// ReadDir reads the directory through the backend
+func (s System) ReadDir(path string) ([]fs.DirEntry, error) {
+	return s.b().ReadDir(path)
+}
+
+// Stat returns information about the file through the backend
+func (s System) Stat(path string) (fs.FileInfo, error) {
+	return s.b().Stat(path)
+}
+
+// Readlink returns the destination of the symbolic link through the backend
+func (s System) Readlink(path string) (string, error) {
+	return s.b().Readlink(path)
+}
+
+// EvalSymlinks returns the path after following the symbolic links through the backend
+func (s System) EvalSymlinks(path string) (string, error) {
+	return s.b().EvalSymlinks(path)
+}
+
+// Glob returns the paths matching pattern through the backend
+func (s System) Glob(pattern string) ([]string, error) {
+	return s.b().Glob(pattern)
+}
+
+// osBackend runs the commands and reads the files of the local system
+type osBackend struct{}
+
+func (osBackend) Command(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+	c := command(ctx, name, args...)
+	c.Env = append(c.Env, env...)
+
+	return c.Output()
+}
+
+func (osBackend) LookPath(file string) (string, error) {
+	if !strings.Contains(file, "/") {
+		execEnvironment.mu.RLock()
+		paths := execEnvironment.paths
+		execEnvironment.mu.RUnlock()
+
+		for _, dir := range paths {
+			path := filepath.Join(dir, file)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() && info.Mode()&0o111 != 0 {
+				return path, nil
+			}
+		}
+	}
+
+	return exec.LookPath(file)
+}
+
+func (osBackend) ReadFile(path string) ([]byte, error)       { return os.ReadFile(path) }
+func (osBackend) ReadDir(path string) ([]fs.DirEntry, error) { return os.ReadDir(path) }
+func (osBackend) Stat(path string) (fs.FileInfo, error)      { return os.Stat(path) }
+func (osBackend) Readlink(path string) (string, error)       { return os.Readlink(path) }
+func (osBackend) EvalSymlinks(path string) (string, error)   { return filepath.EvalSymlinks(path) }
+func (osBackend) Glob(pattern string) ([]string, error)      { return filepath.Glob(pattern) }
+
+// MockCommandsDir is the directory of a MockBackend holding the output of the commands
+const MockCommandsDir = "commands"
+
+// MockBackend serves the commands and files from a directory, e.g. captured from a NAS, so that the exporter can run
+// without QNAP hardware. A file is read from the same path under the directory (e.g. <dir>/proc/mdstat), and the output
+// of a command from <dir>/commands/<command line>, where the command line is the base name of the command followed
+// by its arguments, separated by spaces, with each slash replaced by an underscore (e.g. "smartctl -A _dev_sda").
+// The exit status of a command is read from the same file name with a .exit suffix, if present.
+type MockBackend struct {
+	dir string
+}
+
+// NewMockBackend returns a backend serving the commands and files from dir
+func NewMockBackend(dir string) *MockBackend {
+	dir = filepath.Clean(dir)
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	return &MockBackend{dir: dir}
+}
+
+// mockExitError reports the non-zero exit status of a mocked command
+type mockExitError struct {
+	code int
+}
+
+func (e mockExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func (e mockExitError) ExitCode() int {
+	return e.code
+}
+
+// mockCommandFile returns the name of the file holding the output of the command
+func mockCommandFile(name string, args []string) string {
+	line := strings.Join(append([]string{filepath.Base(name)}, args...), " ")
+
+	return strings.ReplaceAll(line, "/", "_")
+}
+
+func (b *MockBackend) Command(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(b.dir, MockCommandsDir, mockCommandFile(name, args))
+	output, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no mock output for %q: %w", mockCommandFile(name, args), exec.ErrNotFound)
+		}
+
+		return nil, err
+	}
+
+	if status, err := os.ReadFile(path + ".exit"); err == nil {
+		code, err := strconv.Atoi(strings.TrimSpace(string(status)))
+		if err != nil {
+			return nil, fmt.Errorf("parse mock exit status of %q: %w", mockCommandFile(name, args), err)
+		}
+		if code != 0 {
+			return output, mockExitError{code: code}
+		}
+	}
+
+	return output, nil
+}
+
+// LookPath finds the commands for which an output is available, returning their base name
+func (b *MockBackend) LookPath(file string) (string, error) {
+	name := filepath.Base(file)
+	entries, err := os.ReadDir(filepath.Join(b.dir, MockCommandsDir))
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	for _, entry := range entries {
+		if entry.Name() == name || strings.HasPrefix(entry.Name(), name+" ") {
+			return name, nil
+		}
+	}
+
+	return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+}
+
+func (b *MockBackend) path(path string) string {
+	return filepath.Join(b.dir, path)
+}
+
+// unmock maps a path under the directory back to the path on the NAS
+func (b *MockBackend) unmock(path string) (string, error) {
+	rel, err := filepath.Rel(b.dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("%s is outside of the mock directory %s", path, b.dir)
+	}
+
+	return "/" + filepath.ToSlash(rel), nil
+}
+
+func (b *MockBackend) ReadFile(path string) ([]byte, error)       { return os.ReadFile(b.path(path)) }
+func (b *MockBackend) ReadDir(path string) ([]fs.DirEntry, error) { return os.ReadDir(b.path(path)) }
+func (b *MockBackend) Stat(path string) (fs.FileInfo, error)      { return os.Stat(b.path(path)) }
+func (b *MockBackend) Readlink(path string) (string, error)       { return os.Readlink(b.path(path)) }
+
+func (b *MockBackend) EvalSymlinks(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(b.path(path))
+	if err != nil {
+		return "", err
+	}
+
+	return b.unmock(resolved)
+}
+
+func (b *MockBackend) Glob(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(b.path(pattern))
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(matches))
+	for _, m := range matches {
+		if path, err := b.unmock(m); err == nil {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths, nil
+}
+
+// isExitError reports whether err reports the non-zero exit status of a command, returning the status
+func isExitError(err error) (int, bool) {
+	var exitErr interface{ ExitCode() int }
+	if !errors.As(err, &exitErr) {
+		return 0, false
+	}
+
+	return exitErr.ExitCode(), true
+}
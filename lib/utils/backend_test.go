@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockBackend(t *testing.T) {
+	dir := t.TempDir()
+	writeMockFile(t, dir, "proc/mdstat", "Personalities : [raid1]\n")
+	writeMockFile(t, dir, "sys/class/net/eth0/address", "24:5e:be:00:00:01\n")
+	writeMockFile(t, dir, "sys/devices/virtual/block/md1/stat", "1 2 3\n")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sys/class/block"), 0o755))
+	require.NoError(t, os.Symlink("../../devices/virtual/block/md1", filepath.Join(dir, "sys/class/block/md1")))
+	writeMockFile(t, dir, "commands/getsysinfo model", "TS-453D\n")
+	writeMockFile(t, dir, "commands/smartctl -A _dev_sda", "SMART output\n")
+	writeMockFile(t, dir, "commands/smartctl -A _dev_sda.exit", "4\n")
+
+	s := NewSystem(NewMockBackend(dir))
+	ctx := context.Background()
+
+	output, err := s.ExecCommand(ctx, "/sbin/getsysinfo", "model")
+	require.NoError(t, err)
+	assert.Equal(t, "TS-453D", output)
+
+	output, exitCode, err := s.ExecCommandWithExitCode(ctx, "smartctl", "-A", "/dev/sda")
+	require.NoError(t, err)
+	assert.Equal(t, "SMART output", output)
+	assert.Equal(t, 4, exitCode)
+
+	_, err = s.ExecCommand(ctx, "getsysinfo", "hdnum")
+	assert.ErrorIs(t, err, exec.ErrNotFound)
+
+	path, err := s.LookPath("/sbin/getsysinfo")
+	require.NoError(t, err)
+	assert.Equal(t, "getsysinfo", path)
+	_, err = s.LookPath("hal_app")
+	assert.ErrorIs(t, err, exec.ErrNotFound)
+
+	content, err := s.ReadFile("/proc/mdstat")
+	require.NoError(t, err)
+	assert.Equal(t, "Personalities : [raid1]", content)
+
+	matches, err := s.Glob("/sys/class/net/*/address")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/sys/class/net/eth0/address"}, matches)
+
+	resolved, err := s.EvalSymlinks("/sys/class/block/md1")
+	require.NoError(t, err)
+	assert.Equal(t, "/sys/devices/virtual/block/md1", resolved)
+
+	_, err = s.Stat("/proc/diskstats")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func writeMockFile(t *testing.T, dir string, name string, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
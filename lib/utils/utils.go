@@ -1,14 +1,38 @@
 package utils
 
 import (
+	"context"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
+// localeEnv forces the C locale on the commands, so that their output (e.g. decimal separators, messages)
+// does not depend on the locale configured on the NAS
+var localeEnv = []string{"LC_ALL=C", "LANG=C"}
+
+// execEnvironment holds the extra search paths and environment variables of the commands, see SetExecEnvironment
+var execEnvironment struct {
+	mu    sync.RWMutex
+	paths []string
+	env   []string
+}
+
+// execCount counts the processes started through the Exec* functions
+var execCount atomic.Int64
+
+// ExecCount returns the number of processes started through the Exec* functions
+func ExecCount() int64 {
+	return execCount.Load()
+}
+
 // ReadFile reads the entire contents of a file as a string
-func ReadFile(f string) (string, error) {
-	contents, err := os.ReadFile(f)
+func (s System) ReadFile(f string) (string, error) {
+	contents, err := s.b().ReadFile(f)
 	if err != nil {
 		return "", err
 	}
@@ -17,8 +41,8 @@ func ReadFile(f string) (string, error) {
 }
 
 // ReadFileLines reads the entire contents of a file as an array of lines
-func ReadFileLines(f string) ([]string, error) {
-	contents, err := ReadFile(f)
+func (s System) ReadFileLines(f string) ([]string, error) {
+	contents, err := s.ReadFile(f)
 	if err != nil {
 		return nil, err
 	}
@@ -26,25 +50,55 @@ func ReadFileLines(f string) ([]string, error) {
 	return strings.Split(contents, "\n"), nil
 }
 
-// ExecCommand executes a command and returns the standard output, as well as any error
-func ExecCommand(cmd string, args ...string) (string, error) {
-	var (
-		err    error
-		output []byte
-	)
+// WriteFileAtomic writes data to a temporary file which then replaces the file at path,
+// so that a crash or power loss never leaves a truncated file behind
+func WriteFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
 
-	c := exec.Command(cmd, args...)
-	if output, err = c.Output(); err != nil {
+// ExecCommand executes a command and returns the standard output, as well as any error.
+// The command is killed if ctx is done before it completes.
+func (s System) ExecCommand(ctx context.Context, cmd string, args ...string) (string, error) {
+	execCount.Add(1)
+	output, err := s.b().Command(ctx, nil, cmd, args...)
+	if err != nil {
 		return "", err
 	}
 
 	return strings.TrimSpace(string(output)), nil
 }
 
+// ExecCommandWithEnv executes a command with extra environment variables (e.g. credentials, which must not
+// appear in the process list), and returns the standard output, which is also returned on error, as well as any error
+func (s System) ExecCommandWithEnv(ctx context.Context, env []string, cmd string, args ...string) (string, error) {
+	execCount.Add(1)
+	output, err := s.b().Command(ctx, env, cmd, args...)
+
+	return strings.TrimSpace(string(output)), err
+}
+
 // ExecCommandGetLines executes a command and returns the standard output
 // as an array of lines, as well as any error
-func ExecCommandGetLines(cmd string, args ...string) ([]string, error) {
-	output, err := ExecCommand(cmd, args...)
+func (s System) ExecCommandGetLines(ctx context.Context, cmd string, args ...string) ([]string, error) {
+	output, err := s.ExecCommand(ctx, cmd, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -64,3 +118,75 @@ func FindMatchingLines(token string, output string) []string {
 
 	return matchingLines
 }
+
+// ExecCommandWithExitCode executes a command and returns the standard output and exit code.
+// A non-zero exit code is not considered an error, since some tools (e.g. smartctl)
+// use it to report status bits while still producing valid output
+func (s System) ExecCommandWithExitCode(ctx context.Context, cmd string, args ...string) (string, int, error) {
+	execCount.Add(1)
+	output, err := s.b().Command(ctx, nil, cmd, args...)
+	if err != nil {
+		if ctx.Err() != nil {
+			// The process was killed
+			return "", -1, ctx.Err()
+		}
+
+		exitCode, ok := isExitError(err)
+		if !ok {
+			return "", -1, err
+		}
+
+		return strings.TrimSpace(string(output)), exitCode, nil
+	}
+
+	return strings.TrimSpace(string(output)), 0, nil
+}
+
+// SetExecEnvironment configures the commands run through the Exec* functions, since the location of the QTS tools
+// varies between firmware versions: paths are searched for the commands (and prepended to their PATH) before the
+// PATH inherited by the exporter, and env holds extra environment variables, as KEY=value
+func SetExecEnvironment(paths []string, env []string) {
+	execEnvironment.mu.Lock()
+	defer execEnvironment.mu.Unlock()
+
+	execEnvironment.paths = append([]string(nil), paths...)
+	execEnvironment.env = append([]string(nil), env...)
+}
+
+// LookPath searches for an executable named file in the paths set with SetExecEnvironment, then in PATH.
+// A file containing a slash is looked up as is.
+func (s System) LookPath(file string) (string, error) {
+	return s.b().LookPath(file)
+}
+
+func command(ctx context.Context, cmd string, args ...string) *exec.Cmd {
+	if path, err := (osBackend{}).LookPath(cmd); err == nil {
+		cmd = path
+	}
+
+	c := exec.CommandContext(ctx, cmd, args...)
+	c.Env = append(os.Environ(), localeEnv...)
+
+	execEnvironment.mu.RLock()
+	defer execEnvironment.mu.RUnlock()
+
+	if len(execEnvironment.paths) > 0 {
+		sep := string(os.PathListSeparator)
+		c.Env = append(c.Env, "PATH="+strings.Join(execEnvironment.paths, sep)+sep+os.Getenv("PATH"))
+	}
+	// Later values take precedence, so the configured variables may even override the locale
+	c.Env = append(c.Env, execEnvironment.env...)
+
+	return c
+}
+
+// ParseFloat parses a number read from command output, accepting either a dot or a single comma
+// as the decimal separator (e.g. "12.5" or "12,5"), since some tools ignore the C locale
+func ParseFloat(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if strings.Count(s, ",") == 1 && !strings.Contains(s, ".") {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+
+	return strconv.ParseFloat(s, 64)
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFloat(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		"integer":        {input: "42", want: 42},
+		"decimal dot":    {input: "12.5", want: 12.5},
+		"decimal comma":  {input: "12,5", want: 12.5},
+		"surrounding ws": {input: " 3,25\n", want: 3.25},
+		"both":           {input: "1,234.5", wantErr: true},
+		"text":           {input: "N/A", wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseFloat(tc.input)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "snapshot.json")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0o644))
+
+	require.NoError(t, WriteFileAtomic(path, []byte("new")))
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(contents))
+	// The temporary file was renamed, so only the target file is left
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	assert.Error(t, WriteFileAtomic(filepath.Join(dir, "missing", "snapshot.json"), []byte("new")))
+}
+
+func TestExecEnvironment(t *testing.T) {
+	t.Cleanup(func() { SetExecEnvironment(nil, nil) })
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "qnaptool")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho \"$QNAP_VAR $LC_ALL\"\n"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notexecutable"), nil, 0o644))
+
+	_, err := System{}.LookPath("qnaptool")
+	assert.Error(t, err)
+
+	SetExecEnvironment([]string{dir}, []string{"QNAP_VAR=set", "LC_ALL=en_US.UTF-8"})
+
+	path, err := System{}.LookPath("qnaptool")
+	require.NoError(t, err)
+	assert.Equal(t, script, path)
+	_, err = System{}.LookPath("notexecutable")
+	assert.Error(t, err)
+
+	output, err := System{}.ExecCommand(context.Background(), "qnaptool")
+	require.NoError(t, err)
+	assert.Equal(t, "set en_US.UTF-8", output)
+
+	output, err = System{}.ExecCommand(context.Background(), "sh", "-c", "echo $PATH")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(output, dir+":"), output)
+}
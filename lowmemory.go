@@ -0,0 +1,37 @@
+package main
+
+import (
+	"math"
+	"os"
+	"runtime/debug"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+)
+
+const (
+	// lowMemoryGCPercent makes the garbage collector run when the heap grew by 20% instead of 100%
+	lowMemoryGCPercent = 20
+	// lowMemoryLimit is the soft memory limit of the Go runtime in low-memory mode
+	lowMemoryLimit = 32 << 20
+	// defaultGCPercent is the default of the Go runtime, without GOGC
+	defaultGCPercent = 100
+)
+
+// tuneGarbageCollector makes the garbage collector more aggressive in low-memory mode, and restores its defaults
+// otherwise. The GOGC and GOMEMLIMIT environment variables take precedence.
+func tuneGarbageCollector(lowMemory bool, logger *logging.Logger) {
+	gcPercent, limit := defaultGCPercent, int64(math.MaxInt64)
+	if lowMemory {
+		gcPercent, limit = lowMemoryGCPercent, lowMemoryLimit
+	}
+
+	if _, found := os.LookupEnv("GOGC"); !found {
+		debug.SetGCPercent(gcPercent)
+	}
+	if _, found := os.LookupEnv("GOMEMLIMIT"); !found {
+		debug.SetMemoryLimit(limit)
+	}
+	if lowMemory {
+		logger.Info("Low-memory mode enabled", "gc_percent", gcPercent, "memory_limit_bytes", limit)
+	}
+}
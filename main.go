@@ -2,53 +2,203 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/pedropombeiro/qnapexporter/lib/config"
 	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+	"github.com/pedropombeiro/qnapexporter/lib/exporter/csv"
+	"github.com/pedropombeiro/qnapexporter/lib/exporter/influx"
 	"github.com/pedropombeiro/qnapexporter/lib/exporter/prometheus"
+	"github.com/pedropombeiro/qnapexporter/lib/federate"
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
 	"github.com/pedropombeiro/qnapexporter/lib/notifications"
 	"github.com/pedropombeiro/qnapexporter/lib/notifications/tagextractor"
+	"github.com/pedropombeiro/qnapexporter/lib/push"
+	"github.com/pedropombeiro/qnapexporter/lib/sink"
 	"github.com/pedropombeiro/qnapexporter/lib/status"
+	"github.com/pedropombeiro/qnapexporter/lib/systemd"
+	"github.com/pedropombeiro/qnapexporter/lib/telemetry"
+	"github.com/pedropombeiro/qnapexporter/lib/update"
 	"github.com/pedropombeiro/qnapexporter/lib/utils"
+	promclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 const (
-	metricsEndpoint      = "/metrics"
-	notificationEndpoint = "/notification"
+	metricsEndpoint       = "/metrics"
+	notificationEndpoint  = "/notification"
+	metricCatalogEndpoint = "/api/metric-catalog"
+	statusEndpoint        = "/api/status"
+	historyEndpoint       = "/api/history"
+	logEndpoint           = "/api/log"
+	faultsEndpoint        = "/api/faults"
+	federateEndpoint      = "/federate"
+	healthzEndpoint       = "/healthz"
+	readyzEndpoint        = "/readyz"
+
+	formatPrometheus = "prometheus"
+	formatInflux     = "influx"
+	formatCSV        = "csv"
+
+	// sinkTimeout is the maximum time a sink may take to write a collection
+	sinkTimeout = 30 * time.Second
+	// logBufferSize is the number of recent log entries shown on the status page and served on /api/log
+	logBufferSize = 200
 )
 
 var (
 	healthCheckExpiry   time.Time
 	healthCheckValidity time.Duration = time.Duration(5 * time.Minute)
+
+	// collectorHung is set when the watchdog detects a hung collector, to stop the systemd watchdog keepalives
+	collectorHung atomic.Bool
+
+	// faultInjector holds the faults injected in the collectors through the faults endpoint, if enabled
+	faultInjector *prometheus.FaultInjector
+	// mockBackend serves the commands and files from the --mock-data directory, if set
+	mockBackend utils.Backend
+	// servedEndpoints are the paths served by the HTTP server, reported in the configuration summary
+	servedEndpoints []string
 )
 
 type httpServerArgs struct {
-	exporter    exporter.Exporter
-	port        string
-	healthcheck string
-	logger      *log.Logger
+	exporter exporter.Exporter
+	// influxExporter renders the metrics as InfluxDB line protocol
+	influxExporter exporter.Exporter
+	// csvExporter renders the metrics as CSV, e.g. to paste them into a spreadsheet
+	csvExporter exporter.Exporter
+	// format is the default metrics format, which can be overridden with the `format` query parameter
+	format string
+	// metricsHandler serves the metrics endpoint through a client_golang registry, if set
+	metricsHandler http.Handler
+	// history holds the latest collections served on the history endpoint, if enabled
+	history *sink.History
+	// federator serves the metrics of the other exporters on the federation endpoint, if any is configured
+	federator *federate.Federator
+	// readyMaxFailingRatio is the ratio of failing collectors above which the readiness endpoint reports a failure
+	readyMaxFailingRatio float64
+	addresses            []string
+	web                  webConfig
+	healthcheck          string
+	logger               *logging.Logger
 }
 
 func main() {
 	runtime.GOMAXPROCS(0)
 
-	port := flag.String("port", ":9094", "Port to serve at (e.g. :9094).")
-	pingTarget := flag.String("ping-target", "", "Host to periodically ping (e.g. 1.1.1.1).")
+	subcommands := map[string]func(args []string) error{
+		"bench": runBench,
+		"diff":  runDiff,
+		"docs":  runDocs,
+	}
+	if len(os.Args) > 1 {
+		if run, found := subcommands[os.Args[1]]; found {
+			if err := run(os.Args[2:]); err != nil {
+				log.Fatalln(err.Error())
+			}
+			return
+		}
+	}
+
+	configFile := flag.String("config", "", "Path to a YAML configuration file, reloaded on SIGHUP (e.g. /etc/qnapexporter.yml).")
+	port := flag.String("port", ":9094", "Address to serve at (e.g. :9094), or comma-separated addresses (e.g. 0.0.0.0:9094,[::]:9094 to listen on both stacks explicitly).")
+	var pingTargets stringList
+	flag.Var(&pingTargets, "ping-target", "Host to periodically ping (e.g. 1.1.1.1). Can be repeated or comma-separated to ping several hosts.")
+	pingMode := flag.String("ping-mode", prometheus.PingModeICMP, "How to probe the ping target: icmp (falls back to udp without raw socket privilege), udp (unprivileged ICMP), tcp (TCP connect to host:port) or tls (TLS handshake with host:port).")
+	upsAddress := flag.String("ups-address", "127.0.0.1", "Address of the NUT daemon (e.g. 127.0.0.1:3493).")
+	var upsNames stringList
+	flag.Var(&upsNames, "ups-name", "Name of a UPS device to export, as configured in the NUT daemon (e.g. ups). Can be repeated or comma-separated (defaults to all the UPS devices).")
+	scrapeCacheTTL := flag.Duration("scrape-cache-ttl", 0, "How long the metrics of a collection are served to the following scrapes (defaults to 0, i.e. only concurrent scrapes share a collection).")
+	upsCacheTTL := flag.Duration("ups-cache-ttl", 10*time.Second, "How long the UPS metrics are served from the cache before being refreshed in the background (0 disables the cache).")
+	hostname := flag.String("hostname", "", "Value of the node label (defaults to empty, i.e. determined by --hostname-source).")
+	hostnameSource := flag.String("hostname-source", prometheus.HostnameSourceOS, "Where to read the node label from: os (HOSTNAME environment variable or hostname command) or qts (server name configured in QTS).")
+	nodeLabel := flag.String("node-label", prometheus.DefaultNodeLabel, "Name of the label holding the hostname, e.g. to avoid conflicts with Prometheus relabeling rules.")
+	metricNamespace := flag.String("metric-namespace", prometheus.MetricNamespaceNode, "Namespace of the metric names: node (current names) or qnap (qnap_* names, which don't collide with node_exporter).")
+	deprecatedNamesUntil := flag.String("deprecated-names-until", "", "Last day (e.g. 2027-06-30) on which the old names of the renamed metrics are emitted along with the new ones (defaults to empty, i.e. until they are dropped from the exporter).")
+	machineIDLabel := flag.String("machine-id-label", "", "Name of a label holding a stable identifier derived from the hardware serial number, added to every metric (e.g. machine_id, defaults to empty, i.e. disabled).")
+	dropNodeLabel := flag.Bool("drop-node-label", false, "Don't add the node label to the metrics.")
+	var staticLabels stringList
+	flag.Var(&staticLabels, "label", "Static label added to every metric, as name=value (e.g. site=home). Can be repeated.")
+	var getsysinfoCommands stringList
+	flag.Var(&getsysinfoCommands, "getsysinfo-command", "Extra getsysinfo subcommand whose numeric output is exported as node_getsysinfo_value (e.g. \"sysfan 3\"). Can be repeated.")
+	var execPaths stringList
+	flag.Var(&execPaths, "exec-path", "Directory searched for the tools (e.g. getsysinfo) before the inherited PATH. Can be repeated.")
+	var execEnv stringList
+	flag.Var(&execEnv, "exec-env", "Environment variable of the commands run by the collectors, as NAME=value. Can be repeated.")
+	var healthWeights stringList
+	flag.Var(&healthWeights, "health-weight", "Weight of a component of qnap_health_score, as component=weight (e.g. ups=0 to exclude the UPS). Can be repeated.")
 	healthcheck := flag.String("healthcheck", os.Getenv("HEALTHCHECK_CONFIG"), "Healthcheck service to ping every 5 minutes (currently supported: healthchecks.io:<check-id>).")
 	grafanaURL := flag.String("grafana-url", os.Getenv("GRAFANA_URL"), "Grafana host (e.g.: https://grafana.example.com).")
 	grafanaAuthToken := flag.String("grafana-auth-token", os.Getenv("GRAFANA_AUTH_TOKEN"), "Grafana authorization token.")
 	grafanaTags := flag.String("grafana-tags", os.Getenv("GRAFANA_TAGS"), "Grafana annotation tags, separated by quotes (default: 'nas').")
+	collectorTimeout := flag.Duration("collector-timeout", 0, "Maximum time each collector may take before its metrics are dropped (e.g. 5s, defaults to 0, i.e. no timeout).")
+	watchdogTimeout := flag.Duration("watchdog-timeout", 0, "Time after which a running collector is considered hung (e.g. 2m, defaults to 0, i.e. disabled).")
+	watchdogExit := flag.Bool("watchdog-exit", false, "Exit when the watchdog detects a hung collector, so that the service manager restarts the exporter.")
+	staleValueMaxAge := flag.Duration("stale-value-max-age", 0, "How long the last-known-good metrics of a failing collector are served instead of dropping its series (e.g. 5m, defaults to 0, i.e. disabled).")
+	errorComments := flag.Bool("error-comments", false, "Write collector errors as '## error' comment lines in the metrics output (legacy behavior).")
+	stateFile := flag.String("state-file", "", "Path of the file where the state which must survive restarts (e.g. S.M.A.R.T. baselines) is kept (defaults to empty, i.e. kept in memory).")
+	smbProbeShare := flag.String("smb-probe-share", "", "Share to which a file is periodically written and read back over SMB, to probe the file serving path (e.g. //127.0.0.1/probe, defaults to empty, i.e. disabled).")
+	smbProbeUser := flag.String("smb-probe-user", os.Getenv("SMB_PROBE_USER"), "User name used by the SMB probe (defaults to empty, i.e. guest access).")
+	smbProbePassword := flag.String("smb-probe-password", os.Getenv("SMB_PROBE_PASSWORD"), "Password used by the SMB probe.")
+	eventLogSyslog := flag.String("event-log-syslog", "", "Address of a syslog server to forward new QTS system events to (e.g. udp://192.168.1.10:514, defaults to empty, i.e. disabled).")
+	firmwareCheckInterval := flag.Duration("firmware-check-interval", 0, "How often to check whether a newer QTS firmware is available, reported by qnap_firmware_update_available (e.g. 24h, defaults to 0, i.e. disabled).")
+	firmwareReleaseURL := flag.String("firmware-release-url", update.DefaultFirmwareReleaseURL, "URL of the QNAP firmware release feed.")
+	updateCheckInterval := flag.Duration("update-check-interval", 0, "How often to check whether a newer release is available, reported by qnap_exporter_update_available (e.g. 24h, defaults to 0, i.e. disabled).")
+	temperatureTrendWindow := flag.Duration("temperature-trend-window", 6*time.Hour, "Period over which the slope of each disk temperature is computed from the S.M.A.R.T. readings (0 disables it).")
+	volumeForecastWindow := flag.Duration("volume-forecast-window", 7*24*time.Hour, "Period over which the growth of the used space of each volume is computed to estimate when it will be full (0 disables it).")
+	topProcesses := flag.Int("top-processes", 0, "Number of processes reported by the processes collector, by CPU and by memory usage, grouped by command name (defaults to 0, i.e. disabled).")
+	powerDrawWatts := flag.Float64("power-draw-watts", 0, "Estimated power draw of the NAS in watts, from which the energy is computed when no UPS reports its power (defaults to 0, i.e. the power draw of the model quirks, if any).")
+	electricityPrice := flag.Float64("electricity-price", 0, "Price of a kWh, from which the cost of the energy consumed by the NAS is estimated (defaults to 0, i.e. disabled).")
+	electricityCurrency := flag.String("electricity-currency", "EUR", "Currency of --electricity-price, reported by the currency label of node_energy_cost_total.")
+	trafficResetDay := flag.Int("traffic-reset-day", 1, "Day of the month (1-28) on which the monthly traffic counters of the interfaces are reset, e.g. the billing day of a metered connection.")
+	volumeProbe := flag.Bool("volume-probe", false, "Time the read of the root directory of each volume on every scrape, to catch a slow storage pool (wakes sleeping disks when the directory isn't cached).")
+	volumeProbeWrite := flag.Bool("volume-probe-write", false, "Make --volume-probe time the synchronous write of a small file at the root of each volume instead (wakes the disks up on every scrape, keeping them from sleeping).")
+	quirksFile := flag.String("quirks-file", "", "Path of a YAML file mapping NAS models to sensor quirks (fan count, fan names, bogus readings), which take precedence over the built-in ones.")
+	safeMode := flag.Bool("safe-mode", false, "Only enable the collectors reading procfs and sysfs, which neither run commands nor wake the disks.")
+	lowMemory := flag.Bool("low-memory", false, "Trade CPU for memory on the models with 1-2 GB of RAM: disable the caches, run the collectors one at a time and collect the garbage more aggressively.")
+	selfUpdate := flag.Bool("self-update", false, "Replace the executable with the latest release, if newer, and exit.")
+	format := flag.String("format", formatPrometheus, "Default metrics format: prometheus, influx (InfluxDB line protocol) or csv. Can be overridden per request with the format query parameter (e.g. /metrics?format=influx).")
+	warmUp := flag.Bool("warm-up", true, "Collect the metrics once in the background at startup, so that the first scrape doesn't pay for the environment discovery.")
+	usePromhttp := flag.Bool("promhttp", false, "Serve metrics through a prometheus/client_golang registry (enables OpenMetrics negotiation and promhttp instrumentation metrics).")
+	tlsCert := flag.String("tls-cert", "", "Path to a TLS certificate file, to serve HTTPS (requires --tls-key).")
+	tlsKey := flag.String("tls-key", "", "Path to the TLS private key file matching --tls-cert.")
+	webAuthUser := flag.String("web-auth-user", os.Getenv("WEB_AUTH_USER"), "User name required to access the HTTP endpoints through basic authentication (requires --web-auth-password).")
+	webAuthPassword := flag.String("web-auth-password", os.Getenv("WEB_AUTH_PASSWORD"), "Password required to access the HTTP endpoints through basic authentication.")
+	webAuthMode := flag.String("web-auth-mode", webAuthModeBasic, "How the basic authentication credentials are verified: basic (against --web-auth-user and --web-auth-password) or qts (against the accounts of the QTS administrators group).")
+	webAuthQtsURL := flag.String("web-auth-qts-url", "http://127.0.0.1:8080", "URL of the QTS web administration, against which the credentials are verified with --web-auth-mode=qts.")
+	pushMode := flag.String("push-mode", "", "Periodically push the metrics instead of waiting to be scraped: pushgateway or remote_write (defaults to empty, i.e. disabled).")
+	pushURL := flag.String("push-url", "", "Pushgateway URL (e.g. http://pushgateway:9091) or remote write URL (e.g. http://prometheus:9090/api/v1/write) to push to.")
+	pushInterval := flag.Duration("push-interval", time.Minute, "Interval between the collections handed to the push, MQTT and history sinks.")
+	pushJob := flag.String("push-job", "qnapexporter", "Job name used when pushing to a Pushgateway.")
+	mqttBroker := flag.String("mqtt-broker", "", "MQTT broker to publish the metrics to as JSON (e.g. tcp://192.168.1.10:1883, defaults to empty, i.e. disabled).")
+	mqttTopic := flag.String("mqtt-topic", "qnapexporter/metrics", "MQTT topic to publish the metrics to.")
+	mqttUser := flag.String("mqtt-user", os.Getenv("MQTT_USER"), "User name used to connect to the MQTT broker.")
+	mqttPassword := flag.String("mqtt-password", os.Getenv("MQTT_PASSWORD"), "Password used to connect to the MQTT broker.")
+	historySize := flag.Int("history-size", 0, "Number of collections kept in memory and served on /api/history (defaults to 0, i.e. disabled).")
+	readyMaxFailingRatio := flag.Float64("ready-max-failing-ratio", 0.5, "Ratio of collectors which may fail before /readyz reports the exporter as not ready (between 0 and 1).")
+	mockData := flag.String("mock-data", "", "Directory holding data captured from a NAS, from which the commands and files are served instead of the local system, e.g. to develop dashboards without QNAP hardware. It applies to the whole process.")
+	enableTelemetry := flag.Bool("telemetry", false, "Send an anonymous daily report of the NAS model, QTS firmware version and collector outcomes to --telemetry-url, to help prioritize compatibility work (opt-in).")
+	telemetryURL := flag.String("telemetry-url", "", "URL of the endpoint receiving the telemetry reports, as JSON.")
+	enableFaultInjection := flag.Bool("enable-fault-injection", false, "Serve "+faultsEndpoint+", which makes collectors fail or slow down on demand to test the alert rules. Not meant for production use.")
 	logFile := flag.String("log", "", "Log file path (defaults to empty, i.e. STDOUT).")
+	logLevel := flag.String("log-level", "info", "Minimum level of the log entries: debug, info, warn or error.")
+	logFormat := flag.String("log-format", logging.FormatText, "Format of the log entries: text or json.")
 	defaultUsage := flag.Usage
 	flag.Usage = func() {
 		fmt.Fprintf(flag.CommandLine.Output(), "qnapexporter version %s (%s-%s) built on %s\n", utils.VERSION, utils.REVISION, utils.BRANCH, utils.BUILT)
@@ -69,10 +219,94 @@ func main() {
 
 		logWriter = lf
 	}
-	logger := log.New(logWriter, "", log.LstdFlags)
+	level, err := logging.ParseLevel(*logLevel)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	if err := logging.ValidateFormat(*logFormat); err != nil {
+		log.Fatalln(err.Error())
+	}
+	logBuffer := logging.NewBuffer(logBufferSize)
+	logger := logging.New(logWriter, level, *logFormat, logBuffer)
+
+	if *selfUpdate {
+		if err := runSelfUpdate(logger); err != nil {
+			log.Fatalln(err.Error())
+		}
+		return
+	}
+
+	labels, err := parseStaticLabels(staticLabels)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	weights, err := parseHealthWeights(healthWeights)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	env, err := parseExecEnv(execEnv)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	baseConfig := config.Config{
+		Port:                   *port,
+		PingTarget:             pingTargets.String(),
+		PingMode:               *pingMode,
+		UpsAddress:             *upsAddress,
+		UpsName:                upsNames.String(),
+		UpsCacheTTL:            *upsCacheTTL,
+		ScrapeCacheTTL:         *scrapeCacheTTL,
+		Hostname:               *hostname,
+		HostnameSource:         *hostnameSource,
+		MetricNamespace:        *metricNamespace,
+		DeprecatedNamesUntil:   *deprecatedNamesUntil,
+		NodeLabel:              *nodeLabel,
+		DropNodeLabel:          *dropNodeLabel,
+		MachineIDLabel:         *machineIDLabel,
+		Labels:                 labels,
+		CollectorTimeout:       *collectorTimeout,
+		GetsysinfoCommands:     getsysinfoCommands,
+		ExecPaths:              execPaths,
+		ExecEnv:                env,
+		WatchdogTimeout:        *watchdogTimeout,
+		WatchdogExit:           *watchdogExit,
+		StaleValueMaxAge:       *staleValueMaxAge,
+		ErrorComments:          *errorComments,
+		StateFile:              *stateFile,
+		SmbProbeShare:          *smbProbeShare,
+		SmbProbeUser:           *smbProbeUser,
+		SmbProbePassword:       *smbProbePassword,
+		EventLogSyslog:         *eventLogSyslog,
+		UpdateCheckInterval:    *updateCheckInterval,
+		FirmwareCheckInterval:  *firmwareCheckInterval,
+		FirmwareReleaseURL:     *firmwareReleaseURL,
+		TemperatureTrendWindow: *temperatureTrendWindow,
+		VolumeForecastWindow:   *volumeForecastWindow,
+		TopProcesses:           *topProcesses,
+		PowerDrawWatts:         *powerDrawWatts,
+		ElectricityPrice:       *electricityPrice,
+		ElectricityCurrency:    *electricityCurrency,
+		TrafficResetDay:        *trafficResetDay,
+		VolumeProbe:            *volumeProbe,
+		VolumeProbeWrite:       *volumeProbeWrite,
+		SafeMode:               *safeMode,
+		LowMemory:              *lowMemory,
+		QuirksFile:             *quirksFile,
+		HealthWeights:          weights,
+	}
+	cfg, err := loadConfig(*configFile, baseConfig)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	tuneGarbageCollector(cfg.LowMemory, logger)
+	web, err := newWebConfig(*tlsCert, *tlsKey, *webAuthMode, *webAuthUser, *webAuthPassword, *webAuthQtsURL, logger)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
 
 	serverStatus := &status.Status{
 		MetricsEndpoint: metricsEndpoint,
+		Log:             logBuffer,
 		ExporterStatus: exporter.Status{
 			Branch:   utils.BRANCH,
 			Revision: utils.REVISION,
@@ -84,17 +318,67 @@ func main() {
 		serverStatus.NotificationEndpoint = notificationEndpoint
 	}
 
-	config := prometheus.ExporterConfig{
-		PingTarget: *pingTarget,
-		Logger:     logger,
+	if *mockData != "" {
+		if mockBackend, err = newMockBackend(*mockData); err != nil {
+			log.Fatalln(err.Error())
+		}
+		logger.Warn("Serving mock data", "dir", *mockData)
+	}
+	if *enableFaultInjection {
+		faultInjector = prometheus.NewFaultInjector()
+		logger.Warn("Fault injection is enabled", "endpoint", faultsEndpoint)
 	}
-	e := prometheus.NewExporter(config, &serverStatus.ExporterStatus)
 
+	servedEndpoints = []string{metricsEndpoint, metricCatalogEndpoint, statusEndpoint, logEndpoint, healthzEndpoint, readyzEndpoint}
+	if serverStatus.NotificationEndpoint != "" {
+		servedEndpoints = append(servedEndpoints, notificationEndpoint)
+	}
+	if faultInjector != nil {
+		servedEndpoints = append(servedEndpoints, faultsEndpoint)
+	}
+	if *historySize > 0 {
+		servedEndpoints = append(servedEndpoints, historyEndpoint)
+	}
+	if len(cfg.FederateTargets) > 0 {
+		servedEndpoints = append(servedEndpoints, federateEndpoint)
+	}
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+
+	exporterConfig := newExporterConfig(cfg, logger, cancelFn)
+	logConfigSummary(exporterConfig, logger)
+	e := prometheus.NewExporter(exporterConfig, &serverStatus.ExporterStatus)
+
+	if *readyMaxFailingRatio < 0 || *readyMaxFailingRatio > 1 {
+		log.Fatalln("--ready-max-failing-ratio must be between 0 and 1")
+	}
 	args := httpServerArgs{
-		exporter:    e,
-		port:        *port,
-		healthcheck: *healthcheck,
-		logger:      logger,
+		exporter:             e,
+		readyMaxFailingRatio: *readyMaxFailingRatio,
+		addresses:            cfg.ListenAddresses(),
+		web:                  web,
+		healthcheck:          *healthcheck,
+		logger:               logger,
+	}
+	switch *format {
+	case formatPrometheus, formatInflux, formatCSV:
+	default:
+		log.Fatalf("unknown metrics format %q\n", *format)
+	}
+	watchdogInterval, err := systemd.WatchdogInterval()
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	args.format = *format
+	// Only render the NAS metrics, leaving out the Go runtime and process metrics
+	nasRegistry := promclient.NewRegistry()
+	nasRegistry.MustRegister(e)
+	args.influxExporter = influx.NewExporter(nasRegistry)
+	args.csvExporter = csv.NewExporter(nasRegistry)
+
+	registry := newRegistry(e)
+	if *usePromhttp {
+		args.metricsHandler = newPromhttpHandler(registry, logger)
 	}
 	notifCenterAnnotator := notifications.NewRegionMatchingAnnotator(
 		*grafanaURL,
@@ -113,8 +397,6 @@ func main() {
 		logger,
 	)
 
-	ctx, cancelFn := context.WithCancel(context.Background())
-
 	// Setup our Ctrl+C handler
 	exitCh := make(chan os.Signal, 1)
 	signal.Notify(exitCh, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
@@ -122,30 +404,608 @@ func main() {
 		defer cancelFn()
 
 		// Wait for program exit
-		<-exitCh
+		sig := <-exitCh
+
+		if cfg.StateFile != "" {
+			dir := filepath.Dir(cfg.StateFile)
+			logger.Info("Writing the shutdown snapshot", "signal", sig, "dir", dir)
+			if err := writeShutdownSnapshot(dir, e, serverStatus, logger); err != nil {
+				logger.Error("Error writing the shutdown snapshot", "err", err)
+			}
+		}
 	}()
 
+	// Reload the configuration file on SIGHUP
+	reloadCh := make(chan os.Signal, 1)
+	signal.Notify(reloadCh, syscall.SIGHUP)
+	go handleConfigReload(ctx, reloadCh, *configFile, baseConfig, cfg, e, logger, cancelFn)
+
 	go func() { _ = handleDockerEvents(ctx, args, dockerAnnotator, &serverStatus.ExporterStatus) }()
 
-	err := serveHTTP(ctx, args, notifCenterAnnotator, serverStatus)
+	if watchdogInterval > 0 {
+		logger.Info("Sending systemd watchdog keepalives", "interval", watchdogInterval/2)
+		go systemd.RunWatchdog(ctx, watchdogInterval, func() bool { return !collectorHung.Load() }, logger)
+	}
+
+	var sinks []sink.Sink
+	if *pushMode != "" {
+		pushConfig := push.Config{Mode: *pushMode, URL: *pushURL, Job: *pushJob}
+		switch {
+		case pushConfig.Mode != push.ModePushgateway && pushConfig.Mode != push.ModeRemoteWrite:
+			log.Fatalf("unknown push mode %q\n", pushConfig.Mode)
+		case pushConfig.URL == "":
+			log.Fatalln("--push-url is required when --push-mode is set")
+		}
+
+		logger.Info("Pushing metrics", "url", pushConfig.URL, "mode", pushConfig.Mode, "interval", *pushInterval)
+		sinks = append(sinks, push.NewSink(pushConfig))
+	}
+	if *mqttBroker != "" {
+		mqttSink, err := sink.NewMQTT(sink.MQTTConfig{Broker: *mqttBroker, Topic: *mqttTopic, User: *mqttUser, Password: *mqttPassword})
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+		logger.Info("Publishing metrics to MQTT", "broker", *mqttBroker, "topic", *mqttTopic, "interval", *pushInterval)
+		sinks = append(sinks, mqttSink)
+	}
+	if len(cfg.FederateTargets) > 0 {
+		logger.Info("Federating the metrics of other exporters", "targets", len(cfg.FederateTargets), "endpoint", federateEndpoint)
+		args.federator = federate.New(federateTargets(cfg.FederateTargets), logger)
+	}
+	if *historySize > 0 {
+		args.history = sink.NewHistory(*historySize)
+		sinks = append(sinks, args.history)
+	}
+	if len(sinks) > 0 {
+		if *pushInterval <= 0 {
+			log.Fatalln("--push-interval must be positive")
+		}
+
+		go sink.Run(ctx, registry, *pushInterval, sink.NewFanOut(sinks, sinkTimeout, logger))
+	}
+
+	if *enableTelemetry {
+		if *telemetryURL == "" {
+			log.Fatalln("--telemetry-url is required when --telemetry is set")
+		}
+
+		logger.Info("Sending anonymous telemetry reports", "url", *telemetryURL, "interval", telemetry.Interval)
+		go telemetry.Run(ctx, *telemetryURL, func() exporter.Status { return serverStatus.ExporterStatus }, logger)
+	}
+
+	if *warmUp {
+		go runWarmUp(ctx, e, logger)
+	}
+
+	err = serveHTTP(ctx, args, notifCenterAnnotator, serverStatus)
 	if err != nil {
-		log.Println(err.Error())
+		logger.Error("Error serving HTTP requests", "err", err)
 	}
 	os.Exit(1)
 }
 
+// runWarmUp collects the metrics once, discarding them, so that the environment discovery, the cold getsysinfo calls
+// and the UPS connection setup happen before the first scrape instead of slowing it down
+func runWarmUp(ctx context.Context, e exporter.Exporter, logger *logging.Logger) {
+	start := time.Now()
+	if err := e.WriteMetrics(ctx, io.Discard); err != nil {
+		logger.Warn("Warm-up collection completed with errors", "duration", time.Since(start), "err", err)
+		return
+	}
+
+	logger.Info("Warm-up collection completed", "duration", time.Since(start))
+}
+
+// runSelfUpdate replaces the running executable with the latest release
+func runSelfUpdate(logger *logging.Logger) error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exePath, err = filepath.EvalSymlinks(exePath); err != nil {
+		return err
+	}
+
+	logger.Info("Checking for a newer release", "running", utils.VERSION)
+	version, err := update.SelfUpdate(context.Background(), update.DefaultReleaseURL, utils.VERSION, exePath)
+	if err != nil {
+		return fmt.Errorf("self-update: %w", err)
+	}
+	if version == "" {
+		logger.Info("Already running the latest version", "version", utils.VERSION)
+		return nil
+	}
+
+	logger.Info("Updated the executable, restart the exporter to use it", "path", exePath, "version", version)
+	return nil
+}
+
+// stringList is a flag which can be repeated, collecting its values
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseStaticLabels parses name=value label specifications
+func parseStaticLabels(specs []string) (map[string]string, error) {
+	labels := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		name, value, found := strings.Cut(spec, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid label %q, expected name=value", spec)
+		}
+		labels[name] = value
+	}
+
+	return labels, nil
+}
+
+// parseExecEnv parses NAME=value environment variable specifications
+func parseExecEnv(specs []string) (map[string]string, error) {
+	env := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		name, value, found := strings.Cut(spec, "=")
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid environment variable %q, expected NAME=value", spec)
+		}
+		env[name] = value
+	}
+
+	return env, nil
+}
+
+// parseHealthWeights parses component=weight health score weight specifications
+func parseHealthWeights(specs []string) (map[string]float64, error) {
+	weights := make(map[string]float64, len(specs))
+	for _, spec := range specs {
+		component, value, found := strings.Cut(spec, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid health weight %q, expected component=weight", spec)
+		}
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid health weight %q: %w", spec, err)
+		}
+		weights[component] = weight
+	}
+
+	return weights, nil
+}
+
+func loadConfig(path string, baseConfig config.Config) (config.Config, error) {
+	cfg := baseConfig
+	if path != "" {
+		if err := cfg.Load(path); err != nil {
+			return cfg, err
+		}
+		if err := cfg.Validate(prometheus.CollectorNames()); err != nil {
+			return cfg, err
+		}
+	}
+
+	switch cfg.PingMode {
+	case prometheus.PingModeICMP, prometheus.PingModeUDP, prometheus.PingModeTCP, prometheus.PingModeTLS:
+	default:
+		return cfg, fmt.Errorf("unknown ping mode %q", cfg.PingMode)
+	}
+
+	switch cfg.HostnameSource {
+	case prometheus.HostnameSourceOS, prometheus.HostnameSourceQTS:
+	default:
+		return cfg, fmt.Errorf("unknown hostname source %q", cfg.HostnameSource)
+	}
+
+	if err := prometheus.ValidateMetricNamespace(cfg.MetricNamespace); err != nil {
+		return cfg, err
+	}
+	if _, err := prometheus.ParseDeprecatedNamesUntil(cfg.DeprecatedNamesUntil); err != nil {
+		return cfg, err
+	}
+	if err := prometheus.ValidateLabels(cfg.NodeLabel, cfg.Labels); err != nil {
+		return cfg, err
+	}
+	if err := prometheus.ValidateMachineIDLabel(cfg.MachineIDLabel, cfg.NodeLabel, cfg.Labels); err != nil {
+		return cfg, err
+	}
+	if err := prometheus.ValidateCollectorLabels(cfg.NodeLabel, cfg.Labels, cfg.CollectorLabels); err != nil {
+		return cfg, err
+	}
+	if err := prometheus.ValidateHealthWeights(cfg.HealthWeights); err != nil {
+		return cfg, err
+	}
+
+	if cfg.PowerDrawWatts < 0 {
+		return cfg, fmt.Errorf("invalid power draw %v: must not be negative", cfg.PowerDrawWatts)
+	}
+	if cfg.ElectricityPrice < 0 {
+		return cfg, fmt.Errorf("invalid electricity price %v: must not be negative", cfg.ElectricityPrice)
+	}
+
+	if cfg.TrafficResetDay < 1 || cfg.TrafficResetDay > 28 {
+		return cfg, fmt.Errorf("invalid traffic reset day %d: must be between 1 and 28", cfg.TrafficResetDay)
+	}
+
+	if err := federate.ValidateTargets(federateTargets(cfg.FederateTargets)); err != nil {
+		return cfg, err
+	}
+
+	if cfg.EventLogSyslog != "" {
+		if _, _, err := prometheus.ParseSyslogAddress(cfg.EventLogSyslog); err != nil {
+			return cfg, err
+		}
+	}
+
+	return cfg, nil
+}
+
+func federateTargets(targets []config.FederateTarget) []federate.Target {
+	result := make([]federate.Target, 0, len(targets))
+	for _, t := range targets {
+		result = append(result, federate.Target{Name: t.Name, URL: t.URL, Username: t.Username, Password: t.Password})
+	}
+
+	return result
+}
+
+// newMockBackend returns a backend serving the data captured in dir. The collectors reading procfs and sysfs
+// through gopsutil (cpu, loadavg, uptime, filesystem and volumedevices) don't go through the backend: they are pointed
+// to the same directory through the HOST_* environment variables, so the mock data applies to the whole process.
+func newMockBackend(dir string) (utils.Backend, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("mock data: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("mock data: %s is not a directory", dir)
+	}
+
+	for env, subdir := range map[string]string{"HOST_PROC": "proc", "HOST_SYS": "sys", "HOST_ETC": "etc", "HOST_DEV": "dev"} {
+		if err := os.Setenv(env, filepath.Join(dir, subdir)); err != nil {
+			return nil, err
+		}
+	}
+
+	return utils.NewMockBackend(dir), nil
+}
+
+func newExporterConfig(cfg config.Config, logger *logging.Logger, cancelFn context.CancelFunc) prometheus.ExporterConfig {
+	// The end date was validated when the configuration was loaded
+	deprecatedNamesUntil, _ := prometheus.ParseDeprecatedNamesUntil(cfg.DeprecatedNamesUntil)
+	exporterConfig := prometheus.ExporterConfig{
+		PingTargets:            cfg.PingTargets(),
+		PingMode:               cfg.PingMode,
+		UpsAddress:             cfg.UpsAddress,
+		UpsNames:               cfg.UpsNames(),
+		UpsCacheTTL:            cfg.UpsCacheTTL,
+		ScrapeCacheTTL:         cfg.ScrapeCacheTTL,
+		Hostname:               cfg.Hostname,
+		HostnameSource:         cfg.HostnameSource,
+		MetricNamespace:        cfg.MetricNamespace,
+		DeprecatedNamesUntil:   deprecatedNamesUntil,
+		NodeLabel:              cfg.NodeLabel,
+		DropNodeLabel:          cfg.DropNodeLabel,
+		MachineIDLabel:         cfg.MachineIDLabel,
+		StaticLabels:           cfg.Labels,
+		CollectorLabels:        cfg.CollectorLabels,
+		Collectors:             cfg.Collectors,
+		GetsysinfoCommands:     cfg.GetsysinfoCommands,
+		ExecPaths:              cfg.ExecPaths,
+		ExecEnv:                cfg.ExecEnv,
+		CollectorTimeout:       cfg.CollectorTimeout,
+		WatchdogTimeout:        cfg.WatchdogTimeout,
+		StaleValueMaxAge:       cfg.StaleValueMaxAge,
+		ErrorComments:          cfg.ErrorComments,
+		StateFile:              cfg.StateFile,
+		SmbProbeShare:          cfg.SmbProbeShare,
+		SmbProbeUser:           cfg.SmbProbeUser,
+		SmbProbePassword:       cfg.SmbProbePassword,
+		EventLogSyslog:         cfg.EventLogSyslog,
+		UpdateCheckInterval:    cfg.UpdateCheckInterval,
+		FirmwareCheckInterval:  cfg.FirmwareCheckInterval,
+		FirmwareReleaseURL:     cfg.FirmwareReleaseURL,
+		TemperatureTrendWindow: cfg.TemperatureTrendWindow,
+		VolumeForecastWindow:   cfg.VolumeForecastWindow,
+		TopProcesses:           cfg.TopProcesses,
+		PowerDrawWatts:         cfg.PowerDrawWatts,
+		ElectricityPrice:       cfg.ElectricityPrice,
+		ElectricityCurrency:    cfg.ElectricityCurrency,
+		TrafficResetDay:        cfg.TrafficResetDay,
+		VolumeProbe:            cfg.VolumeProbe,
+		VolumeProbeWrite:       cfg.VolumeProbeWrite,
+		SafeMode:               cfg.SafeMode,
+		LowMemory:              cfg.LowMemory,
+		QuirksFile:             cfg.QuirksFile,
+		HealthWeights:          cfg.HealthWeights,
+		Faults:                 faultInjector,
+		ListenAddresses:        cfg.ListenAddresses(),
+		Endpoints:              servedEndpoints,
+		Backend:                mockBackend,
+		Logger:                 logger,
+	}
+	exporterConfig.OnHungCollector = func(collector string) {
+		collectorHung.Store(true)
+		if cfg.WatchdogExit {
+			logger.Error("Exiting due to hung collector", "collector", collector)
+			cancelFn()
+		}
+	}
+
+	return exporterConfig
+}
+
+// logConfigSummary logs the settings which tell what the exporter is configured to do
+func logConfigSummary(c prometheus.ExporterConfig, logger *logging.Logger) {
+	summary := c.Summary()
+	keyvals := make([]interface{}, 0, 2*len(summary))
+	for _, s := range summary {
+		keyvals = append(keyvals, s.Name, s.Value)
+	}
+
+	logger.Info("Effective configuration", keyvals...)
+}
+
+func handleConfigReload(
+	ctx context.Context,
+	reloadCh <-chan os.Signal,
+	path string,
+	baseConfig, currentConfig config.Config,
+	e prometheus.ConfigurableExporter,
+	logger *logging.Logger,
+	cancelFn context.CancelFunc,
+) {
+	for {
+		select {
+		case <-reloadCh:
+			if path == "" {
+				logger.Warn("Received SIGHUP, but no configuration file was specified")
+				continue
+			}
+
+			logger.Info("Reloading configuration", "path", path)
+			cfg, err := loadConfig(path, baseConfig)
+			if err != nil {
+				logger.Error("Error reloading configuration, keeping previous one", "path", path, "err", err)
+				continue
+			}
+			if cfg.Port != currentConfig.Port {
+				logger.Warn("Changing the listen address requires a restart", "current", currentConfig.Port, "new", cfg.Port)
+				cfg.Port = currentConfig.Port
+			}
+
+			if cfg.LowMemory != currentConfig.LowMemory {
+				tuneGarbageCollector(cfg.LowMemory, logger)
+			}
+
+			exporterConfig := newExporterConfig(cfg, logger, cancelFn)
+			logConfigSummary(exporterConfig, logger)
+			e.ApplyConfig(exporterConfig)
+			currentConfig = cfg
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func newRegistry(e prometheus.ConfigurableExporter) *promclient.Registry {
+	registry := promclient.NewRegistry()
+	registry.MustRegister(
+		e,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return registry
+}
+
+func newPromhttpHandler(registry *promclient.Registry, logger *logging.Logger) http.Handler {
+	return promhttp.InstrumentMetricHandler(
+		registry,
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+			ErrorLog:          logger.StdLogger(logging.LevelError),
+			ErrorHandling:     promhttp.ContinueOnError,
+			EnableOpenMetrics: true,
+		}),
+	)
+}
+
 func handleMetricsHTTPRequest(w http.ResponseWriter, r *http.Request, args httpServerArgs) {
-	w.Header().Add("Content-Type", "text/plain")
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = args.format
+	}
+
+	e := args.exporter
+	switch format {
+	case formatPrometheus:
+	case formatInflux:
+		e = args.influxExporter
+	case formatCSV:
+		e = args.csvExporter
+	default:
+		http.Error(w, fmt.Sprintf("unknown format %q", format), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	include := r.URL.Query()["include"]
+	if len(include) > 0 {
+		if format != formatPrometheus {
+			http.Error(w, "include is only supported with the prometheus format", http.StatusBadRequest)
+			return
+		}
+
+		filter, err := prometheus.ParseLabelFilter(include)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx = prometheus.WithLabelFilter(ctx, filter)
+	}
+
+	collect, exclude := r.URL.Query()["collect[]"], r.URL.Query()["exclude[]"]
+	if len(collect) > 0 || len(exclude) > 0 {
+		if format != formatPrometheus {
+			http.Error(w, "collect[] and exclude[] are only supported with the prometheus format", http.StatusBadRequest)
+			return
+		}
+
+		selection, err := prometheus.ParseCollectorSelection(collect, exclude)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx = prometheus.WithCollectorSelection(ctx, selection)
+	}
+
+	// The promhttp handler doesn't support filtering, so filtered requests are served by the exporter directly
+	filtered := len(include) > 0 || len(collect) > 0 || len(exclude) > 0
+	if args.metricsHandler != nil && format == formatPrometheus && !filtered {
+		handleHealthcheckStart(args.healthcheck, args.logger)
+		args.metricsHandler.ServeHTTP(w, r)
+		handleHealthcheckEnd(args.healthcheck, nil, args.logger)
+		return
+	}
 
-	handleHealthcheckStart(args.healthcheck)
+	if format == formatCSV {
+		w.Header().Add("Content-Type", "text/csv")
+		w.Header().Add("Content-Disposition", fmt.Sprintf(`attachment; filename="qnapexporter-%s.csv"`, time.Now().Format("20060102-150405")))
+	} else {
+		w.Header().Add("Content-Type", "text/plain")
+	}
+
+	handleHealthcheckStart(args.healthcheck, args.logger)
 
-	err := args.exporter.WriteMetrics(w)
+	err := e.WriteMetrics(ctx, w)
 	if err != nil {
-		args.logger.Println(err.Error())
+		args.logger.Error("Error writing metrics", "err", err)
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 
-	handleHealthcheckEnd(args.healthcheck, err)
+	handleHealthcheckEnd(args.healthcheck, err, args.logger)
+}
+
+func handleMetricCatalogHTTPRequest(w http.ResponseWriter, r *http.Request, logger *logging.Logger) {
+	w.Header().Add("Content-Type", "application/json")
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(prometheus.MetricCatalog())
+	if err != nil {
+		logger.Error("Error writing response", "path", r.URL.Path, "err", err)
+	}
+}
+
+func handleHistoryHTTPRequest(w http.ResponseWriter, r *http.Request, history *sink.History, logger *logging.Logger) {
+	w.Header().Add("Content-Type", "application/json")
+	w.Header().Add("Cache-Control", "no-cache")
+
+	err := json.NewEncoder(w).Encode(history.Collections())
+	if err != nil {
+		logger.Error("Error writing response", "path", r.URL.Path, "err", err)
+	}
+}
+
+func handleStatusHTTPRequest(w http.ResponseWriter, r *http.Request, serverStatus *status.Status, logger *logging.Logger) {
+	w.Header().Add("Content-Type", "application/json")
+	w.Header().Add("Cache-Control", "no-cache")
+
+	err := serverStatus.WriteJSON(w)
+	if err != nil {
+		logger.Error("Error writing response", "path", r.URL.Path, "err", err)
+	}
+}
+
+func handleLogHTTPRequest(w http.ResponseWriter, r *http.Request, buffer *logging.Buffer, logger *logging.Logger) {
+	w.Header().Add("Content-Type", "application/json")
+	w.Header().Add("Cache-Control", "no-cache")
+
+	entries := buffer.Entries()
+	if entries == nil {
+		entries = []logging.Entry{}
+	}
+
+	err := json.NewEncoder(w).Encode(entries)
+	if err != nil {
+		logger.Error("Error writing response", "path", r.URL.Path, "err", err)
+	}
+}
+
+// handleFaultsHTTPRequest lists the injected faults on GET, injects a fault on POST
+// (e.g. collector=smart&fail=true&delay=30s&for=10m) and clears the faults on DELETE (e.g. collector=smart, or every fault)
+func handleFaultsHTTPRequest(w http.ResponseWriter, r *http.Request, faults *prometheus.FaultInjector, logger *logging.Logger) {
+	w.Header().Add("Cache-Control", "no-cache")
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		fault, err := parseFault(r)
+		if err == nil {
+			err = faults.Set(fault)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.Warn("Injected fault", "collector", fault.Collector, "fail", fault.Fail, "delay", fault.Delay, "expires", fault.Expires)
+	case http.MethodDelete:
+		collector := r.FormValue("collector")
+		faults.Clear(collector)
+		logger.Info("Cleared faults", "collector", collector)
+	default:
+		w.Header().Add("Allow", "GET, POST, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(faults.Faults())
+	if err != nil {
+		logger.Error("Error writing response", "path", r.URL.Path, "err", err)
+	}
+}
+
+// parseFault parses the collector, fail, delay and for parameters of a fault injection request
+func parseFault(r *http.Request) (prometheus.Fault, error) {
+	fault := prometheus.Fault{Collector: r.FormValue("collector")}
+
+	var err error
+	if v := r.FormValue("fail"); v != "" {
+		if fault.Fail, err = strconv.ParseBool(v); err != nil {
+			return fault, fmt.Errorf("invalid fail parameter %q", v)
+		}
+	}
+	if v := r.FormValue("delay"); v != "" {
+		if fault.Delay, err = time.ParseDuration(v); err != nil {
+			return fault, fmt.Errorf("invalid delay parameter %q", v)
+		}
+	}
+	if v := r.FormValue("for"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fault, fmt.Errorf("invalid for parameter %q", v)
+		}
+		fault.Expires = time.Now().Add(d).Round(time.Second)
+	}
+
+	return fault, nil
+}
+
+func handleReadyzHTTPRequest(w http.ResponseWriter, r *http.Request, serverStatus *status.Status, maxFailingRatio float64) {
+	w.Header().Add("Cache-Control", "no-cache")
+
+	if err := serverStatus.Ready(maxFailingRatio); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	_, _ = io.WriteString(w, "OK\n")
 }
 
 func handleNotificationHTTPRequest(w http.ResponseWriter, r *http.Request, annotator notifications.Annotator) {
@@ -158,13 +1018,13 @@ func handleNotificationHTTPRequest(w http.ResponseWriter, r *http.Request, annot
 	_, _ = annotator.Post(notification, time.Now())
 }
 
-func handleRootHTTPRequest(w http.ResponseWriter, r *http.Request, serverStatus *status.Status, logger *log.Logger) {
+func handleRootHTTPRequest(w http.ResponseWriter, r *http.Request, serverStatus *status.Status, logger *logging.Logger) {
 	w.Header().Add("Content-Type", "text/html")
 	w.Header().Add("Cache-Control", "no-cache")
 
 	err := serverStatus.WriteHTML(w)
 	if err != nil {
-		logger.Println(err.Error())
+		logger.Error("Error writing response", "path", r.URL.Path, "err", err)
 
 		w.WriteHeader(http.StatusInternalServerError)
 	}
@@ -174,49 +1034,104 @@ func serveHTTP(ctx context.Context, args httpServerArgs, annotator notifications
 	defer args.exporter.Close()
 
 	// handle route using handler function
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/", args.web.withBasicAuth(func(w http.ResponseWriter, r *http.Request) {
 		handleRootHTTPRequest(w, r, serverStatus, args.logger)
-	})
-	http.HandleFunc(metricsEndpoint, func(w http.ResponseWriter, r *http.Request) {
+	}))
+	http.HandleFunc(metricsEndpoint, args.web.withBasicAuth(func(w http.ResponseWriter, r *http.Request) {
 		handleMetricsHTTPRequest(w, r, args)
+	}))
+	http.HandleFunc(metricCatalogEndpoint, args.web.withBasicAuth(func(w http.ResponseWriter, r *http.Request) {
+		handleMetricCatalogHTTPRequest(w, r, args.logger)
+	}))
+	http.HandleFunc(statusEndpoint, args.web.withBasicAuth(func(w http.ResponseWriter, r *http.Request) {
+		handleStatusHTTPRequest(w, r, serverStatus, args.logger)
+	}))
+	http.HandleFunc(logEndpoint, args.web.withBasicAuth(func(w http.ResponseWriter, r *http.Request) {
+		handleLogHTTPRequest(w, r, serverStatus.Log, args.logger)
+	}))
+	if faultInjector != nil {
+		http.HandleFunc(faultsEndpoint, args.web.withBasicAuth(func(w http.ResponseWriter, r *http.Request) {
+			handleFaultsHTTPRequest(w, r, faultInjector, args.logger)
+		}))
+	}
+	if args.federator != nil {
+		http.Handle(federateEndpoint, args.web.withBasicAuth(args.federator.ServeHTTP))
+	}
+	if args.history != nil {
+		http.HandleFunc(historyEndpoint, args.web.withBasicAuth(func(w http.ResponseWriter, r *http.Request) {
+			handleHistoryHTTPRequest(w, r, args.history, args.logger)
+		}))
+	}
+	// The probe endpoints are used by container runtimes, which can't authenticate, and don't expose any data
+	http.HandleFunc(healthzEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "OK\n")
+	})
+	http.HandleFunc(readyzEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		handleReadyzHTTPRequest(w, r, serverStatus, args.readyMaxFailingRatio)
 	})
 	if serverStatus.NotificationEndpoint != "" {
+		// The notification endpoint is called by the QTS Notification Center, which can't authenticate,
+		// and doesn't expose any data, so it is left unprotected
 		http.HandleFunc(notificationEndpoint, func(w http.ResponseWriter, r *http.Request) {
 			serverStatus.LastNotification = time.Now()
 			handleNotificationHTTPRequest(w, r, annotator)
 		})
 	}
 
-	// listen to port
-	server := http.Server{Addr: args.port}
-	server.ErrorLog = args.logger
+	listeners, err := listen(args.addresses)
+	if err != nil {
+		return err
+	}
+	serverStatus.ListenURLs = make([]string, 0, len(listeners))
+	for _, l := range listeners {
+		serverStatus.ListenURLs = append(serverStatus.ListenURLs, args.web.listenURL(l.Addr()))
+	}
+	server := http.Server{}
+	server.ErrorLog = args.logger.StdLogger(logging.LevelError)
 	go func() {
-		log.Printf("Listening to HTTP requests at %s\n", args.port)
+		args.logger.Info("Listening to HTTP requests", "urls", strings.Join(serverStatus.ListenURLs, ","))
+		if _, err := systemd.Notify(systemd.StateReady); err != nil {
+			args.logger.Error("Error notifying systemd", "err", err)
+		}
 
 		// Wait for program exit
 		<-ctx.Done()
 
-		log.Println("Program aborted, exiting...")
+		args.logger.Info("Program aborted, exiting")
+		_, _ = systemd.Notify(systemd.StateStopping)
 		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(5*time.Second))
 		defer cancel()
 		err := server.Shutdown(ctx)
 		if err != nil {
-			log.Println(err.Error())
+			args.logger.Error("Error shutting down the HTTP server", "err", err)
 		}
 	}()
 
-	return server.ListenAndServe()
+	errCh := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			if args.web.tlsEnabled() {
+				errCh <- server.ServeTLS(l, args.web.tlsCert, args.web.tlsKey)
+				return
+			}
+
+			errCh <- server.Serve(l)
+		}(l)
+	}
+
+	// Serve returns as soon as the server is shut down, so the first error is the one worth reporting
+	return <-errCh
 }
 
-func handleHealthcheckStart(healthcheck string) {
-	handleHealthcheck(healthcheck, true, nil)
+func handleHealthcheckStart(healthcheck string, logger *logging.Logger) {
+	handleHealthcheck(healthcheck, true, nil, logger)
 }
 
-func handleHealthcheckEnd(healthcheck string, err error) {
-	handleHealthcheck(healthcheck, false, err)
+func handleHealthcheckEnd(healthcheck string, err error, logger *logging.Logger) {
+	handleHealthcheck(healthcheck, false, err, logger)
 }
 
-func handleHealthcheck(healthcheck string, start bool, err error) {
+func handleHealthcheck(healthcheck string, start bool, err error, logger *logging.Logger) {
 	if healthcheck == "" {
 		return
 	}
@@ -227,7 +1142,7 @@ func handleHealthcheck(healthcheck string, start bool, err error) {
 
 	parts := strings.SplitN(healthcheck, ":", 2)
 	if len(parts) < 2 {
-		log.Printf("Configuration error in healthcheck: %s\n", healthcheck)
+		logger.Error("Configuration error in healthcheck", "healthcheck", healthcheck)
 		return
 	}
 
@@ -251,7 +1166,7 @@ func handleHealthcheck(healthcheck string, start bool, err error) {
 		} else {
 			_, err = client.Head(url)
 		}
-		log.Printf("Sent %s healthcheck ping to %s: %v\n", endpoint, url, err)
+		logger.Debug("Sent healthcheck ping", "endpoint", endpoint, "url", url, "err", err)
 	}
 
 	if !start {
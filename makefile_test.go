@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var makefileLdflagsRegexp = regexp.MustCompile(`-ldflags "([^"]*)"`)
+
+func TestMakefileLdflagsSetVersion(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the exporter")
+	}
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make is not available")
+	}
+
+	out, err := exec.Command("make", "--no-print-directory", "-n", "build", "PACKAGE_VERSION=v0.0.0-ldflags").Output()
+	require.NoError(t, err)
+	m := makefileLdflagsRegexp.FindSubmatch(out)
+	require.NotNil(t, m, "no -ldflags in %q", out)
+
+	bin := filepath.Join(t.TempDir(), "qnapexporter")
+	out, err = exec.Command("go", "build", "-ldflags", string(m[1]), "-o", bin, ".").CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	// The usage message starts with the version
+	out, _ = exec.Command(bin, "-h").CombinedOutput()
+	assert.Contains(t, string(out), "qnapexporter version v0.0.0-ldflags ")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/pedropombeiro/qnapexporter/lib/exporter"
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+	"github.com/pedropombeiro/qnapexporter/lib/status"
+	"github.com/pedropombeiro/qnapexporter/lib/utils"
+)
+
+const (
+	shutdownMetricsFile = "shutdown-metrics.prom"
+	shutdownStatusFile  = "shutdown-status.json"
+
+	// shutdownSnapshotTimeout bounds the final collection, so that a hung collector doesn't delay the NAS shutdown
+	shutdownSnapshotTimeout = 10 * time.Second
+)
+
+// writeShutdownSnapshot collects the metrics one last time and writes them to dir along with the status,
+// so that the last readings before an unexpected NAS shutdown are available for post-mortem analysis
+func writeShutdownSnapshot(dir string, e exporter.Exporter, serverStatus *status.Status, logger *logging.Logger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownSnapshotTimeout)
+	defer cancel()
+
+	var metrics bytes.Buffer
+	if err := e.WriteMetrics(ctx, &metrics); err != nil {
+		// Write the metrics of the collectors which succeeded anyway
+		logger.Error("Error collecting the shutdown snapshot metrics", "err", err)
+	}
+	if err := utils.WriteFileAtomic(filepath.Join(dir, shutdownMetricsFile), metrics.Bytes()); err != nil {
+		return fmt.Errorf("write shutdown snapshot: %w", err)
+	}
+
+	var statusJSON bytes.Buffer
+	if err := serverStatus.WriteJSON(&statusJSON); err != nil {
+		return fmt.Errorf("write shutdown snapshot: %w", err)
+	}
+	if err := utils.WriteFileAtomic(filepath.Join(dir, shutdownStatusFile), statusJSON.Bytes()); err != nil {
+		return fmt.Errorf("write shutdown snapshot: %w", err)
+	}
+
+	return nil
+}
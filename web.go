@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/pedropombeiro/qnapexporter/lib/logging"
+)
+
+type webConfig struct {
+	tlsCert, tlsKey string
+	// auth verifies the credentials of the requests (nil leaves the endpoints unprotected)
+	auth authenticator
+}
+
+func newWebConfig(tlsCert, tlsKey, authMode, authUser, authPassword, qtsURL string, logger *logging.Logger) (webConfig, error) {
+	if (tlsCert == "") != (tlsKey == "") {
+		return webConfig{}, errors.New("--tls-cert and --tls-key must be specified together")
+	}
+
+	c := webConfig{tlsCert: tlsCert, tlsKey: tlsKey}
+	switch authMode {
+	case webAuthModeBasic:
+		if (authUser == "") != (authPassword == "") {
+			return webConfig{}, errors.New("--web-auth-user and --web-auth-password must be specified together")
+		}
+		if authUser != "" {
+			c.auth = newStaticAuthenticator(authUser, authPassword)
+		}
+	case webAuthModeQTS:
+		if authUser != "" || authPassword != "" {
+			return webConfig{}, errors.New("--web-auth-user and --web-auth-password can't be used with --web-auth-mode=qts")
+		}
+		auth, err := newQtsAuthenticator(qtsURL, logger)
+		if err != nil {
+			return webConfig{}, err
+		}
+		c.auth = auth
+	default:
+		return webConfig{}, fmt.Errorf("unknown web authentication mode %q", authMode)
+	}
+
+	return c, nil
+}
+
+func (c webConfig) tlsEnabled() bool {
+	return c.tlsCert != ""
+}
+
+// withBasicAuth wraps handler so that it requires credentials accepted by the configured authenticator, if any
+func (c webConfig) withBasicAuth(handler http.HandlerFunc) http.HandlerFunc {
+	if c.auth == nil {
+		return handler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		var valid bool
+		if ok {
+			var err error
+			valid, err = c.auth.authenticate(r.Context(), user, password)
+			if err != nil {
+				http.Error(w, "The credentials could not be verified", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		if !valid {
+			w.Header().Set("WWW-Authenticate", `Basic realm="qnapexporter", charset="UTF-8"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+func hashCredential(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return string(sum[:])
+}
+
+// listen opens a listener on each address. An IPv4 or IPv6 literal only listens on that stack (e.g. [::]:9094 doesn't
+// also accept IPv4 connections), so that 0.0.0.0:9094 and [::]:9094 can be listened to side by side, while a host
+// name or an empty host (e.g. :9094) listens on every available stack.
+func listen(addresses []string) ([]net.Listener, error) {
+	if len(addresses) == 0 {
+		return nil, errors.New("no listen address")
+	}
+
+	listeners := make([]net.Listener, 0, len(addresses))
+	for _, address := range addresses {
+		l, err := net.Listen(listenNetwork(address), address)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return nil, fmt.Errorf("listen on %s: %w", address, err)
+		}
+
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+func listenNetwork(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return "tcp"
+	}
+
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return "tcp"
+	case ip.To4() != nil:
+		return "tcp4"
+	default:
+		return "tcp6"
+	}
+}
+
+// listenURL returns the base URL of the endpoints served on addr, with the brackets required around IPv6 addresses
+func (c webConfig) listenURL(addr net.Addr) string {
+	scheme := "http"
+	if c.tlsEnabled() {
+		scheme = "https"
+	}
+
+	return (&url.URL{Scheme: scheme, Host: addr.String()}).String()
+}